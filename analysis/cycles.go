@@ -0,0 +1,231 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/andre-a-alves/flowchart"
+)
+
+// CycleError is returned by TopologicalOrder when the flowchart has a cycle.
+type CycleError struct {
+	Cycle []*flowchart.Node // Nodes of one cycle, in link order, starting with the first in tree order
+}
+
+// Error names the nodes of the cycle, e.g. "flowchart has a cycle: A -> B -> A".
+func (e *CycleError) Error() string {
+	names := make([]string, 0, len(e.Cycle)+1)
+	for _, node := range e.Cycle {
+		names = append(names, node.Name())
+	}
+	if len(e.Cycle) > 0 {
+		names = append(names, e.Cycle[0].Name())
+	}
+	return fmt.Sprintf("flowchart has a cycle: %s", strings.Join(names, " -> "))
+}
+
+// StronglyConnectedComponents returns the strongly connected components of the graph: the largest
+// groups of nodes in which every node can reach every other. Nodes outside any cycle form a
+// component of their own. Each component lists its nodes in tree order, and components are ordered
+// by their first node.
+func (g *Graph) StronglyConnectedComponents() [][]*flowchart.Node {
+	components := g.components(0)
+	result := make([][]*flowchart.Node, len(components))
+	for i, component := range components {
+		result[i] = g.toNodes(component)
+	}
+	return result
+}
+
+// Cycles returns every elementary cycle of the graph, found with Johnson's algorithm. Each cycle
+// lists its nodes in link order starting with the first in tree order, without repeating it at
+// the end. A node linking to itself is a cycle of one node. Cycles are ordered by their first node,
+// then by the order in which they are found.
+//
+// The number of cycles can grow exponentially with the size of densely linked charts.
+func (g *Graph) Cycles() [][]*flowchart.Node {
+	var cycles [][]*flowchart.Node
+	for s := range g.nodes {
+		// Only cycles whose smallest node is s are searched for, in the component of s among the
+		// nodes from s onwards, so that each cycle is found once.
+		var component []int
+		for _, c := range g.components(s) {
+			if c[0] == s {
+				component = c
+				break
+			}
+		}
+		if len(component) == 1 && !slices.Contains(g.succ[s], s) {
+			continue
+		}
+
+		inComponent := make(map[int]bool, len(component))
+		for _, v := range component {
+			inComponent[v] = true
+		}
+		blocked := make(map[int]bool)
+		blockedBy := make(map[int][]int)
+		var stack []int
+
+		var unblock func(u int)
+		unblock = func(u int) {
+			blocked[u] = false
+			for _, w := range blockedBy[u] {
+				if blocked[w] {
+					unblock(w)
+				}
+			}
+			blockedBy[u] = nil
+		}
+		var circuit func(v int) bool
+		circuit = func(v int) bool {
+			found := false
+			stack = append(stack, v)
+			blocked[v] = true
+			for _, w := range g.succ[v] {
+				if !inComponent[w] {
+					continue
+				}
+				if w == s {
+					cycles = append(cycles, g.toNodes(slices.Clone(stack)))
+					found = true
+				} else if !blocked[w] && circuit(w) {
+					found = true
+				}
+			}
+			if found {
+				unblock(v)
+			} else {
+				for _, w := range g.succ[v] {
+					if inComponent[w] && !slices.Contains(blockedBy[w], v) {
+						blockedBy[w] = append(blockedBy[w], v)
+					}
+				}
+			}
+			stack = stack[:len(stack)-1]
+			return found
+		}
+		circuit(s)
+	}
+	return cycles
+}
+
+// TopologicalOrder returns the nodes ordered so that every node comes before the nodes it links
+// to. Among nodes that could come next, the first in tree order is chosen, so the order is stable.
+// If the flowchart has a cycle, it returns a *CycleError describing one of them.
+func (g *Graph) TopologicalOrder() ([]*flowchart.Node, error) {
+	inDegree := make([]int, len(g.nodes))
+	for i := range g.nodes {
+		inDegree[i] = len(g.pred[i])
+	}
+	var ready, order []int
+	for i := range g.nodes {
+		if inDegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		order = append(order, i)
+		for _, w := range g.succ[i] {
+			inDegree[w]--
+			if inDegree[w] == 0 {
+				index, _ := slices.BinarySearch(ready, w)
+				ready = slices.Insert(ready, index, w)
+			}
+		}
+	}
+
+	if len(order) < len(g.nodes) {
+		for _, component := range g.components(0) {
+			if len(component) > 1 || slices.Contains(g.succ[component[0]], component[0]) {
+				return nil, &CycleError{Cycle: g.toNodes(g.cycleIn(component))}
+			}
+		}
+	}
+	return g.toNodes(order), nil
+}
+
+// components returns the strongly connected components of the graph restricted to the nodes from
+// position first onwards, found with Tarjan's algorithm. Each component is sorted, and components
+// are ordered by their first node.
+func (g *Graph) components(first int) [][]int {
+	index := make(map[int]int)
+	lowLink := make(map[int]int)
+	onStack := make(map[int]bool)
+	var stack []int
+	var components [][]int
+
+	var connect func(v int)
+	connect = func(v int) {
+		index[v] = len(index)
+		lowLink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range g.succ[v] {
+			if w < first {
+				continue
+			}
+			if _, visited := index[w]; !visited {
+				connect(w)
+				lowLink[v] = min(lowLink[v], lowLink[w])
+			} else if onStack[w] {
+				lowLink[v] = min(lowLink[v], index[w])
+			}
+		}
+		if lowLink[v] == index[v] {
+			var component []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			slices.Sort(component)
+			components = append(components, component)
+		}
+	}
+	for v := first; v < len(g.nodes); v++ {
+		if _, visited := index[v]; !visited {
+			connect(v)
+		}
+	}
+	slices.SortFunc(components, func(a, b []int) int { return a[0] - b[0] })
+	return components
+}
+
+// cycleIn returns the shortest cycle through the first node of a strongly connected component
+// that has a cycle, in link order.
+func (g *Graph) cycleIn(component []int) []int {
+	start := component[0]
+	if slices.Contains(g.succ[start], start) {
+		return []int{start}
+	}
+	previous := map[int]int{start: start}
+	queue := []int{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range g.succ[v] {
+			if w == start {
+				cycle := []int{v}
+				for v != start {
+					v = previous[v]
+					cycle = append(cycle, v)
+				}
+				slices.Reverse(cycle)
+				return cycle
+			}
+			if _, seen := previous[w]; !seen && slices.Contains(component, w) {
+				previous[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package analysis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andre-a-alves/flowchart"
+	"github.com/google/go-cmp/cmp"
+)
+
+// chartWithLinks returns a chart of process nodes named by names, linked by pairs of names.
+func chartWithLinks(nodeNames []string, links ...[2]string) *flowchart.Flowchart {
+	f := &flowchart.Flowchart{}
+	nodes := make(map[string]*flowchart.Node)
+	for _, name := range nodeNames {
+		nodes[name] = flowchart.ProcessNode(name, nil)
+		f.Nodes = append(f.Nodes, nodes[name])
+	}
+	for _, link := range links {
+		f.Links = append(f.Links, flowchart.SolidLink(nodes[link[0]], nodes[link[1]], nil))
+	}
+	return f
+}
+
+// nameLists returns the names of each list of nodes.
+func nameLists(lists [][]*flowchart.Node) [][]string {
+	var result [][]string
+	for _, nodes := range lists {
+		result = append(result, names(nodes))
+	}
+	return result
+}
+
+func TestGraph_Cycles(t *testing.T) {
+	tests := []struct {
+		name               string
+		flowchart          *flowchart.Flowchart
+		expectedComponents [][]string
+		expectedCycles     [][]string
+	}{
+		{
+			name:               "acyclic",
+			flowchart:          chartWithLinks([]string{"A", "B", "C"}, [2]string{"A", "B"}, [2]string{"A", "C"}, [2]string{"B", "C"}),
+			expectedComponents: [][]string{{"A"}, {"B"}, {"C"}},
+		},
+		{
+			name:               "self-loop",
+			flowchart:          chartWithLinks([]string{"A", "B"}, [2]string{"A", "A"}, [2]string{"A", "B"}),
+			expectedComponents: [][]string{{"A"}, {"B"}},
+			expectedCycles:     [][]string{{"A"}},
+		},
+		{
+			name: "overlapping cycles",
+			flowchart: chartWithLinks([]string{"A", "B", "C", "D"},
+				[2]string{"A", "B"}, [2]string{"B", "C"}, [2]string{"C", "A"}, [2]string{"B", "A"}, [2]string{"C", "D"}),
+			expectedComponents: [][]string{{"A", "B", "C"}, {"D"}},
+			expectedCycles:     [][]string{{"A", "B"}, {"A", "B", "C"}},
+		},
+		{
+			name: "separate cycles",
+			flowchart: chartWithLinks([]string{"A", "B", "C", "D"},
+				[2]string{"D", "C"}, [2]string{"C", "D"}, [2]string{"A", "B"}, [2]string{"B", "A"}),
+			expectedComponents: [][]string{{"A", "B"}, {"C", "D"}},
+			expectedCycles:     [][]string{{"A", "B"}, {"C", "D"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.flowchart)
+			if diff := cmp.Diff(tt.expectedComponents, nameLists(g.StronglyConnectedComponents())); diff != "" {
+				t.Errorf("StronglyConnectedComponents() mismatch (-expected +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.expectedCycles, nameLists(g.Cycles())); diff != "" {
+				t.Errorf("Cycles() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGraph_TopologicalOrder(t *testing.T) {
+	tests := []struct {
+		name          string
+		flowchart     *flowchart.Flowchart
+		expected      []string
+		expectedCycle []string
+		expectedErr   string
+	}{
+		{
+			name:      "ties broken by tree order",
+			flowchart: chartWithLinks([]string{"D", "C", "B", "A"}, [2]string{"C", "A"}, [2]string{"D", "B"}),
+			expected:  []string{"D", "C", "B", "A"},
+		},
+		{
+			name:      "links reorder nodes",
+			flowchart: chartWithLinks([]string{"A", "B", "C"}, [2]string{"C", "A"}, [2]string{"B", "C"}),
+			expected:  []string{"B", "C", "A"},
+		},
+		{
+			name: "cycle",
+			flowchart: chartWithLinks([]string{"A", "B", "C", "D"},
+				[2]string{"A", "B"}, [2]string{"B", "C"}, [2]string{"C", "D"}, [2]string{"D", "B"}),
+			expectedCycle: []string{"B", "C", "D"},
+			expectedErr:   "flowchart has a cycle: B -> C -> D -> B",
+		},
+		{
+			name:          "self-loop",
+			flowchart:     chartWithLinks([]string{"A"}, [2]string{"A", "A"}),
+			expectedCycle: []string{"A"},
+			expectedErr:   "flowchart has a cycle: A -> A",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := New(tt.flowchart).TopologicalOrder()
+			if diff := cmp.Diff(tt.expected, names(order)); diff != "" {
+				t.Errorf("TopologicalOrder() mismatch (-expected +got):\n%s", diff)
+			}
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("TopologicalOrder() error = %v, expected nil", err)
+				}
+				return
+			}
+			var cycleErr *CycleError
+			if !errors.As(err, &cycleErr) {
+				t.Fatalf("TopologicalOrder() error = %v, expected *CycleError", err)
+			}
+			if diff := cmp.Diff(tt.expectedCycle, names(cycleErr.Cycle)); diff != "" {
+				t.Errorf("CycleError.Cycle mismatch (-expected +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.expectedErr, err.Error()); diff != "" {
+				t.Errorf("Error() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Package analysis answers graph questions about a flowchart: which steps follow or precede a
+// node, which steps cannot be reached from a start terminator, which steps lead nowhere, and
+// whether the chart has cycles or a topological order.
+package analysis
+
+import (
+	"slices"
+
+	"github.com/andre-a-alves/flowchart"
+)
+
+// Graph is an adjacency index over the nodes of a flowchart tree, including the nodes of nested
+// subgraphs. It is built once by New and does not follow later changes to the flowchart.
+//
+// Every visible link adds an edge from its origin to its target, whatever its arrows. Links with
+// LineTypeNone only affect layout and are ignored. A link to or from a subgraph connects to every
+// node inside that subgraph, at any depth. Link endpoints are resolved by name, as the renderers
+// do, and links to elements that are not in the flowchart are ignored.
+type Graph struct {
+	nodes []*flowchart.Node // Nodes in tree order
+	index map[string]int    // Position of each node in nodes, by name
+	succ  [][]int           // Successors of each node, in tree order and without repeats
+	pred  [][]int           // Predecessors of each node, in tree order and without repeats
+}
+
+// New builds the adjacency index of the flowchart.
+func New(f *flowchart.Flowchart) *Graph {
+	g := &Graph{index: make(map[string]int)}
+	members := make(map[string][]int) // Nodes inside each titled subgraph, by title
+	var collect func(f *flowchart.Flowchart) []int
+	collect = func(f *flowchart.Flowchart) []int {
+		var inside []int
+		for _, node := range f.Nodes {
+			if _, ok := g.index[node.Name()]; ok {
+				continue
+			}
+			g.index[node.Name()] = len(g.nodes)
+			inside = append(inside, len(g.nodes))
+			g.nodes = append(g.nodes, node)
+		}
+		for _, subgraph := range f.Subgraphs {
+			subgraphInside := collect(subgraph)
+			if subgraph.Title != nil && *subgraph.Title != "" {
+				members[*subgraph.Title] = subgraphInside
+			}
+			inside = append(inside, subgraphInside...)
+		}
+		return inside
+	}
+	collect(f)
+
+	g.succ = make([][]int, len(g.nodes))
+	g.pred = make([][]int, len(g.nodes))
+	resolve := func(l flowchart.Linkable) []int {
+		switch l := l.(type) {
+		case *flowchart.Node:
+			if l == nil {
+				return nil
+			}
+			if i, ok := g.index[l.Name()]; ok {
+				return []int{i}
+			}
+		case *flowchart.Flowchart:
+			if l != nil && l.Title != nil {
+				return members[*l.Title]
+			}
+		}
+		return nil
+	}
+	var link func(f *flowchart.Flowchart)
+	link = func(f *flowchart.Flowchart) {
+		for _, l := range f.Links {
+			if l.LineType == flowchart.LineTypeNone {
+				continue
+			}
+			for _, from := range resolve(l.Origin) {
+				for _, to := range resolve(l.Target) {
+					if !slices.Contains(g.succ[from], to) {
+						g.succ[from] = append(g.succ[from], to)
+						g.pred[to] = append(g.pred[to], from)
+					}
+				}
+			}
+		}
+		for _, subgraph := range f.Subgraphs {
+			link(subgraph)
+		}
+	}
+	link(f)
+	for i := range g.nodes {
+		slices.Sort(g.succ[i])
+		slices.Sort(g.pred[i])
+	}
+	return g
+}
+
+// Nodes returns every node of the flowchart tree, in tree order.
+func (g *Graph) Nodes() []*flowchart.Node {
+	return slices.Clone(g.nodes)
+}
+
+// Node returns the node with the given name, or nil if there is none.
+func (g *Graph) Node(name string) *flowchart.Node {
+	if i, ok := g.index[name]; ok {
+		return g.nodes[i]
+	}
+	return nil
+}
+
+// Successors returns the nodes that the node links to, in tree order.
+func (g *Graph) Successors(n *flowchart.Node) []*flowchart.Node {
+	i, ok := g.lookup(n)
+	if !ok {
+		return nil
+	}
+	return g.toNodes(g.succ[i])
+}
+
+// Predecessors returns the nodes that link to the node, in tree order.
+func (g *Graph) Predecessors(n *flowchart.Node) []*flowchart.Node {
+	i, ok := g.lookup(n)
+	if !ok {
+		return nil
+	}
+	return g.toNodes(g.pred[i])
+}
+
+// StartNodes returns the terminator nodes without predecessors, where the flow begins.
+func (g *Graph) StartNodes() []*flowchart.Node {
+	var starts []int
+	for i, node := range g.nodes {
+		if node.Type == flowchart.NodeTypeTerminator && len(g.pred[i]) == 0 {
+			starts = append(starts, i)
+		}
+	}
+	return g.toNodes(starts)
+}
+
+// EndNodes returns the terminator nodes without successors, where the flow ends.
+func (g *Graph) EndNodes() []*flowchart.Node {
+	var ends []int
+	for i, node := range g.nodes {
+		if node.Type == flowchart.NodeTypeTerminator && len(g.succ[i]) == 0 {
+			ends = append(ends, i)
+		}
+	}
+	return g.toNodes(ends)
+}
+
+// Reachable returns the nodes that can be reached by following links from any of the given nodes,
+// including the nodes themselves, in tree order.
+func (g *Graph) Reachable(from ...*flowchart.Node) []*flowchart.Node {
+	var starts []int
+	for _, n := range from {
+		if i, ok := g.lookup(n); ok {
+			starts = append(starts, i)
+		}
+	}
+	visited := g.visit(starts)
+	var reached []int
+	for i := range g.nodes {
+		if visited[i] {
+			reached = append(reached, i)
+		}
+	}
+	return g.toNodes(reached)
+}
+
+// Unreachable returns the nodes that cannot be reached from any start node, in tree order.
+// If the flowchart has no start node, every node is unreachable.
+func (g *Graph) Unreachable() []*flowchart.Node {
+	var starts []int
+	for _, n := range g.StartNodes() {
+		starts = append(starts, g.index[n.Name()])
+	}
+	visited := g.visit(starts)
+	var unreachable []int
+	for i := range g.nodes {
+		if !visited[i] {
+			unreachable = append(unreachable, i)
+		}
+	}
+	return g.toNodes(unreachable)
+}
+
+// DeadEnds returns the nodes other than terminators that have no successors, in tree order.
+func (g *Graph) DeadEnds() []*flowchart.Node {
+	var deadEnds []int
+	for i, node := range g.nodes {
+		if node.Type != flowchart.NodeTypeTerminator && len(g.succ[i]) == 0 {
+			deadEnds = append(deadEnds, i)
+		}
+	}
+	return g.toNodes(deadEnds)
+}
+
+// visit returns which nodes can be reached from the given nodes.
+func (g *Graph) visit(starts []int) []bool {
+	visited := make([]bool, len(g.nodes))
+	stack := slices.Clone(starts)
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[i] {
+			continue
+		}
+		visited[i] = true
+		stack = append(stack, g.succ[i]...)
+	}
+	return visited
+}
+
+// lookup returns the position of the node with the same name as n.
+func (g *Graph) lookup(n *flowchart.Node) (int, bool) {
+	if n == nil {
+		return 0, false
+	}
+	i, ok := g.index[n.Name()]
+	return i, ok
+}
+
+// toNodes returns the nodes at the given positions.
+func (g *Graph) toNodes(indexes []int) []*flowchart.Node {
+	if len(indexes) == 0 {
+		return nil
+	}
+	nodes := make([]*flowchart.Node, len(indexes))
+	for i, index := range indexes {
+		nodes[i] = g.nodes[index]
+	}
+	return nodes
+}
@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/andre-a-alves/flowchart"
+	"github.com/google/go-cmp/cmp"
+)
+
+// names returns the names of the nodes, for readable comparisons.
+func names(nodes []*flowchart.Node) []string {
+	var result []string
+	for _, node := range nodes {
+		result = append(result, node.Name())
+	}
+	return result
+}
+
+// reviewChart returns a chart with a start and end terminator, a subgraph, a step that is never
+// reached, a dead end and an invisible link.
+func reviewChart() *flowchart.Flowchart {
+	start := flowchart.TerminatorNode("Start", nil)
+	end := flowchart.TerminatorNode("End", nil)
+	check := flowchart.DecisionNode("Check", nil)
+	fix := flowchart.ProcessNode("Fix", nil)
+	orphan := flowchart.ProcessNode("Orphan", nil)
+	stuck := flowchart.ProcessNode("Stuck", nil)
+	review := &flowchart.Flowchart{
+		Title: pointTo("Review"),
+		Nodes: []*flowchart.Node{check, fix},
+		Links: []flowchart.Link{flowchart.SolidLink(check, fix, nil)},
+	}
+	return &flowchart.Flowchart{
+		Nodes:     []*flowchart.Node{start, end, orphan, stuck},
+		Subgraphs: []*flowchart.Flowchart{review},
+		Links: []flowchart.Link{
+			flowchart.SolidLink(start, review, nil),
+			flowchart.DottedLink(review, end, nil),
+			flowchart.BlankLink(start, stuck, nil),
+			flowchart.ThickLink(orphan, stuck, nil),
+			flowchart.SolidLink(fix, flowchart.ProcessNode("Phantom", nil), nil),
+		},
+	}
+}
+
+func pointTo(s string) *string {
+	return &s
+}
+
+func TestGraph(t *testing.T) {
+	g := New(reviewChart())
+	check := g.Node("Check")
+
+	tests := []struct {
+		name     string
+		got      []*flowchart.Node
+		expected []string
+	}{
+		{"nodes in tree order", g.Nodes(), []string{"Start", "End", "Orphan", "Stuck", "Check", "Fix"}},
+		{"links to a subgraph reach every node inside", g.Successors(g.Node("Start")), []string{"Check", "Fix"}},
+		{"links from a subgraph leave every node inside", g.Predecessors(g.Node("End")), []string{"Check", "Fix"}},
+		{"successors within a subgraph", g.Successors(check), []string{"End", "Fix"}},
+		{"invisible links are ignored", g.Predecessors(g.Node("Stuck")), []string{"Orphan"}},
+		{"node not in the graph", g.Successors(flowchart.ProcessNode("Phantom", nil)), nil},
+		{"start nodes", g.StartNodes(), []string{"Start"}},
+		{"end nodes", g.EndNodes(), []string{"End"}},
+		{"reachable", g.Reachable(check), []string{"End", "Check", "Fix"}},
+		{"unreachable", g.Unreachable(), []string{"Orphan", "Stuck"}},
+		{"dead ends", g.DeadEnds(), []string{"Stuck"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, names(tt.got)); diff != "" {
+				t.Errorf("mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGraph_NoStartNodes(t *testing.T) {
+	a := flowchart.ProcessNode("A", nil)
+	b := flowchart.ProcessNode("B", nil)
+	g := New(&flowchart.Flowchart{Nodes: []*flowchart.Node{a, b}, Links: []flowchart.Link{flowchart.SolidLink(a, b, nil)}})
+
+	if diff := cmp.Diff([]string{"A", "B"}, names(g.Unreachable())); diff != "" {
+		t.Errorf("Unreachable() mismatch (-expected +got):\n%s", diff)
+	}
+	if g.Node("C") != nil {
+		t.Errorf("Node(%q) = non-nil, expected nil", "C")
+	}
+}
@@ -0,0 +1,96 @@
+package flowchart
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Size of the glyphs of the bitmap font, in font pixels.
+const (
+	glyphWidth  = 5 // Width of every glyph
+	glyphHeight = 9 // Height of every glyph, including two rows for descenders
+	glyphAscent = 7 // Rows above the baseline
+)
+
+// glyph is the bitmap of a character: one bit per pixel, rows from the top and the leftmost
+// pixel of each row in the highest bit.
+type glyph [glyphHeight]uint8
+
+// bitmapFontSource holds the glyphs of the bitmap font used by RenderPNG, as text.
+//
+//go:embed bitmapfont.txt
+var bitmapFontSource string
+
+var (
+	bitmapFontOnce   sync.Once
+	bitmapFontGlyphs map[rune]glyph
+)
+
+// bitmapFont returns the glyphs of the bitmap font, by character.
+func bitmapFont() map[rune]glyph {
+	bitmapFontOnce.Do(func() {
+		glyphs, err := parseBitmapFont(bitmapFontSource)
+		if err != nil {
+			panic(err) // The font is embedded, so this is a programming error
+		}
+		bitmapFontGlyphs = glyphs
+	})
+	return bitmapFontGlyphs
+}
+
+// parseBitmapFont parses glyphs written as a line ": c" naming the character c, followed by up to
+// glyphHeight rows of glyphWidth characters where "#" is a set pixel and "." a blank one.
+// Lines starting with "#" before the first glyph are comments.
+func parseBitmapFont(source string) (map[rune]glyph, error) {
+	glyphs := make(map[rune]glyph)
+	var current rune
+	row := -1
+	for i, line := range strings.Split(strings.TrimSuffix(source, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, ": "):
+			r, size := utf8.DecodeRuneInString(line[2:])
+			if size == 0 || len(line) != 2+size {
+				return nil, fmt.Errorf("line %d: glyph header must name one character", i+1)
+			}
+			if _, ok := glyphs[r]; ok {
+				return nil, fmt.Errorf("line %d: duplicate glyph for %q", i+1, r)
+			}
+			current, row = r, 0
+			glyphs[r] = glyph{}
+		case row < 0 && strings.HasPrefix(line, "#"):
+			continue
+		case row < 0:
+			return nil, fmt.Errorf("line %d: expected a glyph header", i+1)
+		case row == glyphHeight:
+			return nil, fmt.Errorf("line %d: glyph %q has more than %d rows", i+1, current, glyphHeight)
+		case len(line) != glyphWidth || strings.Trim(line, "#.") != "":
+			return nil, fmt.Errorf("line %d: glyph rows must be %d characters of '#' or '.'", i+1, glyphWidth)
+		default:
+			g := glyphs[current]
+			for x := range glyphWidth {
+				if line[x] == '#' {
+					g[row] |= 1 << (glyphWidth - 1 - x)
+				}
+			}
+			glyphs[current] = g
+			row++
+		}
+	}
+	return glyphs, nil
+}
+
+// set reports whether the pixel of the glyph at column x and row y is set.
+func (g glyph) set(x, y int) bool {
+	return g[y]&(1<<(glyphWidth-1-x)) != 0
+}
+
+// glyphFor returns the glyph of a character, or a hollow box for characters the font does not have.
+func glyphFor(r rune) glyph {
+	if g, ok := bitmapFont()[r]; ok {
+		return g
+	}
+	return glyph{0, 0b11111, 0b10001, 0b10001, 0b10001, 0b10001, 0b11111}
+}
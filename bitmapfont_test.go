@@ -0,0 +1,52 @@
+package flowchart
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBitmapFont(t *testing.T) {
+	glyphs := bitmapFont()
+	for r := rune(' '); r <= '~'; r++ {
+		if _, ok := glyphs[r]; !ok {
+			t.Errorf("bitmapFont() has no glyph for %q", r)
+		}
+	}
+	if len(glyphs) != '~'-' '+1 {
+		t.Errorf("bitmapFont() has %d glyphs, expected only printable ASCII", len(glyphs))
+	}
+}
+
+func TestParseBitmapFont(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		expected    map[rune]glyph
+		expectedErr bool
+	}{
+		{
+			name:     "glyphs and comments",
+			source:   "# comment\n: L\n#....\n#....\n#####\n:  \n",
+			expected: map[rune]glyph{'L': {0b10000, 0b10000, 0b11111}, ' ': {}},
+		},
+		{name: "row before header", source: "....#\n", expectedErr: true},
+		{name: "long header", source: ": ab\n", expectedErr: true},
+		{name: "duplicate glyph", source: ": a\n: a\n", expectedErr: true},
+		{name: "wide row", source: ": a\n#.....\n", expectedErr: true},
+		{name: "bad pixel", source: ": a\n#.x..\n", expectedErr: true},
+		{name: "too many rows", source: ": a\n.....\n.....\n.....\n.....\n.....\n.....\n.....\n.....\n.....\n.....\n", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBitmapFont(tt.source)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("parseBitmapFont() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" && !tt.expectedErr {
+				t.Errorf("parseBitmapFont() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
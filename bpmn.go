@@ -0,0 +1,525 @@
+package flowchart
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Sizes of the BPMN shapes, as drawn by Camunda Modeler.
+const (
+	bpmnEventSize     = 36.0  // Diameter of events
+	bpmnGatewaySize   = 50.0  // Width and height of gateways
+	bpmnDataStoreSize = 50.0  // Width and height of data stores
+	bpmnTaskWidth     = 100.0 // Width of tasks and collapsed sub-processes
+	bpmnTaskHeight    = 80.0  // Height of tasks and collapsed sub-processes
+	bpmnLabelWidth    = 90.0  // Width at which the labels of events, gateways and data stores wrap
+)
+
+// bpmnID converts a name to a BPMN id, which must be an XML name made of ASCII letters, digits,
+// "_", "-" and "." that starts with a letter or "_". Names that need changing get a hash of the
+// name appended so that they cannot collide with names that are already valid ids.
+func bpmnID(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.", r)) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	id := sb.String()
+	if id != name || id == "" || !(unicode.IsLetter(rune(id[0])) || id[0] == '_') {
+		return "_" + id + "_" + nameHash(name)
+	}
+	return id
+}
+
+// bpmnNodeSize returns the size of the BPMN shape of a node.
+func bpmnNodeSize(n *Node) (float64, float64) {
+	switch n.Type {
+	case NodeTypeTerminator, NodeTypeConnector:
+		return bpmnEventSize, bpmnEventSize
+	case NodeTypeDecision:
+		return bpmnGatewaySize, bpmnGatewaySize
+	case NodeTypeDatabase:
+		return bpmnDataStoreSize, bpmnDataStoreSize
+	}
+	return bpmnTaskWidth, bpmnTaskHeight
+}
+
+// isBPMNActivity reports whether an endpoint becomes a BPMN activity, which can read and write
+// data stores: a task, a sub-process or a subgraph.
+func isBPMNActivity(l Linkable) bool {
+	n, ok := l.(*Node)
+	return !ok || (n.Type != NodeTypeTerminator && n.Type != NodeTypeConnector && n.Type != NodeTypeDecision && n.Type != NodeTypeDatabase)
+}
+
+// isBPMNDataStore reports whether an endpoint becomes a BPMN data store reference.
+func isBPMNDataStore(l Linkable) bool {
+	n, ok := l.(*Node)
+	return ok && n.Type == NodeTypeDatabase
+}
+
+// bpmnColorAttributes returns the BPMN in Color attributes of a shape or edge with the given style.
+func bpmnColorAttributes(s Style, fill bool) string {
+	var attrs string
+	if c := hexColor(s.Fill); fill && c != "" {
+		attrs += fmt.Sprintf(` color:background-color="%s"`, c)
+	}
+	if c := hexColor(s.Stroke); c != "" {
+		attrs += fmt.Sprintf(` color:border-color="%s"`, c)
+	}
+	return attrs
+}
+
+// bpmnFlowPrefixes holds the prefix of the ids of each kind of connecting object.
+var bpmnFlowPrefixes = map[string]string{
+	"sequenceFlow":          "Flow",
+	"association":           "Association",
+	"dataInputAssociation":  "DataInputAssociation",
+	"dataOutputAssociation": "DataOutputAssociation",
+}
+
+// bpmnFlow is a link written as a BPMN connecting object.
+type bpmnFlow struct {
+	id             string
+	element        string     // sequenceFlow, association, dataInputAssociation or dataOutputAssociation
+	link           *Link      // The link
+	source, target Linkable   // Endpoints of the flow, which may be subgraphs enclosing those of the link
+	scope          *Flowchart // Flowchart or subgraph holding a sequence flow or association
+	owner          Linkable   // Activity holding a data association
+}
+
+// bpmnRenderer holds the state needed while converting a flowchart to a BPMN document.
+type bpmnRenderer struct {
+	ids       idMap
+	elements  treeElements            // Nodes and subgraphs of the tree, to resolve link endpoints
+	used      map[string]bool         // Ids given out so far
+	parents   map[Linkable]*Flowchart // Flowchart or subgraph containing each node and subgraph
+	subgraphs map[*Flowchart]string   // Ids of the sub-processes of the subgraphs
+	flows     []*bpmnFlow
+	rects     map[Linkable]Rect // Boxes of the nodes and subgraphs
+	edges     map[*Link][]Point // Routes of the links
+	classDefs []StyleClass
+	sb        strings.Builder
+}
+
+// uniqueID returns id, or id with a suffix if it is already used by another element.
+func (r *bpmnRenderer) uniqueID(id string) string {
+	unique := id
+	for i := 2; r.used[unique]; i++ {
+		unique = id + "_" + strconv.Itoa(i)
+	}
+	r.used[unique] = true
+	return unique
+}
+
+// id returns the id of the element of a node or subgraph.
+func (r *bpmnRenderer) id(l Linkable) string {
+	if sub, ok := l.(*Flowchart); ok {
+		return r.subgraphs[sub]
+	}
+	return r.ids.lookup(l.nodeName(), bpmnID)
+}
+
+// line writes a line of the document at the given indentation depth.
+func (r *bpmnRenderer) line(depth int, format string, args ...any) {
+	r.sb.WriteString(strings.Repeat("  ", depth))
+	r.sb.WriteString(fmt.Sprintf(format, args...))
+	r.sb.WriteString("\n")
+}
+
+// ancestors returns the flowcharts containing an endpoint, innermost first.
+func (r *bpmnRenderer) ancestors(l Linkable) []*Flowchart {
+	var chain []*Flowchart
+	for f := r.parents[l]; f != nil; f = r.parents[f] {
+		chain = append(chain, f)
+	}
+	return chain
+}
+
+// lift returns the endpoint, or the subgraph enclosing it, that is directly inside scope.
+func (r *bpmnRenderer) lift(l Linkable, scope *Flowchart) Linkable {
+	for r.parents[l] != nil && r.parents[l] != scope {
+		l = r.parents[l]
+	}
+	return l
+}
+
+// addFlows converts every visible link to a flow. Links touching a data store become data
+// associations of the activity at their other end, or plain associations when there is none.
+// Other links become sequence flows in the innermost flowchart containing both endpoints, with an
+// endpoint nested deeper replaced by the subgraph enclosing it, since sequence flows cannot cross
+// the border of a sub-process. Links between a subgraph and an element nested in it would become
+// flows from a sub-process to itself, and are left out. Endpoints are resolved to the elements of
+// the tree first, so that flows can be matched with the elements they connect.
+func (r *bpmnRenderer) addFlows(root *Flowchart) {
+	walkFlowchart(root, func(f *Flowchart, _ []string) {
+		for i := range f.Links {
+			link := &f.Links[i]
+			if link.LineType == LineTypeNone {
+				continue
+			}
+			origin, target := r.elements.resolve(link.Origin), r.elements.resolve(link.Target)
+			flow := &bpmnFlow{link: link, source: origin, target: target}
+			origins, targets := r.ancestors(origin), r.ancestors(target)
+			flow.scope = root
+			for _, scope := range origins {
+				if slices.Contains(targets, scope) {
+					flow.scope = scope
+					break
+				}
+			}
+			switch {
+			case isBPMNDataStore(target) && isBPMNActivity(origin):
+				flow.element, flow.owner = "dataOutputAssociation", origin
+			case isBPMNDataStore(origin) && isBPMNActivity(target):
+				flow.element, flow.owner = "dataInputAssociation", target
+			case isBPMNDataStore(origin) || isBPMNDataStore(target):
+				flow.element = "association"
+			default:
+				flow.element = "sequenceFlow"
+				flow.source, flow.target = r.lift(origin, flow.scope), r.lift(target, flow.scope)
+				if flow.source == flow.target && origin != target {
+					continue
+				}
+			}
+			count := 1
+			for _, other := range r.flows {
+				if other.element == flow.element {
+					count++
+				}
+			}
+			flow.id = r.uniqueID(bpmnFlowPrefixes[flow.element] + "_" + strconv.Itoa(count))
+			r.flows = append(r.flows, flow)
+		}
+	})
+}
+
+// eventElement returns the BPMN element of a terminator: a start event if no sequence flow
+// reaches it, an end event if none leaves it, and an intermediate event otherwise.
+func (r *bpmnRenderer) eventElement(n *Node) string {
+	var incoming, outgoing bool
+	for _, flow := range r.flows {
+		if flow.element == "sequenceFlow" {
+			incoming = incoming || flow.target == n
+			outgoing = outgoing || flow.source == n
+		}
+	}
+	switch {
+	case !incoming:
+		return "startEvent"
+	case !outgoing:
+		return "endEvent"
+	}
+	return "intermediateThrowEvent"
+}
+
+// element returns the BPMN element of a node.
+func (r *bpmnRenderer) element(n *Node) string {
+	switch n.Type {
+	case NodeTypeTerminator:
+		return r.eventElement(n)
+	case NodeTypeSubprocess:
+		return "subProcess"
+	case NodeTypeDecision:
+		return "exclusiveGateway"
+	case NodeTypeConnector:
+		return "intermediateThrowEvent"
+	case NodeTypeDatabase:
+		return "dataStoreReference"
+	}
+	return "task"
+}
+
+// connected reports whether a sequence flow starts or ends at a flow node, or it holds a data association.
+func (r *bpmnRenderer) connected(l Linkable) bool {
+	return slices.ContainsFunc(r.flows, func(f *bpmnFlow) bool {
+		return f.owner == l || (f.element == "sequenceFlow" && (f.source == l || f.target == l))
+	})
+}
+
+// writeConnections writes the incoming and outgoing sequence flows and the data associations of
+// a flow node.
+func (r *bpmnRenderer) writeConnections(l Linkable, depth int) {
+	for _, flow := range r.flows {
+		if flow.element == "sequenceFlow" && flow.target == l {
+			r.line(depth, "<bpmn:incoming>%s</bpmn:incoming>", flow.id)
+		}
+	}
+	for _, flow := range r.flows {
+		if flow.element == "sequenceFlow" && flow.source == l {
+			r.line(depth, "<bpmn:outgoing>%s</bpmn:outgoing>", flow.id)
+		}
+	}
+	// A data input association needs a target inside the activity; like Camunda Modeler, a
+	// placeholder property is used.
+	var property string
+	if slices.ContainsFunc(r.flows, func(f *bpmnFlow) bool { return f.element == "dataInputAssociation" && f.owner == l }) {
+		property = r.uniqueID("Property_" + r.id(l))
+		r.line(depth, `<bpmn:property id="%s" name="__targetRef_placeholder" />`, property)
+	}
+	for _, flow := range r.flows {
+		if flow.element == "dataInputAssociation" && flow.owner == l {
+			r.line(depth, `<bpmn:dataInputAssociation id="%s">`, flow.id)
+			r.line(depth+1, "<bpmn:sourceRef>%s</bpmn:sourceRef>", r.id(flow.source))
+			r.line(depth+1, "<bpmn:targetRef>%s</bpmn:targetRef>", property)
+			r.line(depth, "</bpmn:dataInputAssociation>")
+		}
+	}
+	for _, flow := range r.flows {
+		if flow.element == "dataOutputAssociation" && flow.owner == l {
+			r.line(depth, `<bpmn:dataOutputAssociation id="%s">`, flow.id)
+			r.line(depth+1, "<bpmn:targetRef>%s</bpmn:targetRef>", r.id(flow.target))
+			r.line(depth, "</bpmn:dataOutputAssociation>")
+		}
+	}
+}
+
+// writeElements writes the flow elements of a process or sub-process: its nodes, its subgraphs as
+// sub-processes, and the sequence flows and associations it holds.
+func (r *bpmnRenderer) writeElements(f *Flowchart, depth int) {
+	for _, node := range f.Nodes {
+		element := r.element(node)
+		start := fmt.Sprintf(`<bpmn:%s id="%s" name="%s"`, element, r.id(node), xmlEscaper.Replace(nodeText(node)))
+		if !r.connected(node) {
+			r.line(depth, "%s />", start)
+			continue
+		}
+		r.line(depth, "%s>", start)
+		r.writeConnections(node, depth+1)
+		r.line(depth, "</bpmn:%s>", element)
+	}
+	for _, sub := range f.Subgraphs {
+		r.line(depth, `<bpmn:subProcess id="%s" name="%s">`, r.id(sub), xmlEscaper.Replace(sub.nodeName()))
+		r.writeConnections(sub, depth+1)
+		r.writeElements(sub, depth+1)
+		r.line(depth, "</bpmn:subProcess>")
+	}
+	for _, flow := range r.flows {
+		if flow.scope != f {
+			continue
+		}
+		switch flow.element {
+		case "sequenceFlow":
+			name := ""
+			if flow.link.Label != nil && *flow.link.Label != "" {
+				name = fmt.Sprintf(` name="%s"`, xmlEscaper.Replace(linkText(flow.link)))
+			}
+			r.line(depth, `<bpmn:sequenceFlow id="%s"%s sourceRef="%s" targetRef="%s" />`, flow.id, name, r.id(flow.source), r.id(flow.target))
+		case "association":
+			r.line(depth, `<bpmn:association id="%s" associationDirection="One" sourceRef="%s" targetRef="%s" />`, flow.id, r.id(flow.source), r.id(flow.target))
+		}
+	}
+}
+
+// linkText returns the label of a link without markdown markers.
+func linkText(l *Link) string {
+	text := *l.Label
+	if l.LabelFormat == LabelFormatMarkdown {
+		text = strings.NewReplacer("**", "", "_", "").Replace(text)
+	}
+	return text
+}
+
+// bounds writes the bounds of a shape or label.
+func (r *bpmnRenderer) bounds(depth int, b Rect) {
+	r.line(depth, `<dc:Bounds x="%s" y="%s" width="%s" height="%s" />`, svgNumber(b.X), svgNumber(b.Y), svgNumber(b.Width), svgNumber(b.Height))
+}
+
+// labelBounds returns the bounds of a label of the given text centred on p, wrapped at bpmnLabelWidth.
+func labelBounds(text string, p Point) Rect {
+	w, h := textSize(text)
+	h *= math.Ceil(w / bpmnLabelWidth)
+	w = min(w, bpmnLabelWidth)
+	return Rect{math.Round(p.X - w/2), math.Round(p.Y - h/2), w, h}
+}
+
+// writeShapes writes the diagram shapes of the nodes and subgraphs of f, sub-processes before
+// their contents.
+func (r *bpmnRenderer) writeShapes(f *Flowchart, depth int) {
+	for _, node := range f.Nodes {
+		id := r.id(node)
+		style := resolveStyle(r.classDefs, node.Classes, node.Style)
+		r.line(depth, `<bpmndi:BPMNShape id="%s" bpmnElement="%s"%s>`, r.uniqueID(id+"_di"), id, bpmnColorAttributes(style, true))
+		rect := r.rects[node]
+		r.bounds(depth+1, rect)
+		if element := r.element(node); element != "task" && element != "subProcess" && nodeText(node) != "" {
+			// Events, gateways and data stores have their label underneath.
+			r.line(depth+1, "<bpmndi:BPMNLabel>")
+			label := labelBounds(nodeText(node), Point{rect.Center().X, 0})
+			label.Y = rect.Y + rect.Height + 7
+			r.bounds(depth+2, label)
+			r.line(depth+1, "</bpmndi:BPMNLabel>")
+		}
+		r.line(depth, "</bpmndi:BPMNShape>")
+	}
+	for _, sub := range f.Subgraphs {
+		id := r.id(sub)
+		style := resolveStyle(r.classDefs, sub.Classes, sub.Style)
+		r.line(depth, `<bpmndi:BPMNShape id="%s" bpmnElement="%s" isExpanded="true"%s>`, r.uniqueID(id+"_di"), id, bpmnColorAttributes(style, true))
+		r.bounds(depth+1, r.rects[sub])
+		r.line(depth, "</bpmndi:BPMNShape>")
+		r.writeShapes(sub, depth)
+	}
+}
+
+// route returns the waypoints of a flow: the route of its link, cut where it crosses the border
+// of a subgraph that replaced one of the link's endpoints, or a straight line if the link has no
+// route.
+func (r *bpmnRenderer) route(flow *bpmnFlow) []Point {
+	points := slices.Clone(r.edges[flow.link])
+	if len(points) < 2 {
+		return []Point{r.rects[flow.source].Center(), r.rects[flow.target].Center()}
+	}
+	if flow.target != r.elements.resolve(flow.link.Target) {
+		box := r.rects[flow.target]
+		for i := 1; i < len(points); i++ {
+			if !contains(box, points[i-1]) && contains(box, points[i]) {
+				points = append(points[:i], clip(box, points[i-1], points[i]))
+				break
+			}
+		}
+	}
+	if flow.source != r.elements.resolve(flow.link.Origin) {
+		box := r.rects[flow.source]
+		for i := len(points) - 2; i >= 0; i-- {
+			if contains(box, points[i]) && !contains(box, points[i+1]) {
+				points = append([]Point{clip(box, points[i+1], points[i])}, points[i+1:]...)
+				break
+			}
+		}
+	}
+	return points
+}
+
+// writeEdges writes the diagram edges of the flows, with the names of sequence flows at their middle.
+func (r *bpmnRenderer) writeEdges(depth int) {
+	for _, flow := range r.flows {
+		style := resolveStyle(r.classDefs, flow.link.Classes, flow.link.Style)
+		r.line(depth, `<bpmndi:BPMNEdge id="%s" bpmnElement="%s"%s>`, r.uniqueID(flow.id+"_di"), flow.id, bpmnColorAttributes(style, false))
+		points := r.route(flow)
+		for _, p := range points {
+			r.line(depth+1, `<di:waypoint x="%s" y="%s" />`, svgNumber(p.X), svgNumber(p.Y))
+		}
+		if flow.element == "sequenceFlow" && flow.link.Label != nil && *flow.link.Label != "" {
+			r.line(depth+1, "<bpmndi:BPMNLabel>")
+			r.bounds(depth+2, labelBounds(linkText(flow.link), EdgeLayout{Points: points}.Midpoint()))
+			r.line(depth+1, "</bpmndi:BPMNLabel>")
+		}
+		r.line(depth, "</bpmndi:BPMNEdge>")
+	}
+}
+
+// RenderBPMN generates a BPMN 2.0 XML document holding the flowchart as a single process, with a
+// diagram laid out with ComputeLayout so that it opens in Camunda Modeler and other BPMN tools.
+//
+// Terminators become start events, or end events when links lead to them, decisions exclusive
+// gateways, connectors intermediate events, subprocess nodes collapsed sub-processes and other
+// nodes tasks. Subgraphs become expanded sub-processes holding their contents. Databases become
+// data store references, and links to or from them data associations of the task at their other
+// end. Other visible links become sequence flows named after their label; as sequence flows cannot
+// cross the border of a sub-process, a link to a node inside a subgraph that the origin is not in
+// leads to the subgraph instead, and links between a subgraph and its contents are left out. Fill
+// and stroke colours are kept with BPMN in Color attributes. The title of the flowchart names the
+// process.
+// It returns the BPMN document or an error if validation fails.
+func RenderBPMN(f *Flowchart) (string, error) {
+	if err := validateBPMN(f); err != nil {
+		return "", err
+	}
+	ids, err := newIDMap(f, bpmnID)
+	if err != nil {
+		return "", err
+	}
+	l, err := ComputeLayout(f, LayoutOptions{NodeSpacing: 50, RankSpacing: 70, ClusterPadding: 25, NodeSize: bpmnNodeSize})
+	if err != nil {
+		return "", err
+	}
+
+	r := &bpmnRenderer{
+		ids:       ids,
+		elements:  newTreeElements(f),
+		used:      make(map[string]bool),
+		parents:   make(map[Linkable]*Flowchart),
+		subgraphs: make(map[*Flowchart]string),
+		rects:     make(map[Linkable]Rect),
+		edges:     make(map[*Link][]Point),
+		classDefs: allClassDefs(f),
+	}
+	for _, id := range ids {
+		r.used[id] = true
+	}
+	for _, n := range l.Nodes {
+		r.rects[n.Node] = n.Rect
+	}
+	for _, c := range l.Clusters {
+		r.rects[c.Subgraph] = c.Rect
+	}
+	untitled, spare := 0, 20.0
+	walkFlowchart(f, func(sub *Flowchart, _ []string) {
+		for _, node := range sub.Nodes {
+			r.parents[node] = sub
+		}
+		for _, child := range sub.Subgraphs {
+			r.parents[child] = sub
+			if child.Title != nil && *child.Title != "" {
+				r.subgraphs[child] = ids.lookup(*child.Title, bpmnID)
+			} else {
+				untitled++
+				r.subgraphs[child] = r.uniqueID("SubProcess_" + strconv.Itoa(untitled))
+			}
+			// Untitled subgraphs without nodes are not laid out, and are drawn below the diagram.
+			if _, ok := r.rects[child]; !ok {
+				r.rects[child] = Rect{spare, l.Height, bpmnTaskWidth, bpmnTaskHeight}
+				spare += bpmnTaskWidth + 20
+			}
+		}
+	})
+	for _, e := range l.Edges {
+		r.edges[e.Link] = e.Points
+	}
+	r.addFlows(f)
+
+	process := r.uniqueID("Process_1")
+	name := ""
+	if f.Title != nil && *f.Title != "" {
+		name = fmt.Sprintf(` name="%s"`, xmlEscaper.Replace(*f.Title))
+	}
+	r.line(0, `<?xml version="1.0" encoding="UTF-8"?>`)
+	r.line(0, `<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:bpmndi="http://www.omg.org/spec/BPMN/20100524/DI" `+
+		`xmlns:dc="http://www.omg.org/spec/DD/20100524/DC" xmlns:di="http://www.omg.org/spec/DD/20100524/DI" `+
+		`xmlns:color="http://www.omg.org/spec/BPMN/non-normative/color/1.0" id="%s" targetNamespace="http://bpmn.io/schema/bpmn" exporter="flowchart">`,
+		r.uniqueID("Definitions_1"))
+	r.line(1, `<bpmn:process id="%s"%s isExecutable="false">`, process, name)
+	r.writeElements(f, 2)
+	r.line(1, "</bpmn:process>")
+	r.line(1, `<bpmndi:BPMNDiagram id="%s">`, r.uniqueID("BPMNDiagram_1"))
+	r.line(2, `<bpmndi:BPMNPlane id="%s" bpmnElement="%s">`, r.uniqueID("BPMNPlane_1"), process)
+	r.writeShapes(f, 3)
+	r.writeEdges(3)
+	r.line(2, "</bpmndi:BPMNPlane>")
+	r.line(1, "</bpmndi:BPMNDiagram>")
+	r.line(0, "</bpmn:definitions>")
+	return r.sb.String(), nil
+}
+
+// validateBPMN validates the Flowchart structure to ensure it can be rendered as BPMN.
+// It checks for the following violations:
+// 1. All node and subgraph names must be unique across the whole flowchart.
+// 2. No two names may end up with the same BPMN id.
+// 3. Style classes must have valid, unique names, and every class assigned must be defined.
+//
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func validateBPMN(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, idCollisionViolations(f, bpmnID)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	violations = append(violations, styleClassViolations(f)...)
+	return newValidationError(violations...)
+}
@@ -0,0 +1,296 @@
+package flowchart
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderBPMN(t *testing.T) {
+	start, check := TerminatorNode("start", pointTo("Start")), DecisionNode("check", pointTo("Valid?"))
+	save, db, end := ProcessNode("save", pointTo("Save")), DatabaseNode("db", pointTo("Orders")), TerminatorNode("end", nil)
+	f := &Flowchart{
+		Title:     pointTo("Orders"),
+		Direction: DirectionVertical,
+		Nodes:     []*Node{start, check, save, db, end},
+		Links: []Link{
+			SolidLink(start, check, nil),
+			SolidLink(check, save, pointTo("yes")),
+			SolidLink(check, end, pointTo("no")),
+			SolidLink(save, db, nil),
+			SolidLink(save, end, nil),
+		},
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:bpmndi="http://www.omg.org/spec/BPMN/20100524/DI" xmlns:dc="http://www.omg.org/spec/DD/20100524/DC" xmlns:di="http://www.omg.org/spec/DD/20100524/DI" xmlns:color="http://www.omg.org/spec/BPMN/non-normative/color/1.0" id="Definitions_1" targetNamespace="http://bpmn.io/schema/bpmn" exporter="flowchart">
+  <bpmn:process id="Process_1" name="Orders" isExecutable="false">
+    <bpmn:startEvent id="start" name="Start">
+      <bpmn:outgoing>Flow_1</bpmn:outgoing>
+    </bpmn:startEvent>
+    <bpmn:exclusiveGateway id="check" name="Valid?">
+      <bpmn:incoming>Flow_1</bpmn:incoming>
+      <bpmn:outgoing>Flow_2</bpmn:outgoing>
+      <bpmn:outgoing>Flow_3</bpmn:outgoing>
+    </bpmn:exclusiveGateway>
+    <bpmn:task id="save" name="Save">
+      <bpmn:incoming>Flow_2</bpmn:incoming>
+      <bpmn:outgoing>Flow_4</bpmn:outgoing>
+      <bpmn:dataOutputAssociation id="DataOutputAssociation_1">
+        <bpmn:targetRef>db</bpmn:targetRef>
+      </bpmn:dataOutputAssociation>
+    </bpmn:task>
+    <bpmn:dataStoreReference id="db" name="Orders" />
+    <bpmn:endEvent id="end" name="end">
+      <bpmn:incoming>Flow_3</bpmn:incoming>
+      <bpmn:incoming>Flow_4</bpmn:incoming>
+    </bpmn:endEvent>
+    <bpmn:sequenceFlow id="Flow_1" sourceRef="start" targetRef="check" />
+    <bpmn:sequenceFlow id="Flow_2" name="yes" sourceRef="check" targetRef="save" />
+    <bpmn:sequenceFlow id="Flow_3" name="no" sourceRef="check" targetRef="end" />
+    <bpmn:sequenceFlow id="Flow_4" sourceRef="save" targetRef="end" />
+  </bpmn:process>
+  <bpmndi:BPMNDiagram id="BPMNDiagram_1">
+    <bpmndi:BPMNPlane id="BPMNPlane_1" bpmnElement="Process_1">
+      <bpmndi:BPMNShape id="start_di" bpmnElement="start">
+        <dc:Bounds x="43.25" y="20" width="36" height="36" />
+        <bpmndi:BPMNLabel>
+          <dc:Bounds x="41" y="63" width="40" height="18" />
+        </bpmndi:BPMNLabel>
+      </bpmndi:BPMNShape>
+      <bpmndi:BPMNShape id="check_di" bpmnElement="check">
+        <dc:Bounds x="36.25" y="126" width="50" height="50" />
+        <bpmndi:BPMNLabel>
+          <dc:Bounds x="37" y="183" width="48" height="18" />
+        </bpmndi:BPMNLabel>
+      </bpmndi:BPMNShape>
+      <bpmndi:BPMNShape id="save_di" bpmnElement="save">
+        <dc:Bounds x="48.75" y="246" width="100" height="80" />
+      </bpmndi:BPMNShape>
+      <bpmndi:BPMNShape id="db_di" bpmnElement="db">
+        <dc:Bounds x="106" y="396" width="50" height="50" />
+        <bpmndi:BPMNLabel>
+          <dc:Bounds x="107" y="453" width="48" height="18" />
+        </bpmndi:BPMNLabel>
+      </bpmndi:BPMNShape>
+      <bpmndi:BPMNShape id="end_di" bpmnElement="end">
+        <dc:Bounds x="20" y="403" width="36" height="36" />
+        <bpmndi:BPMNLabel>
+          <dc:Bounds x="26" y="446" width="24" height="18" />
+        </bpmndi:BPMNLabel>
+      </bpmndi:BPMNShape>
+      <bpmndi:BPMNEdge id="Flow_1_di" bpmnElement="Flow_1">
+        <di:waypoint x="61.25" y="56" />
+        <di:waypoint x="61.25" y="126" />
+      </bpmndi:BPMNEdge>
+      <bpmndi:BPMNEdge id="Flow_2_di" bpmnElement="Flow_2">
+        <di:waypoint x="61.25" y="176" />
+        <di:waypoint x="98.75" y="246" />
+        <bpmndi:BPMNLabel>
+          <dc:Bounds x="68" y="202" width="24" height="18" />
+        </bpmndi:BPMNLabel>
+      </bpmndi:BPMNEdge>
+      <bpmndi:BPMNEdge id="Flow_3_di" bpmnElement="Flow_3">
+        <di:waypoint x="61.25" y="176" />
+        <di:waypoint x="23.75" y="286" />
+        <di:waypoint x="38" y="403" />
+        <bpmndi:BPMNLabel>
+          <dc:Bounds x="16" y="278" width="16" height="18" />
+        </bpmndi:BPMNLabel>
+      </bpmndi:BPMNEdge>
+      <bpmndi:BPMNEdge id="DataOutputAssociation_1_di" bpmnElement="DataOutputAssociation_1">
+        <di:waypoint x="98.75" y="326" />
+        <di:waypoint x="131" y="396" />
+      </bpmndi:BPMNEdge>
+      <bpmndi:BPMNEdge id="Flow_4_di" bpmnElement="Flow_4">
+        <di:waypoint x="98.75" y="326" />
+        <di:waypoint x="38" y="403" />
+      </bpmndi:BPMNEdge>
+    </bpmndi:BPMNPlane>
+  </bpmndi:BPMNDiagram>
+</bpmn:definitions>
+`
+	got, err := RenderBPMN(f)
+	if err != nil {
+		t.Fatalf("RenderBPMN() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("RenderBPMN() mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestRenderBPMN_Elements(t *testing.T) {
+	tests := []struct {
+		name        string
+		flowchart   func() *Flowchart
+		expected    []string
+		notExpected []string
+	}{
+		{
+			name: "links into a subgraph lead to its sub-process",
+			flowchart: func() *Flowchart {
+				a, b, c := ProcessNode("a", nil), ProcessNode("b", nil), ProcessNode("c", nil)
+				inner := &Flowchart{Title: pointTo("Inner"), Nodes: []*Node{c}}
+				outer := &Flowchart{Title: pointTo("Outer step"), Nodes: []*Node{b}, Subgraphs: []*Flowchart{inner}}
+				return &Flowchart{Nodes: []*Node{a}, Subgraphs: []*Flowchart{outer}, Links: []Link{SolidLink(a, c, nil), SolidLink(b, c, nil)}}
+			},
+			expected: []string{
+				`<bpmn:subProcess id="_Outer_step_` + nameHash("Outer step") + `" name="Outer step">`,
+				`    <bpmn:sequenceFlow id="Flow_1" sourceRef="a" targetRef="_Outer_step_` + nameHash("Outer step") + `" />`,
+				`<bpmn:task id="c" name="c" />`,
+				`      <bpmn:sequenceFlow id="Flow_2" sourceRef="b" targetRef="Inner" />`,
+				`<bpmndi:BPMNShape id="Inner_di" bpmnElement="Inner" isExpanded="true">`,
+			},
+		},
+		{
+			name: "links between a subgraph and its contents are left out",
+			flowchart: func() *Flowchart {
+				a := ProcessNode("a", nil)
+				inner := &Flowchart{Title: pointTo("Inner"), Nodes: []*Node{a}}
+				outer := &Flowchart{Title: pointTo("Outer"), Subgraphs: []*Flowchart{inner}}
+				return &Flowchart{Subgraphs: []*Flowchart{outer}, Links: []Link{SolidLink(outer, inner, nil), SolidLink(a, outer, nil), SolidLink(inner, inner, nil)}}
+			},
+			expected: []string{
+				`<bpmn:subProcess id="Outer" name="Outer">`,
+				`<bpmn:task id="a" name="a" />`,
+				`<bpmn:sequenceFlow id="Flow_1" sourceRef="Inner" targetRef="Inner" />`,
+			},
+			notExpected: []string{`sourceRef="Outer"`, "Flow_2"},
+		},
+		{
+			name: "events, collapsed sub-processes and untitled subgraphs",
+			flowchart: func() *Flowchart {
+				start, conn, sub := TerminatorNode("start", nil), ConnectorNode("conn", pointTo("A")), SubprocessNode("sub", pointTo("Pick"))
+				mid, io := TerminatorNode("mid", nil), InputOutputNode("io", pointTo("Read"))
+				return &Flowchart{
+					Nodes:     []*Node{start, conn, sub, mid, io},
+					Subgraphs: []*Flowchart{{}},
+					Links:     []Link{SolidLink(start, conn, nil), SolidLink(conn, sub, nil), SolidLink(sub, mid, nil), SolidLink(mid, io, nil), BlankLink(io, start, nil)},
+				}
+			},
+			expected: []string{
+				`<bpmn:startEvent id="start" name="start">`,
+				`<bpmn:intermediateThrowEvent id="conn" name="A">`,
+				`<bpmn:subProcess id="sub" name="Pick">`,
+				`<bpmn:intermediateThrowEvent id="mid" name="mid">`,
+				`<bpmn:task id="io" name="Read">`,
+				`<bpmn:subProcess id="SubProcess_1" name="">`,
+			},
+		},
+		{
+			name: "data stores",
+			flowchart: func() *Flowchart {
+				read, check, db := ProcessNode("read", nil), DecisionNode("check", nil), DatabaseNode("db", nil)
+				return &Flowchart{Nodes: []*Node{read, check, db}, Links: []Link{SolidLink(db, read, nil), DottedLink(check, db, pointTo("logs"))}}
+			},
+			expected: []string{
+				`<bpmn:property id="Property_read" name="__targetRef_placeholder" />`,
+				"<bpmn:dataInputAssociation id=\"DataInputAssociation_1\">\n        <bpmn:sourceRef>db</bpmn:sourceRef>\n        <bpmn:targetRef>Property_read</bpmn:targetRef>",
+				`<bpmn:association id="Association_1" associationDirection="One" sourceRef="check" targetRef="db" />`,
+			},
+		},
+		{
+			name: "escaped names, markdown and colours",
+			flowchart: func() *Flowchart {
+				a := &Node{name: "a", Type: NodeTypeProcess, Label: pointTo("**Check** \"a\" & <b>\nnow"), LabelFormat: LabelFormatMarkdown, Classes: []string{"hot"}}
+				b := ProcessNode("b", nil)
+				link := SolidLink(a, b, pointTo("_maybe_"))
+				link.LabelFormat = LabelFormatMarkdown
+				link.Style = &Style{Stroke: "#00f"}
+				return &Flowchart{
+					Title:     pointTo("A & B"),
+					ClassDefs: []StyleClass{{Name: "hot", Style: Style{Fill: "red", Stroke: "not a colour"}}},
+					Nodes:     []*Node{a, b},
+					Links:     []Link{link},
+				}
+			},
+			expected: []string{
+				`<bpmn:process id="Process_1" name="A &amp; B" isExecutable="false">`,
+				`<bpmn:task id="a" name="Check &quot;a&quot; &amp; &lt;b&gt;&#10;now">`,
+				`<bpmn:sequenceFlow id="Flow_1" name="maybe" sourceRef="a" targetRef="b" />`,
+				`<bpmndi:BPMNShape id="a_di" bpmnElement="a" color:background-color="#ff0000">`,
+				`<bpmndi:BPMNEdge id="Flow_1_di" bpmnElement="Flow_1" color:border-color="#0000ff">`,
+			},
+		},
+		{
+			name: "diagram ids do not clash with names",
+			flowchart: func() *Flowchart {
+				b, bDI, flowDI := ProcessNode("B", nil), ProcessNode("B_di", nil), ProcessNode("Flow_1_di", nil)
+				return &Flowchart{Nodes: []*Node{b, bDI, flowDI}, Links: []Link{SolidLink(b, bDI, nil)}}
+			},
+			expected: []string{
+				`<bpmndi:BPMNShape id="B_di_2" bpmnElement="B">`,
+				`<bpmndi:BPMNShape id="B_di_di" bpmnElement="B_di">`,
+				`<bpmndi:BPMNShape id="Flow_1_di_di" bpmnElement="Flow_1_di">`,
+				`<bpmndi:BPMNEdge id="Flow_1_di_2" bpmnElement="Flow_1">`,
+			},
+		},
+		{
+			name: "generated ids do not clash with names",
+			flowchart: func() *Flowchart {
+				a, b := ProcessNode("Flow_1", nil), ProcessNode("Process_1", nil)
+				return &Flowchart{Nodes: []*Node{a, b}, Links: []Link{SolidLink(a, b, nil)}}
+			},
+			expected: []string{
+				`<bpmn:process id="Process_1_2" isExecutable="false">`,
+				`<bpmn:sequenceFlow id="Flow_1_2" sourceRef="Flow_1" targetRef="Process_1" />`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderBPMN(tt.flowchart())
+			if err != nil {
+				t.Fatalf("RenderBPMN() unexpected error: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(got, expected) {
+					t.Errorf("RenderBPMN() does not contain %q:\n%s", expected, got)
+				}
+			}
+			for _, notExpected := range tt.notExpected {
+				if strings.Contains(got, notExpected) {
+					t.Errorf("RenderBPMN() contains %q:\n%s", notExpected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderBPMN_Errors(t *testing.T) {
+	a := &Node{name: "a b", Classes: []string{"missing"}}
+	_, err := RenderBPMN(&Flowchart{
+		Nodes: []*Node{a, ProcessNode("a b", nil)},
+		Links: []Link{SolidLink(a, &Node{name: "Phantom"}, nil)},
+	})
+	for _, code := range []error{ErrDuplicateName, ErrUndefinedStyleClass, ErrOrphanedEndpoint} {
+		if !errors.Is(err, code) {
+			t.Errorf("RenderBPMN() error = %v, expected %v", err, code)
+		}
+	}
+}
+
+func TestBPMNID(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "Task_1.a-b", expected: "Task_1.a-b"},
+		{name: "_private", expected: "_private"},
+		{name: "Save order", expected: "_Save_order_" + nameHash("Save order")},
+		{name: "1st", expected: "_1st_" + nameHash("1st")},
+		{name: "-x", expected: "_-x_" + nameHash("-x")},
+		{name: "", expected: "__" + nameHash("")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bpmnID(tt.name); got != tt.expected {
+				t.Errorf("bpmnID(%q) = %q, expected %q", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andre-a-alves/flowchart"
+)
+
+// format is a chart file format that the tool can read and/or write.
+type format struct {
+	name       string                                                                    // Name used with the -from and -to flags
+	extensions []string                                                                  // File extensions, including the dot
+	read       func(r io.Reader) (*flowchart.Flowchart, []flowchart.ParseWarning, error) // nil if the format cannot be read
+	write      func(f *flowchart.Flowchart) (string, error)                              // nil if the format cannot be written
+}
+
+// formats lists the supported formats, in the order they are shown in the usage message.
+var formats = []format{
+	{
+		name:       "json",
+		extensions: []string{".json"},
+		read:       withoutWarnings(readJSON),
+		write:      writeJSON,
+	},
+	{
+		name:       "mermaid",
+		extensions: []string{".mmd", ".mermaid"},
+		read:       withoutWarnings(flowchart.ParseMermaid),
+		write:      flowchart.RenderMermaid,
+	},
+	{
+		name:       "dot",
+		extensions: []string{".dot", ".gv"},
+		read:       flowchart.ParseDOT,
+		write:      flowchart.RenderDOT,
+	},
+	{
+		name:       "svg",
+		extensions: []string{".svg"},
+		write:      flowchart.RenderSVG,
+	},
+	{
+		name:       "png",
+		extensions: []string{".png"},
+		write:      writePNG,
+	},
+	{
+		name:       "text",
+		extensions: []string{".txt"},
+		write:      writeText,
+	},
+	{
+		name:       "plantuml",
+		extensions: []string{".puml", ".plantuml"},
+		write:      flowchart.RenderPlantUML,
+	},
+	{
+		name:       "drawio",
+		extensions: []string{".drawio"},
+		read:       withoutWarnings(flowchart.ParseDrawio),
+		write:      flowchart.RenderDrawio,
+	},
+	{
+		name:       "bpmn",
+		extensions: []string{".bpmn"},
+		write:      flowchart.RenderBPMN,
+	},
+	{
+		name:       "graphml",
+		extensions: []string{".graphml"},
+		write:      flowchart.RenderGraphML,
+	},
+	{
+		name:       "gexf",
+		extensions: []string{".gexf"},
+		write:      flowchart.RenderGEXF,
+	},
+	{
+		name:       "d2",
+		extensions: []string{".d2"},
+		write:      flowchart.RenderD2,
+	},
+}
+
+// formatNames returns the names of the formats that can be read, or written if write is true.
+func formatNames(write bool) string {
+	var names []string
+	for _, f := range formats {
+		if (write && f.write != nil) || (!write && f.read != nil) {
+			names = append(names, f.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// lookupFormat returns the format with the given name or, if name is empty, the format of the
+// file extension of path.
+func lookupFormat(name, path string) (format, error) {
+	if name == "" {
+		ext := strings.ToLower(filepath.Ext(path))
+		for _, f := range formats {
+			if slices.Contains(f.extensions, ext) {
+				return f, nil
+			}
+		}
+		if path == "" || path == "-" {
+			return format{}, fmt.Errorf("cannot detect the format of standard input or output, use -from or -to")
+		}
+		return format{}, fmt.Errorf("cannot detect the format of %s from its extension, use -from or -to", path)
+	}
+	for _, f := range formats {
+		if f.name == name {
+			return f, nil
+		}
+	}
+	return format{}, fmt.Errorf("unknown format %q", name)
+}
+
+// withoutWarnings adapts a reader that never reports warnings to the read function of a format.
+func withoutWarnings(read func(r io.Reader) (*flowchart.Flowchart, error)) func(r io.Reader) (*flowchart.Flowchart, []flowchart.ParseWarning, error) {
+	return func(r io.Reader) (*flowchart.Flowchart, []flowchart.ParseWarning, error) {
+		chart, err := read(r)
+		return chart, nil, err
+	}
+}
+
+// readChart reads a chart in the given format, together with any warnings about parts of the
+// input that could not be represented.
+func readChart(f format, r io.Reader) (*flowchart.Flowchart, []flowchart.ParseWarning, error) {
+	if f.read == nil {
+		return nil, nil, fmt.Errorf("reading %s is not supported, supported input formats are: %s", f.name, formatNames(false))
+	}
+	return f.read(r)
+}
+
+// writeChart renders a chart in the given format.
+func writeChart(f format, chart *flowchart.Flowchart) (string, error) {
+	if f.write == nil {
+		return "", fmt.Errorf("writing %s is not supported, supported output formats are: %s", f.name, formatNames(true))
+	}
+	return f.write(chart)
+}
+
+// readJSON decodes a chart encoded with the flowchart JSON schema.
+func readJSON(r io.Reader) (*flowchart.Flowchart, error) {
+	var chart flowchart.Flowchart
+	if err := json.NewDecoder(r).Decode(&chart); err != nil {
+		return nil, err
+	}
+	return &chart, nil
+}
+
+// writeJSON encodes a chart as indented JSON.
+func writeJSON(f *flowchart.Flowchart) (string, error) {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// writePNG renders a chart as a PNG image with the default raster options.
+func writePNG(f *flowchart.Flowchart) (string, error) {
+	data, err := flowchart.RenderPNG(f, flowchart.RasterOptions{})
+	return string(data), err
+}
+
+// writeText draws a chart with Unicode box-drawing characters.
+func writeText(f *flowchart.Flowchart) (string, error) {
+	return flowchart.RenderText(f, flowchart.TextOptions{})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLookupFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		formatName  string
+		path        string
+		expected    string
+		expectedErr bool
+	}{
+		{name: "explicit name", formatName: "dot", path: "chart.json", expected: "dot"},
+		{name: "json extension", path: "charts/chart.json", expected: "json"},
+		{name: "mmd extension", path: "chart.mmd", expected: "mermaid"},
+		{name: "mermaid extension in capitals", path: "CHART.MERMAID", expected: "mermaid"},
+		{name: "gv extension", path: "chart.gv", expected: "dot"},
+		{name: "svg extension", path: "chart.svg", expected: "svg"},
+		{name: "png extension", path: "chart.PNG", expected: "png"},
+		{name: "txt extension", path: "chart.txt", expected: "text"},
+		{name: "puml extension", path: "chart.puml", expected: "plantuml"},
+		{name: "drawio extension", path: "chart.drawio", expected: "drawio"},
+		{name: "bpmn extension", path: "chart.bpmn", expected: "bpmn"},
+		{name: "graphml extension", path: "chart.graphml", expected: "graphml"},
+		{name: "gexf extension", path: "chart.gexf", expected: "gexf"},
+		{name: "d2 extension", path: "chart.d2", expected: "d2"},
+		{name: "unknown extension", path: "chart.pdf", expectedErr: true},
+		{name: "standard input", path: "-", expectedErr: true},
+		{name: "unknown name", formatName: "pdf", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lookupFormat(tt.formatName, tt.path)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("lookupFormat() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if got.name != tt.expected {
+				t.Errorf("lookupFormat() = %q, expected %q", got.name, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatNames(t *testing.T) {
+	if got, expected := formatNames(false), "json, mermaid, dot, drawio"; got != expected {
+		t.Errorf("formatNames(false) = %q, expected %q", got, expected)
+	}
+	if got, expected := formatNames(true), "json, mermaid, dot, svg, png, text, plantuml, drawio, bpmn, graphml, gexf, d2"; got != expected {
+		t.Errorf("formatNames(true) = %q, expected %q", got, expected)
+	}
+}
@@ -0,0 +1,447 @@
+// Command flowchart converts, validates and formats flowcharts, and draws the call graphs of Go
+// code, without writing a Go program.
+//
+// Usage:
+//
+//	flowchart convert [-from format] [-to format] [-o output] [input]
+//	flowchart validate [-from format] [-target format] [-strict] input...
+//	flowchart fmt [-from format] [-w] [input...]
+//	flowchart lint [-from format] [-format text|json|sarif] [-disable rule[:element],...] [input...]
+//	flowchart friendly [-from format] [-to format] [-o output] [input]
+//	flowchart callgraph [-to format] [-o output] [-root func] [-depth n] [-exclude pattern,...] [-by-file] [dir]
+//
+// The format of a file is detected from its extension (.json, .mmd, .mermaid, .dot, .gv, .svg,
+// .png, .txt, .puml, .plantuml, .drawio, .bpmn, .graphml, .gexf, .d2) unless it is given with -from
+// or -to. Input is read from standard input when no file is given or the file is "-", and output is
+// written to standard output unless -o is given.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/andre-a-alves/flowchart"
+	"github.com/andre-a-alves/flowchart/gosource"
+	"github.com/andre-a-alves/flowchart/lint"
+)
+
+// Exit codes returned by run.
+const (
+	exitOK      = 0 // The command succeeded
+	exitFailure = 1 // A chart could not be read, written or validated
+	exitUsage   = 2 // The command line was invalid
+)
+
+// command is a subcommand of the tool.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, env *environment) int
+}
+
+// environment holds the standard streams used by the commands, so that they can be tested.
+type environment struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// commands lists the subcommands, in the order they are shown in the usage message.
+var commands = []command{
+	{"convert", "convert a chart from one format to another", runConvert},
+	{"validate", "check that charts can be rendered, printing every violation", runValidate},
+	{"fmt", "print charts in their canonical form, or rewrite them in place with -w", runFmt},
+	{"lint", "check that charts describe a sensible process, reporting as text, JSON or SARIF", runLint},
+	{"friendly", "make a chart renderable by Mermaid.js by hoisting and removing offending elements", runFriendly},
+	{"callgraph", "draw the call graph of a Go package or module", runCallGraph},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &environment{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+// run executes the subcommand named by the first argument and returns the exit code.
+func run(args []string, env *environment) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(env.stderr)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], env)
+		}
+	}
+	fmt.Fprintf(env.stderr, "flowchart: unknown command %q\n", args[0])
+	usage(env.stderr)
+	return exitUsage
+}
+
+// usage prints the list of subcommands and formats.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: flowchart <command> [flags] [files]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Input formats: %s\n", formatNames(false))
+	fmt.Fprintf(w, "Output formats: %s\n", formatNames(true))
+	fmt.Fprintln(w, "Run 'flowchart <command> -h' for the flags of a command.")
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors to the environment.
+func newFlagSet(name, arguments string, env *environment) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(env.stderr, "Usage: flowchart %s [flags] %s\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the flags of a subcommand, returning the exit code to use if parsing stopped.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// runConvert reads a chart and writes it in another format.
+func runConvert(args []string, env *environment) int {
+	return transform("convert", args, env, func(f *flowchart.Flowchart) *flowchart.Flowchart { return f })
+}
+
+// runFriendly reads a chart, applies GetMermaidFriendlyFlowchart and writes the result.
+func runFriendly(args []string, env *environment) int {
+	return transform("friendly", args, env, flowchart.GetMermaidFriendlyFlowchart)
+}
+
+// transform implements the commands that read one chart, change it and write it. The output
+// format defaults to the format of the output file, or to the input format when writing to
+// standard output.
+func transform(name string, args []string, env *environment, change func(*flowchart.Flowchart) *flowchart.Flowchart) int {
+	fs := newFlagSet(name, "[input]", env)
+	from := fs.String("from", "", "input format (default: detected from the input file extension)")
+	to := fs.String("to", "", "output format (default: detected from -o, or the input format)")
+	output := fs.String("o", "", "output file (default: standard output)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	input := fs.Arg(0)
+	chart, inFormat, _, err := readFile(*from, input, env)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+		return exitFailure
+	}
+
+	outFormat := inFormat
+	if *to != "" || (*output != "" && *output != "-") {
+		if outFormat, err = lookupFormat(*to, *output); err != nil {
+			fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+			return exitUsage
+		}
+	}
+	text, err := writeChart(outFormat, change(chart))
+	if err != nil {
+		fmt.Fprintf(env.stderr, "flowchart: %s: %v\n", displayName(input), err)
+		return exitFailure
+	}
+	return writeOutput(text, *output, env)
+}
+
+// writeOutput writes a rendered chart to the output file, or to standard output if output is
+// empty or "-".
+func writeOutput(text, output string, env *environment) int {
+	if output == "" || output == "-" {
+		fmt.Fprint(env.stdout, text)
+		return exitOK
+	}
+	if err := os.WriteFile(output, []byte(text), 0o644); err != nil {
+		fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// runCallGraph draws the call graph of the Go package or module in a directory.
+func runCallGraph(args []string, env *environment) int {
+	fs := newFlagSet("callgraph", "[dir]", env)
+	to := fs.String("to", "", "output format (default: detected from -o, or mermaid)")
+	output := fs.String("o", "", "output file (default: standard output)")
+	root := fs.String("root", "", "function the graph starts from, e.g. server.Server.Handle (default: every function)")
+	depth := fs.Int("depth", 0, "largest number of calls followed from -root (default: no limit)")
+	exclude := fs.String("exclude", "", "comma-separated patterns of functions or packages to leave out")
+	byFile := fs.Bool("by-file", false, "group the functions of each package by file")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+
+	outFormat, err := lookupFormat("mermaid", "")
+	if *to != "" || (*output != "" && *output != "-") {
+		outFormat, err = lookupFormat(*to, *output)
+	}
+	if err != nil {
+		fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+		return exitUsage
+	}
+	opts := gosource.CallGraphOptions{Root: *root, Depth: *depth, ByFile: *byFile}
+	for _, field := range strings.FieldsFunc(*exclude, func(r rune) bool { return r == ',' }) {
+		opts.Exclude = append(opts.Exclude, strings.TrimSpace(field))
+	}
+
+	chart, err := gosource.CallGraph(dir, opts)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+		return exitFailure
+	}
+	text, err := writeChart(outFormat, chart)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "flowchart: %s: %v\n", dir, err)
+		return exitFailure
+	}
+	return writeOutput(text, *output, env)
+}
+
+// runValidate reads every input and checks that it can be rendered in the target format and,
+// with -strict, that it has no self-loops or duplicate links. It prints one line per violation
+// prefixed with the file it was found in.
+func runValidate(args []string, env *environment) int {
+	fs := newFlagSet("validate", "input...", env)
+	from := fs.String("from", "", "input format (default: detected from each file extension)")
+	target := fs.String("target", "mermaid", "format whose rules the charts must follow")
+	strict := fs.Bool("strict", false, "also report self-loops and duplicate links")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	targetFormat, err := lookupFormat(*target, "")
+	if err != nil {
+		fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+		return exitUsage
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	code := exitOK
+	for _, input := range inputs {
+		chart, _, _, err := readFile(*from, input, env)
+		if err != nil {
+			fmt.Fprintln(env.stdout, err)
+			code = exitFailure
+			continue
+		}
+		_, err = writeChart(targetFormat, chart)
+		if err == nil && *strict {
+			err = flowchart.CheckIntegrity(chart, flowchart.IntegrityOptions{})
+		}
+		if err != nil {
+			for _, problem := range problems(err) {
+				fmt.Fprintf(env.stdout, "%s: %v\n", displayName(input), problem)
+			}
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+// problems splits a validation error into its violations, each of which names the offending
+// element and the subgraphs enclosing it. Other errors are returned as they are.
+func problems(err error) []error {
+	var validationErr *flowchart.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []error{err}
+	}
+	return validationErr.Unwrap()
+}
+
+// runFmt prints every input in the canonical form produced by rendering it again in its own
+// format. With -w it rewrites each file in place instead, but only if nothing was lost: a file is
+// left untouched when reading it gave warnings, or when reading the formatted text back does not
+// give the same chart. Standard input is always formatted to standard output.
+func runFmt(args []string, env *environment) int {
+	fs := newFlagSet("fmt", "[input...]", env)
+	from := fs.String("from", "", "input format (default: detected from each file extension)")
+	write := fs.Bool("w", false, "write the result to each input file instead of standard output")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	code := exitOK
+	for _, input := range inputs {
+		chart, f, warnings, err := readFile(*from, input, env)
+		if err != nil {
+			fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+			code = exitFailure
+			continue
+		}
+		text, err := writeChart(f, chart)
+		if err != nil {
+			fmt.Fprintf(env.stderr, "flowchart: %s: %v\n", displayName(input), err)
+			code = exitFailure
+			continue
+		}
+		if !*write || input == "-" {
+			fmt.Fprint(env.stdout, text)
+			continue
+		}
+		if len(warnings) > 0 {
+			fmt.Fprintf(env.stderr, "flowchart: %s: not rewritten, reading it lost the parts warned about\n", displayName(input))
+			code = exitFailure
+			continue
+		}
+		if err := checkStable(f, text); err != nil {
+			fmt.Fprintf(env.stderr, "flowchart: %s: not rewritten, %v\n", displayName(input), err)
+			code = exitFailure
+			continue
+		}
+		if err := os.WriteFile(input, []byte(text), 0o644); err != nil {
+			fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+// checkStable reads back a chart formatted by fmt and checks that it gives the same chart, by
+// rendering it again and comparing the text.
+func checkStable(f format, text string) error {
+	chart, warnings, err := readChart(f, strings.NewReader(text))
+	if err != nil {
+		return fmt.Errorf("the formatted chart cannot be read back: %w", err)
+	}
+	if len(warnings) > 0 {
+		return fmt.Errorf("reading the formatted chart back gives warnings: %s", warnings[0])
+	}
+	again, err := writeChart(f, chart)
+	if err != nil {
+		return fmt.Errorf("the formatted chart cannot be written again: %w", err)
+	}
+	if again != text {
+		return errors.New("reading the formatted chart back gives a different chart")
+	}
+	return nil
+}
+
+// runLint runs the default lint rules over every input and writes the findings in the chosen
+// report format. It fails if a chart cannot be read or has a finding with severity error.
+func runLint(args []string, env *environment) int {
+	fs := newFlagSet("lint", "[input...]", env)
+	from := fs.String("from", "", "input format (default: detected from each file extension)")
+	reportFormat := fs.String("format", "text", "report format: text, json or sarif")
+	disable := fs.String("disable", "", "comma-separated rules to suppress, each optionally followed by :element")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	write, ok := map[string]func(io.Writer, ...lint.Report) error{
+		"text":  lint.WriteText,
+		"json":  lint.WriteJSON,
+		"sarif": lint.WriteSARIF,
+	}[*reportFormat]
+	if !ok {
+		fmt.Fprintf(env.stderr, "flowchart: unknown report format %q\n", *reportFormat)
+		return exitUsage
+	}
+	var suppressions []lint.Suppression
+	for _, field := range strings.FieldsFunc(*disable, func(r rune) bool { return r == ',' }) {
+		suppression, err := lint.ParseSuppression(strings.TrimSpace(field))
+		if err != nil {
+			fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+			return exitUsage
+		}
+		suppressions = append(suppressions, suppression)
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	code := exitOK
+	var reports []lint.Report
+	for _, input := range inputs {
+		chart, _, _, err := readFile(*from, input, env)
+		if err != nil {
+			fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+			code = exitFailure
+			continue
+		}
+		report := lint.Lint(chart, suppressions...)
+		report.Source = displayName(input)
+		if report.HasErrors() {
+			code = exitFailure
+		}
+		reports = append(reports, report)
+	}
+	if err := write(env.stdout, reports...); err != nil {
+		fmt.Fprintf(env.stderr, "flowchart: %v\n", err)
+		return exitFailure
+	}
+	return code
+}
+
+// readFile reads the chart in input, or in standard input if input is empty or "-", and returns
+// it together with its format and any warnings about parts of the input that were lost. Errors are
+// prefixed with the input name, and the warnings are also written to standard error.
+func readFile(from, input string, env *environment) (*flowchart.Flowchart, format, []flowchart.ParseWarning, error) {
+	f, err := lookupFormat(from, input)
+	if err != nil {
+		return nil, format{}, nil, err
+	}
+
+	r := env.stdin
+	if input != "" && input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return nil, format{}, nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	chart, warnings, err := readChart(f, r)
+	if err != nil {
+		return nil, format{}, nil, fmt.Errorf("%s: %w", displayName(input), err)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(env.stderr, "flowchart: %s: warning: %s\n", displayName(input), w)
+	}
+	return chart, f, warnings, nil
+}
+
+// displayName returns the name used for an input in messages.
+func displayName(input string) string {
+	if input == "" || input == "-" {
+		return "<stdin>"
+	}
+	return strings.TrimPrefix(input, "./")
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const sourceMermaid = `flowchart LR
+A[Start] --> B{ok?}
+`
+
+const canonicalMermaid = `flowchart LR;
+    A["Start"];
+    B{"ok?"};
+    A --> B;
+`
+
+// lintMermaid is a chart whose decision has a single branch.
+const lintMermaid = `flowchart LR
+S([Start]) --> C{ok?}
+C -->|yes| E([End])
+`
+
+// writeTemp writes content to a file named name in a temporary directory and returns its path.
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	chart := writeTemp(t, "chart.mmd", sourceMermaid)
+	invalid := writeTemp(t, "invalid.json", `{"direction":"vertical","nodes":[{"id":" ","type":"process"}],`+
+		`"subgraphs":[{"title":"Group","direction":"vertical","nodes":[{"id":"A","type":"process","classes":["missing"]}]}]}`)
+	broken := writeTemp(t, "broken.mmd", "flowchart LR\nA -->\n")
+	module := filepath.Dir(writeTemp(t, "go.mod", "module example.com/calls\n"))
+	if err := os.WriteFile(filepath.Join(module, "calls.go"), []byte("package calls\n\nfunc A() { B() }\n\nfunc B() {}\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string // Substring expected in standard error
+	}{
+		{
+			name:           "no command",
+			args:           nil,
+			expectedCode:   exitUsage,
+			expectedStderr: "Usage: flowchart <command>",
+		},
+		{
+			name:           "unknown command",
+			args:           []string{"draw"},
+			expectedCode:   exitUsage,
+			expectedStderr: `unknown command "draw"`,
+		},
+		{
+			name:           "convert file to standard output",
+			args:           []string{"convert", "-to", "dot", chart},
+			expectedCode:   exitOK,
+			expectedStdout: "digraph {\n    rankdir=LR;\n    \"A\" [label=\"Start\", shape=box];\n    \"B\" [label=\"ok?\", shape=diamond];\n    \"A\" -> \"B\" [style=solid, dir=forward, arrowhead=normal];\n}\n",
+		},
+		{
+			name:           "convert standard input defaults to the input format",
+			args:           []string{"convert", "-from", "mermaid"},
+			stdin:          sourceMermaid,
+			expectedCode:   exitOK,
+			expectedStdout: canonicalMermaid,
+		},
+		{
+			name:           "convert standard input without format",
+			args:           []string{"convert"},
+			stdin:          sourceMermaid,
+			expectedCode:   exitFailure,
+			expectedStderr: "cannot detect the format of standard input",
+		},
+		{
+			name:           "convert to unknown format",
+			args:           []string{"convert", "-to", "pdf", chart},
+			expectedCode:   exitUsage,
+			expectedStderr: `unknown format "pdf"`,
+		},
+		{
+			name:           "convert from unreadable format",
+			args:           []string{"convert", "-from", "svg", "-to", "json"},
+			stdin:          "<svg></svg>",
+			expectedCode:   exitFailure,
+			expectedStderr: "reading svg is not supported",
+		},
+		{
+			name:           "convert from dot with warnings",
+			args:           []string{"convert", "-from", "dot", "-to", "mermaid"},
+			stdin:          "digraph {\n  rankdir=BT\n  a -> b\n}\n",
+			expectedCode:   exitOK,
+			expectedStdout: "flowchart TB;\n    a;\n    b;\n    a --> b;\n",
+			expectedStderr: "flowchart: <stdin>: warning: line 2:",
+		},
+		{
+			name:           "validate valid chart",
+			args:           []string{"validate", chart},
+			expectedCode:   exitOK,
+			expectedStdout: "",
+		},
+		{
+			name:         "validate reports every file",
+			args:         []string{"validate", invalid, broken, chart},
+			expectedCode: exitFailure,
+			expectedStdout: invalid + ": node \" \" has an invalid mermaid name\n" +
+				invalid + ": Group: node \"A\" uses undefined style class \"missing\"\n" + broken + ": line 2: expected node id at \"\"\n",
+		},
+		{
+			name:           "validate strict",
+			args:           []string{"validate", "-strict", "-from", "mermaid"},
+			stdin:          "flowchart LR\nA --> A\n",
+			expectedCode:   exitFailure,
+			expectedStdout: "<stdin>: link from \"A\" to \"A\" starts and ends at the same element\n",
+		},
+		{
+			name:           "lint reports findings",
+			args:           []string{"lint", "-from", "mermaid"},
+			stdin:          lintMermaid,
+			expectedCode:   exitFailure,
+			expectedStdout: "<stdin>: error: decision \"C\" has 1 outgoing link, expected at least 2 [decision-branches]\n",
+		},
+		{
+			name:         "lint with suppression",
+			args:         []string{"lint", "-from", "mermaid", "-disable", "decision-branches:C", "-format", "json"},
+			stdin:        lintMermaid,
+			expectedCode: exitOK,
+			expectedStdout: `{
+  "findings": [],
+  "summary": {
+    "error": 0,
+    "info": 0,
+    "warning": 0
+  }
+}
+`,
+		},
+		{
+			name:         "callgraph",
+			args:         []string{"callgraph", "-root", "calls.A", module},
+			expectedCode: exitOK,
+			expectedStdout: `---
+title: example.com/calls call graph
+---
+flowchart LR;
+    subgraph example_com_calls_64d216f8 [example.com/calls];
+        direction LR;
+        example_com_calls_A_2bb8f699[["A"]];
+        example_com_calls_B_28b8f1e0[["B"]];
+    end;
+    example_com_calls_A_2bb8f699 --> example_com_calls_B_28b8f1e0;
+`,
+		},
+		{
+			name:           "callgraph with unknown root",
+			args:           []string{"callgraph", "-root", "calls.C", module},
+			expectedCode:   exitFailure,
+			expectedStderr: "root function calls.C not found",
+		},
+		{
+			name:           "lint with unknown report format",
+			args:           []string{"lint", "-format", "xml"},
+			expectedCode:   exitUsage,
+			expectedStderr: `unknown report format "xml"`,
+		},
+		{
+			name:           "friendly",
+			args:           []string{"friendly", "-to", "mermaid", writeTemp(t, "friendly.json", `{"direction":"vertical","nodes":[{"id":" ","type":"process"}]}`)},
+			expectedCode:   exitOK,
+			expectedStdout: "flowchart TB;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &environment{stdin: strings.NewReader(tt.stdin), stdout: &stdout, stderr: &stderr})
+			if code != tt.expectedCode {
+				t.Errorf("run() = %d, expected %d (stderr: %s)", code, tt.expectedCode, stderr.String())
+			}
+			if diff := cmp.Diff(tt.expectedStdout, stdout.String()); diff != "" {
+				t.Errorf("run() stdout mismatch (-expected +got):\n%s", diff)
+			}
+			if !strings.Contains(stderr.String(), tt.expectedStderr) {
+				t.Errorf("run() stderr = %q, expected it to contain %q", stderr.String(), tt.expectedStderr)
+			}
+		})
+	}
+}
+
+func TestRun_WritesFiles(t *testing.T) {
+	t.Run("convert to output file", func(t *testing.T) {
+		chart := writeTemp(t, "chart.mmd", sourceMermaid)
+		output := filepath.Join(t.TempDir(), "chart.json")
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"convert", "-o", output, chart}, &environment{stdout: &stdout, stderr: &stderr}); code != exitOK {
+			t.Fatalf("run() = %d, expected %d (stderr: %s)", code, exitOK, stderr.String())
+		}
+
+		// Converting the JSON back must give the same chart.
+		stdout.Reset()
+		if code := run([]string{"convert", "-to", "mermaid", output}, &environment{stdout: &stdout, stderr: &stderr}); code != exitOK {
+			t.Fatalf("run() = %d, expected %d (stderr: %s)", code, exitOK, stderr.String())
+		}
+		if diff := cmp.Diff(canonicalMermaid, stdout.String()); diff != "" {
+			t.Errorf("converted chart mismatch (-expected +got):\n%s", diff)
+		}
+	})
+
+	t.Run("fmt writes to standard output by default", func(t *testing.T) {
+		chart := writeTemp(t, "chart.mmd", sourceMermaid)
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"fmt", chart}, &environment{stdout: &stdout, stderr: &stderr}); code != exitOK {
+			t.Fatalf("run() = %d, expected %d (stderr: %s)", code, exitOK, stderr.String())
+		}
+		if diff := cmp.Diff(canonicalMermaid, stdout.String()); diff != "" {
+			t.Errorf("run() stdout mismatch (-expected +got):\n%s", diff)
+		}
+		got, err := os.ReadFile(chart)
+		if err != nil {
+			t.Fatalf("os.ReadFile() unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(sourceMermaid, string(got)); diff != "" {
+			t.Errorf("input file changed (-expected +got):\n%s", diff)
+		}
+	})
+
+	t.Run("fmt -w rewrites in place", func(t *testing.T) {
+		chart := writeTemp(t, "chart.mmd", sourceMermaid)
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"fmt", "-w", chart}, &environment{stdout: &stdout, stderr: &stderr}); code != exitOK {
+			t.Fatalf("run() = %d, expected %d (stderr: %s)", code, exitOK, stderr.String())
+		}
+		got, err := os.ReadFile(chart)
+		if err != nil {
+			t.Fatalf("os.ReadFile() unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(canonicalMermaid, string(got)); diff != "" {
+			t.Errorf("formatted chart mismatch (-expected +got):\n%s", diff)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("run() wrote to stdout: %q", stdout.String())
+		}
+	})
+
+	t.Run("fmt -w leaves files with warnings untouched", func(t *testing.T) {
+		const source = "digraph {\n  rankdir=BT\n  a -> b\n}\n"
+		chart := writeTemp(t, "chart.dot", source)
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"fmt", "-w", chart}, &environment{stdout: &stdout, stderr: &stderr}); code != exitFailure {
+			t.Fatalf("run() = %d, expected %d (stderr: %s)", code, exitFailure, stderr.String())
+		}
+		if !strings.Contains(stderr.String(), "not rewritten") {
+			t.Errorf("run() stderr = %q, expected it to contain %q", stderr.String(), "not rewritten")
+		}
+		got, err := os.ReadFile(chart)
+		if err != nil {
+			t.Fatalf("os.ReadFile() unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(source, string(got)); diff != "" {
+			t.Errorf("input file changed (-expected +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,357 @@
+package flowchart
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// d2Keywords holds the reserved keywords of D2, which cannot be used as shape keys, together with
+// "title", which is the key of the chart's title.
+var d2Keywords = map[string]bool{
+	"label": true, "shape": true, "icon": true, "width": true, "height": true, "constraint": true,
+	"tooltip": true, "link": true, "near": true, "class": true, "classes": true, "style": true,
+	"direction": true, "vars": true, "layers": true, "scenarios": true, "steps": true,
+	"source-arrowhead": true, "target-arrowhead": true, "grid-rows": true, "grid-columns": true,
+	"grid-gap": true, "vertical-gap": true, "horizontal-gap": true, "top": true, "left": true,
+	"filled": true, "title": true,
+}
+
+// d2ID derives a D2 key from a node name or subgraph title. Keys are made of lowercase ASCII
+// letters, digits, underscores and single inner dashes, since D2 keys are case-insensitive and
+// dots, colons and arrows have a meaning of their own. When anything other than the case had to be
+// changed, or the key is empty or a reserved keyword, a hash of the original name is appended so
+// that the key stays unique and stable.
+func d2ID(name string) string {
+	lower := strings.ToLower(name)
+
+	var sb strings.Builder
+	for i, r := range lower {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' ||
+			(r == '-' && i > 0 && i < len(lower)-1 && lower[i-1] != '-')) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+
+	id := sb.String()
+	if id != lower || id == "" || d2Keywords[id] {
+		return id + "_" + nameHash(name)
+	}
+	return id
+}
+
+// d2Quote returns s as a double-quoted D2 string. D2 uses the same escapes as Graphviz.
+func d2Quote(s string) string {
+	return dotQuote(s)
+}
+
+// d2Direction converts a DirectionEnum to a D2 direction.
+func d2Direction(d DirectionEnum) string {
+	switch d {
+	case DirectionHorizontalRight:
+		return "right"
+	case DirectionHorizontalLeft:
+		return "left"
+	default:
+		return "down"
+	}
+}
+
+// d2Shape returns the D2 shape drawn for a node type.
+func d2Shape(t NodeTypeEnum) string {
+	switch t {
+	case NodeTypeTerminator:
+		return "oval"
+	case NodeTypeSubprocess:
+		return "page"
+	case NodeTypeDecision:
+		return "diamond"
+	case NodeTypeInputOutput:
+		return "parallelogram"
+	case NodeTypeConnector:
+		return "circle"
+	case NodeTypeDatabase:
+		return "cylinder"
+	default:
+		return "rectangle"
+	}
+}
+
+// d2Arrowhead returns the D2 arrowhead shape of a link's arrows, or "" for the default triangle.
+func d2Arrowhead(t ArrowTypeEnum) string {
+	switch t {
+	case ArrowTypeCircle:
+		return "circle"
+	case ArrowTypeCross:
+		return "cross"
+	default:
+		return ""
+	}
+}
+
+// d2Connection returns the D2 connection operator of a link, which draws its arrows.
+func d2Connection(l *Link) string {
+	origin := l.OriginArrow && l.ArrowType != ArrowTypeNone
+	target := l.TargetArrow && l.ArrowType != ArrowTypeNone
+	switch {
+	case origin && target:
+		return "<->"
+	case origin:
+		return "<-"
+	case target:
+		return "->"
+	default:
+		return "--"
+	}
+}
+
+// d2StyleLines returns the D2 style properties of a style. The width of the line is given
+// separately so that thick links can supply their own default.
+func d2StyleLines(s Style, strokeWidth string) []string {
+	var lines []string
+	if s.Fill != "" {
+		lines = append(lines, "style.fill: "+d2Quote(s.Fill))
+	}
+	if s.Stroke != "" {
+		lines = append(lines, "style.stroke: "+d2Quote(s.Stroke))
+	}
+	if strokeWidth != "" {
+		lines = append(lines, "style.stroke-width: "+strokeWidth)
+	}
+	if s.Color != "" {
+		lines = append(lines, "style.font-color: "+d2Quote(s.Color))
+	}
+	if size := styleWidth(s.FontSize, 0); size > 0 {
+		lines = append(lines, "style.font-size: "+svgNumber(size))
+	}
+	if s.FontWeight == "bold" || s.FontWeight == "bolder" {
+		lines = append(lines, "style.bold: true")
+	}
+	return lines
+}
+
+// d2StrokeWidth returns the D2 stroke-width of a style, or "" if it is not set or not understood.
+func d2StrokeWidth(s Style) string {
+	if w := styleWidth(s.StrokeWidth, 0); w > 0 {
+		return svgNumber(w)
+	}
+	return ""
+}
+
+// d2Renderer writes the D2 source of a flowchart.
+type d2Renderer struct {
+	sb        strings.Builder
+	ids       idMap
+	elements  treeElements            // Nodes and subgraphs of the tree, to resolve link endpoints
+	subgraphs map[*Flowchart]string   // Keys of the subgraphs
+	parents   map[Linkable]*Flowchart // Subgraph containing each node and subgraph
+	root      *Flowchart
+}
+
+// line writes a line of the source indented to the given depth.
+func (r *d2Renderer) line(depth int, format string, args ...any) {
+	r.sb.WriteString(strings.Repeat("  ", depth))
+	r.sb.WriteString(fmt.Sprintf(format, args...))
+	r.sb.WriteString("\n")
+}
+
+// key returns the key of a node or subgraph within its container.
+func (r *d2Renderer) key(l Linkable) string {
+	if sub, ok := l.(*Flowchart); ok {
+		return r.subgraphs[sub]
+	}
+	return r.ids.lookup(l.nodeName(), d2ID)
+}
+
+// path returns the key of the node or subgraph a link endpoint stands for, from the top level of
+// the chart.
+func (r *d2Renderer) path(l Linkable) string {
+	l = r.elements.resolve(l)
+	path := r.key(l)
+	for f := r.parents[l]; f != nil && f != r.root; f = r.parents[f] {
+		path = r.subgraphs[f] + "." + path
+	}
+	return path
+}
+
+// block writes a declaration followed by its properties in braces, or on its own if it has none.
+func (r *d2Renderer) block(depth int, declaration string, properties []string) {
+	if len(properties) == 0 {
+		r.line(depth, "%s", declaration)
+		return
+	}
+	r.line(depth, "%s {", declaration)
+	for _, p := range properties {
+		r.line(depth+1, "%s", p)
+	}
+	r.line(depth, "}")
+}
+
+// d2ClassLine returns the D2 property assigning style classes, or nil if there are none.
+func d2ClassLine(classes []string) []string {
+	switch len(classes) {
+	case 0:
+		return nil
+	case 1:
+		return []string{"class: " + classes[0]}
+	default:
+		return []string{"class: [" + strings.Join(classes, "; ") + "]"}
+	}
+}
+
+// writeContents writes the nodes and subgraphs of f.
+func (r *d2Renderer) writeContents(f *Flowchart, depth int) {
+	for _, node := range f.Nodes {
+		properties := []string{"shape: " + d2Shape(node.Type)}
+		properties = append(properties, d2ClassLine(node.Classes)...)
+		if node.Style != nil {
+			properties = append(properties, d2StyleLines(*node.Style, d2StrokeWidth(*node.Style))...)
+		}
+		r.block(depth, fmt.Sprintf("%s: %s", r.key(node), d2Quote(nodeText(node))), properties)
+	}
+	for _, sub := range f.Subgraphs {
+		r.line(depth, "%s: %s {", r.key(sub), d2Quote(sub.nodeName()))
+		if sub.Direction != f.Direction {
+			r.line(depth+1, "direction: %s", d2Direction(sub.Direction))
+		}
+		for _, p := range d2ClassLine(sub.Classes) {
+			r.line(depth+1, "%s", p)
+		}
+		if sub.Style != nil {
+			for _, p := range d2StyleLines(*sub.Style, d2StrokeWidth(*sub.Style)) {
+				r.line(depth+1, "%s", p)
+			}
+		}
+		r.writeContents(sub, depth+1)
+		r.line(depth, "}")
+	}
+}
+
+// writeLink writes the connection of a link. Invisible links are kept with no opacity, so that
+// they still affect the layout.
+func (r *d2Renderer) writeLink(l *Link) {
+	declaration := fmt.Sprintf("%s %s %s", r.path(l.Origin), d2Connection(l), r.path(l.Target))
+	if l.Label != nil && *l.Label != "" {
+		declaration += ": " + d2Quote(linkText(l))
+	}
+
+	var style Style
+	if l.Style != nil {
+		style = *l.Style
+	}
+	width := d2StrokeWidth(style)
+	if width == "" && l.LineType == LineTypeThick {
+		width = "3"
+	}
+	properties := d2ClassLine(l.Classes)
+	properties = append(properties, d2StyleLines(style, width)...)
+	switch l.LineType {
+	case LineTypeDotted:
+		properties = append(properties, "style.stroke-dash: 3")
+	case LineTypeNone:
+		properties = append(properties, "style.opacity: 0")
+	}
+	if shape := d2Arrowhead(l.ArrowType); shape != "" {
+		if l.OriginArrow {
+			properties = append(properties, "source-arrowhead.shape: "+shape)
+		}
+		if l.TargetArrow {
+			properties = append(properties, "target-arrowhead.shape: "+shape)
+		}
+	}
+	r.block(0, declaration, properties)
+}
+
+// RenderD2 generates the D2 (Terrastruct) source of the flowchart.
+//
+// Nodes are shapes keyed by their sanitized names (see d2ID) and labelled with their text, drawn
+// with the D2 shape for their type. Subgraphs are nested containers, and links are connections
+// between the full paths of their endpoints, declared after all shapes, with dashes for dotted
+// lines, a wider stroke for thick lines and arrowheads for the arrow type. Style classes become D2
+// classes and inline styles become style properties. The title of the chart is a text shape at the
+// top, and the direction of the chart is the D2 direction.
+// It returns the D2 source or an error if validation fails.
+func RenderD2(f *Flowchart) (string, error) {
+	if err := validateD2(f); err != nil {
+		return "", err
+	}
+	ids, err := newIDMap(f, d2ID)
+	if err != nil {
+		return "", err
+	}
+
+	r := &d2Renderer{
+		ids:       ids,
+		elements:  newTreeElements(f),
+		subgraphs: make(map[*Flowchart]string),
+		parents:   make(map[Linkable]*Flowchart),
+		root:      f,
+	}
+	used := make(map[string]bool)
+	for _, id := range ids {
+		used[id] = true
+	}
+	untitled := 0
+	walkFlowchart(f, func(sub *Flowchart, _ []string) {
+		for _, node := range sub.Nodes {
+			r.parents[node] = sub
+		}
+		for _, child := range sub.Subgraphs {
+			r.parents[child] = sub
+			if child.Title != nil && *child.Title != "" {
+				r.subgraphs[child] = ids.lookup(*child.Title, d2ID)
+				continue
+			}
+			untitled++
+			id := "subgraph_" + strconv.Itoa(untitled)
+			unique := id
+			for i := 2; used[unique]; i++ {
+				unique = id + "_" + strconv.Itoa(i)
+			}
+			used[unique] = true
+			r.subgraphs[child] = unique
+		}
+	})
+
+	r.line(0, "direction: %s", d2Direction(f.Direction))
+	if f.Title != nil && *f.Title != "" {
+		r.block(0, "title: "+d2Quote(*f.Title), []string{"shape: text", "near: top-center", "style.font-size: 24"})
+	}
+	if classDefs := allClassDefs(f); len(classDefs) > 0 {
+		r.line(0, "classes: {")
+		for _, def := range classDefs {
+			r.block(1, def.Name+":", d2StyleLines(def.Style, d2StrokeWidth(def.Style)))
+		}
+		r.line(0, "}")
+	}
+	r.line(0, "")
+	r.writeContents(f, 0)
+
+	links := getAllLinks(f)
+	if len(links) > 0 {
+		r.line(0, "")
+	}
+	for i := range links {
+		r.writeLink(&links[i])
+	}
+	return r.sb.String(), nil
+}
+
+// validateD2 validates the Flowchart structure to ensure it can be rendered as D2.
+// It checks for the following violations:
+// 1. All node and subgraph names must be unique across the whole flowchart.
+// 2. Names must map to distinct D2 keys (see d2ID).
+// 3. Style classes must have valid, unique names, and every class assigned must be defined.
+//
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func validateD2(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, idCollisionViolations(f, d2ID)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	violations = append(violations, styleClassViolations(f)...)
+	return newValidationError(violations...)
+}
@@ -0,0 +1,133 @@
+package flowchart
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderD2(t *testing.T) {
+	f := graphExportFixture()
+	f.Links[0].OriginArrow = true
+	f.Subgraphs = append(f.Subgraphs, &Flowchart{
+		Direction: DirectionVertical,
+		Nodes:     []*Node{InputOutputNode("Read input", nil), ConnectorNode("Label", nil)},
+	})
+
+	expected := `direction: right
+title: "Orders" {
+  shape: text
+  near: top-center
+  style.font-size: 24
+}
+classes: {
+  warn: {
+    style.fill: "#f96"
+    style.stroke-width: 2
+  }
+}
+
+start: "Start" {
+  shape: oval
+}
+check: "Valid?" {
+  shape: diamond
+  class: warn
+}
+storage: "Storage" {
+  db: "Orders" {
+    shape: cylinder
+  }
+}
+subgraph_1: "" {
+  direction: down
+  read_input_017f606d: "Read input" {
+    shape: parallelogram
+  }
+  label_9eccf29d: "Label" {
+    shape: circle
+  }
+}
+
+check -> storage.db: "yes & save" {
+  style.stroke-width: 3
+  target-arrowhead.shape: circle
+}
+start -> storage {
+  style.stroke-dash: 3
+}
+start <-> check
+start -> storage.db {
+  style.opacity: 0
+}
+`
+
+	got, err := RenderD2(f)
+	if err != nil {
+		t.Fatalf("RenderD2() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("RenderD2() mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestRenderD2_Errors(t *testing.T) {
+	a := &Node{name: "a", Classes: []string{"missing"}}
+	_, err := RenderD2(&Flowchart{
+		Nodes: []*Node{a, ProcessNode("a", nil)},
+		Links: []Link{SolidLink(a, &Node{name: "Phantom"}, nil)},
+	})
+	for _, code := range []error{ErrDuplicateName, ErrUndefinedStyleClass, ErrOrphanedEndpoint} {
+		if !errors.Is(err, code) {
+			t.Errorf("RenderD2() error = %v, expected %v", err, code)
+		}
+	}
+}
+
+func TestD2ID(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "start", expected: "start"},
+		{name: "Save_Order", expected: "save_order"},
+		{name: "step-2", expected: "step-2"},
+		{name: "Save order", expected: "save_order_" + nameHash("Save order")},
+		{name: "a.b", expected: "a_b_" + nameHash("a.b")},
+		{name: "a--b", expected: "a-_b_" + nameHash("a--b")},
+		{name: "-x", expected: "_x_" + nameHash("-x")},
+		{name: "Shape", expected: "shape_" + nameHash("Shape")},
+		{name: "", expected: "_" + nameHash("")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d2ID(tt.name); got != tt.expected {
+				t.Errorf("d2ID(%q) = %q, expected %q", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestD2Connection(t *testing.T) {
+	tests := []struct {
+		name     string
+		link     Link
+		expected string
+	}{
+		{name: "target arrow", link: Link{ArrowType: ArrowTypeNormal, TargetArrow: true}, expected: "->"},
+		{name: "origin arrow", link: Link{ArrowType: ArrowTypeCircle, OriginArrow: true}, expected: "<-"},
+		{name: "both arrows", link: Link{ArrowType: ArrowTypeCross, OriginArrow: true, TargetArrow: true}, expected: "<->"},
+		{name: "no arrows", link: Link{ArrowType: ArrowTypeNormal}, expected: "--"},
+		{name: "arrow type none", link: Link{ArrowType: ArrowTypeNone, TargetArrow: true}, expected: "--"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d2Connection(&tt.link); got != tt.expected {
+				t.Errorf("d2Connection() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
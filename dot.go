@@ -0,0 +1,250 @@
+package flowchart
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// dotRankDir converts a DirectionEnum to a Graphviz rankdir value.
+// Valid values are:
+// - "LR" for left-to-right horizontal direction.
+// - "RL" for right-to-left horizontal direction.
+// - "TB" for top-to-bottom vertical direction (default if direction is not recognized).
+func dotRankDir(d DirectionEnum) string {
+	switch d {
+	case DirectionHorizontalRight:
+		return "LR"
+	case DirectionHorizontalLeft:
+		return "RL"
+	case DirectionVertical:
+		return "TB"
+	}
+	return "TB"
+}
+
+// dotNodeShape returns the Graphviz node attributes (shape and, where needed, style or
+// peripheries) that best represent the given NodeTypeEnum.
+func dotNodeShape(t NodeTypeEnum) string {
+	switch t {
+	case NodeTypeTerminator:
+		return "shape=box, style=rounded"
+	case NodeTypeProcess:
+		return "shape=box"
+	case NodeTypeSubprocess:
+		return "shape=box, peripheries=2"
+	case NodeTypeDecision:
+		return "shape=diamond"
+	case NodeTypeInputOutput:
+		return "shape=parallelogram"
+	case NodeTypeConnector:
+		return "shape=circle"
+	case NodeTypeDatabase:
+		return "shape=cylinder"
+	default:
+		return "shape=box"
+	}
+}
+
+// dotLineStyle converts a LineTypeEnum to a Graphviz edge style.
+// A LineTypeNone link is rendered as an invisible edge so that it still affects layout.
+func dotLineStyle(l LineTypeEnum) string {
+	switch l {
+	case LineTypeNone:
+		return "invis"
+	case LineTypeSolid:
+		return "solid"
+	case LineTypeDotted:
+		return "dotted"
+	case LineTypeThick:
+		return "bold"
+	default:
+		return "solid"
+	}
+}
+
+// dotArrowShape converts an ArrowTypeEnum to a Graphviz arrow shape.
+// Graphviz has no cross arrowhead, so ArrowTypeCross is approximated with "tee".
+func dotArrowShape(a ArrowTypeEnum) string {
+	switch a {
+	case ArrowTypeNormal:
+		return "normal"
+	case ArrowTypeCircle:
+		return "dot"
+	case ArrowTypeCross:
+		return "tee"
+	default:
+		return "none"
+	}
+}
+
+// renderDOTArrows generates the Graphviz dir, arrowhead and arrowtail attributes for a Link
+// based on its ArrowType and the OriginArrow and TargetArrow flags.
+func renderDOTArrows(l Link) string {
+	head := l.TargetArrow && l.ArrowType != ArrowTypeNone
+	tail := l.OriginArrow && l.ArrowType != ArrowTypeNone
+	shape := dotArrowShape(l.ArrowType)
+
+	switch {
+	case head && tail:
+		return fmt.Sprintf("dir=both, arrowhead=%s, arrowtail=%s", shape, shape)
+	case head:
+		return fmt.Sprintf("dir=forward, arrowhead=%s", shape)
+	case tail:
+		return fmt.Sprintf("dir=back, arrowtail=%s", shape)
+	default:
+		return "dir=none"
+	}
+}
+
+// dotQuote returns s as a double-quoted Graphviz ID, escaping backslashes, quotes and newlines.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// dotClusterID returns the Graphviz subgraph ID for a subgraph title. The "cluster_" prefix
+// is what makes Graphviz draw the subgraph as a boxed cluster.
+func dotClusterID(title string) string {
+	return dotQuote("cluster_" + title)
+}
+
+// dotAnchorID returns the ID of the invisible anchor node placed inside a cluster so that
+// links pointing at a subgraph have a concrete endpoint.
+func dotAnchorID(title string) string {
+	return dotQuote("__anchor_" + title)
+}
+
+// renderDOTNode generates a Graphviz representation of a Node based on its type and label.
+// Nodes without a label are labelled with their name.
+// It returns a string with the proper indentation for the node's position in the graph.
+func renderDOTNode(n *Node, indents int) string {
+	indentSpaces := strings.Repeat(" ", 4*indents)
+
+	label := n.name
+	if n.Label != nil && *n.Label != "" {
+		label = *n.Label
+	}
+	return fmt.Sprintf("%s%s [label=%s, %s];\n", indentSpaces, dotQuote(n.name), dotQuote(label), dotNodeShape(n.Type))
+}
+
+// renderDOTLink generates a Graphviz representation of a Link between two Linkables.
+// Links to or from a subgraph are attached to the subgraph's anchor node and clipped at the
+// cluster border using lhead and ltail.
+// Returns an empty string if either the origin or target is nil.
+func renderDOTLink(l Link) string {
+	if l.Target == nil || l.Origin == nil {
+		return ""
+	}
+
+	origin, target := dotQuote(l.Origin.nodeName()), dotQuote(l.Target.nodeName())
+	attrs := []string{"style=" + dotLineStyle(l.LineType), renderDOTArrows(l)}
+	if _, ok := l.Origin.(*Flowchart); ok {
+		origin = dotAnchorID(l.Origin.nodeName())
+		attrs = append(attrs, "ltail="+dotClusterID(l.Origin.nodeName()))
+	}
+	if _, ok := l.Target.(*Flowchart); ok {
+		target = dotAnchorID(l.Target.nodeName())
+		attrs = append(attrs, "lhead="+dotClusterID(l.Target.nodeName()))
+	}
+	if l.Label != nil && *l.Label != "" {
+		attrs = append(attrs, "label="+dotQuote(*l.Label))
+	}
+
+	return fmt.Sprintf("%s -> %s [%s]", origin, target, strings.Join(attrs, ", "))
+}
+
+// renderDOTFlowchart generates a Graphviz representation of a Flowchart.
+// It recursively renders subgraphs as "cluster_" subgraphs, to any depth. Subgraphs named in
+// anchors receive an invisible anchor node used as the endpoint of links to the subgraph.
+// Graphviz has no per-cluster rank direction, so the direction of subgraphs is not rendered.
+func renderDOTFlowchart(f *Flowchart, indents int, subgraph bool, anchors []string) string {
+	indentSpaces := strings.Repeat(" ", 4*indents)
+	innerSpaces := strings.Repeat(" ", 4*(indents+1))
+	var sb strings.Builder
+
+	if subgraph {
+		// start subgraph
+		if f.Title == nil || *f.Title == "" {
+			panic("subgraph with no title")
+		}
+		sb.WriteString(fmt.Sprintf("%ssubgraph %s {\n", indentSpaces, dotClusterID(*f.Title)))
+		sb.WriteString(fmt.Sprintf("%slabel=%s;\n", innerSpaces, dotQuote(*f.Title)))
+		if slices.Contains(anchors, *f.Title) {
+			sb.WriteString(fmt.Sprintf("%s%s [shape=point, style=invis];\n", innerSpaces, dotAnchorID(*f.Title)))
+		}
+	} else {
+		sb.WriteString("digraph {\n")
+		if f.Title != nil && *f.Title != "" {
+			sb.WriteString(fmt.Sprintf("%slabel=%s;\n", innerSpaces, dotQuote(*f.Title)))
+			sb.WriteString(fmt.Sprintf("%slabelloc=t;\n", innerSpaces))
+		}
+		sb.WriteString(fmt.Sprintf("%srankdir=%s;\n", innerSpaces, dotRankDir(f.Direction)))
+		if len(anchors) > 0 {
+			sb.WriteString(fmt.Sprintf("%scompound=true;\n", innerSpaces))
+		}
+	}
+
+	// nodes
+	for _, node := range f.Nodes {
+		sb.WriteString(renderDOTNode(node, indents+1))
+	}
+
+	// subgraphs
+	for _, sub := range f.Subgraphs {
+		sb.WriteString(renderDOTFlowchart(sub, indents+1, true, anchors))
+	}
+
+	if !subgraph {
+		for _, link := range getAllLinks(f) {
+			sb.WriteString(fmt.Sprintf("%s%s;\n", innerSpaces, renderDOTLink(link)))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("%s}\n", indentSpaces))
+
+	return sb.String()
+}
+
+// RenderDOT generates a Graphviz DOT digraph string for the given Flowchart object.
+// Unlike RenderMermaid, nested subgraphs are supported to any depth and are rendered as
+// nested clusters.
+// It returns the DOT representation of the flowchart or an error if validation fails.
+func RenderDOT(f *Flowchart) (string, error) {
+	err := validateDOT(f)
+	if err != nil {
+		return "", err
+	}
+
+	return renderDOTFlowchart(f, 0, false, linkedSubgraphTitles(f)), nil
+}
+
+// validateDOT validates the Flowchart structure to ensure it can be rendered as a DOT digraph.
+// It checks for the following violations:
+// 1. Every subgraph, at any depth, must have a title.
+// 2. All node and subgraph names must be unique across the whole flowchart.
+//
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func validateDOT(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, untitledSubgraphViolations(f)...)
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	return newValidationError(violations...)
+}
+
+// linkedSubgraphTitles returns the titles of all subgraphs that are used as a link origin or
+// target anywhere in the flowchart.
+func linkedSubgraphTitles(f *Flowchart) []string {
+	var titles []string
+	for _, link := range getAllLinks(f) {
+		for _, end := range []Linkable{link.Origin, link.Target} {
+			if sub, ok := end.(*Flowchart); ok && !slices.Contains(titles, sub.nodeName()) {
+				titles = append(titles, sub.nodeName())
+			}
+		}
+	}
+	return titles
+}
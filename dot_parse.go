@@ -0,0 +1,995 @@
+package flowchart
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ParseWarning describes part of the input of a parser that could not be represented exactly in a
+// Flowchart and was approximated or left out.
+type ParseWarning struct {
+	Line    int    // Line of the input the warning is about, or 0 if it is about the input as a whole
+	Message string // What was approximated or left out
+}
+
+// String returns the warning prefixed with its line, e.g. "line 3: shape \"star\" ...".
+func (w ParseWarning) String() string {
+	if w.Line == 0 {
+		return w.Message
+	}
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+// dotNodeShapes maps the Graphviz shapes that have an equivalent node type. Boxes with rounded
+// corners are terminators and boxes with two peripheries are subprocesses, as in RenderDOT.
+var dotNodeShapes = map[string]NodeTypeEnum{
+	"box":           NodeTypeProcess,
+	"rect":          NodeTypeProcess,
+	"rectangle":     NodeTypeProcess,
+	"square":        NodeTypeProcess,
+	"ellipse":       NodeTypeTerminator,
+	"oval":          NodeTypeTerminator,
+	"diamond":       NodeTypeDecision,
+	"parallelogram": NodeTypeInputOutput,
+	"circle":        NodeTypeConnector,
+	"doublecircle":  NodeTypeConnector,
+	"point":         NodeTypeConnector,
+	"cylinder":      NodeTypeDatabase,
+}
+
+// dotLayoutAttributes holds the Graphviz attributes that only affect how Graphviz lays out or
+// outputs a graph. They are ignored without a warning.
+var dotLayoutAttributes = map[string]bool{
+	"arrowsize": true, "bb": true, "center": true, "charset": true, "compound": true,
+	"concentrate": true, "constraint": true, "dpi": true, "fixedsize": true, "forcelabels": true,
+	"group": true, "headport": true, "height": true, "labelangle": true, "labeldistance": true,
+	"labelfloat": true, "labeljust": true, "labelloc": true, "landscape": true, "layout": true,
+	"lhead": true, "lp": true, "ltail": true, "margin": true, "mclimit": true, "minlen": true,
+	"newrank": true, "nodesep": true, "nslimit": true, "ordering": true, "orientation": true,
+	"outputorder": true, "overlap": true, "pack": true, "packmode": true, "pad": true, "pos": true,
+	"rank": true, "ranksep": true, "ratio": true, "remincross": true, "rotate": true,
+	"samehead": true, "sametail": true, "searchsize": true, "size": true, "splines": true,
+	"tailport": true, "weight": true, "width": true,
+}
+
+// Regular expressions matching the line breaks and the other tags of HTML-like labels.
+var (
+	dotHTMLBreakRe = regexp.MustCompile(`(?i)<br\s*/?>`)
+	dotHTMLTagRe   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// dotColorNameRe matches colour names, which Graphviz shares with CSS.
+var dotColorNameRe = regexp.MustCompile(`^[A-Za-z]+$`)
+
+// dotToken is a token of DOT source.
+type dotToken struct {
+	text   string // The ID, without quotes, or the punctuation
+	id     bool   // Whether the token is an ID rather than punctuation or the end of the input
+	quoted bool   // Whether the ID was a quoted string, which is never a keyword
+	html   bool   // Whether the ID was an HTML string
+	line   int
+}
+
+// keyword reports whether the token is the given DOT keyword, which is case-insensitive.
+func (t dotToken) keyword(word string) bool {
+	return t.id && !t.quoted && !t.html && strings.EqualFold(t.text, word)
+}
+
+// punct reports whether the token is the given punctuation.
+func (t dotToken) punct(text string) bool {
+	return !t.id && t.text == text
+}
+
+// describe returns the token as shown in error messages.
+func (t dotToken) describe() string {
+	if !t.id && t.text == "" {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// isDOTIDByte reports whether c can be part of an unquoted alphanumeric DOT ID.
+func isDOTIDByte(c byte) bool {
+	return c == '_' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// scanDOT splits DOT source into tokens, dropping comments and preprocessor lines and joining
+// quoted strings concatenated with "+".
+func scanDOT(src string) ([]dotToken, error) {
+	var tokens []dotToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case c == '#' && (i == 0 || src[i-1] == '\n'):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			tokens = append(tokens, dotToken{text: src[i : i+2], line: line})
+			i += 2
+		case strings.ContainsRune("{}[];,=:+", rune(c)):
+			tokens = append(tokens, dotToken{text: string(c), line: line})
+			i++
+		case c == '"':
+			start := line
+			var sb strings.Builder
+			i++
+			for ; i < len(src) && src[i] != '"'; i++ {
+				switch {
+				case src[i] == '\\' && i+1 < len(src) && src[i+1] == '"':
+					sb.WriteByte('"')
+					i++
+				case src[i] == '\\' && i+1 < len(src) && src[i+1] == '\n':
+					line++
+					i++
+				case src[i] == '\\' && strings.HasPrefix(src[i+1:], "\r\n"):
+					line++
+					i += 2
+				default:
+					if src[i] == '\n' {
+						line++
+					}
+					sb.WriteByte(src[i])
+				}
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			i++
+			tokens = append(tokens, dotToken{text: sb.String(), id: true, quoted: true, line: start})
+		case c == '<':
+			start, depth, j := line, 0, i
+			for ; j < len(src); j++ {
+				if src[j] == '\n' {
+					line++
+				}
+				if src[j] == '<' {
+					depth++
+				} else if src[j] == '>' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated HTML string", start)
+			}
+			tokens = append(tokens, dotToken{text: src[i+1 : j], id: true, html: true, line: start})
+			i = j + 1
+		case c == '-' || c == '.' || ('0' <= c && c <= '9'):
+			j := i + 1
+			for j < len(src) && (src[j] == '.' || ('0' <= src[j] && src[j] <= '9')) {
+				j++
+			}
+			tokens = append(tokens, dotToken{text: src[i:j], id: true, line: line})
+			i = j
+		case isDOTIDByte(c):
+			j := i + 1
+			for j < len(src) && isDOTIDByte(src[j]) {
+				j++
+			}
+			tokens = append(tokens, dotToken{text: src[i:j], id: true, line: line})
+			i = j
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+		}
+	}
+
+	// join "a" + "b"
+	var joined []dotToken
+	for _, t := range tokens {
+		n := len(joined)
+		if t.quoted && n >= 2 && joined[n-1].punct("+") && joined[n-2].quoted {
+			joined[n-2].text += t.text
+			joined = joined[:n-1]
+			continue
+		}
+		joined = append(joined, t)
+	}
+	return joined, nil
+}
+
+// dotValue is the value of a Graphviz attribute.
+type dotValue struct {
+	text string
+	html bool // Whether the value was an HTML string
+	line int  // Line where the value was set
+}
+
+// dotAttrs holds the attributes of a graph, node or edge by name.
+type dotAttrs map[string]dotValue
+
+// merge returns a copy of the attributes with other applied on top of them.
+func (a dotAttrs) merge(other dotAttrs) dotAttrs {
+	merged := make(dotAttrs, len(a)+len(other))
+	for k, v := range a {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
+
+// text returns the text of an attribute, or "" if it is not set.
+func (a dotAttrs) text(name string) string {
+	return a[name].text
+}
+
+// styles returns the comma-separated entries of the style attribute.
+func (a dotAttrs) styles() []string {
+	var styles []string
+	for _, s := range strings.Split(a.text("style"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			styles = append(styles, s)
+		}
+	}
+	return styles
+}
+
+// dotScope holds the attribute defaults of a graph or subgraph being read, and the flowchart that
+// the nodes declared in it belong to.
+type dotScope struct {
+	graph     *Flowchart // The root flowchart, or the subgraph of the innermost cluster
+	cluster   bool       // Whether the scope is the body of a cluster
+	root      bool       // Whether the scope is the body of the graph
+	nodeAttrs dotAttrs
+	edgeAttrs dotAttrs
+}
+
+// pendingDOTEdge is an edge read from the source, resolved to a link once all nodes are known.
+type pendingDOTEdge struct {
+	origin, target string
+	attrs          dotAttrs
+	line           int
+}
+
+// dotParser holds the state needed while reading DOT source.
+type dotParser struct {
+	tokens    []dotToken
+	pos       int
+	root      *Flowchart
+	directed  bool
+	names     []string                  // Node names, in the order the nodes were first mentioned
+	nodeAttrs map[string]dotAttrs       // Attributes of each node
+	nodeLines map[string]int            // Line where each node was first mentioned
+	nodeScope map[string]*Flowchart     // The flowchart or subgraph each node belongs to
+	parents   map[*Flowchart]*Flowchart // The flowchart or subgraph containing each subgraph
+	clusters  map[string]*Flowchart     // Subgraphs by cluster ID
+	graphs    map[*Flowchart]dotAttrs   // Attributes of the root flowchart and each subgraph
+	clusterID map[*Flowchart]string     // Cluster ID of each subgraph
+	lines     map[*Flowchart]int        // Line where each subgraph was opened
+	edges     []pendingDOTEdge
+	warnings  []ParseWarning
+	warned    map[string]bool
+}
+
+// ParseDOT reads a Graphviz graph or digraph and returns the equivalent Flowchart, together with
+// warnings about the parts of the graph that could only be approximated or were left out.
+//
+// Nodes are named after their DOT IDs and typed after their shape: boxes are processes, or
+// terminators when rounded and subprocesses with two peripheries, ellipses are terminators,
+// diamonds decisions, parallelograms input-output, circles and points connectors and cylinders
+// databases. Nodes without a shape become process nodes, and other shapes process nodes with a
+// warning. Clusters (subgraphs whose ID starts with "cluster") become subgraphs titled with their
+// label, or their ID without the "cluster_" prefix; other subgraphs only group statements.
+// Edges become links with the line type of their style (dashed and dotted lines are dotted, bold
+// lines thick, invisible edges have no line), arrows following dir, arrowhead and arrowtail, and
+// end at a subgraph when they are clipped with lhead or ltail. The rankdir of the graph is its
+// direction and its label is its title. Colours, pen widths and fonts become inline styles.
+// Attributes that only affect the Graphviz layout are ignored; any other attribute, port or HTML
+// label that cannot be represented is reported as a warning. Only the first graph of the input is
+// read. It understands everything RenderDOT emits.
+func ParseDOT(r io.Reader) (*Flowchart, []ParseWarning, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, err := scanDOT(string(src))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := &dotParser{
+		tokens:    tokens,
+		nodeAttrs: make(map[string]dotAttrs),
+		nodeLines: make(map[string]int),
+		nodeScope: make(map[string]*Flowchart),
+		parents:   make(map[*Flowchart]*Flowchart),
+		clusters:  make(map[string]*Flowchart),
+		graphs:    make(map[*Flowchart]dotAttrs),
+		clusterID: make(map[*Flowchart]string),
+		lines:     make(map[*Flowchart]int),
+		warned:    make(map[string]bool),
+	}
+	if err := p.parseGraph(); err != nil {
+		return nil, nil, err
+	}
+	if t := p.peek(); t.id || t.text != "" {
+		p.warn(t.line, "only the first graph is imported")
+	}
+
+	p.buildGraph()
+	p.buildLinks(p.buildNodes())
+	slices.SortStableFunc(p.warnings, func(a, b ParseWarning) int { return a.Line - b.Line })
+	return p.root, p.warnings, nil
+}
+
+// warn records a warning, unless the same warning was already recorded.
+func (p *dotParser) warn(line int, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if p.warned[message] {
+		return
+	}
+	p.warned[message] = true
+	p.warnings = append(p.warnings, ParseWarning{Line: line, Message: message})
+}
+
+// peek returns the next token without consuming it.
+func (p *dotParser) peek() dotToken {
+	return p.peekAt(0)
+}
+
+// peekAt returns the token offset tokens after the next one, or the end of input.
+func (p *dotParser) peekAt(offset int) dotToken {
+	if p.pos+offset >= len(p.tokens) {
+		line := 1
+		if len(p.tokens) > 0 {
+			line = p.tokens[len(p.tokens)-1].line
+		}
+		return dotToken{line: line}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+// next consumes and returns the next token.
+func (p *dotParser) next() dotToken {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+// expect consumes the next token, which must be the given punctuation.
+func (p *dotParser) expect(text string) error {
+	if t := p.next(); !t.punct(text) {
+		return fmt.Errorf("line %d: expected %q, got %s", t.line, text, t.describe())
+	}
+	return nil
+}
+
+// expectID consumes the next token, which must be an ID.
+func (p *dotParser) expectID() (dotToken, error) {
+	t := p.next()
+	if !t.id {
+		return t, fmt.Errorf("line %d: expected an ID, got %s", t.line, t.describe())
+	}
+	return t, nil
+}
+
+// parseGraph reads "[strict] (graph | digraph) [ID] { statements }".
+func (p *dotParser) parseGraph() error {
+	t := p.next()
+	if t.keyword("strict") {
+		t = p.next()
+	}
+	switch {
+	case t.keyword("digraph"):
+		p.directed = true
+	case t.keyword("graph"):
+	default:
+		return fmt.Errorf("line %d: expected graph or digraph, got %s", t.line, t.describe())
+	}
+	if p.peek().id {
+		p.next()
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+
+	p.root = &Flowchart{}
+	p.graphs[p.root] = dotAttrs{}
+	scope := &dotScope{graph: p.root, root: true, nodeAttrs: dotAttrs{}, edgeAttrs: dotAttrs{}}
+	if _, err := p.parseStatements(scope); err != nil {
+		return err
+	}
+	return p.expect("}")
+}
+
+// parseStatements reads statements up to the closing brace of the scope, and returns the names of
+// the nodes mentioned in them.
+func (p *dotParser) parseStatements(scope *dotScope) ([]string, error) {
+	var names []string
+	for {
+		t := p.peek()
+		switch {
+		case t.punct("}"):
+			return names, nil
+		case !t.id && t.text == "":
+			return nil, fmt.Errorf("line %d: expected \"}\", got end of input", t.line)
+		case t.punct(";"):
+			p.next()
+		default:
+			mentioned, err := p.parseStatement(scope)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, mentioned...)
+		}
+	}
+}
+
+// parseStatement reads an attribute, node, edge or subgraph statement, and returns the names of
+// the nodes mentioned in it.
+func (p *dotParser) parseStatement(scope *dotScope) ([]string, error) {
+	t := p.peek()
+	switch {
+	case (t.keyword("graph") || t.keyword("node") || t.keyword("edge")) && p.peekAt(1).punct("["):
+		p.next()
+		attrs, err := p.parseAttrLists()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.keyword("graph"):
+			p.setGraphAttrs(scope, attrs)
+		case t.keyword("node"):
+			scope.nodeAttrs = scope.nodeAttrs.merge(attrs)
+		default:
+			scope.edgeAttrs = scope.edgeAttrs.merge(attrs)
+		}
+		return nil, nil
+	case t.id && !t.keyword("subgraph") && p.peekAt(1).punct("="):
+		p.next()
+		p.next()
+		value, err := p.expectID()
+		if err != nil {
+			return nil, err
+		}
+		p.setGraphAttrs(scope, dotAttrs{t.text: {text: value.text, html: value.html, line: value.line}})
+		return nil, nil
+	}
+
+	endpoint, err := p.parseEndpoint(scope)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := [][]string{endpoint}
+	for p.peek().punct("->") || p.peek().punct("--") {
+		op := p.next()
+		if op.text == "->" && !p.directed {
+			return nil, fmt.Errorf("line %d: -> used in an undirected graph", op.line)
+		}
+		if op.text == "--" && p.directed {
+			return nil, fmt.Errorf("line %d: -- used in a digraph", op.line)
+		}
+		endpoint, err := p.parseEndpoint(scope)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	attrs := dotAttrs{}
+	if p.peek().punct("[") {
+		if attrs, err = p.parseAttrLists(); err != nil {
+			return nil, err
+		}
+	}
+	if len(endpoints) == 1 {
+		// node statement, or a subgraph on its own
+		if !t.keyword("subgraph") && !t.punct("{") {
+			name := endpoints[0][0]
+			p.nodeAttrs[name] = p.nodeAttrs[name].merge(attrs)
+		}
+		return endpoints[0], nil
+	}
+
+	var names []string
+	edgeAttrs := scope.edgeAttrs.merge(attrs)
+	for i, endpoint := range endpoints {
+		names = append(names, endpoint...)
+		if i == 0 {
+			continue
+		}
+		for _, origin := range endpoints[i-1] {
+			for _, target := range endpoint {
+				p.edges = append(p.edges, pendingDOTEdge{origin: origin, target: target, attrs: edgeAttrs, line: t.line})
+			}
+		}
+	}
+	return names, nil
+}
+
+// parseEndpoint reads a node ID with an optional port, or a subgraph, and returns the names of
+// the nodes it stands for.
+func (p *dotParser) parseEndpoint(scope *dotScope) ([]string, error) {
+	t := p.peek()
+	if t.keyword("subgraph") || t.punct("{") {
+		return p.parseSubgraph(scope)
+	}
+	id, err := p.expectID()
+	if err != nil {
+		return nil, err
+	}
+	if id.keyword("graph") || id.keyword("node") || id.keyword("edge") || id.keyword("digraph") || id.keyword("strict") {
+		return nil, fmt.Errorf("line %d: unexpected keyword %q", id.line, id.text)
+	}
+	if p.peek().punct(":") {
+		p.next()
+		if _, err := p.expectID(); err != nil {
+			return nil, err
+		}
+		if p.peek().punct(":") {
+			p.next()
+			if _, err := p.expectID(); err != nil {
+				return nil, err
+			}
+		}
+		p.warn(id.line, "ports of node %s are ignored", id.text)
+	}
+	p.mention(scope, id.text, id.line)
+	return []string{id.text}, nil
+}
+
+// parseSubgraph reads "[subgraph [ID]] { statements }". Clusters become subgraphs of the
+// flowchart; other subgraphs only scope attribute defaults.
+func (p *dotParser) parseSubgraph(scope *dotScope) ([]string, error) {
+	name := ""
+	if p.peek().keyword("subgraph") {
+		p.next()
+		if p.peek().id {
+			name = p.next().text
+		}
+	}
+	open := p.peek()
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	inner := &dotScope{graph: scope.graph, nodeAttrs: scope.nodeAttrs, edgeAttrs: scope.edgeAttrs}
+	if strings.HasPrefix(name, "cluster") {
+		cluster, ok := p.clusters[name]
+		if !ok {
+			cluster = &Flowchart{}
+			p.clusters[name] = cluster
+			p.clusterID[cluster] = name
+			p.graphs[cluster] = dotAttrs{}
+			p.lines[cluster] = open.line
+			p.parents[cluster] = scope.graph
+			scope.graph.Subgraphs = append(scope.graph.Subgraphs, cluster)
+		}
+		inner.graph, inner.cluster = cluster, true
+	}
+	names, err := p.parseStatements(inner)
+	if err != nil {
+		return nil, err
+	}
+	return names, p.expect("}")
+}
+
+// parseAttrLists reads one or more "[ name = value, ... ]" lists.
+func (p *dotParser) parseAttrLists() (dotAttrs, error) {
+	attrs := dotAttrs{}
+	for p.peek().punct("[") {
+		p.next()
+		for !p.peek().punct("]") {
+			name, err := p.expectID()
+			if err != nil {
+				return nil, err
+			}
+			value := dotValue{text: "true", line: name.line}
+			if p.peek().punct("=") {
+				p.next()
+				v, err := p.expectID()
+				if err != nil {
+					return nil, err
+				}
+				value = dotValue{text: v.text, html: v.html, line: v.line}
+			}
+			attrs[name.text] = value
+			if p.peek().punct(",") || p.peek().punct(";") {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	return attrs, nil
+}
+
+// setGraphAttrs applies graph attributes set in a scope. Attributes of subgraphs that are not
+// clusters are not drawn by Graphviz and are ignored.
+func (p *dotParser) setGraphAttrs(scope *dotScope, attrs dotAttrs) {
+	if scope.root || scope.cluster {
+		p.graphs[scope.graph] = p.graphs[scope.graph].merge(attrs)
+	}
+}
+
+// mention records that a node is mentioned in a scope, creating it with the node defaults of the
+// scope the first time. A node belongs to the innermost cluster it is mentioned in; a node
+// mentioned in two clusters that are not nested stays in the first one.
+func (p *dotParser) mention(scope *dotScope, name string, line int) {
+	current, ok := p.nodeScope[name]
+	if !ok {
+		p.names = append(p.names, name)
+		p.nodeAttrs[name] = scope.nodeAttrs.merge(nil)
+		p.nodeLines[name] = line
+		p.nodeScope[name] = scope.graph
+		return
+	}
+	switch {
+	case current == scope.graph || p.contains(scope.graph, current):
+	case p.contains(current, scope.graph):
+		p.nodeScope[name] = scope.graph
+	default:
+		p.warn(line, "node %s is in clusters %s and %s, it is kept in %s",
+			name, p.clusterID[current], p.clusterID[scope.graph], p.clusterID[current])
+	}
+}
+
+// contains reports whether the flowchart outer contains the subgraph inner.
+func (p *dotParser) contains(outer, inner *Flowchart) bool {
+	for f := p.parents[inner]; f != nil; f = p.parents[f] {
+		if f == outer {
+			return true
+		}
+	}
+	return false
+}
+
+// commonScope returns the innermost flowchart that contains both a and b.
+func (p *dotParser) commonScope(a, b *Flowchart) *Flowchart {
+	for f := a; f != nil; f = p.parents[f] {
+		if f == b || p.contains(f, b) {
+			return f
+		}
+	}
+	return p.root
+}
+
+// decodeDOTLabel returns the text of a label, expanding the escape sequences of Graphviz labels.
+// \N stands for the name of the object and \G for the name of the graph; \n, \l and \r end lines.
+func decodeDOTLabel(v dotValue, name string) string {
+	if v.html {
+		text := dotHTMLBreakRe.ReplaceAllString(v.text, "\n")
+		return strings.TrimSpace(html.UnescapeString(dotHTMLTagRe.ReplaceAllString(text, "")))
+	}
+	var sb strings.Builder
+	for i := 0; i < len(v.text); i++ {
+		if v.text[i] != '\\' || i+1 == len(v.text) {
+			sb.WriteByte(v.text[i])
+			continue
+		}
+		i++
+		switch v.text[i] {
+		case 'N', 'E', 'T', 'H':
+			sb.WriteString(name)
+		case 'G':
+		case 'n', 'l', 'r':
+			sb.WriteByte('\n')
+		default:
+			sb.WriteByte(v.text[i])
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// dotColor returns a Graphviz colour as a style colour, or "" with a warning if it cannot be
+// represented.
+func (p *dotParser) dotColor(v dotValue, what string) string {
+	if v.text == "" {
+		return ""
+	}
+	if _, ok := parseColor(v.text); !ok && !dotColorNameRe.MatchString(v.text) {
+		p.warn(v.line, "colour %q of %s is not supported", v.text, what)
+		return ""
+	}
+	return v.text
+}
+
+// dotFill returns the colour a shape is filled with: Graphviz only fills shapes whose style is
+// filled, with their fillcolor, their color or light grey.
+func dotFill(attrs dotAttrs) dotValue {
+	switch {
+	case !slices.Contains(attrs.styles(), "filled"):
+		return dotValue{}
+	case attrs.text("fillcolor") != "":
+		return attrs["fillcolor"]
+	case attrs.text("color") != "":
+		return attrs["color"]
+	default:
+		return dotValue{text: "lightgrey"}
+	}
+}
+
+// dotStyle returns the inline style given by the fill and the colour, pen and font attributes,
+// or nil if there is none.
+func (p *dotParser) dotStyle(attrs dotAttrs, fill dotValue, what string) *Style {
+	var style Style
+	style.Stroke = p.dotColor(attrs["color"], what)
+	style.Fill = p.dotColor(fill, what)
+	style.Color = p.dotColor(attrs["fontcolor"], what)
+	if w := attrs.text("penwidth"); w != "" {
+		style.StrokeWidth = w + "px"
+	}
+	if size := attrs.text("fontsize"); size != "" {
+		style.FontSize = size + "px"
+	}
+	style.FontFamily = attrs.text("fontname")
+	if style.isEmpty() {
+		return nil
+	}
+	return &style
+}
+
+// warnUnsupported reports the attributes of a graph, node or edge that were not used, except
+// those that only affect the layout.
+func (p *dotParser) warnUnsupported(attrs dotAttrs, used []string, kind string) {
+	var names []string
+	for name := range attrs {
+		if !dotLayoutAttributes[name] && !slices.Contains(used, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		p.warn(attrs[name].line, "%s attribute %s is not supported", kind, name)
+	}
+}
+
+// buildGraph sets the title, direction and style of the root flowchart and its subgraphs. Subgraph
+// titles that clash with node names or other titles get a number appended.
+func (p *dotParser) buildGraph() {
+	attrs := p.graphs[p.root]
+	if label, ok := attrs["label"]; ok && label.text != "" {
+		p.root.Title = pointTo(decodeDOTLabel(label, ""))
+		if label.html {
+			p.warn(label.line, "HTML label of the graph is imported as text")
+		}
+	}
+	p.root.Direction = DirectionVertical
+	switch rankdir := attrs["rankdir"]; strings.ToUpper(rankdir.text) {
+	case "LR":
+		p.root.Direction = DirectionHorizontalRight
+	case "RL":
+		p.root.Direction = DirectionHorizontalLeft
+	case "BT":
+		p.warn(rankdir.line, "rankdir BT is imported as a top-to-bottom direction")
+	}
+	p.warnUnsupported(attrs, []string{"label", "rankdir"}, "graph")
+
+	used := make(map[string]bool)
+	for _, name := range p.names {
+		used[name] = true
+	}
+	walkFlowchart(p.root, func(f *Flowchart, _ []string) {
+		for _, sub := range f.Subgraphs {
+			id, attrs := p.clusterID[sub], p.graphs[sub]
+			title := strings.TrimPrefix(strings.TrimPrefix(id, "cluster"), "_")
+			if label, ok := attrs["label"]; ok && label.text != "" {
+				title = decodeDOTLabel(label, id)
+				if label.html {
+					p.warn(label.line, "HTML label of cluster %s is imported as text", id)
+				}
+			}
+			if title == "" {
+				title = id
+			}
+			unique := title
+			for i := 2; used[unique]; i++ {
+				unique = fmt.Sprintf("%s %d", title, i)
+			}
+			if unique != title {
+				p.warn(p.lines[sub], "cluster %s is renamed %q, as %q is already used", id, unique, title)
+			}
+			used[unique] = true
+			sub.Title = pointTo(unique)
+			sub.Direction = p.root.Direction
+			fill := dotFill(attrs)
+			if attrs.text("bgcolor") != "" {
+				fill = attrs["bgcolor"]
+			}
+			sub.Style = p.dotStyle(attrs, fill, "cluster "+id)
+			p.warnUnsupported(attrs, []string{"label", "style", "color", "fillcolor", "bgcolor", "fontcolor", "penwidth", "fontsize", "fontname"}, "cluster")
+		}
+	})
+}
+
+// isDOTAnchor reports whether a node is only an invisible point used as the end of edges clipped
+// at a cluster border, as RenderDOT emits for links to subgraphs.
+func isDOTAnchor(attrs dotAttrs, clipped, direct int) bool {
+	return attrs.text("shape") == "point" && slices.Contains(attrs.styles(), "invis") && clipped > 0 && direct == 0
+}
+
+// buildNodes creates the nodes, in the order they were first mentioned, and adds them to their
+// flowchart. Anchor nodes of clipped edges are left out.
+func (p *dotParser) buildNodes() map[string]*Node {
+	clipped, direct := make(map[string]int), make(map[string]int)
+	for _, e := range p.edges {
+		for _, end := range []struct{ name, clip string }{{e.origin, "ltail"}, {e.target, "lhead"}} {
+			if _, ok := p.clusters[e.attrs.text(end.clip)]; ok {
+				clipped[end.name]++
+			} else {
+				direct[end.name]++
+			}
+		}
+	}
+
+	nodes := make(map[string]*Node)
+	for _, name := range p.names {
+		attrs, line := p.nodeAttrs[name], p.nodeLines[name]
+		if isDOTAnchor(attrs, clipped[name], direct[name]) {
+			continue
+		}
+		styles := attrs.styles()
+		shape := strings.ToLower(attrs.text("shape"))
+		nodeType, ok := dotNodeShapes[shape]
+		if !ok {
+			nodeType = NodeTypeProcess
+			if shape != "" {
+				p.warn(line, "shape %s of node %s is imported as a process", shape, name)
+			}
+		}
+		switch {
+		case nodeType != NodeTypeProcess || (!ok && shape != ""):
+		case slices.Contains(styles, "rounded"):
+			nodeType = NodeTypeTerminator
+		case attrs.text("peripheries") == "2":
+			nodeType = NodeTypeSubprocess
+		}
+		for _, s := range styles {
+			if !slices.Contains([]string{"filled", "rounded", "solid"}, s) {
+				p.warn(attrs["style"].line, "style %s of node %s is not supported", s, name)
+			}
+		}
+
+		node := &Node{name: name, Type: nodeType}
+		if label, ok := attrs["label"]; ok {
+			if text := decodeDOTLabel(label, name); text != name {
+				node.Label = pointTo(text)
+			}
+			if label.html {
+				p.warn(label.line, "HTML label of node %s is imported as text", name)
+			} else if shape == "record" || shape == "mrecord" {
+				p.warn(label.line, "record fields of node %s are imported as text", name)
+			}
+		}
+		node.Style = p.dotStyle(attrs, dotFill(attrs), "node "+name)
+		p.warnUnsupported(attrs, []string{"label", "shape", "style", "peripheries", "color", "fillcolor", "fontcolor", "penwidth", "fontsize", "fontname"}, "node")
+
+		nodes[name] = node
+		scope := p.nodeScope[name]
+		scope.Nodes = append(scope.Nodes, node)
+	}
+	return nodes
+}
+
+// dotArrowType returns the arrow type of a Graphviz arrow shape, and whether it is exact.
+// Arrows filled or open, and clipped to either side, are treated alike.
+func dotArrowType(shape string) (ArrowTypeEnum, bool) {
+	base := strings.TrimLeft(shape, "olr")
+	switch base {
+	case "normal":
+		return ArrowTypeNormal, true
+	case "dot":
+		return ArrowTypeCircle, true
+	case "tee":
+		return ArrowTypeCross, true
+	case "none":
+		return ArrowTypeNone, true
+	}
+	return ArrowTypeNormal, false
+}
+
+// dotLink returns the link of an edge, without its endpoints, reporting attributes that cannot be
+// represented.
+func (p *dotParser) dotLink(e pendingDOTEdge) Link {
+	link := Link{LineType: LineTypeSolid}
+	for _, s := range e.attrs.styles() {
+		switch s {
+		case "solid":
+		case "dashed", "dotted":
+			link.LineType = LineTypeDotted
+		case "bold":
+			link.LineType = LineTypeThick
+		case "invis":
+			link.LineType = LineTypeNone
+		default:
+			p.warn(e.attrs["style"].line, "edge style %s is not supported", s)
+		}
+	}
+
+	dir := e.attrs.text("dir")
+	if dir == "" && p.directed {
+		dir = "forward"
+	} else if dir == "" {
+		dir = "none"
+	}
+	head, tail := dir == "forward" || dir == "both", dir == "back" || dir == "both"
+	if !slices.Contains([]string{"forward", "back", "both", "none"}, dir) {
+		p.warn(e.attrs["dir"].line, "edge direction %s is not supported", dir)
+	}
+	arrow := func(attr string) ArrowTypeEnum {
+		shape := e.attrs.text(attr)
+		if shape == "" {
+			return ArrowTypeNormal
+		}
+		arrowType, exact := dotArrowType(shape)
+		if !exact {
+			p.warn(e.attrs[attr].line, "arrow %s is imported as a normal arrow", shape)
+		}
+		return arrowType
+	}
+	headType, tailType := arrow("arrowhead"), arrow("arrowtail")
+	link.TargetArrow = head && headType != ArrowTypeNone
+	link.OriginArrow = tail && tailType != ArrowTypeNone
+	switch {
+	case link.TargetArrow:
+		link.ArrowType = headType
+		if link.OriginArrow && tailType != headType {
+			p.warn(e.line, "edges with different arrows at their ends are imported with the head arrow at both")
+		}
+	case link.OriginArrow:
+		link.ArrowType = tailType
+	default:
+		link.ArrowType = ArrowTypeNone
+	}
+
+	label, ok := e.attrs["label"]
+	if !ok || label.text == "" {
+		label, ok = e.attrs["xlabel"]
+	}
+	if ok && label.text != "" {
+		link.Label = pointTo(decodeDOTLabel(label, e.origin+"->"+e.target))
+		if label.html {
+			p.warn(label.line, "HTML label of edge %s -> %s is imported as text", e.origin, e.target)
+		}
+	}
+	link.Style = p.dotStyle(e.attrs, dotValue{}, fmt.Sprintf("edge %s -> %s", e.origin, e.target))
+	p.warnUnsupported(e.attrs, []string{"style", "dir", "arrowhead", "arrowtail", "label", "xlabel", "color", "fontcolor", "penwidth", "fontsize", "fontname"}, "edge")
+	return link
+}
+
+// buildLinks creates the links of the edges and adds each to the innermost flowchart containing
+// both of its endpoints. Edges clipped with lhead or ltail end at the cluster.
+func (p *dotParser) buildLinks(nodes map[string]*Node) {
+	for _, e := range p.edges {
+		link := p.dotLink(e)
+		var scopes [2]*Flowchart
+		for i, end := range []struct {
+			name, clip string
+			set        *Linkable
+		}{{e.origin, "ltail", &link.Origin}, {e.target, "lhead", &link.Target}} {
+			if cluster, ok := p.clusters[e.attrs.text(end.clip)]; ok {
+				*end.set, scopes[i] = cluster, p.parents[cluster]
+				continue
+			}
+			*end.set, scopes[i] = nodes[end.name], p.nodeScope[end.name]
+		}
+		scope := p.commonScope(scopes[0], scopes[1])
+		scope.Links = append(scope.Links, link)
+	}
+}
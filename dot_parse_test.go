@@ -0,0 +1,301 @@
+package flowchart
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDOT(t *testing.T) {
+	tests := []struct {
+		name             string
+		source           string
+		expected         func() *Flowchart
+		expectedWarnings []ParseWarning
+		expectedErr      bool
+	}{
+		{
+			name:   "empty digraph",
+			source: "digraph {}",
+			expected: func() *Flowchart {
+				return &Flowchart{Direction: DirectionVertical}
+			},
+		},
+		{
+			name: "shapes, defaults and clusters",
+			source: `# generated by a legacy script
+strict digraph "orders" {
+	graph [rankdir=LR, label="Orders", splines=ortho];
+	node [shape=box];
+	start [style=rounded, label="Start"];
+	check [shape=diamond, label="Valid?"];
+	sub [peripheries=2];
+	io [shape=parallelogram, label="Read\nform"];
+	join [shape=circle, label="\N"];
+	plain [shape=""];
+	subgraph cluster_store {
+		label = "Storage";
+		style = filled; fillcolor = "#eeeeee"; color = blue;
+		db [shape=cylinder, label=<<b>Orders</b><br/>table>];
+	}
+	/* the flow */
+	start -> check;
+	check -> db [label="yes", style=bold, color="#ff0000", penwidth=3];
+	check -> sub [label="no", style=dashed];
+	sub -> io -> join;
+}`,
+			expected: func() *Flowchart {
+				start, check := TerminatorNode("start", pointTo("Start")), DecisionNode("check", pointTo("Valid?"))
+				sub, io := SubprocessNode("sub", nil), InputOutputNode("io", pointTo("Read\nform"))
+				join, plain := ConnectorNode("join", nil), ProcessNode("plain", nil)
+				db := DatabaseNode("db", pointTo("Orders\ntable"))
+				store := &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Title:     pointTo("Storage"),
+					Style:     &Style{Fill: "#eeeeee", Stroke: "blue"},
+					Nodes:     []*Node{db},
+				}
+				thick := ThickLink(check, db, pointTo("yes"))
+				thick.Style = &Style{Stroke: "#ff0000", StrokeWidth: "3px"}
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Title:     pointTo("Orders"),
+					Nodes:     []*Node{start, check, sub, io, join, plain},
+					Subgraphs: []*Flowchart{store},
+					Links: []Link{
+						SolidLink(start, check, nil),
+						thick,
+						DottedLink(check, sub, pointTo("no")),
+						SolidLink(sub, io, nil),
+						SolidLink(io, join, nil),
+					},
+				}
+			},
+			expectedWarnings: []ParseWarning{
+				{Line: 14, Message: "HTML label of node db is imported as text"},
+			},
+		},
+		{
+			name: "arrows and invisible edges",
+			source: `digraph {
+	a -> b [dir=both, arrowhead=dot, arrowtail=odot];
+	a -> b [dir=back, arrowtail=tee];
+	a -> b [dir=none];
+	a -> b [arrowhead=none];
+	a -> b [style=invis];
+	a -> b [style="dotted,bold"];
+}`,
+			expected: func() *Flowchart {
+				a, b := ProcessNode("a", nil), ProcessNode("b", nil)
+				return &Flowchart{
+					Direction: DirectionVertical,
+					Nodes:     []*Node{a, b},
+					Links: []Link{
+						{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeCircle, OriginArrow: true, TargetArrow: true},
+						{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeCross, OriginArrow: true},
+						{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeNone},
+						{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeNone},
+						{Origin: a, Target: b, LineType: LineTypeNone, ArrowType: ArrowTypeNormal, TargetArrow: true},
+						{Origin: a, Target: b, LineType: LineTypeThick, ArrowType: ArrowTypeNormal, TargetArrow: true},
+					},
+				}
+			},
+		},
+		{
+			name: "undirected graph with subgraph endpoints",
+			source: `graph {
+	rankdir=RL
+	{rank=same; a b} -- c
+	"x" + "y" -- z [label="joined"]
+}`,
+			expected: func() *Flowchart {
+				a, b, c := ProcessNode("a", nil), ProcessNode("b", nil), ProcessNode("c", nil)
+				xy, z := ProcessNode("xy", nil), ProcessNode("z", nil)
+				return &Flowchart{
+					Direction: DirectionHorizontalLeft,
+					Nodes:     []*Node{a, b, c, xy, z},
+					Links: []Link{
+						{Origin: a, Target: c, LineType: LineTypeSolid, ArrowType: ArrowTypeNone},
+						{Origin: b, Target: c, LineType: LineTypeSolid, ArrowType: ArrowTypeNone},
+						{Origin: xy, Target: z, LineType: LineTypeSolid, ArrowType: ArrowTypeNone, Label: pointTo("joined")},
+					},
+				}
+			},
+		},
+		{
+			name: "nodes move into the innermost cluster mentioning them",
+			source: `digraph {
+	a -> b
+	subgraph cluster_outer {
+		a
+		subgraph cluster_inner { b; c }
+	}
+	subgraph cluster_other { c }
+}`,
+			expected: func() *Flowchart {
+				a, b, c := ProcessNode("a", nil), ProcessNode("b", nil), ProcessNode("c", nil)
+				inner := &Flowchart{Direction: DirectionVertical, Title: pointTo("inner"), Nodes: []*Node{b, c}}
+				outer := &Flowchart{
+					Direction: DirectionVertical,
+					Title:     pointTo("outer"),
+					Nodes:     []*Node{a},
+					Subgraphs: []*Flowchart{inner},
+					Links:     []Link{SolidLink(a, b, nil)},
+				}
+				other := &Flowchart{Direction: DirectionVertical, Title: pointTo("other")}
+				return &Flowchart{Direction: DirectionVertical, Subgraphs: []*Flowchart{outer, other}}
+			},
+			expectedWarnings: []ParseWarning{
+				{Line: 7, Message: "node c is in clusters cluster_inner and cluster_other, it is kept in cluster_inner"},
+			},
+		},
+		{
+			name: "lossy attributes",
+			source: `digraph {
+	rankdir=BT; bgcolor=white
+	a [shape=star, tooltip="hi", style="rounded,dashed"]
+	a:n -> b:s [arrowhead=vee, color="0.6 0.4 1", headlabel="h"]
+	a -> b [dir=both, arrowtail=dot]
+	subgraph cluster_a { label=a; c }
+}
+digraph second {}`,
+			expected: func() *Flowchart {
+				a, b, c := ProcessNode("a", nil), ProcessNode("b", nil), ProcessNode("c", nil)
+				return &Flowchart{
+					Direction: DirectionVertical,
+					Nodes:     []*Node{a, b},
+					Subgraphs: []*Flowchart{{Direction: DirectionVertical, Title: pointTo("a 2"), Nodes: []*Node{c}}},
+					Links: []Link{
+						SolidLink(a, b, nil),
+						{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeNormal, OriginArrow: true, TargetArrow: true},
+					},
+				}
+			},
+			expectedWarnings: []ParseWarning{
+				{Line: 2, Message: "rankdir BT is imported as a top-to-bottom direction"},
+				{Line: 2, Message: "graph attribute bgcolor is not supported"},
+				{Line: 3, Message: "shape star of node a is imported as a process"},
+				{Line: 3, Message: "style dashed of node a is not supported"},
+				{Line: 3, Message: "node attribute tooltip is not supported"},
+				{Line: 4, Message: "ports of node a are ignored"},
+				{Line: 4, Message: "ports of node b are ignored"},
+				{Line: 4, Message: "arrow vee is imported as a normal arrow"},
+				{Line: 4, Message: `colour "0.6 0.4 1" of edge a -> b is not supported`},
+				{Line: 4, Message: "edge attribute headlabel is not supported"},
+				{Line: 5, Message: "edges with different arrows at their ends are imported with the head arrow at both"},
+				{Line: 6, Message: `cluster cluster_a is renamed "a 2", as "a" is already used`},
+				{Line: 8, Message: "only the first graph is imported"},
+			},
+		},
+		{
+			name:        "edge operator of the wrong kind",
+			source:      "digraph { a -- b }",
+			expectedErr: true,
+		},
+		{
+			name:        "unclosed graph",
+			source:      "digraph { a -> b",
+			expectedErr: true,
+		},
+		{
+			name:        "unterminated string",
+			source:      `digraph { a [label="oops] }`,
+			expectedErr: true,
+		},
+		{
+			name:        "missing attribute value",
+			source:      "digraph { a [label=] }",
+			expectedErr: true,
+		},
+		{
+			name:        "not a graph",
+			source:      "flowchart TB; a --> b",
+			expectedErr: true,
+		},
+		{
+			name:        "empty input",
+			source:      "",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := ParseDOT(strings.NewReader(tt.source))
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("ParseDOT() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if tt.expectedErr {
+				return
+			}
+			if diff := cmp.Diff(tt.expected(), got, parsedFlowchartOptions); diff != "" {
+				t.Errorf("ParseDOT() mismatch (-expected +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.expectedWarnings, warnings, parsedFlowchartOptions); diff != "" {
+				t.Errorf("ParseDOT() warnings mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseDOT_RoundTrip(t *testing.T) {
+	start, check := TerminatorNode("start", pointTo("Start")), DecisionNode("check", pointTo(`Valid "order"?`))
+	sub, io := SubprocessNode("sub", nil), InputOutputNode("io", pointTo("Read\nform"))
+	conn, db := ConnectorNode("conn", pointTo("A")), DatabaseNode("db", nil)
+	inner := &Flowchart{Direction: DirectionHorizontalRight, Title: pointTo("Inner"), Nodes: []*Node{conn, db},
+		Links: []Link{DottedLink(conn, db, nil)}}
+	outer := &Flowchart{Direction: DirectionHorizontalRight, Title: pointTo("Outer store"), Nodes: []*Node{io}, Subgraphs: []*Flowchart{inner},
+		Links: []Link{SolidLink(io, inner, nil)}}
+	f := &Flowchart{
+		Direction: DirectionHorizontalRight,
+		Title:     pointTo("Every type"),
+		Nodes:     []*Node{start, check, sub},
+		Subgraphs: []*Flowchart{outer},
+		Links: []Link{
+			SolidLink(start, check, nil),
+			ThickLink(check, sub, pointTo("no")),
+			BlankLink(check, outer, nil),
+			{Origin: sub, Target: db, LineType: LineTypeSolid, ArrowType: ArrowTypeCross, OriginArrow: true, TargetArrow: true},
+			{Origin: outer, Target: start, LineType: LineTypeDotted, ArrowType: ArrowTypeCircle, OriginArrow: true},
+			{Origin: sub, Target: start, LineType: LineTypeSolid, ArrowType: ArrowTypeNone},
+		},
+	}
+
+	source, err := RenderDOT(f)
+	if err != nil {
+		t.Fatalf("RenderDOT() unexpected error: %v", err)
+	}
+	got, warnings, err := ParseDOT(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("ParseDOT() unexpected error: %v", err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("ParseDOT() unexpected warnings: %v", warnings)
+	}
+	if diff := cmp.Diff(f, got, parsedFlowchartOptions); diff != "" {
+		t.Errorf("ParseDOT(RenderDOT()) mismatch (-expected +got):\n%s\nsource:\n%s", diff, source)
+	}
+}
+
+func TestDecodeDOTLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    dotValue
+		expected string
+	}{
+		{name: "plain", value: dotValue{text: "Save"}, expected: "Save"},
+		{name: "line breaks", value: dotValue{text: `left\lcentre\nright\r`}, expected: "left\ncentre\nright"},
+		{name: "object name", value: dotValue{text: `\N (\G)`}, expected: "node ()"},
+		{name: "escaped backslash", value: dotValue{text: `a\\b\x`}, expected: `a\bx`},
+		{name: "html", value: dotValue{text: `<b>A &amp; B</b><BR/>c`, html: true}, expected: "A & B\nc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeDOTLabel(tt.value, "node"); got != tt.expected {
+				t.Errorf("decodeDOTLabel(%q) = %q, expected %q", tt.value.text, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package flowchart
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderDOTArrows(t *testing.T) {
+	tests := []struct {
+		name     string
+		link     Link
+		expected string
+	}{
+		{
+			name:     "no arrows",
+			link:     Link{ArrowType: ArrowTypeNormal},
+			expected: "dir=none",
+		},
+		{
+			name:     "arrow type none",
+			link:     Link{ArrowType: ArrowTypeNone, OriginArrow: true, TargetArrow: true},
+			expected: "dir=none",
+		},
+		{
+			name:     "target arrow",
+			link:     Link{ArrowType: ArrowTypeNormal, TargetArrow: true},
+			expected: "dir=forward, arrowhead=normal",
+		},
+		{
+			name:     "origin arrow",
+			link:     Link{ArrowType: ArrowTypeCross, OriginArrow: true},
+			expected: "dir=back, arrowtail=tee",
+		},
+		{
+			name:     "both circle arrows",
+			link:     Link{ArrowType: ArrowTypeCircle, OriginArrow: true, TargetArrow: true},
+			expected: "dir=both, arrowhead=dot, arrowtail=dot",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderDOTArrows(tt.link)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("renderDOTArrows() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderDOTNode(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     *Node
+		expected string
+	}{
+		{
+			name:     "no label",
+			node:     &Node{name: "Node One", Type: NodeTypeProcess},
+			expected: "    \"Node One\" [label=\"Node One\", shape=box];\n",
+		},
+		{
+			name:     "terminator",
+			node:     TerminatorNode("Start", pointTo("Begin")),
+			expected: "    \"Start\" [label=\"Begin\", shape=box, style=rounded];\n",
+		},
+		{
+			name:     "subprocess",
+			node:     SubprocessNode("Sub", pointTo("Sub")),
+			expected: "    \"Sub\" [label=\"Sub\", shape=box, peripheries=2];\n",
+		},
+		{
+			name:     "decision",
+			node:     DecisionNode("Choice", pointTo("Yes or no?")),
+			expected: "    \"Choice\" [label=\"Yes or no?\", shape=diamond];\n",
+		},
+		{
+			name:     "input output",
+			node:     InputOutputNode("Read", pointTo("Read")),
+			expected: "    \"Read\" [label=\"Read\", shape=parallelogram];\n",
+		},
+		{
+			name:     "connector",
+			node:     ConnectorNode("A", pointTo("A")),
+			expected: "    \"A\" [label=\"A\", shape=circle];\n",
+		},
+		{
+			name:     "database with quoted label",
+			node:     DatabaseNode("DB", pointTo(`the "main" db`)),
+			expected: "    \"DB\" [label=\"the \\\"main\\\" db\", shape=cylinder];\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderDOTNode(tt.node, 1)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("renderDOTNode() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderDOTLink(t *testing.T) {
+	origin := &Node{name: "Origin"}
+	target := &Node{name: "Target"}
+	subgraph := &Flowchart{Title: pointTo("Group")}
+
+	tests := []struct {
+		name     string
+		link     Link
+		expected string
+	}{
+		{
+			name:     "no target",
+			link:     SolidLink(origin, nil, nil),
+			expected: "",
+		},
+		{
+			name:     "blank link",
+			link:     BlankLink(origin, target, nil),
+			expected: `"Origin" -> "Target" [style=invis, dir=forward, arrowhead=normal]`,
+		},
+		{
+			name:     "solid link with label",
+			link:     SolidLink(origin, target, pointTo("yes")),
+			expected: `"Origin" -> "Target" [style=solid, dir=forward, arrowhead=normal, label="yes"]`,
+		},
+		{
+			name:     "dotted link",
+			link:     DottedLink(origin, target, nil),
+			expected: `"Origin" -> "Target" [style=dotted, dir=forward, arrowhead=normal]`,
+		},
+		{
+			name:     "thick link",
+			link:     ThickLink(origin, target, nil),
+			expected: `"Origin" -> "Target" [style=bold, dir=forward, arrowhead=normal]`,
+		},
+		{
+			name:     "link to subgraph",
+			link:     SolidLink(origin, subgraph, nil),
+			expected: `"Origin" -> "__anchor_Group" [style=solid, dir=forward, arrowhead=normal, lhead="cluster_Group"]`,
+		},
+		{
+			name:     "link from subgraph",
+			link:     SolidLink(subgraph, target, nil),
+			expected: `"__anchor_Group" -> "Target" [style=solid, dir=forward, arrowhead=normal, ltail="cluster_Group"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderDOTLink(tt.link)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("renderDOTLink() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	tests := []struct {
+		name        string
+		flowchart   *Flowchart
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name: "Flowchart with no title",
+			flowchart: &Flowchart{
+				Direction: DirectionHorizontalLeft,
+				Nodes:     []*Node{{name: "Node One", Type: NodeTypeProcess}},
+			},
+			expected:    "digraph {\n    rankdir=RL;\n    \"Node One\" [label=\"Node One\", shape=box];\n}\n",
+			expectedErr: false,
+		},
+		{
+			name:      "Full Flowchart with nested subgraphs",
+			flowchart: fixtureFlowchart(),
+			expected: `digraph {
+    label="Test Title";
+    labelloc=t;
+    rankdir=LR;
+    "Node One" [label="Node One", shape=box];
+    "Node Two" [label="Node Two", shape=box];
+    "Node Three" [label="Node Three", shape=box];
+    "Node Four" [label="Node Four", shape=box];
+    subgraph "cluster_Subgraph One" {
+        label="Subgraph One";
+        "Node Five" [label="Node Five", shape=box];
+        "Node Six" [label="Node Six", shape=box];
+        subgraph "cluster_Subgraph Two" {
+            label="Subgraph Two";
+            "Node Seven" [label="Node Seven", shape=box];
+            "Node Eight" [label="Node Eight", shape=box];
+        }
+    }
+    "Node Five" -> "Node Six" [style=solid, dir=forward, arrowhead=normal];
+    "Node One" -> "Node Two" [style=solid, dir=forward, arrowhead=normal];
+    "Node Seven" -> "Node Eight" [style=solid, dir=forward, arrowhead=normal];
+    "Node Two" -> "Node Four" [style=solid, dir=forward, arrowhead=normal];
+    "Node Two" -> "Node Three" [style=solid, dir=forward, arrowhead=normal];
+}
+`,
+			expectedErr: false,
+		},
+		{
+			name: "Flowchart with link to subgraph",
+			flowchart: func() *Flowchart {
+				sub := &Flowchart{Title: pointTo("Group"), Nodes: []*Node{ProcessNode("Inner", nil)}}
+				outer := ProcessNode("Outer", nil)
+				return &Flowchart{
+					Direction: DirectionVertical,
+					Nodes:     []*Node{outer},
+					Subgraphs: []*Flowchart{sub},
+					Links:     []Link{DottedLink(outer, sub, nil)},
+				}
+			}(),
+			expected: `digraph {
+    rankdir=TB;
+    compound=true;
+    "Outer" [label="Outer", shape=box];
+    subgraph "cluster_Group" {
+        label="Group";
+        "__anchor_Group" [shape=point, style=invis];
+        "Inner" [label="Inner", shape=box];
+    }
+    "Outer" -> "__anchor_Group" [style=dotted, dir=forward, arrowhead=normal, lhead="cluster_Group"];
+}
+`,
+			expectedErr: false,
+		},
+		{
+			name: "untitled subgraph",
+			flowchart: &Flowchart{
+				Subgraphs: []*Flowchart{{Nodes: []*Node{{name: "Inner"}}}},
+			},
+			expected:    "",
+			expectedErr: true,
+		},
+		{
+			name: "repeated names across levels",
+			flowchart: &Flowchart{
+				Nodes: []*Node{{name: "Repeated"}},
+				Subgraphs: []*Flowchart{{
+					Title: pointTo("Group"),
+					Subgraphs: []*Flowchart{{
+						Title: pointTo("Inner"),
+						Nodes: []*Node{{name: "Repeated"}},
+					}},
+				}},
+			},
+			expected:    "",
+			expectedErr: true,
+		},
+		{
+			name: "link without target",
+			flowchart: &Flowchart{
+				Nodes: []*Node{{name: "Node"}},
+				Links: []Link{{Origin: &Node{name: "Node"}}},
+			},
+			expected:    "",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderDOT(tt.flowchart)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("RenderDOT() mismatch (-expected +got):\n%s", diff)
+			}
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("RenderDOT() error = %v, expected %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
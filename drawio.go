@@ -0,0 +1,431 @@
+package flowchart
+
+import (
+	"encoding/xml"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Ids of the two cells every draw.io model starts with: the root, and the default layer that
+// holds the drawing.
+const (
+	drawioRootID  = "0"
+	drawioLayerID = "1"
+)
+
+// drawioFile is a draw.io (diagrams.net) file, holding one diagram per page.
+type drawioFile struct {
+	XMLName  xml.Name        `xml:"mxfile"`
+	Host     string          `xml:"host,attr,omitempty"`
+	Diagrams []drawioDiagram `xml:"diagram"`
+}
+
+// drawioDiagram is a page of a draw.io file. Its model is either written out, or stored in Data
+// compressed as draw.io did by default before version 14.
+type drawioDiagram struct {
+	ID    string       `xml:"id,attr,omitempty"`
+	Name  string       `xml:"name,attr,omitempty"`
+	Model *drawioModel `xml:"mxGraphModel"`
+	Data  string       `xml:",chardata"`
+}
+
+// drawioModel is the mxGraphModel of a diagram: a flat list of cells forming a tree through their
+// parent attributes.
+type drawioModel struct {
+	XMLName    xml.Name   `xml:"mxGraphModel"`
+	Grid       string     `xml:"grid,attr,omitempty"`
+	GridSize   string     `xml:"gridSize,attr,omitempty"`
+	Guides     string     `xml:"guides,attr,omitempty"`
+	Connect    string     `xml:"connect,attr,omitempty"`
+	Arrows     string     `xml:"arrows,attr,omitempty"`
+	Fold       string     `xml:"fold,attr,omitempty"`
+	Page       string     `xml:"page,attr,omitempty"`
+	PageWidth  string     `xml:"pageWidth,attr,omitempty"`
+	PageHeight string     `xml:"pageHeight,attr,omitempty"`
+	Root       drawioRoot `xml:"root"`
+}
+
+// drawioRoot holds the cells of a model.
+type drawioRoot struct {
+	Cells []drawioCell `xml:"mxCell"`
+}
+
+// drawioObject is a cell wrapped in an object or UserObject element, which draw.io writes for
+// cells with custom properties. The wrapper carries the cell's id and label.
+type drawioObject struct {
+	ID    string     `xml:"id,attr"`
+	Label string     `xml:"label,attr"`
+	Cell  drawioCell `xml:"mxCell"`
+}
+
+// UnmarshalXML decodes the cells of a model in document order, unwrapping the cells with custom
+// properties.
+func (r *drawioRoot) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "mxCell":
+				var cell drawioCell
+				if err := d.DecodeElement(&cell, &t); err != nil {
+					return err
+				}
+				r.Cells = append(r.Cells, cell)
+			case "object", "UserObject":
+				var object drawioObject
+				if err := d.DecodeElement(&object, &t); err != nil {
+					return err
+				}
+				object.Cell.ID, object.Cell.Value = object.ID, object.Label
+				r.Cells = append(r.Cells, object.Cell)
+			default:
+				if err := d.Skip(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// drawioCell is a vertex, an edge, a layer or the root of a model.
+type drawioCell struct {
+	ID       string          `xml:"id,attr"`
+	Value    string          `xml:"value,attr,omitempty"`
+	Style    string          `xml:"style,attr,omitempty"`
+	Vertex   string          `xml:"vertex,attr,omitempty"`
+	Edge     string          `xml:"edge,attr,omitempty"`
+	Parent   string          `xml:"parent,attr,omitempty"`
+	Source   string          `xml:"source,attr,omitempty"`
+	Target   string          `xml:"target,attr,omitempty"`
+	Geometry *drawioGeometry `xml:"mxGeometry"`
+}
+
+// drawioGeometry is the position of a vertex relative to its parent, or marks an edge's geometry
+// as relative to its endpoints.
+type drawioGeometry struct {
+	X        float64 `xml:"x,attr,omitempty"`
+	Y        float64 `xml:"y,attr,omitempty"`
+	Width    float64 `xml:"width,attr,omitempty"`
+	Height   float64 `xml:"height,attr,omitempty"`
+	Relative string  `xml:"relative,attr,omitempty"`
+	As       string  `xml:"as,attr"`
+}
+
+// drawioShapeStyle returns the draw.io style of the shape representing the given NodeTypeEnum.
+// These are the shapes of the General and Flowchart palettes that ParseDrawio recognises.
+func drawioShapeStyle(t NodeTypeEnum) string {
+	switch t {
+	case NodeTypeTerminator:
+		return "rounded=1;arcSize=50"
+	case NodeTypeSubprocess:
+		return "shape=process;backgroundOutline=1"
+	case NodeTypeDecision:
+		return "rhombus"
+	case NodeTypeInputOutput:
+		return "shape=parallelogram;perimeter=parallelogramPerimeter;fixedSize=1"
+	case NodeTypeConnector:
+		return "ellipse;aspect=fixed"
+	case NodeTypeDatabase:
+		return "shape=cylinder3;boundedLbl=1;backgroundOutline=1;size=10"
+	default:
+		return "rounded=0"
+	}
+}
+
+// drawioArrow converts an ArrowTypeEnum to a draw.io startArrow or endArrow value.
+func drawioArrow(a ArrowTypeEnum) string {
+	switch a {
+	case ArrowTypeNormal:
+		return "block"
+	case ArrowTypeCircle:
+		return "oval"
+	case ArrowTypeCross:
+		return "cross"
+	default:
+		return "none"
+	}
+}
+
+// drawioEdgeStyle returns the draw.io style of a link: orthogonal routing, dashed for dotted
+// lines, a wider stroke for thick lines, no stroke for invisible ones, and its arrow ends.
+func drawioEdgeStyle(l Link, style Style) string {
+	keys := []string{"edgeStyle=orthogonalEdgeStyle", "rounded=0", "html=1"}
+	switch l.LineType {
+	case LineTypeNone:
+		style.Stroke = "none"
+	case LineTypeDotted:
+		keys = append(keys, "dashed=1")
+	case LineTypeThick:
+		if style.StrokeWidth == "" {
+			style.StrokeWidth = "3"
+		}
+	}
+	start, end := ArrowTypeNone, ArrowTypeNone
+	if l.OriginArrow {
+		start = l.ArrowType
+	}
+	if l.TargetArrow {
+		end = l.ArrowType
+	}
+	keys = append(keys, "startArrow="+drawioArrow(start), "endArrow="+drawioArrow(end))
+	if start == ArrowTypeNormal || start == ArrowTypeCircle {
+		keys = append(keys, "startFill=1")
+	}
+	if end == ArrowTypeNormal || end == ArrowTypeCircle {
+		keys = append(keys, "endFill=1")
+	}
+	return strings.Join(append(keys, drawioStyleKeys(style)...), ";") + ";"
+}
+
+// drawioStyleKeys converts the properties of a style that are set to draw.io style keys.
+func drawioStyleKeys(s Style) []string {
+	var keys []string
+	for _, p := range []struct{ key, value string }{
+		{"fillColor", s.Fill},
+		{"strokeColor", s.Stroke},
+		{"strokeWidth", strings.TrimSuffix(s.StrokeWidth, "px")},
+		{"fontColor", s.Color},
+		{"fontFamily", s.FontFamily},
+		{"fontSize", strings.TrimSuffix(s.FontSize, "px")},
+	} {
+		if p.value != "" {
+			keys = append(keys, p.key+"="+p.value)
+		}
+	}
+	if s.FontWeight == "bold" || s.FontWeight == "bolder" {
+		keys = append(keys, "fontStyle=1")
+	} else if weight, err := strconv.Atoi(s.FontWeight); err == nil && weight >= 600 {
+		keys = append(keys, "fontStyle=1")
+	}
+	return keys
+}
+
+// drawioValue returns a label as the HTML value of a cell, with markdown bold and italic text
+// converted to <b> and <i> and line breaks to <br>.
+func drawioValue(label string, format LabelFormatEnum) string {
+	lines := strings.Split(label, "\n")
+	for i, line := range lines {
+		if format != LabelFormatMarkdown {
+			lines[i] = html.EscapeString(line)
+			continue
+		}
+		var sb strings.Builder
+		for _, span := range markdownSpans(line) {
+			text := html.EscapeString(span.text)
+			if span.italic {
+				text = "<i>" + text + "</i>"
+			}
+			if span.bold {
+				text = "<b>" + text + "</b>"
+			}
+			sb.WriteString(text)
+		}
+		lines[i] = sb.String()
+	}
+	return strings.Join(lines, "<br>")
+}
+
+// drawioCellID returns the id of the cell of a node or subgraph, which is its name unless the name
+// is empty or the id of one of the root cells.
+func drawioCellID(name string) string {
+	if name == "" || name == drawioRootID || name == drawioLayerID {
+		return "cell-" + nameHash(name)
+	}
+	return name
+}
+
+// drawioGeometryOf returns the geometry of a vertex whose box is r, relative to the box of its parent.
+func drawioGeometryOf(r, parent Rect) *drawioGeometry {
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	return &drawioGeometry{
+		X:      round(r.X - parent.X),
+		Y:      round(r.Y - parent.Y),
+		Width:  round(r.Width),
+		Height: round(r.Height),
+		As:     "geometry",
+	}
+}
+
+// drawioRenderer holds the state needed while converting a flowchart to draw.io cells.
+type drawioRenderer struct {
+	layout    *Layout
+	classDefs []StyleClass
+	cells     []drawioCell
+	elements  treeElements        // Nodes and subgraphs of the tree, to resolve link endpoints
+	ids       map[Linkable]string // Cell ids of the nodes and subgraphs
+	rects     map[*Flowchart]Rect // Absolute boxes of the subgraphs
+	used      map[string]bool     // Cell ids given out so far
+	nodeRects map[*Node]Rect      // Absolute boxes of the nodes
+	spare     float64             // Left of the next box for a subgraph that the layout did not place
+	edges     int                 // Number of edge cells so far
+}
+
+// uniqueID returns id, or id with a suffix if it is already the id of another cell.
+func (r *drawioRenderer) uniqueID(id string) string {
+	unique := id
+	for i := 2; r.used[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	r.used[unique] = true
+	return unique
+}
+
+// addFlowchart adds the cells of the nodes and subgraphs of f, whose own cell has the given id
+// and box, followed by the cells of the links stored in f.
+func (r *drawioRenderer) addFlowchart(f *Flowchart, id string, box Rect) {
+	for _, node := range f.Nodes {
+		label := node.name
+		if node.Label != nil {
+			label = *node.Label
+		}
+		style := resolveStyle(r.classDefs, node.Classes, node.Style)
+		r.cells = append(r.cells, drawioCell{
+			ID:       r.ids[node],
+			Value:    drawioValue(label, node.LabelFormat),
+			Style:    strings.Join(append([]string{drawioShapeStyle(node.Type), "whiteSpace=wrap", "html=1"}, drawioStyleKeys(style)...), ";") + ";",
+			Vertex:   "1",
+			Parent:   id,
+			Geometry: drawioGeometryOf(r.nodeRects[node], box),
+		})
+	}
+	for _, sub := range f.Subgraphs {
+		rect, ok := r.rects[sub]
+		if !ok {
+			rect = Rect{r.spare, r.layout.Height, 120, 60}
+			r.spare += rect.Width + 20
+		}
+		title := ""
+		if sub.Title != nil {
+			title = *sub.Title
+		}
+		style := resolveStyle(r.classDefs, sub.Classes, sub.Style)
+		r.cells = append(r.cells, drawioCell{
+			ID:       r.ids[sub],
+			Value:    drawioValue(title, LabelFormatText),
+			Style:    strings.Join(append([]string{"swimlane", "startSize=23", "whiteSpace=wrap", "html=1"}, drawioStyleKeys(style)...), ";") + ";",
+			Vertex:   "1",
+			Parent:   id,
+			Geometry: drawioGeometryOf(rect, box),
+		})
+		r.addFlowchart(sub, r.ids[sub], rect)
+	}
+	for i := range f.Links {
+		link := &f.Links[i]
+		value := ""
+		if link.Label != nil {
+			value = drawioValue(*link.Label, link.LabelFormat)
+		}
+		r.edges++
+		r.cells = append(r.cells, drawioCell{
+			ID:       r.uniqueID("edge-" + strconv.Itoa(r.edges)),
+			Value:    value,
+			Style:    drawioEdgeStyle(*link, resolveStyle(r.classDefs, link.Classes, link.Style)),
+			Edge:     "1",
+			Parent:   id,
+			Source:   r.ids[r.elements.resolve(link.Origin)],
+			Target:   r.ids[r.elements.resolve(link.Target)],
+			Geometry: &drawioGeometry{Relative: "1", As: "geometry"},
+		})
+	}
+}
+
+// RenderDrawio generates a draw.io (diagrams.net) file holding the flowchart, laid out with
+// ComputeLayout, so that it can be edited in draw.io and read back with ParseDrawio.
+// Nodes are drawn with the draw.io shape for their type and named after their cell id, subgraphs
+// are swimlane containers holding the cells of their contents, and links are edges with their
+// line type and arrow ends. Styles assigned through classes or inline are applied to every cell,
+// since draw.io has no style classes. The title of the flowchart names the page.
+// It returns the draw.io file or an error if validation fails.
+func RenderDrawio(f *Flowchart) (string, error) {
+	if err := validateDrawio(f); err != nil {
+		return "", err
+	}
+	l, err := ComputeLayout(f, LayoutOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	r := &drawioRenderer{
+		layout:    l,
+		classDefs: allClassDefs(f),
+		elements:  newTreeElements(f),
+		ids:       make(map[Linkable]string),
+		rects:     make(map[*Flowchart]Rect),
+		used:      map[string]bool{drawioRootID: true, drawioLayerID: true},
+		nodeRects: make(map[*Node]Rect),
+		spare:     20,
+	}
+	for _, n := range l.Nodes {
+		r.nodeRects[n.Node] = n.Rect
+	}
+	for _, c := range l.Clusters {
+		r.rects[c.Subgraph] = c.Rect
+	}
+	untitled := 0
+	walkFlowchart(f, func(sub *Flowchart, _ []string) {
+		for _, node := range sub.Nodes {
+			r.ids[node] = r.uniqueID(drawioCellID(node.name))
+		}
+		for _, child := range sub.Subgraphs {
+			if child.Title != nil && *child.Title != "" {
+				r.ids[child] = r.uniqueID(drawioCellID(*child.Title))
+			} else {
+				untitled++
+				r.ids[child] = r.uniqueID("subgraph-" + strconv.Itoa(untitled))
+			}
+		}
+	})
+	r.cells = []drawioCell{{ID: drawioRootID}, {ID: drawioLayerID, Parent: drawioRootID}}
+	r.addFlowchart(f, drawioLayerID, Rect{})
+
+	name := "Page-1"
+	if f.Title != nil && *f.Title != "" {
+		name = *f.Title
+	}
+	file := drawioFile{
+		Host: "flowchart",
+		Diagrams: []drawioDiagram{{
+			ID:   "flowchart-" + nameHash(name),
+			Name: name,
+			Model: &drawioModel{
+				Grid:       "1",
+				GridSize:   "10",
+				Guides:     "1",
+				Connect:    "1",
+				Arrows:     "1",
+				Fold:       "1",
+				Page:       "1",
+				PageWidth:  strconv.Itoa(int(math.Ceil(l.Width))),
+				PageHeight: strconv.Itoa(int(math.Ceil(l.Height))),
+				Root:       drawioRoot{Cells: r.cells},
+			},
+		}},
+	}
+	out, err := xml.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+// validateDrawio validates the Flowchart structure to ensure it can be rendered as a draw.io file.
+// It checks for the following violations:
+// 1. All node and subgraph names must be unique across the whole flowchart.
+// 2. Style classes must have valid, unique names, and every class assigned must be defined.
+//
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func validateDrawio(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	violations = append(violations, styleClassViolations(f)...)
+	return newValidationError(violations...)
+}
@@ -0,0 +1,477 @@
+package flowchart
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// drawioTagRe matches an HTML tag in the value of a cell and captures its name.
+	drawioTagRe = regexp.MustCompile(`<(/?)([A-Za-z][A-Za-z0-9]*)[^>]*>`)
+	// drawioPageNameRe matches the names draw.io gives new pages, which are not used as titles.
+	drawioPageNameRe = regexp.MustCompile(`^Page-\d+$`)
+)
+
+// drawioNodeShapes maps the draw.io shapes that ParseDrawio recognises to node types. A shape is
+// named by the shape key of a style or, for built-in shapes such as "rhombus", by its first entry.
+var drawioNodeShapes = map[string]NodeTypeEnum{
+	"mxgraph.flowchart.terminator":         NodeTypeTerminator,
+	"mxgraph.flowchart.start_1":            NodeTypeTerminator,
+	"mxgraph.flowchart.start_2":            NodeTypeTerminator,
+	"process":                              NodeTypeSubprocess,
+	"mxgraph.flowchart.predefined_process": NodeTypeSubprocess,
+	"rhombus":                              NodeTypeDecision,
+	"mxgraph.flowchart.decision":           NodeTypeDecision,
+	"parallelogram":                        NodeTypeInputOutput,
+	"mxgraph.flowchart.data":               NodeTypeInputOutput,
+	"ellipse":                              NodeTypeConnector,
+	"doubleEllipse":                        NodeTypeConnector,
+	"mxgraph.flowchart.on-page_reference":  NodeTypeConnector,
+	"cylinder":                             NodeTypeDatabase,
+	"cylinder3":                            NodeTypeDatabase,
+	"datastore":                            NodeTypeDatabase,
+	"mxgraph.flowchart.database":           NodeTypeDatabase,
+	"mxgraph.flowchart.stored_data":        NodeTypeDatabase,
+}
+
+// drawioStyle is a parsed draw.io style: its first entry when it names a built-in style or shape,
+// and its key=value pairs.
+type drawioStyle struct {
+	name   string
+	values map[string]string
+}
+
+// parseDrawioStyle parses a style such as "rhombus;whiteSpace=wrap;html=1;".
+func parseDrawioStyle(s string) drawioStyle {
+	style := drawioStyle{values: make(map[string]string)}
+	for i, entry := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		switch {
+		case ok:
+			style.values[key] = value
+		case i == 0:
+			style.name = key
+		}
+	}
+	return style
+}
+
+// shape returns the name of the shape drawn by the style.
+func (s drawioStyle) shape() string {
+	if shape, ok := s.values["shape"]; ok {
+		return shape
+	}
+	return s.name
+}
+
+// nodeType returns the node type drawn by the style: the type of its shape, a terminator for
+// rectangles rounded into a pill, and a process for anything else.
+func (s drawioStyle) nodeType() NodeTypeEnum {
+	if t, ok := drawioNodeShapes[s.shape()]; ok {
+		return t
+	}
+	if arc, err := strconv.ParseFloat(s.values["arcSize"], 64); err == nil && s.values["rounded"] == "1" && arc >= 40 {
+		return NodeTypeTerminator
+	}
+	return NodeTypeProcess
+}
+
+// isContainer reports whether the style draws a container, such as a swimlane.
+func (s drawioStyle) isContainer() bool {
+	return s.name == "swimlane" || s.shape() == "swimlane" || s.values["container"] == "1"
+}
+
+// inlineStyle returns the properties of the style as a Style, or nil if none are set. The default
+// values draw.io writes for colours are skipped.
+func (s drawioStyle) inlineStyle() *Style {
+	var style Style
+	for _, p := range []struct {
+		dst *string
+		key string
+	}{
+		{&style.Fill, "fillColor"},
+		{&style.Stroke, "strokeColor"},
+		{&style.Color, "fontColor"},
+		{&style.FontFamily, "fontFamily"},
+	} {
+		if value := s.values[p.key]; value != "" && value != "default" {
+			*p.dst = value
+		}
+	}
+	for _, p := range []struct {
+		dst *string
+		key string
+	}{
+		{&style.StrokeWidth, "strokeWidth"},
+		{&style.FontSize, "fontSize"},
+	} {
+		if _, err := strconv.ParseFloat(s.values[p.key], 64); err == nil {
+			*p.dst = s.values[p.key] + "px"
+		}
+	}
+	if fontStyle, err := strconv.Atoi(s.values["fontStyle"]); err == nil && fontStyle&1 != 0 {
+		style.FontWeight = "bold"
+	}
+	if style.isEmpty() {
+		return nil
+	}
+	return &style
+}
+
+// drawioArrowType converts a draw.io startArrow or endArrow value to an ArrowTypeEnum. Every
+// arrow head that is not a circle or a cross is taken as a normal arrow.
+func drawioArrowType(arrow string) ArrowTypeEnum {
+	switch arrow {
+	case "none", "":
+		return ArrowTypeNone
+	case "oval", "circle", "circlePlus":
+		return ArrowTypeCircle
+	case "cross":
+		return ArrowTypeCross
+	default:
+		return ArrowTypeNormal
+	}
+}
+
+// link returns a link with the line type, arrow ends and inline style drawn by an edge's style.
+// Edges without a stroke are invisible links, and edges at least 3 wide are thick links.
+func (s drawioStyle) link() Link {
+	link := Link{LineType: LineTypeSolid}
+	switch width, _ := strconv.ParseFloat(s.values["strokeWidth"], 64); {
+	case s.values["strokeColor"] == "none":
+		link.LineType = LineTypeNone
+		delete(s.values, "strokeColor")
+	case s.values["dashed"] == "1":
+		link.LineType = LineTypeDotted
+	case width >= 3:
+		link.LineType = LineTypeThick
+		delete(s.values, "strokeWidth")
+	}
+
+	end, ok := s.values["endArrow"]
+	if !ok {
+		end = "classic" // The default of draw.io
+	}
+	start, target := drawioArrowType(s.values["startArrow"]), drawioArrowType(end)
+	link.OriginArrow, link.TargetArrow = start != ArrowTypeNone, target != ArrowTypeNone
+	link.ArrowType = target
+	if target == ArrowTypeNone {
+		link.ArrowType = start
+	}
+	link.Style = s.inlineStyle()
+	return link
+}
+
+// decodeDrawioValue converts the value of a cell to a label. HTML values are converted to text,
+// with line breaks and blocks starting new lines; if they have bold or italic text, the label is
+// markdown.
+func decodeDrawioValue(value string, isHTML bool) (string, LabelFormatEnum) {
+	if !isHTML {
+		return value, LabelFormatText
+	}
+	var sb strings.Builder
+	format := LabelFormatText
+	last := 0
+	for _, match := range drawioTagRe.FindAllStringSubmatchIndex(value, -1) {
+		sb.WriteString(html.UnescapeString(value[last:match[0]]))
+		last = match[1]
+		closing, tag := value[match[2]:match[3]] == "/", strings.ToLower(value[match[4]:match[5]])
+		switch tag {
+		case "br":
+			sb.WriteString("\n")
+		case "div", "p", "li":
+			if !closing && sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+				sb.WriteString("\n")
+			}
+		case "b", "strong":
+			sb.WriteString("**")
+			format = LabelFormatMarkdown
+		case "i", "em":
+			sb.WriteString("_")
+			format = LabelFormatMarkdown
+		}
+	}
+	sb.WriteString(html.UnescapeString(value[last:]))
+	return strings.TrimSuffix(strings.ReplaceAll(sb.String(), "\u00a0", " "), "\n"), format
+}
+
+// decodeDrawioDiagram returns the model of a compressed diagram: base64 of the deflated,
+// URL-encoded XML of the model.
+func decodeDrawioDiagram(data string) (*drawioModel, error) {
+	compressed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, fmt.Errorf("compressed diagram: %w", err)
+	}
+	inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		return nil, fmt.Errorf("compressed diagram: %w", err)
+	}
+	source, err := url.PathUnescape(string(inflated))
+	if err != nil {
+		return nil, fmt.Errorf("compressed diagram: %w", err)
+	}
+	var model drawioModel
+	if err := xml.Unmarshal([]byte(source), &model); err != nil {
+		return nil, fmt.Errorf("compressed diagram: %w", err)
+	}
+	return &model, nil
+}
+
+// readDrawioModel reads a draw.io file, or a bare mxGraphModel as copied from draw.io, and returns
+// the model of its first page together with the page's name.
+func readDrawioModel(r io.Reader) (*drawioModel, string, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, "", fmt.Errorf("missing mxfile or mxGraphModel element")
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "mxGraphModel":
+			var model drawioModel
+			return &model, "", decoder.DecodeElement(&model, &start)
+		case "mxfile":
+			var file drawioFile
+			if err := decoder.DecodeElement(&file, &start); err != nil {
+				return nil, "", err
+			}
+			if len(file.Diagrams) == 0 {
+				return nil, "", fmt.Errorf("file has no diagram")
+			}
+			diagram := file.Diagrams[0]
+			if diagram.Model != nil {
+				return diagram.Model, diagram.Name, nil
+			}
+			model, err := decodeDrawioDiagram(diagram.Data)
+			return model, diagram.Name, err
+		default:
+			return nil, "", fmt.Errorf("expected mxfile or mxGraphModel element, got %s", start.Name.Local)
+		}
+	}
+}
+
+// drawioParser holds the state needed while converting the cells of a draw.io model to a flowchart.
+type drawioParser struct {
+	root      *Flowchart
+	cells     map[string]*drawioCell    // Cells by id
+	children  map[string][]*drawioCell  // Child cells by parent id, in document order
+	elements  map[string]Linkable       // Nodes and subgraphs by cell id
+	scopes    map[string]*Flowchart     // The flowchart or subgraph each node or subgraph belongs to
+	parents   map[*Flowchart]*Flowchart // The flowchart or subgraph containing each subgraph
+	endpoints map[string]bool           // Ids of the cells that edges are connected to
+}
+
+// ParseDrawio reads a draw.io (diagrams.net) file, or an mxGraphModel copied from draw.io, and
+// returns the equivalent Flowchart, so that charts edited in draw.io can be brought back.
+// It reads everything RenderDrawio writes, compressed diagrams included, and only the first page
+// of files with several pages.
+//
+// Vertices become nodes named after their cell id, with a type recognised from their shape: the
+// shapes written by RenderDrawio, and the equivalent shapes of the Flowchart palette. Unknown
+// shapes become process nodes, and free text that no edge is connected to is skipped. Containers
+// such as swimlanes become subgraphs titled with their label, while the contents of groups belong
+// to the group's container. Edges become links with the line type and arrow ends of their style,
+// and are stored in the innermost subgraph containing both of their endpoints. HTML labels are
+// converted to text, or markdown when they have bold or italic text, and colours, stroke widths
+// and fonts become inline styles. The direction is guessed from the positions of linked vertices,
+// and the name of the page becomes the title unless it is a default name such as "Page-1".
+func ParseDrawio(r io.Reader) (*Flowchart, error) {
+	model, name, err := readDrawioModel(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &drawioParser{
+		root:      basicFlowchart(nil, DirectionVertical),
+		cells:     make(map[string]*drawioCell),
+		children:  make(map[string][]*drawioCell),
+		elements:  make(map[string]Linkable),
+		scopes:    make(map[string]*Flowchart),
+		parents:   make(map[*Flowchart]*Flowchart),
+		endpoints: make(map[string]bool),
+	}
+	if name != "" && !drawioPageNameRe.MatchString(name) {
+		p.root.Title = pointTo(name)
+	}
+
+	cells := model.Root.Cells
+	for i := range cells {
+		cell := &cells[i]
+		if _, ok := p.cells[cell.ID]; ok {
+			return nil, fmt.Errorf("cell %q: duplicate id", cell.ID)
+		}
+		p.cells[cell.ID] = cell
+		p.children[cell.Parent] = append(p.children[cell.Parent], cell)
+		if cell.Edge == "1" {
+			p.endpoints[cell.Source] = true
+			p.endpoints[cell.Target] = true
+		}
+	}
+
+	// The root cell has no parent, and its children are the layers of the drawing.
+	for _, root := range p.children[""] {
+		for _, layer := range p.children[root.ID] {
+			if layer.Vertex != "1" && layer.Edge != "1" {
+				p.addCells(layer.ID, p.root)
+			}
+		}
+	}
+	if err := p.addLinks(cells); err != nil {
+		return nil, err
+	}
+	p.root.Direction = p.direction(cells)
+	walkFlowchart(p.root, func(f *Flowchart, _ []string) {
+		for _, sub := range f.Subgraphs {
+			sub.Direction = p.root.Direction
+		}
+	})
+	return p.root, nil
+}
+
+// addCells adds the vertices that are children of the cell with the given id to scope: nodes,
+// subgraphs for containers, and the contents of groups.
+func (p *drawioParser) addCells(parent string, scope *Flowchart) {
+	for _, cell := range p.children[parent] {
+		if cell.Vertex != "1" {
+			continue
+		}
+		style := parseDrawioStyle(cell.Style)
+		label, format := decodeDrawioValue(cell.Value, style.values["html"] == "1")
+		switch {
+		case style.name == "group":
+			p.addCells(cell.ID, scope)
+		case style.isContainer() || p.hasVertices(cell.ID):
+			sub := basicFlowchart(nil, DirectionVertical)
+			if label != "" {
+				sub.Title = pointTo(label)
+			}
+			sub.Style = style.inlineStyle()
+			scope.Subgraphs = append(scope.Subgraphs, sub)
+			p.elements[cell.ID], p.scopes[cell.ID], p.parents[sub] = sub, scope, scope
+			p.addCells(cell.ID, sub)
+		case (style.name == "text" || style.name == "edgeLabel") && !p.endpoints[cell.ID]:
+			continue
+		default:
+			node := basicNode(cell.ID, nil, style.nodeType())
+			if label != cell.ID || format != LabelFormatText {
+				node.Label, node.LabelFormat = pointTo(label), format
+			}
+			node.Style = style.inlineStyle()
+			scope.Nodes = append(scope.Nodes, node)
+			p.elements[cell.ID], p.scopes[cell.ID] = node, scope
+		}
+	}
+}
+
+// hasVertices reports whether the cell with the given id has child vertices.
+func (p *drawioParser) hasVertices(id string) bool {
+	return slices.ContainsFunc(p.children[id], func(c *drawioCell) bool { return c.Vertex == "1" })
+}
+
+// addLinks adds a link for every edge to the innermost flowchart containing both of its endpoints.
+// The labels of an edge are its value and the values of its child label cells.
+func (p *drawioParser) addLinks(cells []drawioCell) error {
+	for _, cell := range cells {
+		if cell.Edge != "1" {
+			continue
+		}
+		origin, ok := p.elements[cell.Source]
+		if !ok {
+			return fmt.Errorf("cell %q: edge is not connected to a node or container at its source", cell.ID)
+		}
+		target, ok := p.elements[cell.Target]
+		if !ok {
+			return fmt.Errorf("cell %q: edge is not connected to a node or container at its target", cell.ID)
+		}
+
+		style := parseDrawioStyle(cell.Style)
+		link := style.link()
+		link.Origin, link.Target = origin, target
+		var labels []string
+		for _, c := range append([]*drawioCell{&cell}, p.children[cell.ID]...) {
+			label, format := decodeDrawioValue(c.Value, parseDrawioStyle(c.Style).values["html"] == "1")
+			if label != "" {
+				labels = append(labels, label)
+				link.LabelFormat = max(link.LabelFormat, format)
+			}
+		}
+		if len(labels) > 0 {
+			link.Label = pointTo(strings.Join(labels, "\n"))
+		}
+		scope := p.commonScope(p.scopes[cell.Source], p.scopes[cell.Target])
+		scope.Links = append(scope.Links, link)
+	}
+	return nil
+}
+
+// commonScope returns the innermost flowchart that contains both a and b.
+func (p *drawioParser) commonScope(a, b *Flowchart) *Flowchart {
+	var ancestors []*Flowchart
+	for f := a; f != nil; f = p.parents[f] {
+		ancestors = append(ancestors, f)
+	}
+	for f := b; f != nil; f = p.parents[f] {
+		if slices.Contains(ancestors, f) {
+			return f
+		}
+	}
+	return p.root
+}
+
+// center returns the center of a vertex in the coordinates of the page, or false if it has no geometry.
+func (p *drawioParser) center(id string) (Point, bool) {
+	cell, ok := p.cells[id]
+	if !ok || cell.Geometry == nil {
+		return Point{}, false
+	}
+	c := Point{cell.Geometry.X + cell.Geometry.Width/2, cell.Geometry.Y + cell.Geometry.Height/2}
+	for parent := p.cells[cell.Parent]; parent != nil && parent.Vertex == "1" && parent.Geometry != nil; parent = p.cells[parent.Parent] {
+		c.X += parent.Geometry.X
+		c.Y += parent.Geometry.Y
+	}
+	return c, true
+}
+
+// direction guesses the direction of the flowchart from the edges between vertices: horizontal if
+// they mostly run sideways, to the left if most of them run leftwards, and vertical otherwise.
+func (p *drawioParser) direction(cells []drawioCell) DirectionEnum {
+	var across, along, sideways float64
+	for _, cell := range cells {
+		if cell.Edge != "1" {
+			continue
+		}
+		from, ok := p.center(cell.Source)
+		to, ok2 := p.center(cell.Target)
+		if !ok || !ok2 {
+			continue
+		}
+		across += to.X - from.X
+		sideways += max(to.X-from.X, from.X-to.X)
+		along += max(to.Y-from.Y, from.Y-to.Y)
+	}
+	switch {
+	case sideways <= along:
+		return DirectionVertical
+	case across < 0:
+		return DirectionHorizontalLeft
+	default:
+		return DirectionHorizontalRight
+	}
+}
@@ -0,0 +1,310 @@
+package flowchart
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// drawioEditedModel is a model as saved by draw.io after an analyst edited it, with shapes from
+// the Flowchart palette, a wrapped cell, a group, a separate edge label and a free text note.
+const drawioEditedModel = `<mxGraphModel dx="946" dy="528" grid="1">
+  <root>
+    <mxCell id="0" />
+    <mxCell id="1" parent="0" />
+    <mxCell id="start" value="Start" style="strokeWidth=2;html=1;shape=mxgraph.flowchart.terminator;whiteSpace=wrap;" vertex="1" parent="1">
+      <mxGeometry x="100" y="20" width="100" height="40" as="geometry" />
+    </mxCell>
+    <object label="Read&lt;div&gt;&lt;b&gt;order&lt;/b&gt;&amp;nbsp;form&lt;/div&gt;" id="read" tooltip="From the shop">
+      <mxCell style="shape=parallelogram;html=1;fillColor=#dae8fc;strokeColor=#6c8ebf;fontStyle=1;fontSize=14;" vertex="1" parent="1">
+        <mxGeometry x="90" y="100" width="120" height="60" as="geometry" />
+      </mxCell>
+    </object>
+    <mxCell id="Xk2-9" value="Valid?" style="strokeWidth=2;html=1;shape=mxgraph.flowchart.decision;whiteSpace=wrap;" vertex="1" parent="1">
+      <mxGeometry x="100" y="200" width="100" height="100" as="geometry" />
+    </mxCell>
+    <mxCell id="lane" value="Warehouse" style="swimlane;html=1;" vertex="1" parent="1">
+      <mxGeometry x="60" y="340" width="200" height="200" as="geometry" />
+    </mxCell>
+    <mxCell id="grp" value="" style="group" vertex="1" connectable="0" parent="lane">
+      <mxGeometry x="20" y="40" width="160" height="140" as="geometry" />
+    </mxCell>
+    <mxCell id="pick" value="pick" style="rounded=0;whiteSpace=wrap;" vertex="1" parent="grp">
+      <mxGeometry width="120" height="40" as="geometry" />
+    </mxCell>
+    <mxCell id="db" value="Stock" style="shape=cylinder3;whiteSpace=wrap;html=1;" vertex="1" parent="grp">
+      <mxGeometry y="80" width="60" height="60" as="geometry" />
+    </mxCell>
+    <mxCell id="note" value="Ask Sam about returns" style="text;html=1;" vertex="1" parent="1">
+      <mxGeometry x="300" y="20" width="140" height="30" as="geometry" />
+    </mxCell>
+    <mxCell id="e1" style="edgeStyle=orthogonalEdgeStyle;html=1;" edge="1" parent="1" source="start" target="read">
+      <mxGeometry relative="1" as="geometry" />
+    </mxCell>
+    <mxCell id="e2" value="" style="edgeStyle=orthogonalEdgeStyle;html=1;endArrow=open;startArrow=oval;dashed=1;" edge="1" parent="1" source="read" target="Xk2-9">
+      <mxGeometry relative="1" as="geometry" />
+    </mxCell>
+    <mxCell id="e3" value="yes" style="html=1;strokeWidth=4;strokeColor=#FF0000;" edge="1" parent="1" source="Xk2-9" target="lane">
+      <mxGeometry relative="1" as="geometry" />
+    </mxCell>
+    <mxCell id="e3-label" value="&lt;i&gt;in stock&lt;/i&gt;" style="edgeLabel;html=1;" vertex="1" connectable="0" parent="e3">
+      <mxGeometry x="-0.2" relative="1" as="geometry" />
+    </mxCell>
+    <mxCell id="e4" style="endArrow=none;strokeColor=none;" edge="1" parent="grp" source="pick" target="db">
+      <mxGeometry relative="1" as="geometry" />
+    </mxCell>
+  </root>
+</mxGraphModel>
+`
+
+// compressDrawioModel compresses a model the way draw.io stored diagrams before version 14.
+func compressDrawioModel(t *testing.T, model string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(url.PathEscape(model))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestParseDrawio(t *testing.T) {
+	edited := func() *Flowchart {
+		start := &Node{name: "start", Type: NodeTypeTerminator, Label: pointTo("Start"), Style: &Style{StrokeWidth: "2px"}}
+		read := &Node{name: "read", Type: NodeTypeInputOutput, Label: pointTo("Read\n**order** form"), LabelFormat: LabelFormatMarkdown,
+			Style: &Style{Fill: "#dae8fc", Stroke: "#6c8ebf", FontSize: "14px", FontWeight: "bold"}}
+		valid := &Node{name: "Xk2-9", Type: NodeTypeDecision, Label: pointTo("Valid?"), Style: &Style{StrokeWidth: "2px"}}
+		pick, db := ProcessNode("pick", nil), DatabaseNode("db", pointTo("Stock"))
+		lane := &Flowchart{Direction: DirectionVertical, Title: pointTo("Warehouse"), Nodes: []*Node{pick, db}, Links: []Link{
+			{Origin: pick, Target: db, LineType: LineTypeNone},
+		}}
+		return &Flowchart{
+			Direction: DirectionVertical,
+			Nodes:     []*Node{start, read, valid},
+			Subgraphs: []*Flowchart{lane},
+			Links: []Link{
+				SolidLink(start, read, nil),
+				{Origin: read, Target: valid, LineType: LineTypeDotted, ArrowType: ArrowTypeNormal, OriginArrow: true, TargetArrow: true},
+				{Origin: valid, Target: lane, LineType: LineTypeThick, ArrowType: ArrowTypeNormal, TargetArrow: true,
+					Label: pointTo("yes\n_in stock_"), LabelFormat: LabelFormatMarkdown, Style: &Style{Stroke: "#FF0000"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		source      func(t *testing.T) string
+		expected    func() *Flowchart
+		expectedErr bool
+	}{
+		{
+			name:     "edited model",
+			source:   func(*testing.T) string { return drawioEditedModel },
+			expected: edited,
+		},
+		{
+			name: "file with a named page",
+			source: func(*testing.T) string {
+				return `<mxfile host="app.diagrams.net"><diagram id="x" name="Orders">` + drawioEditedModel + `</diagram><diagram name="Page-2"></diagram></mxfile>`
+			},
+			expected: func() *Flowchart {
+				f := edited()
+				f.Title = pointTo("Orders")
+				return f
+			},
+		},
+		{
+			name: "compressed diagram with a default page name",
+			source: func(t *testing.T) string {
+				return `<mxfile><diagram id="x" name="Page-1">` + compressDrawioModel(t, drawioEditedModel) + `</diagram></mxfile>`
+			},
+			expected: edited,
+		},
+		{
+			name: "horizontal flow to the left",
+			source: func(*testing.T) string {
+				return `<mxGraphModel><root><mxCell id="0"/><mxCell id="1" parent="0"/>
+<mxCell id="a" value="A" vertex="1" parent="1"><mxGeometry x="300" y="0" width="80" height="40" as="geometry"/></mxCell>
+<mxCell id="b" value="B &amp;amp; C" vertex="1" parent="1"><mxGeometry x="100" y="20" width="80" height="40" as="geometry"/></mxCell>
+<mxCell id="e" edge="1" parent="1" source="a" target="b"/>
+</root></mxGraphModel>`
+			},
+			expected: func() *Flowchart {
+				a, b := ProcessNode("a", pointTo("A")), ProcessNode("b", pointTo("B &amp; C"))
+				return &Flowchart{Direction: DirectionHorizontalLeft, Nodes: []*Node{a, b}, Links: []Link{SolidLink(a, b, nil)}}
+			},
+		},
+		{
+			name: "edge without a target",
+			source: func(*testing.T) string {
+				return `<mxGraphModel><root><mxCell id="0"/><mxCell id="1" parent="0"/><mxCell id="a" vertex="1" parent="1"/><mxCell id="e" edge="1" parent="1" source="a"/></root></mxGraphModel>`
+			},
+			expectedErr: true,
+		},
+		{
+			name: "edge to a note",
+			source: func(*testing.T) string {
+				return `<mxGraphModel><root><mxCell id="0"/><mxCell id="1" parent="0"/><mxCell id="a" vertex="1" parent="1"/><mxCell id="g" style="group" vertex="1" parent="1"/><mxCell id="e" edge="1" parent="1" source="a" target="g"/></root></mxGraphModel>`
+			},
+			expectedErr: true,
+		},
+		{
+			name: "duplicate cell id",
+			source: func(*testing.T) string {
+				return `<mxGraphModel><root><mxCell id="0"/><mxCell id="0"/></root></mxGraphModel>`
+			},
+			expectedErr: true,
+		},
+		{
+			name:        "file without diagrams",
+			source:      func(*testing.T) string { return `<mxfile></mxfile>` },
+			expectedErr: true,
+		},
+		{
+			name:        "corrupt compressed diagram",
+			source:      func(*testing.T) string { return `<mxfile><diagram>not base64!</diagram></mxfile>` },
+			expectedErr: true,
+		},
+		{
+			name:        "not a draw.io file",
+			source:      func(*testing.T) string { return `<svg></svg>` },
+			expectedErr: true,
+		},
+		{
+			name:        "empty input",
+			source:      func(*testing.T) string { return "" },
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDrawio(strings.NewReader(tt.source(t)))
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("ParseDrawio() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if tt.expectedErr {
+				return
+			}
+			if diff := cmp.Diff(tt.expected(), got, parsedFlowchartOptions); diff != "" {
+				t.Errorf("ParseDrawio() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseDrawio_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		flowchart func() *Flowchart
+	}{
+		{
+			name: "every node type in nested subgraphs",
+			flowchart: func() *Flowchart {
+				start, check := TerminatorNode("start", pointTo("Start")), DecisionNode("check", pointTo("Valid?"))
+				sub, io := SubprocessNode("sub", nil), InputOutputNode("io", pointTo("Read <form>"))
+				conn, db := ConnectorNode("conn", pointTo("A")), DatabaseNode("db", nil)
+				inner := &Flowchart{Direction: DirectionVertical, Title: pointTo("Inner"), Nodes: []*Node{conn, db}}
+				outer := &Flowchart{Direction: DirectionVertical, Title: pointTo("Outer"), Nodes: []*Node{io}, Subgraphs: []*Flowchart{inner}}
+				return &Flowchart{
+					Direction: DirectionVertical,
+					Title:     pointTo("Every type"),
+					Nodes:     []*Node{start, check, sub},
+					Subgraphs: []*Flowchart{outer},
+					Links: []Link{
+						SolidLink(start, check, nil),
+						SolidLink(check, sub, pointTo("no")),
+						DottedLink(check, outer, pointTo("yes")),
+						SolidLink(sub, db, nil),
+					},
+				}
+			},
+		},
+		{
+			name: "every link type",
+			flowchart: func() *Flowchart {
+				a, b := ProcessNode("A", pointTo("Start")), ProcessNode("B", pointTo("Choose"))
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Nodes:     []*Node{a, b},
+					Links: []Link{
+						BlankLink(a, b, nil),
+						{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeNone},
+						SolidLink(a, b, pointTo("solid")),
+						DottedLink(a, b, nil),
+						ThickLink(a, b, pointTo("thick")),
+						{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeNormal, OriginArrow: true, TargetArrow: true},
+						{Origin: a, Target: b, LineType: LineTypeDotted, ArrowType: ArrowTypeCircle, OriginArrow: true, TargetArrow: true},
+						{Origin: a, Target: b, LineType: LineTypeThick, ArrowType: ArrowTypeCross, TargetArrow: true},
+					},
+				}
+			},
+		},
+		{
+			name: "markdown labels and inline styles",
+			flowchart: func() *Flowchart {
+				a := &Node{name: "A", Type: NodeTypeProcess, Label: pointTo("**Valid**\n_maybe_ & more"), LabelFormat: LabelFormatMarkdown,
+					Style: &Style{Fill: "#f9f", Stroke: "#333", StrokeWidth: "2px", Color: "#fff", FontFamily: "Arial", FontSize: "14px", FontWeight: "bold"}}
+				b := ProcessNode("B", nil)
+				link := SolidLink(a, b, pointTo("**retry**"))
+				link.LabelFormat = LabelFormatMarkdown
+				link.Style = &Style{Stroke: "#f00"}
+				return &Flowchart{Direction: DirectionVertical, Nodes: []*Node{a, b}, Links: []Link{link}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.flowchart()
+			rendered, err := RenderDrawio(original)
+			if err != nil {
+				t.Fatalf("RenderDrawio() unexpected error: %v", err)
+			}
+
+			got, err := ParseDrawio(strings.NewReader(rendered))
+			if err != nil {
+				t.Fatalf("ParseDrawio() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(original, got, parsedFlowchartOptions); diff != "" {
+				t.Errorf("ParseDrawio(RenderDrawio()) mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeDrawioValue(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          string
+		isHTML         bool
+		expected       string
+		expectedFormat LabelFormatEnum
+	}{
+		{name: "plain text keeps markup", value: "a <b>b</b>\nc", expected: "a <b>b</b>\nc"},
+		{name: "entities", value: "a &amp;&nbsp;b &lt;c&gt;", isHTML: true, expected: "a & b <c>"},
+		{name: "line breaks", value: "one<br>two<br/>three", isHTML: true, expected: "one\ntwo\nthree"},
+		{name: "blocks", value: "one<div>two</div><div><br></div>", isHTML: true, expected: "one\ntwo\n"},
+		{name: "bold and italic", value: `<span style="color: red">a <strong>b</strong> <em>c</em></span>`, isHTML: true, expected: "a **b** _c_", expectedFormat: LabelFormatMarkdown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, format := decodeDrawioValue(tt.value, tt.isHTML)
+			if got != tt.expected || format != tt.expectedFormat {
+				t.Errorf("decodeDrawioValue() = %q, %v, expected %q, %v", got, format, tt.expected, tt.expectedFormat)
+			}
+		})
+	}
+}
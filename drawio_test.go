@@ -0,0 +1,141 @@
+package flowchart
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderDrawio(t *testing.T) {
+	start, check := TerminatorNode("start", pointTo("Start")), DecisionNode("check", pointTo("**Valid**?"))
+	check.LabelFormat = LabelFormatMarkdown
+	save := &Node{name: "save", Type: NodeTypeSubprocess, Label: pointTo("Save <order>"), Classes: []string{"done"}}
+	storage := &Flowchart{Title: pointTo("Storage"), Nodes: []*Node{save}, Style: &Style{Fill: "#eee"}}
+	f := &Flowchart{
+		Title:     pointTo("Orders"),
+		Direction: DirectionHorizontalRight,
+		ClassDefs: []StyleClass{{Name: "done", Style: Style{Fill: "#0f0", FontWeight: "bold"}}},
+		Nodes:     []*Node{start, check},
+		Subgraphs: []*Flowchart{storage},
+		Links: []Link{
+			SolidLink(start, check, nil),
+			DottedLink(check, storage, pointTo("yes\nnow")),
+		},
+	}
+
+	expected := `<mxfile host="flowchart">
+  <diagram id="flowchart-7872110c" name="Orders">
+    <mxGraphModel grid="1" gridSize="10" guides="1" connect="1" arrows="1" fold="1" page="1" pageWidth="488" pageHeight="158">
+      <root>
+        <mxCell id="0"></mxCell>
+        <mxCell id="1" parent="0"></mxCell>
+        <mxCell id="start" value="Start" style="rounded=1;arcSize=50;whiteSpace=wrap;html=1;" vertex="1" parent="1">
+          <mxGeometry x="20" y="60" width="72" height="38" as="geometry"></mxGeometry>
+        </mxCell>
+        <mxCell id="check" value="&lt;b&gt;Valid&lt;/b&gt;?" style="rhombus;whiteSpace=wrap;html=1;" vertex="1" parent="1">
+          <mxGeometry x="142" y="20" width="118" height="118" as="geometry"></mxGeometry>
+        </mxCell>
+        <mxCell id="Storage" value="Storage" style="swimlane;startSize=23;whiteSpace=wrap;html=1;fillColor=#eee;" vertex="1" parent="1">
+          <mxGeometry x="310" y="23" width="158" height="90" as="geometry"></mxGeometry>
+        </mxCell>
+        <mxCell id="save" value="Save &amp;lt;order&amp;gt;" style="shape=process;backgroundOutline=1;whiteSpace=wrap;html=1;fillColor=#0f0;fontStyle=1;" vertex="1" parent="Storage">
+          <mxGeometry x="15" y="37" width="128" height="38" as="geometry"></mxGeometry>
+        </mxCell>
+        <mxCell id="edge-1" style="edgeStyle=orthogonalEdgeStyle;rounded=0;html=1;startArrow=none;endArrow=block;endFill=1;" edge="1" parent="1" source="start" target="check">
+          <mxGeometry relative="1" as="geometry"></mxGeometry>
+        </mxCell>
+        <mxCell id="edge-2" value="yes&lt;br&gt;now" style="edgeStyle=orthogonalEdgeStyle;rounded=0;html=1;dashed=1;startArrow=none;endArrow=block;endFill=1;" edge="1" parent="1" source="check" target="Storage">
+          <mxGeometry relative="1" as="geometry"></mxGeometry>
+        </mxCell>
+      </root>
+    </mxGraphModel>
+  </diagram>
+</mxfile>
+`
+	got, err := RenderDrawio(f)
+	if err != nil {
+		t.Fatalf("RenderDrawio() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("RenderDrawio() mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestRenderDrawio_Errors(t *testing.T) {
+	a := &Node{name: "A", Classes: []string{"missing"}}
+	_, err := RenderDrawio(&Flowchart{Nodes: []*Node{a, ProcessNode("A", nil)}, Links: []Link{SolidLink(a, &Node{name: "Phantom"}, nil)}})
+	for _, code := range []error{ErrDuplicateName, ErrUndefinedStyleClass, ErrOrphanedEndpoint} {
+		if !errors.Is(err, code) {
+			t.Errorf("RenderDrawio() error = %v, expected %v", err, code)
+		}
+	}
+}
+
+func TestDrawioEdgeStyle(t *testing.T) {
+	a, b := ProcessNode("A", nil), ProcessNode("B", nil)
+	tests := []struct {
+		name     string
+		link     Link
+		style    Style
+		expected string
+	}{
+		{
+			name:     "solid",
+			link:     SolidLink(a, b, nil),
+			expected: "edgeStyle=orthogonalEdgeStyle;rounded=0;html=1;startArrow=none;endArrow=block;endFill=1;",
+		},
+		{
+			name:     "invisible",
+			link:     BlankLink(a, b, nil),
+			expected: "edgeStyle=orthogonalEdgeStyle;rounded=0;html=1;startArrow=none;endArrow=block;endFill=1;strokeColor=none;",
+		},
+		{
+			name:     "thick with circles at both ends",
+			link:     Link{Origin: a, Target: b, LineType: LineTypeThick, ArrowType: ArrowTypeCircle, OriginArrow: true, TargetArrow: true},
+			expected: "edgeStyle=orthogonalEdgeStyle;rounded=0;html=1;startArrow=oval;endArrow=oval;startFill=1;endFill=1;strokeWidth=3;",
+		},
+		{
+			name:     "dotted cross with style",
+			link:     Link{Origin: a, Target: b, LineType: LineTypeDotted, ArrowType: ArrowTypeCross, TargetArrow: true},
+			style:    Style{Stroke: "red", StrokeWidth: "2px", FontSize: "10px"},
+			expected: "edgeStyle=orthogonalEdgeStyle;rounded=0;html=1;dashed=1;startArrow=none;endArrow=cross;strokeColor=red;strokeWidth=2;fontSize=10;",
+		},
+		{
+			name:     "no arrows",
+			link:     Link{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeNormal},
+			expected: "edgeStyle=orthogonalEdgeStyle;rounded=0;html=1;startArrow=none;endArrow=none;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := drawioEdgeStyle(tt.link, tt.style); got != tt.expected {
+				t.Errorf("drawioEdgeStyle() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDrawioValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		label    string
+		format   LabelFormatEnum
+		expected string
+	}{
+		{name: "plain", label: "Save order", expected: "Save order"},
+		{name: "escaped", label: `a < b & "c"`, expected: "a &lt; b &amp; &#34;c&#34;"},
+		{name: "newlines", label: "one\ntwo", expected: "one<br>two"},
+		{name: "markdown", label: "**bold** and _italic_\n**_both_**", format: LabelFormatMarkdown, expected: "<b>bold</b> and <i>italic</i><br><b><i>both</i></b>"},
+		{name: "markdown markers in text", label: "**bold**", expected: "**bold**"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := drawioValue(tt.label, tt.format); got != tt.expected {
+				t.Errorf("drawioValue() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package flowchart
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// jsonSchema is the JSON Schema describing the encoded form of a Flowchart.
+//
+//go:embed flowchart.schema.json
+var jsonSchema []byte
+
+// JSONSchema returns the JSON Schema (draft 2020-12) describing the JSON and YAML encoding of a
+// Flowchart. The same document validates YAML files once they are converted to JSON.
+func JSONSchema() []byte {
+	return slices.Clone(jsonSchema)
+}
+
+// Names used for the enums in the encoded form of a Flowchart, indexed by enum value.
+var (
+	directionNames   = []string{"horizontal-right", "horizontal-left", "vertical"}
+	nodeTypeNames    = []string{"terminator", "process", "subprocess", "decision", "input-output", "connector", "database"}
+	lineTypeNames    = []string{"none", "solid", "dotted", "thick"}
+	arrowTypeNames   = []string{"none", "normal", "circle", "cross"}
+	labelFormatNames = []string{"text", "markdown"}
+)
+
+// enumText returns the name of an enum value, or an error if the value has no name.
+func enumText(names []string, value int, kind string) ([]byte, error) {
+	if value < 0 || value >= len(names) {
+		return nil, fmt.Errorf("invalid %s %d", kind, value)
+	}
+	return []byte(names[value]), nil
+}
+
+// parseEnumText returns the enum value with the given name, or an error if there is none.
+func parseEnumText(names []string, text []byte, kind string) (int, error) {
+	value := slices.Index(names, string(text))
+	if value < 0 {
+		return 0, fmt.Errorf("invalid %s %q", kind, text)
+	}
+	return value, nil
+}
+
+// MarshalText encodes the direction as "horizontal-right", "horizontal-left" or "vertical".
+func (d DirectionEnum) MarshalText() ([]byte, error) {
+	return enumText(directionNames, int(d), "direction")
+}
+
+// UnmarshalText decodes a direction encoded by MarshalText.
+func (d *DirectionEnum) UnmarshalText(text []byte) error {
+	value, err := parseEnumText(directionNames, text, "direction")
+	*d = DirectionEnum(value)
+	return err
+}
+
+// MarshalText encodes the node type as "terminator", "process", "subprocess", "decision",
+// "input-output", "connector" or "database".
+func (t NodeTypeEnum) MarshalText() ([]byte, error) {
+	return enumText(nodeTypeNames, int(t), "node type")
+}
+
+// UnmarshalText decodes a node type encoded by MarshalText.
+func (t *NodeTypeEnum) UnmarshalText(text []byte) error {
+	value, err := parseEnumText(nodeTypeNames, text, "node type")
+	*t = NodeTypeEnum(value)
+	return err
+}
+
+// MarshalText encodes the line type as "none", "solid", "dotted" or "thick".
+func (l LineTypeEnum) MarshalText() ([]byte, error) {
+	return enumText(lineTypeNames, int(l), "line type")
+}
+
+// UnmarshalText decodes a line type encoded by MarshalText.
+func (l *LineTypeEnum) UnmarshalText(text []byte) error {
+	value, err := parseEnumText(lineTypeNames, text, "line type")
+	*l = LineTypeEnum(value)
+	return err
+}
+
+// MarshalText encodes the arrow type as "none", "normal", "circle" or "cross".
+func (a ArrowTypeEnum) MarshalText() ([]byte, error) {
+	return enumText(arrowTypeNames, int(a), "arrow type")
+}
+
+// UnmarshalText decodes an arrow type encoded by MarshalText.
+func (a *ArrowTypeEnum) UnmarshalText(text []byte) error {
+	value, err := parseEnumText(arrowTypeNames, text, "arrow type")
+	*a = ArrowTypeEnum(value)
+	return err
+}
+
+// MarshalText encodes the label format as "text" or "markdown".
+func (l LabelFormatEnum) MarshalText() ([]byte, error) {
+	return enumText(labelFormatNames, int(l), "label format")
+}
+
+// UnmarshalText decodes a label format encoded by MarshalText.
+func (l *LabelFormatEnum) UnmarshalText(text []byte) error {
+	value, err := parseEnumText(labelFormatNames, text, "label format")
+	*l = LabelFormatEnum(value)
+	return err
+}
+
+// flowchartDocument is the encoded form of a Flowchart, as described by the JSON Schema.
+type flowchartDocument struct {
+	Title     *string             `json:"title,omitempty" yaml:"title,omitempty"`
+	Direction DirectionEnum       `json:"direction" yaml:"direction"`
+	ClassDefs []StyleClass        `json:"classDefs,omitempty" yaml:"classDefs,omitempty"`
+	Classes   []string            `json:"classes,omitempty" yaml:"classes,omitempty"`
+	Style     *Style              `json:"style,omitempty" yaml:"style,omitempty"`
+	Nodes     []nodeDocument      `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+	Subgraphs []flowchartDocument `json:"subgraphs,omitempty" yaml:"subgraphs,omitempty"`
+	Links     []linkDocument      `json:"links,omitempty" yaml:"links,omitempty"`
+}
+
+// nodeDocument is the encoded form of a Node, identified by its name.
+type nodeDocument struct {
+	ID          string          `json:"id" yaml:"id"`
+	Type        NodeTypeEnum    `json:"type" yaml:"type"`
+	Label       *string         `json:"label,omitempty" yaml:"label,omitempty"`
+	LabelFormat LabelFormatEnum `json:"labelFormat,omitempty" yaml:"labelFormat,omitempty"`
+	Classes     []string        `json:"classes,omitempty" yaml:"classes,omitempty"`
+	Style       *Style          `json:"style,omitempty" yaml:"style,omitempty"`
+}
+
+// linkDocument is the encoded form of a Link. Its endpoints refer to the id of a node or the
+// title of a subgraph.
+type linkDocument struct {
+	Origin      string          `json:"origin" yaml:"origin"`
+	Target      string          `json:"target" yaml:"target"`
+	LineType    LineTypeEnum    `json:"lineType" yaml:"lineType"`
+	ArrowType   ArrowTypeEnum   `json:"arrowType" yaml:"arrowType"`
+	OriginArrow bool            `json:"originArrow,omitempty" yaml:"originArrow,omitempty"`
+	TargetArrow bool            `json:"targetArrow,omitempty" yaml:"targetArrow,omitempty"`
+	Label       *string         `json:"label,omitempty" yaml:"label,omitempty"`
+	LabelFormat LabelFormatEnum `json:"labelFormat,omitempty" yaml:"labelFormat,omitempty"`
+	Classes     []string        `json:"classes,omitempty" yaml:"classes,omitempty"`
+	Style       *Style          `json:"style,omitempty" yaml:"style,omitempty"`
+}
+
+// MarshalJSON encodes the flowchart as described by JSONSchema. Links are encoded by the names of
+// their endpoints, so every endpoint must be a node or titled subgraph of the flowchart tree, and
+// the names of the nodes and subgraphs, including the title of the flowchart, must be unique.
+func (f Flowchart) MarshalJSON() ([]byte, error) {
+	doc, err := newFlowchartDocument(&f)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON decodes a flowchart encoded by MarshalJSON, resolving link endpoints to the nodes
+// and subgraphs they name.
+func (f *Flowchart) UnmarshalJSON(data []byte) error {
+	var doc flowchartDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return doc.decode(f)
+}
+
+// MarshalYAML returns the document encoded in YAML in place of the flowchart. It implements the
+// Marshaler interface of gopkg.in/yaml.v2 and gopkg.in/yaml.v3 without depending on either, and
+// produces the same structure as MarshalJSON.
+func (f Flowchart) MarshalYAML() (any, error) {
+	return newFlowchartDocument(&f)
+}
+
+// UnmarshalYAML decodes a flowchart encoded by MarshalYAML. It implements the function-based
+// Unmarshaler interface supported by gopkg.in/yaml.v2 and gopkg.in/yaml.v3.
+func (f *Flowchart) UnmarshalYAML(unmarshal func(any) error) error {
+	var doc flowchartDocument
+	if err := unmarshal(&doc); err != nil {
+		return err
+	}
+	return doc.decode(f)
+}
+
+// MarshalJSON encodes the node, including its name as "id".
+func (n Node) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newNodeDocument(&n))
+}
+
+// UnmarshalJSON decodes a node encoded by MarshalJSON.
+func (n *Node) UnmarshalJSON(data []byte) error {
+	var doc nodeDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*n = *doc.node()
+	return nil
+}
+
+// newFlowchartDocument returns the encoded form of a flowchart tree. It returns an error if two
+// nodes or subgraphs, including the root, share a name, which the document could not be decoded
+// with, or if a link has a missing endpoint or refers to a name that is not in the tree.
+func newFlowchartDocument(f *Flowchart) (flowchartDocument, error) {
+	names := f.allNames()
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			return flowchartDocument{}, fmt.Errorf("cannot encode repeated node or subgraph name %q", name)
+		}
+		seen[name] = true
+	}
+	var build func(f *Flowchart) (flowchartDocument, error)
+	build = func(f *Flowchart) (flowchartDocument, error) {
+		doc := flowchartDocument{
+			Title:     f.Title,
+			Direction: f.Direction,
+			ClassDefs: f.ClassDefs,
+			Classes:   f.Classes,
+			Style:     f.Style,
+		}
+		for _, node := range f.Nodes {
+			doc.Nodes = append(doc.Nodes, newNodeDocument(node))
+		}
+		for _, subgraph := range f.Subgraphs {
+			subDoc, err := build(subgraph)
+			if err != nil {
+				return flowchartDocument{}, err
+			}
+			doc.Subgraphs = append(doc.Subgraphs, subDoc)
+		}
+		for _, link := range f.Links {
+			if link.Origin == (Linkable)(nil) || link.Target == (Linkable)(nil) {
+				return flowchartDocument{}, fmt.Errorf("cannot encode link with no origin or target")
+			}
+			for _, endpoint := range []string{link.Origin.nodeName(), link.Target.nodeName()} {
+				if !slices.Contains(names, endpoint) {
+					return flowchartDocument{}, fmt.Errorf("cannot encode link to %q, which is not in the flowchart", endpoint)
+				}
+			}
+			doc.Links = append(doc.Links, linkDocument{
+				Origin:      link.Origin.nodeName(),
+				Target:      link.Target.nodeName(),
+				LineType:    link.LineType,
+				ArrowType:   link.ArrowType,
+				OriginArrow: link.OriginArrow,
+				TargetArrow: link.TargetArrow,
+				Label:       link.Label,
+				LabelFormat: link.LabelFormat,
+				Classes:     link.Classes,
+				Style:       link.Style,
+			})
+		}
+		return doc, nil
+	}
+	return build(f)
+}
+
+// newNodeDocument returns the encoded form of a node.
+func newNodeDocument(n *Node) nodeDocument {
+	return nodeDocument{
+		ID:          n.name,
+		Type:        n.Type,
+		Label:       n.Label,
+		LabelFormat: n.LabelFormat,
+		Classes:     n.Classes,
+		Style:       n.Style,
+	}
+}
+
+// node returns the node described by the document.
+func (d nodeDocument) node() *Node {
+	return &Node{
+		name:        d.ID,
+		Type:        d.Type,
+		Label:       d.Label,
+		LabelFormat: d.LabelFormat,
+		Classes:     d.Classes,
+		Style:       d.Style,
+	}
+}
+
+// decode replaces f with the flowchart tree described by the document. Node ids and subgraph
+// titles must be unique across the tree, and every link endpoint must refer to one of them.
+func (d flowchartDocument) decode(f *Flowchart) error {
+	linkables := make(map[string]Linkable)
+	var build func(d flowchartDocument) (*Flowchart, error)
+	build = func(d flowchartDocument) (*Flowchart, error) {
+		chart := basicFlowchart(d.Title, d.Direction)
+		chart.ClassDefs, chart.Classes, chart.Style = d.ClassDefs, d.Classes, d.Style
+		if d.Title != nil {
+			if _, ok := linkables[*d.Title]; ok {
+				return nil, fmt.Errorf("repeated node or subgraph name %q", *d.Title)
+			}
+			linkables[*d.Title] = chart
+		}
+		for _, nodeDoc := range d.Nodes {
+			if _, ok := linkables[nodeDoc.ID]; ok {
+				return nil, fmt.Errorf("repeated node or subgraph name %q", nodeDoc.ID)
+			}
+			node := nodeDoc.node()
+			linkables[node.name] = node
+			chart.Nodes = append(chart.Nodes, node)
+		}
+		for _, subDoc := range d.Subgraphs {
+			subgraph, err := build(subDoc)
+			if err != nil {
+				return nil, err
+			}
+			chart.Subgraphs = append(chart.Subgraphs, subgraph)
+		}
+		return chart, nil
+	}
+
+	chart, err := build(d)
+	if err != nil {
+		return err
+	}
+	// Links to the root flowchart must point to f, which chart is copied into.
+	if d.Title != nil {
+		linkables[*d.Title] = f
+	}
+
+	// Links are resolved once every node and subgraph is known, as they may refer forward.
+	var resolve func(chart *Flowchart, d flowchartDocument) error
+	resolve = func(chart *Flowchart, d flowchartDocument) error {
+		for _, linkDoc := range d.Links {
+			origin, ok := linkables[linkDoc.Origin]
+			if !ok {
+				return fmt.Errorf("link origin %q is not a node or subgraph", linkDoc.Origin)
+			}
+			target, ok := linkables[linkDoc.Target]
+			if !ok {
+				return fmt.Errorf("link target %q is not a node or subgraph", linkDoc.Target)
+			}
+			chart.Links = append(chart.Links, Link{
+				Origin:      origin,
+				Target:      target,
+				LineType:    linkDoc.LineType,
+				ArrowType:   linkDoc.ArrowType,
+				OriginArrow: linkDoc.OriginArrow,
+				TargetArrow: linkDoc.TargetArrow,
+				Label:       linkDoc.Label,
+				LabelFormat: linkDoc.LabelFormat,
+				Classes:     linkDoc.Classes,
+				Style:       linkDoc.Style,
+			})
+		}
+		for i, subDoc := range d.Subgraphs {
+			if err := resolve(chart.Subgraphs[i], subDoc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := resolve(chart, d); err != nil {
+		return err
+	}
+	*f = *chart
+	return nil
+}
@@ -0,0 +1,320 @@
+package flowchart
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// decodedFlowchartOptions compares decoded flowcharts, which always have non-nil node and subgraph slices.
+var decodedFlowchartOptions = cmp.Options{cmp.AllowUnexported(Node{}), cmpopts.EquateEmpty()}
+
+// encodingFixture returns a flowchart using every part of the model, including links to
+// subgraphs and a link stored in a subgraph that refers to a node outside it.
+func encodingFixture() *Flowchart {
+	start := TerminatorNode("Start", pointTo("Begin"))
+	check := &Node{name: "Check", Type: NodeTypeDecision, Label: pointTo("**ok?**"), LabelFormat: LabelFormatMarkdown, Classes: []string{"failed"}}
+	store := &Node{name: "Store", Type: NodeTypeDatabase, Style: &Style{Fill: "#eee"}}
+	inner := &Flowchart{
+		Title:     pointTo("Inner"),
+		Direction: DirectionHorizontalLeft,
+		Nodes:     []*Node{store},
+	}
+	group := &Flowchart{
+		Title:     pointTo("Group"),
+		Direction: DirectionVertical,
+		Classes:   []string{"failed"},
+		Style:     &Style{Stroke: "#000"},
+		Nodes:     []*Node{check},
+		Subgraphs: []*Flowchart{inner, {Direction: DirectionVertical, Nodes: []*Node{ConnectorNode("Loose", nil)}}},
+		Links:     []Link{DottedLink(check, start, pointTo("retry"))},
+	}
+
+	toGroup := ThickLink(start, group, nil)
+	toGroup.ArrowType = ArrowTypeCircle
+	toGroup.OriginArrow = true
+	toGroup.Classes = []string{"failed"}
+	toGroup.Style = &Style{StrokeWidth: "2px"}
+	blank := BlankLink(inner, start, pointTo("*back*"))
+	blank.LabelFormat = LabelFormatMarkdown
+
+	return &Flowchart{
+		Title:     pointTo("Root"),
+		Direction: DirectionHorizontalRight,
+		ClassDefs: []StyleClass{{Name: "failed", Style: Style{Fill: "#f00", Color: "#fff"}}},
+		Nodes:     []*Node{start},
+		Subgraphs: []*Flowchart{group},
+		Links:     []Link{toGroup, blank},
+	}
+}
+
+func TestFlowchart_JSONRoundTrip(t *testing.T) {
+	original := encodingFixture()
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+
+	var got Flowchart
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(original, &got, decodedFlowchartOptions); diff != "" {
+		t.Errorf("json.Unmarshal(json.Marshal()) mismatch (-expected +got):\n%s", diff)
+	}
+
+	// Link endpoints must be the decoded nodes and subgraphs themselves, not copies.
+	if got.Links[0].Target != got.Subgraphs[0] {
+		t.Errorf("link to subgraph does not point to the decoded subgraph")
+	}
+	if got.Subgraphs[0].Links[0].Target != got.Nodes[0] {
+		t.Errorf("link to node does not point to the decoded node")
+	}
+}
+
+func TestFlowchart_MarshalJSON(t *testing.T) {
+	start := TerminatorNode("Start", nil)
+	end := &Node{name: "End", Type: NodeTypeProcess, Label: pointTo("Done"), Classes: []string{"done"}}
+	chart := &Flowchart{
+		Direction: DirectionVertical,
+		Nodes:     []*Node{start, end},
+		Links:     []Link{SolidLink(start, end, nil)},
+	}
+
+	got, err := json.Marshal(chart)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	expected := `{"direction":"vertical","nodes":[{"id":"Start","type":"terminator"},` +
+		`{"id":"End","type":"process","label":"Done","classes":["done"]}],` +
+		`"links":[{"origin":"Start","target":"End","lineType":"solid","arrowType":"normal","targetArrow":true}]}`
+	if diff := cmp.Diff(expected, string(got)); diff != "" {
+		t.Errorf("json.Marshal() mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestFlowchart_MarshalJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		flowchart *Flowchart
+		written   bool // Whether the error is only found when the document is written, which MarshalYAML leaves to the YAML library
+	}{
+		{
+			name: "link without origin",
+			flowchart: &Flowchart{
+				Nodes: []*Node{{name: "A"}},
+				Links: []Link{{Target: &Node{name: "A"}}},
+			},
+		},
+		{
+			name: "link to node outside the flowchart",
+			flowchart: &Flowchart{
+				Nodes: []*Node{{name: "A"}},
+				Links: []Link{SolidLink(&Node{name: "A"}, &Node{name: "Missing"}, nil)},
+			},
+		},
+		{
+			name:      "unknown enum value",
+			flowchart: &Flowchart{Direction: DirectionEnum(42)},
+			written:   true,
+		},
+		{
+			name: "repeated node name",
+			flowchart: &Flowchart{
+				Nodes:     []*Node{{name: "A"}},
+				Subgraphs: []*Flowchart{{Nodes: []*Node{{name: "A"}}}},
+			},
+		},
+		{
+			name: "subgraph title repeating the flowchart title",
+			flowchart: &Flowchart{
+				Title:     pointTo("Root"),
+				Subgraphs: []*Flowchart{{Title: pointTo("Root"), Nodes: []*Node{{name: "A"}}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := json.Marshal(tt.flowchart); err == nil {
+				t.Errorf("json.Marshal() expected error, got nil")
+			}
+			if _, err := tt.flowchart.MarshalYAML(); err == nil && !tt.written {
+				t.Errorf("MarshalYAML() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFlowchart_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		expected    func() *Flowchart
+		expectedErr bool
+	}{
+		{
+			name: "link to root flowchart and forward reference",
+			data: `{"title":"Root","direction":"horizontal-left","nodes":[{"id":"A","type":"process"}],` +
+				`"links":[{"origin":"A","target":"Root","lineType":"dotted","arrowType":"cross"},` +
+				`{"origin":"A","target":"B","lineType":"none","arrowType":"none"}],` +
+				`"subgraphs":[{"direction":"vertical","nodes":[{"id":"B","type":"input-output"}]}]}`,
+			expected: func() *Flowchart {
+				a := ProcessNode("A", nil)
+				b := InputOutputNode("B", nil)
+				root := &Flowchart{
+					Title:     pointTo("Root"),
+					Direction: DirectionHorizontalLeft,
+					Nodes:     []*Node{a},
+					Subgraphs: []*Flowchart{{Direction: DirectionVertical, Nodes: []*Node{b}, Subgraphs: []*Flowchart{}}},
+				}
+				root.Links = []Link{
+					{Origin: a, Target: root, LineType: LineTypeDotted, ArrowType: ArrowTypeCross},
+					{Origin: a, Target: b, LineType: LineTypeNone, ArrowType: ArrowTypeNone},
+				}
+				return root
+			},
+		},
+		{
+			name:        "unknown node type",
+			data:        `{"direction":"vertical","nodes":[{"id":"A","type":"circle"}]}`,
+			expectedErr: true,
+		},
+		{
+			name:        "missing direction value",
+			data:        `{"direction":""}`,
+			expectedErr: true,
+		},
+		{
+			name:        "unknown link target",
+			data:        `{"direction":"vertical","nodes":[{"id":"A","type":"process"}],"links":[{"origin":"A","target":"B","lineType":"solid","arrowType":"normal"}]}`,
+			expectedErr: true,
+		},
+		{
+			name:        "repeated node id",
+			data:        `{"direction":"vertical","nodes":[{"id":"A","type":"process"}],"subgraphs":[{"direction":"vertical","nodes":[{"id":"A","type":"process"}]}]}`,
+			expectedErr: true,
+		},
+		{
+			name:        "subgraph title repeating a node id",
+			data:        `{"direction":"vertical","nodes":[{"id":"A","type":"process"}],"subgraphs":[{"title":"A","direction":"vertical"}]}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Flowchart
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("json.Unmarshal() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if tt.expectedErr {
+				return
+			}
+			expected := tt.expected()
+			if diff := cmp.Diff(expected, &got, decodedFlowchartOptions); diff != "" {
+				t.Errorf("json.Unmarshal() mismatch (-expected +got):\n%s", diff)
+			}
+			if got.Links[0].Target != &got {
+				t.Errorf("link to root flowchart does not point to the decoded flowchart")
+			}
+		})
+	}
+}
+
+func TestFlowchart_YAMLRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		flowchart func() *Flowchart
+	}{
+		{name: "every part of the model", flowchart: encodingFixture},
+		{
+			name: "link to root flowchart",
+			flowchart: func() *Flowchart {
+				a := ProcessNode("A", nil)
+				root := &Flowchart{Title: pointTo("Root"), Direction: DirectionVertical, Nodes: []*Node{a}}
+				root.Links = []Link{DottedLink(a, root, pointTo("again"))}
+				return root
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.flowchart()
+			doc, err := original.MarshalYAML()
+			if err != nil {
+				t.Fatalf("MarshalYAML() unexpected error: %v", err)
+			}
+
+			// A YAML library decodes into the same document structure, so JSON stands in for it here.
+			data, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatalf("json.Marshal() unexpected error: %v", err)
+			}
+			var got Flowchart
+			if err := got.UnmarshalYAML(func(v any) error { return json.Unmarshal(data, v) }); err != nil {
+				t.Fatalf("UnmarshalYAML() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(original, &got, decodedFlowchartOptions); diff != "" {
+				t.Errorf("UnmarshalYAML(MarshalYAML()) mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNode_JSONRoundTrip(t *testing.T) {
+	original := &Node{name: "Check", Type: NodeTypeDecision, Label: pointTo("ok?"), Style: &Style{Fill: "#f00"}}
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(`{"id":"Check","type":"decision","label":"ok?","style":{"fill":"#f00"}}`, string(data)); diff != "" {
+		t.Errorf("json.Marshal() mismatch (-expected +got):\n%s", diff)
+	}
+
+	var got Node
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(original, &got, decodedFlowchartOptions); diff != "" {
+		t.Errorf("json.Unmarshal(json.Marshal()) mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	var schema struct {
+		Defs map[string]struct {
+			Enum       []string `json:"enum"`
+			Properties map[string]struct {
+				Enum []string `json:"enum"`
+			} `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(JSONSchema(), &schema); err != nil {
+		t.Fatalf("JSONSchema() is not valid JSON: %v", err)
+	}
+
+	// The enums published in the schema must match the names used by MarshalText.
+	tests := []struct {
+		name     string
+		got      []string
+		expected []string
+	}{
+		{"direction", schema.Defs["flowchart"].Properties["direction"].Enum, directionNames},
+		{"node type", schema.Defs["node"].Properties["type"].Enum, nodeTypeNames},
+		{"line type", schema.Defs["link"].Properties["lineType"].Enum, lineTypeNames},
+		{"arrow type", schema.Defs["link"].Properties["arrowType"].Enum, arrowTypeNames},
+		{"label format", schema.Defs["labelFormat"].Enum, labelFormatNames},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, tt.got); diff != "" {
+				t.Errorf("JSONSchema() enum mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -20,6 +20,9 @@ type (
 
 	// ArrowTypeEnum represents the type of arrow used in flowchart links (e.g., normal, circle).
 	ArrowTypeEnum int
+
+	// LabelFormatEnum represents how the label of a node or link is interpreted (e.g., plain text, markdown).
+	LabelFormatEnum int
 )
 
 // Constants for flowchart directions.
@@ -56,15 +59,24 @@ const (
 	ArrowTypeCross                       // Cross arrow
 )
 
+// Constants for label formats.
+const (
+	LabelFormatText     LabelFormatEnum = iota // Plain text, escaped when rendered
+	LabelFormatMarkdown                        // Markdown with **bold** and _italic_ text
+)
+
 // Link represents a connection between two nodes in a flowchart.
 type Link struct {
-	Origin      Linkable      // The origin node of the link
-	Target      Linkable      // The target node of the link
-	LineType    LineTypeEnum  // Type of line connecting the nodes
-	ArrowType   ArrowTypeEnum // Type of arrow used in the link
-	OriginArrow bool          // Whether the link has an arrow at the origin
-	TargetArrow bool          // Whether the link has an arrow at the target
-	Label       *string       // Optional label for the link
+	Origin      Linkable        // The origin node of the link
+	Target      Linkable        // The target node of the link
+	LineType    LineTypeEnum    // Type of line connecting the nodes
+	ArrowType   ArrowTypeEnum   // Type of arrow used in the link
+	OriginArrow bool            // Whether the link has an arrow at the origin
+	TargetArrow bool            // Whether the link has an arrow at the target
+	Label       *string         // Optional label for the link
+	LabelFormat LabelFormatEnum // How the label is interpreted
+	Classes     []string        // Names of the style classes applied to the link
+	Style       *Style          // Optional inline style, applied after the classes
 }
 
 // Linkable represents an object that can be linked in a flowchart.
@@ -78,6 +90,11 @@ func (n *Node) nodeName() string {
 	return n.name
 }
 
+// Name returns the name that identifies the node within its flowchart.
+func (n *Node) Name() string {
+	return n.name
+}
+
 // nodeName returns the title of the flowchart if available, or an empty string if no title is set.
 // It is used to uniquely identify a Flowchart when linking or creating subgraphs.
 func (f *Flowchart) nodeName() string {
@@ -89,9 +106,12 @@ func (f *Flowchart) nodeName() string {
 
 // Node represents a node in the flowchart.
 type Node struct {
-	name  string       // Internal name of the node
-	Type  NodeTypeEnum // Type of the node
-	Label *string      // Optional label for the node
+	name        string          // Internal name of the node
+	Type        NodeTypeEnum    // Type of the node
+	Label       *string         // Optional label for the node
+	LabelFormat LabelFormatEnum // How the label is interpreted
+	Classes     []string        // Names of the style classes applied to the node
+	Style       *Style          // Optional inline style, applied after the classes
 }
 
 // Flowchart represents a flowchart with nodes, subgraphs, and links.
@@ -101,15 +121,27 @@ type Flowchart struct {
 	Nodes     []*Node       // List of nodes in the flowchart
 	Subgraphs []*Flowchart  // List of subgraphs
 	Links     []Link        // List of links between nodes
+	ClassDefs []StyleClass  // Style classes defined for the flowchart
+	Classes   []string      // Names of the style classes applied when used as a subgraph
+	Style     *Style        // Optional inline style, applied when used as a subgraph
 }
 
 // AddLink adds a link to the flowchart.
+// It returns a *ValidationError with code ErrMissingEndpoint if the link has no origin or target.
 func (f *Flowchart) AddLink(link Link) error {
 	if link.Target == (Linkable)(nil) {
-		return fmt.Errorf("cannot add link with no target node")
+		return newValidationError(Violation{
+			Code:    ErrMissingEndpoint,
+			Element: &link,
+			Message: "cannot add link with no target node",
+		})
 	}
 	if link.Origin == (Linkable)(nil) {
-		return fmt.Errorf("cannot add link with no origin node")
+		return newValidationError(Violation{
+			Code:    ErrMissingEndpoint,
+			Element: &link,
+			Message: "cannot add link with no origin node",
+		})
 	}
 	f.Links = append(f.Links, link)
 	return nil
@@ -136,21 +168,35 @@ func (f *Flowchart) containsName(name string) bool {
 }
 
 // AddNode adds a node to the flowchart, ensuring it has a unique name.
+// It returns a *ValidationError with code ErrDuplicateName if the name is already used.
 func (f *Flowchart) AddNode(node *Node) error {
 	if f.containsName(node.name) {
-		return fmt.Errorf("cannot add node with non-unique name")
+		return newValidationError(Violation{
+			Code:    ErrDuplicateName,
+			Element: node,
+			Message: fmt.Sprintf("cannot add node %q with non-unique name", node.name),
+		})
 	}
 	f.Nodes = append(f.Nodes, node)
 	return nil
 }
 
 // AddSubgraph adds a subgraph to the flowchart, ensuring it has a unique title.
+// It returns a *ValidationError with code ErrMissingTitle or ErrDuplicateName if it does not.
 func (f *Flowchart) AddSubgraph(subgraph *Flowchart) error {
 	if subgraph.Title == nil {
-		return fmt.Errorf("cannot add subgraph with no title")
+		return newValidationError(Violation{
+			Code:    ErrMissingTitle,
+			Element: subgraph,
+			Message: "cannot add subgraph with no title",
+		})
 	}
 	if f.containsName(*subgraph.Title) {
-		return fmt.Errorf("cannot add subgraph with already existing title")
+		return newValidationError(Violation{
+			Code:    ErrDuplicateName,
+			Element: subgraph,
+			Message: fmt.Sprintf("cannot add subgraph %q with already existing title", *subgraph.Title),
+		})
 	}
 	f.Subgraphs = append(f.Subgraphs, subgraph)
 	return nil
@@ -59,8 +59,8 @@ func TestFlowchart_AddLink(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			err := tt.chart.AddLink(tt.link)
 
-			if diff := cmp.Diff(tt.expectedErr, err, cmp.Comparer(compareErrors)); diff != "" {
-				t.Errorf("AddLink() error mismatch (-want +got):\n%s", diff)
+			if !compareErrors(tt.expectedErr, err) {
+				t.Errorf("AddLink() error = %v, want %v", err, tt.expectedErr)
 			}
 
 			if diff := cmp.Diff(tt.expectedLinks, tt.chart.Links, cmp.AllowUnexported(Node{})); diff != "" {
@@ -89,14 +89,14 @@ func TestFlowchart_AddNode(t *testing.T) {
 			name:          "Add duplicate node",
 			flowchart:     &Flowchart{Nodes: []*Node{{name: "Singleton"}}},
 			node:          &Node{name: "Singleton"},
-			expectedErr:   fmt.Errorf("cannot add node with non-unique name"),
+			expectedErr:   fmt.Errorf(`cannot add node "Singleton" with non-unique name`),
 			expectedNodes: []*Node{{name: "Singleton"}},
 		},
 		{
 			name:          "Add node with subgraph names",
 			flowchart:     &Flowchart{Subgraphs: []*Flowchart{LrFlowchart(pointTo("Singleton"))}},
 			node:          &Node{name: "Singleton"},
-			expectedErr:   fmt.Errorf("cannot add node with non-unique name"),
+			expectedErr:   fmt.Errorf(`cannot add node "Singleton" with non-unique name`),
 			expectedNodes: nil,
 		},
 		{
@@ -108,7 +108,7 @@ func TestFlowchart_AddNode(t *testing.T) {
 				}},
 			},
 			node:          &Node{name: "Singleton"},
-			expectedErr:   fmt.Errorf("cannot add node with non-unique name"),
+			expectedErr:   fmt.Errorf(`cannot add node "Singleton" with non-unique name`),
 			expectedNodes: nil,
 		},
 	}
@@ -117,8 +117,8 @@ func TestFlowchart_AddNode(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			err := tt.flowchart.AddNode(tt.node)
 
-			if diff := cmp.Diff(tt.expectedErr, err, cmp.Comparer(compareErrors)); diff != "" {
-				t.Errorf("AddLink() error mismatch (-want +got):\n%s", diff)
+			if !compareErrors(tt.expectedErr, err) {
+				t.Errorf("AddLink() error = %v, want %v", err, tt.expectedErr)
 			}
 
 			if diff := cmp.Diff(tt.expectedNodes, tt.flowchart.Nodes, cmp.AllowUnexported(Node{})); diff != "" {
@@ -154,7 +154,7 @@ func TestFlowchart_AddSubgraph(t *testing.T) {
 			name:              "Add duplicate subgraph",
 			flowchart:         &Flowchart{Subgraphs: []*Flowchart{{Title: pointTo("Singleton")}}},
 			subgraph:          &Flowchart{Title: pointTo("Singleton")},
-			expectedErr:       fmt.Errorf("cannot add subgraph with already existing title"),
+			expectedErr:       fmt.Errorf(`cannot add subgraph "Singleton" with already existing title`),
 			expectedSubgraphs: []*Flowchart{{Title: pointTo("Singleton")}},
 		},
 	}
@@ -163,8 +163,8 @@ func TestFlowchart_AddSubgraph(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			err := tt.flowchart.AddSubgraph(tt.subgraph)
 
-			if diff := cmp.Diff(tt.expectedErr, err, cmp.Comparer(compareErrors)); diff != "" {
-				t.Errorf("AddLink() error mismatch (-want +got):\n%s", diff)
+			if !compareErrors(tt.expectedErr, err) {
+				t.Errorf("AddLink() error = %v, want %v", err, tt.expectedErr)
 			}
 
 			if diff := cmp.Diff(tt.expectedSubgraphs, tt.flowchart.Subgraphs); diff != "" {
@@ -0,0 +1,152 @@
+package flowchart
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// gexfShapes holds the Gephi shape drawn for each node type; types not listed are squares.
+var gexfShapes = map[NodeTypeEnum]string{
+	NodeTypeTerminator: "disc",
+	NodeTypeDecision:   "diamond",
+	NodeTypeConnector:  "disc",
+}
+
+// gexfColor returns the viz:color element of a style colour, or of the fallback if it is not set or
+// not understood.
+func gexfColor(value, fallback string) string {
+	c, _ := parseColor(colorOr(value, fallback))
+	return fmt.Sprintf(`<viz:color r="%d" g="%d" b="%d" />`, c.R, c.G, c.B)
+}
+
+// writeAttvalues writes the attribute values of a node or edge, skipping empty ones. The values are
+// given as pairs of attribute id and value.
+func (g *graphExport) writeAttvalues(depth int, values ...string) {
+	g.line(depth, "<attvalues>")
+	for i := 0; i+1 < len(values); i += 2 {
+		if values[i+1] != "" {
+			g.line(depth+1, `<attvalue for="%s" value="%s" />`, values[i], xmlEscaper.Replace(values[i+1]))
+		}
+	}
+	g.line(depth, "</attvalues>")
+}
+
+// writeGEXFNode writes a node of the GEXF document for a node or subgraph.
+func (g *graphExport) writeGEXFNode(l Linkable, label, nodeType, shape string, style Style, fill string) {
+	pid := ""
+	if sub := g.subgraph(l); sub != "" {
+		pid = fmt.Sprintf(` pid="%s"`, xmlEscaper.Replace(sub))
+	}
+	r := g.rects[l]
+	c := r.Center()
+	g.line(3, `<node id="%s" label="%s"%s>`, xmlEscaper.Replace(g.ids[l]), xmlEscaper.Replace(label), pid)
+	g.writeAttvalues(4, "type", nodeType, "subgraph", g.subgraph(l))
+	g.line(4, "%s", gexfColor(style.Fill, fill))
+	g.line(4, `<viz:position x="%s" y="%s" z="0" />`, svgNumber(c.X), svgNumber(-c.Y))
+	g.line(4, `<viz:size value="%s" />`, svgNumber(math.Max(r.Width, r.Height)/2))
+	g.line(4, `<viz:shape value="%s" />`, shape)
+	g.line(3, "</node>")
+}
+
+// RenderGEXF generates a GEXF 1.3 document of the flowchart, for graph analysis tools such as
+// Gephi.
+//
+// Nodes are identified by their names, labelled with their text and carry their type as an
+// attribute. Subgraphs are nodes of type "subgraph", and every node records the subgraph directly
+// containing it both as an attribute and as its parent (pid). Links are directed edges carrying
+// their label, line type, arrow type and arrow ends; invisible links only affect the layout and are
+// left out. Positions, sizes, colours and shapes follow the drawing of RenderSVG, with the y axis
+// pointing up as in Gephi.
+// It returns the GEXF document or an error if validation fails.
+func RenderGEXF(f *Flowchart) (string, error) {
+	if err := validateGEXF(f); err != nil {
+		return "", err
+	}
+	g, err := newGraphExport(f)
+	if err != nil {
+		return "", err
+	}
+
+	g.line(0, `<?xml version="1.0" encoding="UTF-8"?>`)
+	g.line(0, `<gexf xmlns="http://gexf.net/1.3" xmlns:viz="http://gexf.net/1.3/viz" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://gexf.net/1.3 http://gexf.net/1.3/gexf.xsd" version="1.3">`)
+	g.line(1, "<meta>")
+	g.line(2, "<creator>flowchart</creator>")
+	if f.Title != nil && *f.Title != "" {
+		g.line(2, "<description>%s</description>", xmlEscaper.Replace(*f.Title))
+	}
+	g.line(1, "</meta>")
+	g.line(1, `<graph defaultedgetype="directed" mode="static">`)
+	g.line(2, `<attributes class="node">`)
+	g.line(3, `<attribute id="type" title="type" type="string" />`)
+	g.line(3, `<attribute id="subgraph" title="subgraph" type="string" />`)
+	g.line(2, "</attributes>")
+	g.line(2, `<attributes class="edge">`)
+	g.line(3, `<attribute id="lineType" title="lineType" type="string" />`)
+	g.line(3, `<attribute id="arrowType" title="arrowType" type="string" />`)
+	g.line(3, `<attribute id="originArrow" title="originArrow" type="boolean" />`)
+	g.line(3, `<attribute id="targetArrow" title="targetArrow" type="boolean" />`)
+	g.line(2, "</attributes>")
+
+	g.line(2, "<nodes>")
+	walkFlowchart(f, func(sub *Flowchart, _ []string) {
+		if sub != f {
+			style := resolveStyle(g.classDefs, sub.Classes, sub.Style)
+			g.writeGEXFNode(sub, sub.nodeName(), "subgraph", "square", style, svgClusterFill)
+		}
+		for _, node := range sub.Nodes {
+			shape, ok := gexfShapes[node.Type]
+			if !ok {
+				shape = "square"
+			}
+			style := resolveStyle(g.classDefs, node.Classes, node.Style)
+			g.writeGEXFNode(node, nodeText(node), enumName(nodeTypeNames, int(node.Type)), shape, style, svgNodeFill)
+		}
+	})
+	g.line(2, "</nodes>")
+
+	g.line(2, "<edges>")
+	for i, link := range g.links {
+		style := resolveStyle(g.classDefs, link.Classes, link.Style)
+		label := ""
+		if link.Label != nil && *link.Label != "" {
+			label = fmt.Sprintf(` label="%s"`, xmlEscaper.Replace(linkText(link)))
+		}
+		shape, width := "solid", 1.0
+		switch link.LineType {
+		case LineTypeDotted:
+			shape = "dotted"
+		case LineTypeThick:
+			width = 3
+		}
+		g.line(3, `<edge id="%d" source="%s" target="%s"%s>`, i, xmlEscaper.Replace(g.endpoint(link.Origin)), xmlEscaper.Replace(g.endpoint(link.Target)), label)
+		g.writeAttvalues(4,
+			"lineType", enumName(lineTypeNames, int(link.LineType)),
+			"arrowType", enumName(arrowTypeNames, int(link.ArrowType)),
+			"originArrow", strconv.FormatBool(link.OriginArrow),
+			"targetArrow", strconv.FormatBool(link.TargetArrow),
+		)
+		g.line(4, "%s", gexfColor(style.Stroke, svgLineStroke))
+		g.line(4, `<viz:thickness value="%s" />`, svgNumber(styleWidth(style.StrokeWidth, width)))
+		g.line(4, `<viz:shape value="%s" />`, shape)
+		g.line(3, "</edge>")
+	}
+	g.line(2, "</edges>")
+	g.line(1, "</graph>")
+	g.line(0, "</gexf>")
+	return g.sb.String(), nil
+}
+
+// validateGEXF validates the Flowchart structure to ensure it can be rendered as GEXF.
+// It checks for the following violations:
+// 1. All node and subgraph names must be unique across the whole flowchart.
+// 2. Style classes must have valid, unique names, and every class assigned must be defined.
+//
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func validateGEXF(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	violations = append(violations, styleClassViolations(f)...)
+	return newValidationError(violations...)
+}
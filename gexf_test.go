@@ -0,0 +1,126 @@
+package flowchart
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderGEXF(t *testing.T) {
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<gexf xmlns="http://gexf.net/1.3" xmlns:viz="http://gexf.net/1.3/viz" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://gexf.net/1.3 http://gexf.net/1.3/gexf.xsd" version="1.3">
+  <meta>
+    <creator>flowchart</creator>
+    <description>Orders</description>
+  </meta>
+  <graph defaultedgetype="directed" mode="static">
+    <attributes class="node">
+      <attribute id="type" title="type" type="string" />
+      <attribute id="subgraph" title="subgraph" type="string" />
+    </attributes>
+    <attributes class="edge">
+      <attribute id="lineType" title="lineType" type="string" />
+      <attribute id="arrowType" title="arrowType" type="string" />
+      <attribute id="originArrow" title="originArrow" type="boolean" />
+      <attribute id="targetArrow" title="targetArrow" type="boolean" />
+    </attributes>
+    <nodes>
+      <node id="start" label="Start">
+        <attvalues>
+          <attvalue for="type" value="terminator" />
+        </attvalues>
+        <viz:color r="236" g="236" b="255" />
+        <viz:position x="56" y="-94" z="0" />
+        <viz:size value="36" />
+        <viz:shape value="disc" />
+      </node>
+      <node id="check" label="Valid?">
+        <attvalues>
+          <attvalue for="type" value="decision" />
+        </attvalues>
+        <viz:color r="255" g="153" b="102" />
+        <viz:position x="201" y="-94" z="0" />
+        <viz:size value="59" />
+        <viz:shape value="diamond" />
+      </node>
+      <node id="Storage" label="Storage">
+        <attvalues>
+          <attvalue for="type" value="subgraph" />
+        </attvalues>
+        <viz:color r="255" g="255" b="222" />
+        <viz:position x="365" y="-83" z="0" />
+        <viz:size value="55" />
+        <viz:shape value="square" />
+      </node>
+      <node id="db" label="Orders" pid="Storage">
+        <attvalues>
+          <attvalue for="type" value="database" />
+          <attvalue for="subgraph" value="Storage" />
+        </attvalues>
+        <viz:color r="236" g="236" b="255" />
+        <viz:position x="365" y="-94" z="0" />
+        <viz:size value="40" />
+        <viz:shape value="square" />
+      </node>
+    </nodes>
+    <edges>
+      <edge id="0" source="start" target="check">
+        <attvalues>
+          <attvalue for="lineType" value="solid" />
+          <attvalue for="arrowType" value="normal" />
+          <attvalue for="originArrow" value="false" />
+          <attvalue for="targetArrow" value="true" />
+        </attvalues>
+        <viz:color r="51" g="51" b="51" />
+        <viz:thickness value="1" />
+        <viz:shape value="solid" />
+      </edge>
+      <edge id="1" source="check" target="db" label="yes &amp; save">
+        <attvalues>
+          <attvalue for="lineType" value="thick" />
+          <attvalue for="arrowType" value="circle" />
+          <attvalue for="originArrow" value="false" />
+          <attvalue for="targetArrow" value="true" />
+        </attvalues>
+        <viz:color r="51" g="51" b="51" />
+        <viz:thickness value="3" />
+        <viz:shape value="solid" />
+      </edge>
+      <edge id="2" source="start" target="Storage">
+        <attvalues>
+          <attvalue for="lineType" value="dotted" />
+          <attvalue for="arrowType" value="normal" />
+          <attvalue for="originArrow" value="false" />
+          <attvalue for="targetArrow" value="true" />
+        </attvalues>
+        <viz:color r="51" g="51" b="51" />
+        <viz:thickness value="1" />
+        <viz:shape value="dotted" />
+      </edge>
+    </edges>
+  </graph>
+</gexf>
+`
+
+	got, err := RenderGEXF(graphExportFixture())
+	if err != nil {
+		t.Fatalf("RenderGEXF() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("RenderGEXF() mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestRenderGEXF_Errors(t *testing.T) {
+	a := &Node{name: "a", Classes: []string{"missing"}}
+	_, err := RenderGEXF(&Flowchart{
+		Nodes: []*Node{a, ProcessNode("a", nil)},
+		Links: []Link{SolidLink(a, &Node{name: "Phantom"}, nil)},
+	})
+	for _, code := range []error{ErrDuplicateName, ErrUndefinedStyleClass, ErrOrphanedEndpoint} {
+		if !errors.Is(err, code) {
+			t.Errorf("RenderGEXF() error = %v, expected %v", err, code)
+		}
+	}
+}
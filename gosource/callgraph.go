@@ -0,0 +1,505 @@
+package gosource
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andre-a-alves/flowchart"
+)
+
+// CallGraphOptions holds the options of CallGraph.
+type CallGraphOptions struct {
+	// Root is the function the graph starts from, named as the nodes of the graph, or with only the
+	// last element of the import path if that is not ambiguous, as in "server.Server.Handle". If it
+	// is empty, the graph holds every function.
+	Root string
+
+	// Depth is the largest number of calls followed from Root, or 0 for no limit. It is ignored
+	// without a root.
+	Depth int
+
+	// Exclude holds shell patterns (see path.Match) of the functions to leave out, together with the
+	// calls made through them. A function is left out if a pattern matches its full name, its name
+	// within its package, as in "Server.Handle", or the import path of its package.
+	Exclude []string
+
+	// ByFile groups the functions of each package by the file declaring them.
+	ByFile bool
+}
+
+// goPackage is a package of Go source loaded by CallGraph.
+type goPackage struct {
+	path      string
+	fileNames []string // Names of the files, without directory, sorted
+	files     []*ast.File
+	types     *types.Package
+	info      *types.Info
+	checking  bool
+}
+
+// packageLoader parses and type-checks the packages of a directory tree. Packages outside the tree
+// are imported from their source, and are left incomplete if they cannot be found.
+type packageLoader struct {
+	fset     *token.FileSet
+	packages map[string]*goPackage // Packages of the tree, by import path
+	fallback types.Importer
+}
+
+// Import implements types.Importer, checking the packages of the tree on demand.
+func (l *packageLoader) Import(importPath string) (*types.Package, error) {
+	p, ok := l.packages[importPath]
+	if !ok {
+		return l.fallback.Import(importPath)
+	}
+	if p.checking {
+		return nil, fmt.Errorf("import cycle through %s", importPath)
+	}
+	l.check(p)
+	return p.types, nil
+}
+
+// check type-checks a package of the tree, once. Type errors are ignored, leaving the calls that
+// cannot be resolved out of the graph.
+func (l *packageLoader) check(p *goPackage) {
+	if p.types != nil {
+		return
+	}
+	p.checking = true
+	p.info = &types.Info{
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	conf := types.Config{Importer: l, Error: func(error) {}}
+	p.types, _ = conf.Check(p.path, l.fset, p.files, p.info)
+	p.checking = false
+}
+
+// findModule returns the root directory and path of the module enclosing dir, or "" and "" if
+// there is none.
+func findModule(dir string) (string, string) {
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+					modulePath := strings.TrimSpace(rest)
+					if unquoted, err := strconv.Unquote(modulePath); err == nil {
+						modulePath = unquoted
+					}
+					return dir, modulePath
+				}
+			}
+			return dir, ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// loadDir parses the Go files of a directory, leaving out tests and files excluded by build
+// constraints. It returns nil if the directory has none.
+func (l *packageLoader) loadDir(dir, importPath string) (*goPackage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	p := &goPackage{path: importPath}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		file, err := parser.ParseFile(l.fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		if len(p.files) > 0 && file.Name.Name != p.files[0].Name.Name {
+			return nil, fmt.Errorf("%s: found packages %s and %s", dir, p.files[0].Name.Name, file.Name.Name)
+		}
+		p.fileNames = append(p.fileNames, name)
+		p.files = append(p.files, file)
+	}
+	if len(p.files) == 0 {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// load loads the package in dir or, if dir is the root of a module, every package of the module.
+// It returns the packages sorted by import path and the import path of dir.
+func (l *packageLoader) load(dir string) ([]*goPackage, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	root, modulePath := findModule(dir)
+	importPath := func(d string) string {
+		if root == "" || modulePath == "" {
+			return filepath.Base(d)
+		}
+		rel, err := filepath.Rel(root, d)
+		if err != nil || rel == "." {
+			return modulePath
+		}
+		return modulePath + "/" + filepath.ToSlash(rel)
+	}
+
+	dirs := []string{dir}
+	dirPath := importPath(dir)
+	if root == dir {
+		dirs = nil
+		err := filepath.WalkDir(dir, func(d string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.IsDir() {
+				return err
+			}
+			name := entry.Name()
+			if d != dir {
+				if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+					return filepath.SkipDir
+				}
+				if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			dirs = append(dirs, d)
+			return nil
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	var packages []*goPackage
+	for _, d := range dirs {
+		p, err := l.loadDir(d, importPath(d))
+		if err != nil {
+			return nil, "", err
+		}
+		if p != nil {
+			l.packages[p.path] = p
+			packages = append(packages, p)
+		}
+	}
+	if len(packages) == 0 {
+		return nil, "", fmt.Errorf("%s: no Go files", dir)
+	}
+	slices.SortFunc(packages, func(a, b *goPackage) int { return strings.Compare(a.path, b.path) })
+	for _, p := range packages {
+		l.check(p)
+	}
+	return packages, dirPath, nil
+}
+
+// goFunc is a function or method of the call graph.
+type goFunc struct {
+	pkg      *goPackage
+	file     string // Name of the file declaring the function, without directory
+	receiver string // Name of the receiver type, or "" for functions
+	name     string // Full name, as in "example.com/server.Server.Handle"
+	short    string // Name within the package, as in "Server.Handle"
+	decl     *ast.FuncDecl
+}
+
+// call is a call between two functions of the call graph.
+type call struct {
+	caller, callee int  // Positions of the functions in the graph
+	dynamic        bool // Whether the call is made through an interface
+}
+
+// callGraph holds the functions of the loaded packages and the calls between them.
+type callGraph struct {
+	funcs   []*goFunc
+	index   map[*types.Func]int // Position of each function, by its object
+	calls   []call
+	types   []*types.Named        // Named types of the loaded packages that can implement interfaces
+	methods map[*types.Func][]int // Implementations of each interface method
+}
+
+// newCallGraph collects the functions of the packages and the calls between them.
+func newCallGraph(packages []*goPackage) *callGraph {
+	g := &callGraph{index: make(map[*types.Func]int), methods: make(map[*types.Func][]int)}
+	inits := make(map[string]int)
+	for _, p := range packages {
+		for i, file := range p.files {
+			for _, d := range file.Decls {
+				decl, ok := d.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				fn := &goFunc{pkg: p, file: p.fileNames[i], short: FuncName(decl), decl: decl}
+				if decl.Recv != nil && len(decl.Recv.List) > 0 {
+					fn.receiver = receiverType(decl.Recv.List[0].Type)
+				}
+				fn.name = p.path + "." + fn.short
+				if decl.Recv == nil && decl.Name.Name == "init" {
+					inits[p.path]++
+					if n := inits[p.path]; n > 1 {
+						fn.name += "#" + strconv.Itoa(n)
+					}
+				}
+				if obj, ok := p.info.Defs[decl.Name].(*types.Func); ok {
+					g.index[obj] = len(g.funcs)
+				}
+				g.funcs = append(g.funcs, fn)
+			}
+		}
+		if p.types == nil {
+			continue
+		}
+		scope := p.types.Scope()
+		for _, name := range scope.Names() {
+			if tn, ok := scope.Lookup(name).(*types.TypeName); ok && !tn.IsAlias() {
+				if named, ok := tn.Type().(*types.Named); ok && !types.IsInterface(named) && named.TypeParams() == nil {
+					g.types = append(g.types, named)
+				}
+			}
+		}
+	}
+
+	for caller, fn := range g.funcs {
+		if fn.decl.Body == nil {
+			continue
+		}
+		seen := make(map[int]bool)
+		ast.Inspect(fn.decl.Body, func(n ast.Node) bool {
+			c, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			static, dynamic := g.callees(fn.pkg.info, c)
+			for _, callee := range static {
+				if !seen[callee] {
+					seen[callee] = true
+					g.calls = append(g.calls, call{caller: caller, callee: callee})
+				}
+			}
+			for _, callee := range dynamic {
+				if !seen[callee] {
+					seen[callee] = true
+					g.calls = append(g.calls, call{caller: caller, callee: callee, dynamic: true})
+				}
+			}
+			return true
+		})
+	}
+	// A function called both directly and through an interface is drawn with the direct call.
+	direct := make(map[[2]int]bool)
+	for _, c := range g.calls {
+		if !c.dynamic {
+			direct[[2]int{c.caller, c.callee}] = true
+		}
+	}
+	g.calls = slices.DeleteFunc(g.calls, func(c call) bool {
+		return c.dynamic && direct[[2]int{c.caller, c.callee}]
+	})
+	return g
+}
+
+// callees returns the functions of the graph that a call runs directly, and those it may run
+// through an interface.
+func (g *callGraph) callees(info *types.Info, c *ast.CallExpr) ([]int, []int) {
+	var fn *types.Func
+	switch fun := unwrapInstance(c.Fun).(type) {
+	case *ast.Ident:
+		fn, _ = info.Uses[fun].(*types.Func)
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[fun]; ok {
+			fn, _ = sel.Obj().(*types.Func)
+		} else {
+			fn, _ = info.Uses[fun.Sel].(*types.Func)
+		}
+	}
+	if fn == nil {
+		return nil, nil
+	}
+	fn = fn.Origin()
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil && types.IsInterface(recv.Type()) {
+		return nil, g.implementations(fn)
+	}
+	if i, ok := g.index[fn]; ok {
+		return []int{i}, nil
+	}
+	return nil, nil
+}
+
+// implementations returns the methods of the graph implementing an interface method.
+func (g *callGraph) implementations(method *types.Func) []int {
+	if impls, ok := g.methods[method]; ok {
+		return impls
+	}
+	iface, _ := method.Type().(*types.Signature).Recv().Type().Underlying().(*types.Interface)
+	var impls []int
+	for _, named := range g.types {
+		if iface == nil {
+			break
+		}
+		ptr := types.NewPointer(named)
+		if !types.Implements(named, iface) && !types.Implements(ptr, iface) {
+			continue
+		}
+		obj, _, _ := types.LookupFieldOrMethod(ptr, false, method.Pkg(), method.Name())
+		if impl, ok := obj.(*types.Func); ok {
+			if i, ok := g.index[impl.Origin()]; ok && !slices.Contains(impls, i) {
+				impls = append(impls, i)
+			}
+		}
+	}
+	g.methods[method] = impls
+	return impls
+}
+
+// excluded reports whether a function matches one of the exclusion patterns.
+func excluded(fn *goFunc, patterns []string) bool {
+	for _, pattern := range patterns {
+		for _, name := range []string{fn.name, fn.short, fn.pkg.path} {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectFuncs returns whether each function of the graph is drawn, given the options.
+func (g *callGraph) selectFuncs(opts CallGraphOptions) ([]bool, error) {
+	for _, pattern := range opts.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclusion pattern %q", pattern)
+		}
+	}
+	kept := make([]bool, len(g.funcs))
+	for i, fn := range g.funcs {
+		kept[i] = !excluded(fn, opts.Exclude)
+	}
+	if opts.Root == "" {
+		return kept, nil
+	}
+
+	root := -1
+	for i, fn := range g.funcs {
+		if fn.name == opts.Root || strings.HasSuffix(fn.name, "/"+opts.Root) {
+			if root >= 0 {
+				return nil, fmt.Errorf("root function %s is ambiguous: %s and %s", opts.Root, g.funcs[root].name, fn.name)
+			}
+			root = i
+		}
+	}
+	if root < 0 {
+		return nil, fmt.Errorf("root function %s not found", opts.Root)
+	}
+	if !kept[root] {
+		return nil, fmt.Errorf("root function %s is excluded", opts.Root)
+	}
+
+	reached := make([]bool, len(g.funcs))
+	reached[root] = true
+	frontier := []int{root}
+	for depth := 0; len(frontier) > 0 && (opts.Depth <= 0 || depth < opts.Depth); depth++ {
+		var next []int
+		for _, c := range g.calls {
+			if slices.Contains(frontier, c.caller) && kept[c.callee] && !reached[c.callee] {
+				reached[c.callee] = true
+				next = append(next, c.callee)
+			}
+		}
+		frontier = next
+	}
+	return reached, nil
+}
+
+// CallGraph loads the Go package in dir or, if dir is the root of a module, every package of the
+// module, and returns its call graph as a left-to-right flowchart titled "<import path> call graph".
+//
+// Each package is a subgraph titled with its import path holding its functions as subprocess nodes,
+// named with the import path and labelled with their name within the package. With ByFile, the
+// functions of each package are further grouped in a subgraph per file. The methods of each type
+// are grouped in a subgraph titled with the qualified name of the type, and labelled with the name
+// of the method. Calls are solid links from the caller to the function called, and calls made
+// through an interface are dotted links to every method of the loaded packages implementing it.
+// Calls to functions outside the loaded packages and calls through function values are left out.
+//
+// Test files and files excluded by build constraints are not loaded. Packages imported from
+// outside the loaded packages are read from their source when they can be found, and calls that
+// cannot be resolved because of type errors are left out.
+// It returns an error if dir holds no Go files, a file cannot be parsed, or the options are invalid.
+func CallGraph(dir string, opts CallGraphOptions) (*flowchart.Flowchart, error) {
+	fset := token.NewFileSet()
+	l := &packageLoader{
+		fset:     fset,
+		packages: make(map[string]*goPackage),
+		fallback: importer.ForCompiler(fset, "source", nil),
+	}
+	packages, dirPath, err := l.load(dir)
+	if err != nil {
+		return nil, err
+	}
+	g := newCallGraph(packages)
+	kept, err := g.selectFuncs(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The title names the graph rather than the package, whose subgraph has the import path as title.
+	title := dirPath + " call graph"
+	chart := flowchart.LrFlowchart(&title)
+	nodes := make([]*flowchart.Node, len(g.funcs))
+	containers := make(map[string]*flowchart.Flowchart)
+	// container returns the subgraph with the given title inside parent, adding it if it is new.
+	container := func(parent *flowchart.Flowchart, title string) *flowchart.Flowchart {
+		sub, ok := containers[title]
+		if !ok {
+			sub = flowchart.LrFlowchart(&title)
+			containers[title] = sub
+			parent.Subgraphs = append(parent.Subgraphs, sub)
+		}
+		return sub
+	}
+	for i, fn := range g.funcs {
+		if !kept[i] {
+			continue
+		}
+		parent := container(chart, fn.pkg.path)
+		typeTitle := fn.pkg.path + "." + fn.receiver
+		if opts.ByFile {
+			parent = container(parent, fn.pkg.path+"/"+fn.file)
+			typeTitle += " (" + fn.file + ")"
+		}
+		label := fn.short
+		if fn.receiver != "" {
+			parent = container(parent, typeTitle)
+			label = fn.decl.Name.Name
+		}
+		nodes[i] = flowchart.SubprocessNode(fn.name, &label)
+		parent.Nodes = append(parent.Nodes, nodes[i])
+	}
+	for _, c := range g.calls {
+		if !kept[c.caller] || !kept[c.callee] {
+			continue
+		}
+		if c.dynamic {
+			chart.Links = append(chart.Links, flowchart.DottedLink(nodes[c.caller], nodes[c.callee], nil))
+		} else {
+			chart.Links = append(chart.Links, flowchart.SolidLink(nodes[c.caller], nodes[c.callee], nil))
+		}
+	}
+	return chart, nil
+}
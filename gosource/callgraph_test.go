@@ -0,0 +1,287 @@
+package gosource
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andre-a-alves/flowchart"
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeModule writes the files of a module to a temporary directory and returns its path.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// describeCalls returns the nodes of a call graph as described by describe, followed by a line
+// for each call, as "caller -> callee", or "caller -.-> callee" for calls through an interface.
+func describeCalls(f *flowchart.Flowchart) []string {
+	lines := describe(f)
+	lines = lines[:len(lines)-len(f.Links)]
+	for _, link := range f.Links {
+		arrow := "->"
+		if link.LineType == flowchart.LineTypeDotted {
+			arrow = "-.->"
+		}
+		lines = append(lines, link.Origin.(*flowchart.Node).Name()+" "+arrow+" "+link.Target.(*flowchart.Node).Name())
+	}
+	return lines
+}
+
+// callGraphModule returns the files of the module drawn by the call graph tests.
+func callGraphModule() map[string]string {
+	return map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.23\n",
+		"main.go": `package main
+
+import "example.com/m/store"
+
+func main() {
+	s := store.New()
+	run(s)
+}
+
+func run(r store.Reader) {
+	r.Get("key")
+	run(r)
+}
+`,
+		"main_test.go": `package main
+
+func helper() { main() }
+`,
+		"store/store.go": `package store
+
+type Reader interface {
+	Get(key string) string
+}
+
+type Memory struct {
+	data map[string]string
+}
+
+func New() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Get(key string) string {
+	return m.lookup(key)
+}
+
+func (m *Memory) lookup(key string) string {
+	return m.data[key]
+}
+
+type Cache struct {
+	Memory
+}
+`,
+		"store/disk.go": `package store
+
+type Disk struct{}
+
+func (Disk) Get(key string) string {
+	return load(key)
+}
+
+func load(string) string {
+	return ""
+}
+
+func init() {}
+
+func init() {}
+`,
+		"store/ignored.go": `//go:build ignore
+
+package other
+`,
+		"testdata/data.go": "package broken {",
+	}
+}
+
+func TestCallGraph(t *testing.T) {
+	dir := writeModule(t, callGraphModule())
+
+	tests := []struct {
+		name     string
+		dir      string
+		options  CallGraphOptions
+		expected []string
+	}{
+		{
+			name: "module",
+			dir:  dir,
+			expected: []string{
+				"example.com/m: example.com/m.main subprocess main",
+				"example.com/m: example.com/m.run subprocess run",
+				"example.com/m/store: example.com/m/store.load subprocess load",
+				"example.com/m/store: example.com/m/store.init subprocess init",
+				"example.com/m/store: example.com/m/store.init#2 subprocess init",
+				"example.com/m/store: example.com/m/store.New subprocess New",
+				"example.com/m/store: example.com/m/store.Disk: example.com/m/store.Disk.Get subprocess Get",
+				"example.com/m/store: example.com/m/store.Memory: example.com/m/store.Memory.Get subprocess Get",
+				"example.com/m/store: example.com/m/store.Memory: example.com/m/store.Memory.lookup subprocess lookup",
+				"example.com/m.main -> example.com/m/store.New",
+				"example.com/m.main -> example.com/m.run",
+				"example.com/m.run -.-> example.com/m/store.Memory.Get",
+				"example.com/m.run -.-> example.com/m/store.Disk.Get",
+				"example.com/m.run -> example.com/m.run",
+				"example.com/m/store.Disk.Get -> example.com/m/store.load",
+				"example.com/m/store.Memory.Get -> example.com/m/store.Memory.lookup",
+			},
+		},
+		{
+			name:    "package by file",
+			dir:     filepath.Join(dir, "store"),
+			options: CallGraphOptions{ByFile: true},
+			expected: []string{
+				"example.com/m/store: example.com/m/store/disk.go: example.com/m/store.load subprocess load",
+				"example.com/m/store: example.com/m/store/disk.go: example.com/m/store.init subprocess init",
+				"example.com/m/store: example.com/m/store/disk.go: example.com/m/store.init#2 subprocess init",
+				"example.com/m/store: example.com/m/store/disk.go: example.com/m/store.Disk (disk.go): example.com/m/store.Disk.Get subprocess Get",
+				"example.com/m/store: example.com/m/store/store.go: example.com/m/store.New subprocess New",
+				"example.com/m/store: example.com/m/store/store.go: example.com/m/store.Memory (store.go): example.com/m/store.Memory.Get subprocess Get",
+				"example.com/m/store: example.com/m/store/store.go: example.com/m/store.Memory (store.go): example.com/m/store.Memory.lookup subprocess lookup",
+				"example.com/m/store.Disk.Get -> example.com/m/store.load",
+				"example.com/m/store.Memory.Get -> example.com/m/store.Memory.lookup",
+			},
+		},
+		{
+			name:    "root and depth",
+			dir:     dir,
+			options: CallGraphOptions{Root: "m.run", Depth: 1},
+			expected: []string{
+				"example.com/m: example.com/m.run subprocess run",
+				"example.com/m/store: example.com/m/store.Disk: example.com/m/store.Disk.Get subprocess Get",
+				"example.com/m/store: example.com/m/store.Memory: example.com/m/store.Memory.Get subprocess Get",
+				"example.com/m.run -.-> example.com/m/store.Memory.Get",
+				"example.com/m.run -.-> example.com/m/store.Disk.Get",
+				"example.com/m.run -> example.com/m.run",
+			},
+		},
+		{
+			name:    "exclusions",
+			dir:     dir,
+			options: CallGraphOptions{Root: "example.com/m.main", Exclude: []string{"Disk.*", "*.lookup"}},
+			expected: []string{
+				"example.com/m: example.com/m.main subprocess main",
+				"example.com/m: example.com/m.run subprocess run",
+				"example.com/m/store: example.com/m/store.New subprocess New",
+				"example.com/m/store: example.com/m/store.Memory: example.com/m/store.Memory.Get subprocess Get",
+				"example.com/m.main -> example.com/m/store.New",
+				"example.com/m.main -> example.com/m.run",
+				"example.com/m.run -.-> example.com/m/store.Memory.Get",
+				"example.com/m.run -> example.com/m.run",
+			},
+		},
+		{
+			name:    "excluded package",
+			dir:     dir,
+			options: CallGraphOptions{Exclude: []string{"example.com/m/store"}},
+			expected: []string{
+				"example.com/m: example.com/m.main subprocess main",
+				"example.com/m: example.com/m.run subprocess run",
+				"example.com/m.main -> example.com/m.run",
+				"example.com/m.run -> example.com/m.run",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := CallGraph(tt.dir, tt.options)
+			if err != nil {
+				t.Fatalf("CallGraph() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, describeCalls(f)); diff != "" {
+				t.Errorf("CallGraph() mismatch (-expected +got):\n%s", diff)
+			}
+			if _, err := flowchart.RenderMermaid(f); err != nil {
+				t.Errorf("RenderMermaid() error = %v", err)
+			}
+
+			data, err := json.Marshal(f)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			var decoded flowchart.Flowchart
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if diff := cmp.Diff(describeCalls(f), describeCalls(&decoded)); diff != "" {
+				t.Errorf("json.Unmarshal(json.Marshal()) mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCallGraph_Errors(t *testing.T) {
+	dir := writeModule(t, callGraphModule())
+	broken := writeModule(t, map[string]string{"a.go": "package a\nfunc F() {"})
+	mixed := writeModule(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	empty := writeModule(t, map[string]string{"README.md": "# Empty\n"})
+
+	tests := []struct {
+		name          string
+		dir           string
+		options       CallGraphOptions
+		expectedError string
+	}{
+		{
+			name:          "root not found",
+			dir:           dir,
+			options:       CallGraphOptions{Root: "m.missing"},
+			expectedError: "root function m.missing not found",
+		},
+		{
+			name:          "root excluded",
+			dir:           dir,
+			options:       CallGraphOptions{Root: "m.run", Exclude: []string{"run"}},
+			expectedError: "root function m.run is excluded",
+		},
+		{
+			name:          "invalid pattern",
+			dir:           dir,
+			options:       CallGraphOptions{Exclude: []string{"["}},
+			expectedError: `invalid exclusion pattern "["`,
+		},
+		{
+			name:          "no Go files",
+			dir:           empty,
+			expectedError: empty + ": no Go files",
+		},
+		{
+			name:          "syntax error",
+			dir:           broken,
+			expectedError: filepath.Join(broken, "a.go") + ":2:11: expected '}', found 'EOF'",
+		},
+		{
+			name:          "several packages",
+			dir:           mixed,
+			expectedError: mixed + ": found packages a and b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CallGraph(tt.dir, tt.options)
+			if err == nil || err.Error() != tt.expectedError {
+				t.Errorf("CallGraph() error = %v, expected %q", err, tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,551 @@
+// Package gosource draws flowcharts of Go source code, such as the control flow of a function or the
+// call graph of a package, so that code can be documented without drawing the charts by hand.
+package gosource
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andre-a-alves/flowchart"
+)
+
+// maxLabel is the number of characters kept from the source of a statement in a node label.
+const maxLabel = 50
+
+// span is the range of source code that a node was built from.
+type span struct {
+	pos, end token.Pos
+}
+
+// exit is a pending link leaving a node, which is connected to the next node built.
+type exit struct {
+	from  *flowchart.Node
+	label string
+}
+
+// jumpTarget is a statement that break, and continue for loops, can jump out of.
+type jumpTarget struct {
+	label     string // Label of the statement, or "" if it has none
+	loop      bool   // Whether continue applies to the statement
+	breaks    []exit // Links leaving the statement after it ends
+	continues []exit // Links going to the next iteration of a loop
+}
+
+// cfg is the control-flow flowchart of a function together with the source of its nodes.
+type cfg struct {
+	chart   *flowchart.Flowchart
+	spans   map[*flowchart.Node]span
+	body    span                     // Body of the function
+	repeats map[*flowchart.Node]bool // Nodes of loop headers, which run again on every iteration
+}
+
+// cfgBuilder builds the control-flow flowchart of a function.
+type cfgBuilder struct {
+	fset      *token.FileSet
+	cfg       *cfg
+	container *flowchart.Flowchart // Flowchart receiving the nodes being built
+	local     map[string]bool      // Functions declared in the file
+	methods   map[string]bool      // Methods declared in the file
+	receiver  string               // Name of the receiver of the function, or "" if it has none
+	count     int                  // Number of nodes built, used to name them
+
+	targets []*jumpTarget              // Enclosing statements, innermost last
+	labels  map[string]*flowchart.Node // First node of each labelled statement
+	gotos   map[string][]exit          // Links to labels that are not built yet
+	label   string                     // Label waiting for the next node built
+	falls   []exit                     // Links leaving a switch case through fallthrough
+	closure bool                       // Whether a deferred function literal is being built
+	returns []exit                     // Links leaving a deferred function literal through return
+}
+
+// FuncName returns the name of a function declaration as used by ParseFunc: the name of the
+// function, or the name of the receiver type and the name of the method, as in "Server.Handle".
+func FuncName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	return receiverType(decl.Recv.List[0].Type) + "." + decl.Name.Name
+}
+
+// receiverType returns the name of the type of a method receiver, without pointer or type
+// parameters.
+func receiverType(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// ParseFunc parses the Go source file filename and returns the control-flow flowchart of the
+// function or method with the given name (see FuncName). If src is not nil, the source is read
+// from src instead of the file, as with go/parser.ParseFile.
+// It returns an error if the file cannot be parsed or does not declare the function with a body.
+func ParseFunc(filename string, src any, name string) (*flowchart.Flowchart, error) {
+	fset, file, decl, err := parseFunc(filename, src, name)
+	if err != nil {
+		return nil, err
+	}
+	return FuncChart(fset, file, decl), nil
+}
+
+// parseFunc parses a Go source file and finds the declaration of a function with a body in it.
+func parseFunc(filename string, src any, name string) (*token.FileSet, *ast.File, *ast.FuncDecl, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, d := range file.Decls {
+		if decl, ok := d.(*ast.FuncDecl); ok && FuncName(decl) == name {
+			if decl.Body == nil {
+				return nil, nil, nil, fmt.Errorf("%s: function %s has no body", filename, name)
+			}
+			return fset, file, decl, nil
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("%s: function %s not found", filename, name)
+}
+
+// FuncChart returns the control-flow flowchart of a function declared in file, which must have a
+// body.
+//
+// The chart starts with a terminator named after the function. Each statement is a process node
+// labelled with its source, or a subprocess node if it calls a function declared in the same file
+// or a method of the same receiver declared in the file. Conditions of if and for statements,
+// switch, type switch, select and range statements are decision nodes whose links are labelled
+// with the branch taken: "true" or "false", the expressions of each case or "default", and "next"
+// or "done" for range loops. Loops link back to their condition, or to a connector node for loops
+// without one, and break, continue, goto and fallthrough follow the jumps of the language. Return
+// statements and calls to panic end the flow as terminators, and the end of the function body is
+// an "end" terminator when it can be reached.
+//
+// Deferred calls are not steps of the flow: they are gathered in a subgraph titled "defer", in the
+// order they run when the function returns, with the body of deferred function literals drawn in
+// full.
+func FuncChart(fset *token.FileSet, file *ast.File, decl *ast.FuncDecl) *flowchart.Flowchart {
+	return buildCFG(fset, file, decl).chart
+}
+
+// buildCFG builds the control-flow flowchart of a function declared in file.
+func buildCFG(fset *token.FileSet, file *ast.File, decl *ast.FuncDecl) *cfg {
+	title := FuncName(decl)
+	c := &cfg{
+		chart:   flowchart.VerticalFlowchart(&title),
+		spans:   make(map[*flowchart.Node]span),
+		body:    span{decl.Body.Lbrace, decl.Body.Rbrace + 1},
+		repeats: make(map[*flowchart.Node]bool),
+	}
+	b := &cfgBuilder{
+		fset:      fset,
+		cfg:       c,
+		container: c.chart,
+		local:     make(map[string]bool),
+		methods:   make(map[string]bool),
+		labels:    make(map[string]*flowchart.Node),
+		gotos:     make(map[string][]exit),
+	}
+	for _, d := range file.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok {
+			if fd.Recv == nil {
+				b.local[fd.Name.Name] = true
+			} else {
+				b.methods[fd.Name.Name] = true
+			}
+		}
+	}
+	if decl.Recv != nil && len(decl.Recv.List) > 0 && len(decl.Recv.List[0].Names) > 0 {
+		b.receiver = decl.Recv.List[0].Names[0].Name
+	}
+
+	start := b.add(flowchart.NodeTypeTerminator, title, span{decl.Pos(), decl.Body.Lbrace + 1}, nil, "start")
+	out := b.stmts(decl.Body.List, []exit{{from: start}})
+	if len(out) > 0 || len(b.gotos[b.label]) > 0 {
+		b.add(flowchart.NodeTypeTerminator, "end", span{decl.Body.Rbrace, decl.Body.Rbrace + 1}, out, "end")
+	}
+	b.deferred(decl.Body)
+	return c
+}
+
+// add builds a node of the given type and label, linked from the exits given. The first node built
+// after a label becomes the target of the jumps to that label. The node is named after its
+// position in the flow unless a name is given.
+func (b *cfgBuilder) add(typ flowchart.NodeTypeEnum, label string, s span, in []exit, name string) *flowchart.Node {
+	b.count++
+	if name == "" {
+		name = fmt.Sprintf("n%d", b.count)
+	}
+	node := flowchart.ProcessNode(name, &label)
+	node.Type = typ
+	b.container.Nodes = append(b.container.Nodes, node)
+	b.cfg.spans[node] = s
+	b.connect(in, node)
+	if b.label != "" {
+		b.labels[b.label] = node
+		b.connect(b.gotos[b.label], node)
+		delete(b.gotos, b.label)
+		b.label = ""
+	}
+	return node
+}
+
+// connect links the exits given to a node.
+func (b *cfgBuilder) connect(in []exit, to *flowchart.Node) {
+	for _, e := range in {
+		var label *string
+		if e.label != "" {
+			label = &e.label
+		}
+		b.cfg.chart.Links = append(b.cfg.chart.Links, flowchart.SolidLink(e.from, to, label))
+	}
+}
+
+// text returns the source of a syntax node on a single line, shortened to maxLabel characters.
+func (b *cfgBuilder) text(n ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, b.fset, n); err != nil {
+		return ""
+	}
+	text := strings.Join(strings.Fields(buf.String()), " ")
+	if utf8.RuneCountInString(text) > maxLabel {
+		text = string([]rune(text)[:maxLabel-1]) + "…"
+	}
+	return text
+}
+
+// texts returns the source of a list of expressions, separated by commas.
+func (b *cfgBuilder) texts(list []ast.Expr) string {
+	texts := make([]string, len(list))
+	for i, e := range list {
+		texts[i] = b.text(e)
+	}
+	return strings.Join(texts, ", ")
+}
+
+// callsLocal reports whether a statement calls a function declared in the file, or a method
+// declared in the file on the receiver of the function. Function literals are not searched.
+func (b *cfgBuilder) callsLocal(n ast.Node) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			switch fun := unwrapInstance(n.Fun).(type) {
+			case *ast.Ident:
+				found = b.local[fun.Name]
+			case *ast.SelectorExpr:
+				x, ok := fun.X.(*ast.Ident)
+				found = ok && b.receiver != "" && x.Name == b.receiver && b.methods[fun.Sel.Name]
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// unwrapInstance returns the generic function of an instantiation, such as f for f[int], or the
+// expression itself.
+func unwrapInstance(expr ast.Expr) ast.Expr {
+	for {
+		switch e := expr.(type) {
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		default:
+			return expr
+		}
+	}
+}
+
+// isPanic reports whether an expression is a call to the built-in panic.
+func isPanic(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	fun, ok := call.Fun.(*ast.Ident)
+	return ok && fun.Name == "panic"
+}
+
+// simple builds the process or subprocess node of a statement.
+func (b *cfgBuilder) simple(s ast.Stmt, in []exit) []exit {
+	typ := flowchart.NodeTypeProcess
+	if b.callsLocal(s) {
+		typ = flowchart.NodeTypeSubprocess
+	}
+	return []exit{{from: b.add(typ, b.text(s), span{s.Pos(), s.End()}, in, "")}}
+}
+
+// stmts builds a list of statements, each following the previous one.
+func (b *cfgBuilder) stmts(list []ast.Stmt, in []exit) []exit {
+	for _, s := range list {
+		in = b.stmt(s, "", in)
+	}
+	return in
+}
+
+// stmt builds a statement linked from the exits given and returns the exits leaving it. label is
+// the label of the statement, used by break and continue.
+func (b *cfgBuilder) stmt(s ast.Stmt, label string, in []exit) []exit {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		return b.stmts(s.List, in)
+	case *ast.LabeledStmt:
+		b.label = s.Label.Name
+		return b.stmt(s.Stmt, s.Label.Name, in)
+	case *ast.EmptyStmt:
+		return in
+	case *ast.ReturnStmt:
+		if b.closure {
+			b.returns = append(b.returns, b.simple(s, in)...)
+			return nil
+		}
+		b.add(flowchart.NodeTypeTerminator, b.text(s), span{s.Pos(), s.End()}, in, "")
+		return nil
+	case *ast.ExprStmt:
+		if isPanic(s.X) {
+			b.add(flowchart.NodeTypeTerminator, b.text(s), span{s.Pos(), s.End()}, in, "")
+			return nil
+		}
+		return b.simple(s, in)
+	case *ast.DeferStmt:
+		return in
+	case *ast.BranchStmt:
+		return b.branch(s, in)
+	case *ast.IfStmt:
+		return b.ifStmt(s, in)
+	case *ast.ForStmt:
+		return b.forStmt(s, label, in)
+	case *ast.RangeStmt:
+		return b.rangeStmt(s, label, in)
+	case *ast.SwitchStmt:
+		head := "switch"
+		if s.Tag != nil {
+			head += " " + b.text(s.Tag)
+		}
+		return b.switchStmt(s.Init, head, span{s.Switch, s.Body.Lbrace}, s.Body, label, in)
+	case *ast.TypeSwitchStmt:
+		return b.switchStmt(s.Init, "switch "+b.text(s.Assign), span{s.Switch, s.Body.Lbrace}, s.Body, label, in)
+	case *ast.SelectStmt:
+		return b.selectStmt(s, label, in)
+	default:
+		return b.simple(s, in)
+	}
+}
+
+// target returns the innermost statement that a break, or a continue if loop is true, jumps out
+// of, or the statement with the given label. It returns nil if there is none.
+func (b *cfgBuilder) target(label string, loop bool) *jumpTarget {
+	for i := len(b.targets) - 1; i >= 0; i-- {
+		t := b.targets[i]
+		if (label == "" || t.label == label) && (!loop || t.loop) {
+			return t
+		}
+	}
+	return nil
+}
+
+// branch builds a break, continue, goto or fallthrough statement, which never leaves to the next
+// statement.
+func (b *cfgBuilder) branch(s *ast.BranchStmt, in []exit) []exit {
+	label := ""
+	if s.Label != nil {
+		label = s.Label.Name
+	}
+	switch s.Tok {
+	case token.BREAK:
+		if t := b.target(label, false); t != nil {
+			t.breaks = append(t.breaks, in...)
+		}
+	case token.CONTINUE:
+		if t := b.target(label, true); t != nil {
+			t.continues = append(t.continues, in...)
+		}
+	case token.GOTO:
+		if node, ok := b.labels[label]; ok {
+			b.connect(in, node)
+		} else {
+			b.gotos[label] = append(b.gotos[label], in...)
+		}
+	case token.FALLTHROUGH:
+		b.falls = append(b.falls, in...)
+	}
+	return nil
+}
+
+// ifStmt builds an if statement as a decision on its condition.
+func (b *cfgBuilder) ifStmt(s *ast.IfStmt, in []exit) []exit {
+	if s.Init != nil {
+		in = b.simple(s.Init, in)
+	}
+	cond := b.add(flowchart.NodeTypeDecision, b.text(s.Cond), span{s.Cond.Pos(), s.Cond.End()}, in, "")
+	out := b.stmt(s.Body, "", []exit{{cond, "true"}})
+	if s.Else == nil {
+		return append(out, exit{cond, "false"})
+	}
+	return append(out, b.stmt(s.Else, "", []exit{{cond, "false"}})...)
+}
+
+// loop builds the body of a loop, returning the exits reaching the end of an iteration and the
+// jump target of the loop.
+func (b *cfgBuilder) loop(body *ast.BlockStmt, label string, in []exit) ([]exit, *jumpTarget) {
+	t := &jumpTarget{label: label, loop: true}
+	b.targets = append(b.targets, t)
+	out := b.stmts(body.List, in)
+	b.targets = b.targets[:len(b.targets)-1]
+	return append(out, t.continues...), t
+}
+
+// forStmt builds a for statement as a decision on its condition, or as a connector if it has none,
+// to which the end of each iteration links back.
+func (b *cfgBuilder) forStmt(s *ast.ForStmt, label string, in []exit) []exit {
+	if s.Init != nil {
+		in = b.simple(s.Init, in)
+	}
+	var head *flowchart.Node
+	var out []exit
+	if s.Cond != nil {
+		head = b.add(flowchart.NodeTypeDecision, b.text(s.Cond), span{s.Cond.Pos(), s.Cond.End()}, in, "")
+		out = append(out, exit{head, "false"})
+		in = []exit{{head, "true"}}
+	} else {
+		head = b.add(flowchart.NodeTypeConnector, "for", span{s.For, s.Body.Lbrace}, in, "")
+		in = []exit{{from: head}}
+	}
+	b.cfg.repeats[head] = true
+	next, t := b.loop(s.Body, label, in)
+	// The post statement is unreachable if no iteration reaches the end of the body.
+	if s.Post != nil && len(next) > 0 {
+		next = b.simple(s.Post, next)
+		b.cfg.repeats[next[0].from] = true
+	}
+	b.connect(next, head)
+	return append(out, t.breaks...)
+}
+
+// rangeStmt builds a range statement as a decision on whether there is a next element, to which
+// the end of each iteration links back.
+func (b *cfgBuilder) rangeStmt(s *ast.RangeStmt, label string, in []exit) []exit {
+	header := "range " + b.text(s.X)
+	if s.Key != nil {
+		vars := b.text(s.Key)
+		if s.Value != nil {
+			vars += ", " + b.text(s.Value)
+		}
+		header = vars + " " + s.Tok.String() + " " + header
+	}
+	head := b.add(flowchart.NodeTypeDecision, header, span{s.For, s.X.End()}, in, "")
+	b.cfg.repeats[head] = true
+	next, t := b.loop(s.Body, label, []exit{{head, "next"}})
+	b.connect(next, head)
+	return append([]exit{{head, "done"}}, t.breaks...)
+}
+
+// switchStmt builds a switch or type switch statement as a decision with a link to each case.
+func (b *cfgBuilder) switchStmt(init ast.Stmt, header string, s span, body *ast.BlockStmt, label string, in []exit) []exit {
+	if init != nil {
+		in = b.simple(init, in)
+	}
+	head := b.add(flowchart.NodeTypeDecision, header, s, in, "")
+	t := &jumpTarget{label: label}
+	b.targets = append(b.targets, t)
+	var out, fall []exit
+	hasDefault := false
+	for _, stmt := range body.List {
+		clause := stmt.(*ast.CaseClause)
+		caseLabel := "default"
+		if clause.List != nil {
+			caseLabel = b.texts(clause.List)
+		} else {
+			hasDefault = true
+		}
+		b.falls = nil
+		out = append(out, b.stmts(clause.Body, append([]exit{{head, caseLabel}}, fall...))...)
+		fall = b.falls
+	}
+	b.falls = nil
+	b.targets = b.targets[:len(b.targets)-1]
+	if !hasDefault {
+		out = append(out, exit{head, "default"})
+	}
+	return append(out, t.breaks...)
+}
+
+// selectStmt builds a select statement as a decision with a link to each communication. A select
+// without a default case waits for one of them.
+func (b *cfgBuilder) selectStmt(s *ast.SelectStmt, label string, in []exit) []exit {
+	head := b.add(flowchart.NodeTypeDecision, "select", span{s.Select, s.Body.Lbrace}, in, "")
+	t := &jumpTarget{label: label}
+	b.targets = append(b.targets, t)
+	var out []exit
+	for _, stmt := range s.Body.List {
+		clause := stmt.(*ast.CommClause)
+		caseLabel := "default"
+		if clause.Comm != nil {
+			caseLabel = b.text(clause.Comm)
+		}
+		out = append(out, b.stmts(clause.Body, []exit{{head, caseLabel}})...)
+	}
+	b.targets = b.targets[:len(b.targets)-1]
+	return append(out, t.breaks...)
+}
+
+// deferred builds the "defer" subgraph holding the deferred calls of a function body, in the order
+// they run: the last one deferred first. Deferred function literals are not searched.
+func (b *cfgBuilder) deferred(body *ast.BlockStmt) {
+	var defers []*ast.DeferStmt
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			defers = append(defers, n)
+		}
+		return true
+	})
+	if len(defers) == 0 {
+		return
+	}
+
+	title := "defer"
+	sub := flowchart.VerticalFlowchart(&title)
+	b.cfg.chart.Subgraphs = append(b.cfg.chart.Subgraphs, sub)
+	b.container = sub
+	b.closure = true
+	b.label = ""
+	var in []exit
+	for i := len(defers) - 1; i >= 0; i-- {
+		d := defers[i]
+		lit, ok := d.Call.Fun.(*ast.FuncLit)
+		if !ok {
+			in = b.simple(d, in)
+			continue
+		}
+		b.targets, b.labels, b.gotos, b.returns = nil, make(map[string]*flowchart.Node), make(map[string][]exit), nil
+		in = append(b.stmts(lit.Body.List, in), b.returns...)
+	}
+}
@@ -0,0 +1,410 @@
+package gosource
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/andre-a-alves/flowchart"
+	"github.com/google/go-cmp/cmp"
+)
+
+// describe returns a line for each node of a chart, as "name type label", with the nodes of
+// subgraphs prefixed by the subgraph title, followed by a line for each link, as
+// "origin -label-> target".
+func describe(f *flowchart.Flowchart) []string {
+	var lines []string
+	var nodes func(f *flowchart.Flowchart, prefix string)
+	nodes = func(f *flowchart.Flowchart, prefix string) {
+		for _, node := range f.Nodes {
+			typ, _ := node.Type.MarshalText()
+			lines = append(lines, fmt.Sprintf("%s%s %s %s", prefix, node.Name(), typ, *node.Label))
+		}
+		for _, sub := range f.Subgraphs {
+			nodes(sub, prefix+*sub.Title+": ")
+		}
+	}
+	nodes(f, "")
+	for _, link := range f.Links {
+		label := ""
+		if link.Label != nil {
+			label = *link.Label
+		}
+		lines = append(lines, fmt.Sprintf("%s -%s-> %s", link.Origin.(*flowchart.Node).Name(), label, link.Target.(*flowchart.Node).Name()))
+	}
+	return lines
+}
+
+func TestParseFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		function string
+		expected []string
+	}{
+		{
+			name: "statements and local calls",
+			source: `
+func helper() {}
+
+type Server struct{}
+
+func (s *Server) log(msg string) {}
+
+func (s *Server) Handle(w io.Writer) {
+	x := 1
+	helper()
+	s.log("handled")
+	fmt.Fprintln(w, x)
+}`,
+			function: "Server.Handle",
+			expected: []string{
+				"start terminator Server.Handle",
+				"n2 process x := 1",
+				"n3 subprocess helper()",
+				`n4 subprocess s.log("handled")`,
+				"n5 process fmt.Fprintln(w, x)",
+				"end terminator end",
+				"start --> n2",
+				"n2 --> n3",
+				"n3 --> n4",
+				"n4 --> n5",
+				"n5 --> end",
+			},
+		},
+		{
+			name: "if and return",
+			source: `
+func Sign(x int) string {
+	if x < 0 {
+		return "negative"
+	} else if x == 0 {
+		return "zero"
+	}
+	if y := x * 2; y > 10 {
+		x = y
+	}
+	return "positive"
+}`,
+			function: "Sign",
+			expected: []string{
+				"start terminator Sign",
+				"n2 decision x < 0",
+				`n3 terminator return "negative"`,
+				"n4 decision x == 0",
+				`n5 terminator return "zero"`,
+				"n6 process y := x * 2",
+				"n7 decision y > 10",
+				"n8 process x = y",
+				`n9 terminator return "positive"`,
+				"start --> n2",
+				"n2 -true-> n3",
+				"n2 -false-> n4",
+				"n4 -true-> n5",
+				"n4 -false-> n6",
+				"n6 --> n7",
+				"n7 -true-> n8",
+				"n8 --> n9",
+				"n7 -false-> n9",
+			},
+		},
+		{
+			name: "for loops with break and continue",
+			source: `
+func Loop(n int) {
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			continue
+		}
+		work(i)
+	}
+outer:
+	for {
+		for j := range n {
+			if j > 3 {
+				break outer
+			}
+		}
+	}
+}`,
+			function: "Loop",
+			expected: []string{
+				"start terminator Loop",
+				"n2 process i := 0",
+				"n3 decision i < n",
+				"n4 decision i%2 == 0",
+				"n5 process work(i)",
+				"n6 process i++",
+				"n7 connector for",
+				"n8 decision j := range n",
+				"n9 decision j > 3",
+				"end terminator end",
+				"start --> n2",
+				"n2 --> n3",
+				"n3 -true-> n4",
+				"n4 -false-> n5",
+				"n5 --> n6",
+				"n4 -true-> n6",
+				"n6 --> n3",
+				"n3 -false-> n7",
+				"n7 --> n8",
+				"n8 -next-> n9",
+				"n9 -false-> n8",
+				"n8 -done-> n7",
+				"n9 -true-> end",
+			},
+		},
+		{
+			name: "for loop whose iterations never end",
+			source: `
+func First(xs []int, n int) int {
+	for i := 0; i < n; i++ {
+		return xs[i]
+	}
+	return -1
+}`,
+			function: "First",
+			expected: []string{
+				"start terminator First",
+				"n2 process i := 0",
+				"n3 decision i < n",
+				"n4 terminator return xs[i]",
+				"n5 terminator return -1",
+				"start --> n2",
+				"n2 --> n3",
+				"n3 -true-> n4",
+				"n3 -false-> n5",
+			},
+		},
+		{
+			name: "switch with fallthrough",
+			source: `
+func Grade(score int) (grade string) {
+	switch s := score / 10; s {
+	case 10:
+		fallthrough
+	case 9:
+		grade = "A"
+	case 8, 7:
+		grade = "B"
+	}
+	return
+}`,
+			function: "Grade",
+			expected: []string{
+				"start terminator Grade",
+				"n2 process s := score / 10",
+				"n3 decision switch s",
+				`n4 process grade = "A"`,
+				`n5 process grade = "B"`,
+				"n6 terminator return",
+				"start --> n2",
+				"n2 --> n3",
+				"n3 -9-> n4",
+				"n3 -10-> n4",
+				"n3 -8, 7-> n5",
+				"n4 --> n6",
+				"n5 --> n6",
+				"n3 -default-> n6",
+			},
+		},
+		{
+			name: "type switch, select and panic",
+			source: `
+func Receive(ch chan any, done chan struct{}) {
+	select {
+	case v := <-ch:
+		switch v.(type) {
+		case error:
+			panic(v)
+		default:
+			break
+		}
+	case <-done:
+	}
+}`,
+			function: "Receive",
+			expected: []string{
+				"start terminator Receive",
+				"n2 decision select",
+				"n3 decision switch v.(type)",
+				"n4 terminator panic(v)",
+				"end terminator end",
+				"start --> n2",
+				"n2 -v := <-ch-> n3",
+				"n3 -error-> n4",
+				"n3 -default-> end",
+				"n2 -<-done-> end",
+			},
+		},
+		{
+			name: "goto",
+			source: `
+func Retry() {
+again:
+	if !try() {
+		goto again
+	}
+	goto done
+done:
+}`,
+			function: "Retry",
+			expected: []string{
+				"start terminator Retry",
+				"n2 decision !try()",
+				"end terminator end",
+				"start --> n2",
+				"n2 -true-> n2",
+				"n2 -false-> end",
+			},
+		},
+		{
+			name: "defer",
+			source: `
+func cleanup() {}
+
+func Open(name string) (err error) {
+	defer cleanup()
+	if name == "" {
+		return errors.New("no name")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+			return
+		}
+		log.Print("opened")
+	}()
+	return nil
+}`,
+			function: "Open",
+			expected: []string{
+				"start terminator Open",
+				`n2 decision name == ""`,
+				`n3 terminator return errors.New("no name")`,
+				"n4 terminator return nil",
+				"defer: n5 process r := recover()",
+				"defer: n6 decision r != nil",
+				`defer: n7 process err = fmt.Errorf("%v", r)`,
+				"defer: n8 process return",
+				`defer: n9 process log.Print("opened")`,
+				"defer: n10 subprocess defer cleanup()",
+				"start --> n2",
+				"n2 -true-> n3",
+				"n2 -false-> n4",
+				"n5 --> n6",
+				"n6 -true-> n7",
+				"n7 --> n8",
+				"n6 -false-> n9",
+				"n9 --> n10",
+				"n8 --> n10",
+			},
+		},
+		{
+			name: "long statements",
+			source: `
+func Long() {
+	message := fmt.Sprintf("%s and %s and %s and %s", "first", "second", "third", "fourth")
+	_ = []int{
+		1,
+		2,
+	}
+}`,
+			function: "Long",
+			expected: []string{
+				"start terminator Long",
+				`n2 process message := fmt.Sprintf("%s and %s and %s and %s",…`,
+				"n3 process _ = []int{ 1, 2, }",
+				"end terminator end",
+				"start --> n2",
+				"n2 --> n3",
+				"n3 --> end",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFunc("test.go", "package test\n"+tt.source, tt.function)
+			if err != nil {
+				t.Fatalf("ParseFunc() error = %v", err)
+			}
+			if *f.Title != tt.function || f.Direction != flowchart.DirectionVertical {
+				t.Errorf("ParseFunc() title = %q, direction = %d", *f.Title, f.Direction)
+			}
+			if diff := cmp.Diff(tt.expected, describe(f)); diff != "" {
+				t.Errorf("ParseFunc() mismatch (-expected +got):\n%s", diff)
+			}
+			if _, err := flowchart.RenderMermaid(f); err != nil {
+				t.Errorf("RenderMermaid() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestParseFunc_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		source        string
+		function      string
+		expectedError string
+	}{
+		{
+			name:          "syntax error",
+			source:        "package test\nfunc F() {",
+			function:      "F",
+			expectedError: "test.go:2:11: expected '}', found 'EOF'",
+		},
+		{
+			name:          "not found",
+			source:        "package test\nfunc F() {}",
+			function:      "G",
+			expectedError: "test.go: function G not found",
+		},
+		{
+			name:          "method named without receiver",
+			source:        "package test\ntype T int\nfunc (T) F() {}",
+			function:      "F",
+			expectedError: "test.go: function F not found",
+		},
+		{
+			name:          "no body",
+			source:        "package test\nfunc F()",
+			function:      "F",
+			expectedError: "test.go: function F has no body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFunc("test.go", tt.source, tt.function)
+			if err == nil || err.Error() != tt.expectedError {
+				t.Errorf("ParseFunc() error = %v, expected %q", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestFuncName(t *testing.T) {
+	source := `package test
+func F() {}
+func (t T) Value() {}
+func (t *T) Pointer() {}
+func (l *List[E]) Generic() {}
+func (m Map[K, V]) Generics() {}
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "test.go", source, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, decl := range file.Decls {
+		names = append(names, FuncName(decl.(*ast.FuncDecl)))
+	}
+	expected := []string{"F", "T.Value", "T.Pointer", "List.Generic", "Map.Generics"}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Errorf("FuncName() mismatch (-expected +got):\n%s", diff)
+	}
+}
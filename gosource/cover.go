@@ -0,0 +1,396 @@
+package gosource
+
+import (
+	"bufio"
+	"fmt"
+	"go/token"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andre-a-alves/flowchart"
+)
+
+// Style classes assigned by CoverFunc.
+const (
+	ClassCovered   = "covered"   // Nodes whose code all ran
+	ClassPartial   = "partial"   // Nodes whose code partly ran
+	ClassUncovered = "uncovered" // Nodes whose code never ran
+	ClassTaken     = "taken"     // Links that were followed
+	ClassNotTaken  = "not-taken" // Links that were never followed
+)
+
+// coverageClassDefs holds the style classes added by CoverFunc, in the order they are defined.
+var coverageClassDefs = []flowchart.StyleClass{
+	{Name: ClassCovered, Style: flowchart.Style{Fill: "#d4edda", Stroke: "#28a745"}},
+	{Name: ClassPartial, Style: flowchart.Style{Fill: "#fff3cd", Stroke: "#e0a800"}},
+	{Name: ClassUncovered, Style: flowchart.Style{Fill: "#f8d7da", Stroke: "#dc3545"}},
+	{Name: ClassTaken, Style: flowchart.Style{Stroke: "#28a745"}},
+	{Name: ClassNotTaken, Style: flowchart.Style{Stroke: "#dc3545"}},
+}
+
+// ProfileBlock is a block of statements of a coverage profile, which either all ran or not. Lines
+// and columns start at 1, and the end column is exclusive.
+type ProfileBlock struct {
+	StartLine int // Line of the first character of the block
+	StartCol  int // Column of the first character of the block
+	EndLine   int // Line of the end of the block
+	EndCol    int // Column just after the end of the block
+	NumStmt   int // Number of statements in the block
+	Count     int // Number of times the block ran, or 1 if it ran in set mode
+}
+
+// Profile is a Go coverage profile, as written by go test -coverprofile.
+type Profile struct {
+	Mode   string                    // "set", "count" or "atomic"
+	Blocks map[string][]ProfileBlock // Blocks of each source file, sorted by position
+}
+
+// ParseProfile reads a coverage profile written by go test -coverprofile. Blocks listed more than
+// once, as in profiles of several test runs joined together, are merged by adding their counts, or
+// by keeping whether any ran in set mode.
+// It returns an error if the profile is malformed.
+func ParseProfile(r io.Reader) (*Profile, error) {
+	p := &Profile{Blocks: make(map[string][]ProfileBlock)}
+	index := make(map[string]map[[4]int]int) // Position of each block in the blocks of its file
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if mode, ok := strings.CutPrefix(line, "mode:"); ok {
+			mode = strings.TrimSpace(mode)
+			if mode != "set" && mode != "count" && mode != "atomic" {
+				return nil, fmt.Errorf("line %d: unknown coverage mode %q", lineNumber, mode)
+			}
+			if p.Mode != "" && p.Mode != mode {
+				return nil, fmt.Errorf("line %d: coverage mode %q does not match mode %q", lineNumber, mode, p.Mode)
+			}
+			p.Mode = mode
+			continue
+		}
+		if p.Mode == "" {
+			return nil, fmt.Errorf("line %d: expected mode line", lineNumber)
+		}
+
+		colon := strings.LastIndexByte(line, ':')
+		var b ProfileBlock
+		if colon <= 0 {
+			return nil, fmt.Errorf("line %d: invalid block %q", lineNumber, line)
+		}
+		if _, err := fmt.Sscanf(line[colon+1:], "%d.%d,%d.%d %d %d", &b.StartLine, &b.StartCol, &b.EndLine, &b.EndCol, &b.NumStmt, &b.Count); err != nil {
+			return nil, fmt.Errorf("line %d: invalid block %q", lineNumber, line)
+		}
+		name := line[:colon]
+		key := [4]int{b.StartLine, b.StartCol, b.EndLine, b.EndCol}
+		if index[name] == nil {
+			index[name] = make(map[[4]int]int)
+		}
+		if i, ok := index[name][key]; ok {
+			if p.Mode == "set" {
+				p.Blocks[name][i].Count = max(p.Blocks[name][i].Count, b.Count)
+			} else {
+				p.Blocks[name][i].Count += b.Count
+			}
+			continue
+		}
+		index[name][key] = len(p.Blocks[name])
+		p.Blocks[name] = append(p.Blocks[name], b)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if p.Mode == "" {
+		return nil, fmt.Errorf("missing mode line")
+	}
+	for _, blocks := range p.Blocks {
+		slices.SortFunc(blocks, func(a, b ProfileBlock) int {
+			return comparePositions(a.StartLine, a.StartCol, b.StartLine, b.StartCol)
+		})
+	}
+	return p, nil
+}
+
+// comparePositions compares two positions given as line and column.
+func comparePositions(line1, col1, line2, col2 int) int {
+	if line1 != line2 {
+		return line1 - line2
+	}
+	return col1 - col2
+}
+
+// fileBlocks returns the blocks of a source file. Profiles name files by import path, so the file
+// is matched with the profile file sharing the most trailing path elements with its name.
+func (p *Profile) fileBlocks(filename string) ([]ProfileBlock, error) {
+	elements := strings.Split(filepath.ToSlash(filepath.Clean(filename)), "/")
+	best, bestShared := "", 0
+	ambiguous := false
+	for name := range p.Blocks {
+		profileElements := strings.Split(name, "/")
+		shared := 0
+		for shared < len(elements) && shared < len(profileElements) &&
+			elements[len(elements)-1-shared] == profileElements[len(profileElements)-1-shared] {
+			shared++
+		}
+		switch {
+		case shared > bestShared:
+			best, bestShared, ambiguous = name, shared, false
+		case shared == bestShared && shared > 0:
+			ambiguous = true
+		}
+	}
+	if bestShared == 0 {
+		return nil, fmt.Errorf("%s: no coverage data in profile", filename)
+	}
+	if ambiguous {
+		return nil, fmt.Errorf("%s: several files of the profile match, use a longer path", filename)
+	}
+	return p.Blocks[best], nil
+}
+
+// CoverFunc parses the Go source file filename and returns the control-flow flowchart of the
+// function or method with the given name (see FuncChart), with the coverage of a profile drawn on
+// it. If src is not nil, the source is read from src instead of the file, as with
+// go/parser.ParseFile. The file is found in the profile by the trailing elements of its path.
+//
+// Nodes are assigned the ClassCovered, ClassPartial or ClassUncovered style class, depending on
+// whether the code they were built from all ran, partly ran or never ran. Links are assigned the
+// ClassTaken or ClassNotTaken class when it is known whether they were followed, and links not
+// taken are dotted. The labels of the branches leaving decisions end with "(taken)" or
+// "(not taken)". Coverage profiles count blocks of statements rather than branches, so whether a
+// branch was taken is worked out from the nodes at its ends: a branch is taken if its target only
+// runs through it, and not taken if either end never ran. In count and atomic modes, branches are
+// also worked out from the number of times their decision ran and the other branches were taken.
+// Branches that cannot be worked out are left as they are.
+//
+// It returns an error if the file cannot be parsed, does not declare the function, or has no
+// coverage data for the function in the profile.
+func CoverFunc(filename string, src any, name string, profile *Profile) (*flowchart.Flowchart, error) {
+	fset, file, decl, err := parseFunc(filename, src, name)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := profile.fileBlocks(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := buildCFG(fset, file, decl)
+	start, end := fset.Position(c.body.pos), fset.Position(c.body.end)
+	var inside []ProfileBlock
+	for _, b := range blocks {
+		if comparePositions(b.StartLine, b.StartCol, start.Line, start.Column) >= 0 &&
+			comparePositions(b.EndLine, b.EndCol, end.Line, end.Column) <= 0 {
+			inside = append(inside, b)
+		}
+	}
+	if len(inside) == 0 {
+		return nil, fmt.Errorf("%s: no coverage data for function %s in profile", filename, name)
+	}
+	(&coverage{cfg: c, fset: fset, blocks: inside, exact: profile.Mode != "set"}).apply()
+	return c.chart, nil
+}
+
+// coverage draws the blocks of a coverage profile on the control-flow flowchart of a function.
+type coverage struct {
+	cfg    *cfg
+	fset   *token.FileSet
+	blocks []ProfileBlock // Blocks of the function, sorted by position
+	exact  bool           // Whether blocks count the times they ran rather than whether they ran
+
+	counts map[*flowchart.Node]int // Times each node ran, or -1 if unknown
+	taken  []int                   // Times each link was followed, or -1 if unknown
+	sure   []bool                  // Whether the count of each link is exact
+}
+
+// nodeBlocks returns the blocks overlapping the code of a node, and the block in which the node
+// starts: the innermost block containing its first character or, for nodes outside any statement
+// such as the start of the function, the next block. The latter is nil if there is none.
+func (cv *coverage) nodeBlocks(node *flowchart.Node) ([]ProfileBlock, *ProfileBlock) {
+	s := cv.cfg.spans[node]
+	start, end := cv.fset.Position(s.pos), cv.fset.Position(s.end)
+	var overlapping []ProfileBlock
+	var containing, next *ProfileBlock
+	for i, b := range cv.blocks {
+		startsBefore := comparePositions(b.StartLine, b.StartCol, start.Line, start.Column) <= 0
+		endsAfter := comparePositions(b.EndLine, b.EndCol, start.Line, start.Column) > 0
+		startsInside := !startsBefore && comparePositions(b.StartLine, b.StartCol, end.Line, end.Column) < 0
+		if (startsBefore && endsAfter) || startsInside {
+			overlapping = append(overlapping, b)
+		}
+		switch {
+		case startsBefore && endsAfter:
+			containing = &cv.blocks[i]
+		case !startsBefore && next == nil:
+			next = &cv.blocks[i]
+		}
+	}
+	if containing != nil {
+		return overlapping, containing
+	}
+	return overlapping, next
+}
+
+// apply assigns the style classes of the coverage to the nodes and links of the chart.
+func (cv *coverage) apply() {
+	chart := cv.cfg.chart
+	var nodes []*flowchart.Node
+	nodes = append(nodes, chart.Nodes...)
+	for _, sub := range chart.Subgraphs {
+		nodes = append(nodes, sub.Nodes...)
+	}
+
+	cv.counts = make(map[*flowchart.Node]int)
+	classes := make(map[*flowchart.Node]string)
+	for _, node := range nodes {
+		cv.counts[node] = -1
+		overlapping, first := cv.nodeBlocks(node)
+		if first != nil && len(overlapping) == 0 {
+			overlapping = []ProfileBlock{*first}
+		}
+		ran, missed := false, false
+		for _, b := range overlapping {
+			ran = ran || b.Count > 0
+			missed = missed || b.Count == 0
+		}
+		switch {
+		case ran && missed:
+			classes[node] = ClassPartial
+		case ran:
+			classes[node] = ClassCovered
+		case missed:
+			classes[node] = ClassUncovered
+		}
+		if first != nil {
+			cv.counts[node] = first.Count
+		}
+	}
+
+	cv.followLinks(nodes)
+
+	for _, node := range nodes {
+		if _, ok := classes[node]; ok {
+			continue
+		}
+		// Nodes outside any block, such as the end of the function, ran if a link to them was taken.
+		known, ran := true, false
+		for i, link := range chart.Links {
+			if link.Target == flowchart.Linkable(node) {
+				known = known && cv.taken[i] >= 0
+				ran = ran || cv.taken[i] > 0
+			}
+		}
+		switch {
+		case ran:
+			classes[node] = ClassCovered
+		case known:
+			classes[node] = ClassUncovered
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, node := range nodes {
+		if class, ok := classes[node]; ok {
+			node.Classes = append(node.Classes, class)
+			used[class] = true
+		}
+	}
+	for i := range chart.Links {
+		link := &chart.Links[i]
+		if cv.taken[i] < 0 {
+			continue
+		}
+		class, note := ClassTaken, " (taken)"
+		if cv.taken[i] == 0 {
+			class, note = ClassNotTaken, " (not taken)"
+			link.LineType = flowchart.LineTypeDotted
+		}
+		link.Classes = append(link.Classes, class)
+		used[class] = true
+		if link.Label != nil && link.Origin.(*flowchart.Node).Type == flowchart.NodeTypeDecision {
+			label := *link.Label + note
+			link.Label = &label
+		}
+	}
+	for _, def := range coverageClassDefs {
+		if used[def.Name] {
+			chart.ClassDefs = append(chart.ClassDefs, def)
+		}
+	}
+}
+
+// followLinks works out how many times each link was followed from the counts of the nodes at its
+// ends, until nothing more can be worked out.
+func (cv *coverage) followLinks(nodes []*flowchart.Node) {
+	links := cv.cfg.chart.Links
+	cv.taken = make([]int, len(links))
+	cv.sure = make([]bool, len(links))
+	incoming := make(map[*flowchart.Node][]int)
+	outgoing := make(map[*flowchart.Node][]int)
+	for i, link := range links {
+		cv.taken[i] = -1
+		origin, target := link.Origin.(*flowchart.Node), link.Target.(*flowchart.Node)
+		outgoing[origin] = append(outgoing[origin], i)
+		incoming[target] = append(incoming[target], i)
+	}
+
+	// exact reports whether the count of a node is the number of times it ran.
+	exact := func(node *flowchart.Node) bool {
+		return cv.exact && !cv.cfg.repeats[node]
+	}
+	// conserve works out the one unknown link of a node, if there is only one, as the times the node
+	// ran less the times its other links were followed.
+	conserve := func(node *flowchart.Node, list []int) bool {
+		count := cv.counts[node]
+		if count < 0 || !exact(node) {
+			return false
+		}
+		unknown := -1
+		for _, i := range list {
+			switch {
+			case cv.taken[i] < 0 && unknown >= 0:
+				return false
+			case cv.taken[i] < 0:
+				unknown = i
+			case !cv.sure[i]:
+				return false
+			default:
+				count -= cv.taken[i]
+			}
+		}
+		if unknown < 0 || count < 0 {
+			return false
+		}
+		cv.taken[unknown], cv.sure[unknown] = count, true
+		return true
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i, link := range links {
+			if cv.taken[i] >= 0 {
+				continue
+			}
+			origin, target := link.Origin.(*flowchart.Node), link.Target.(*flowchart.Node)
+			switch {
+			case cv.counts[origin] == 0 || cv.counts[target] == 0:
+				cv.taken[i], cv.sure[i] = 0, true
+			case len(incoming[target]) == 1 && cv.counts[target] > 0:
+				cv.taken[i], cv.sure[i] = cv.counts[target], exact(target)
+			case len(outgoing[origin]) == 1 && cv.counts[origin] > 0:
+				cv.taken[i], cv.sure[i] = cv.counts[origin], exact(origin)
+			default:
+				continue
+			}
+			changed = true
+		}
+		for _, node := range nodes {
+			if conserve(node, outgoing[node]) || conserve(node, incoming[node]) {
+				changed = true
+			}
+		}
+	}
+}
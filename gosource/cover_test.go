@@ -0,0 +1,311 @@
+package gosource
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/andre-a-alves/flowchart"
+	"github.com/google/go-cmp/cmp"
+)
+
+// coverSource is the source of the function drawn by the coverage tests, tested with F(2) twice.
+const coverSource = `package cov
+
+import "errors"
+
+func F(x int) (n int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("panic")
+		}
+	}()
+	double := func(v int) int { return v * 2 }
+	if x > 0 {
+		n = 1
+	}
+	for i := 0; i < x; i++ {
+		n++
+	}
+	switch {
+	case n > 5:
+		return double(n), nil
+	case n < 0:
+		panic("negative")
+	}
+	return n, nil
+}
+`
+
+// coverProfile returns the profile of coverSource written by go test in the given mode.
+func coverProfile(mode string) string {
+	counts := []int{2, 2, 0, 2, 0, 2, 2, 2, 4, 2, 0, 0, 2}
+	blocks := []string{"6.2,6.15", "7.3,7.31", "8.4,9.1", "11.2,11.28", "11.30,11.44", "12.2,12.11", "13.3,14.1",
+		"15.2,15.25", "16.3,17.1", "18.2,18.9", "20.3,20.24", "22.3,22.20", "24.2,24.15"}
+	var sb strings.Builder
+	sb.WriteString("mode: " + mode + "\n")
+	for i, block := range blocks {
+		count := counts[i]
+		if mode == "set" {
+			count = min(count, 1)
+		}
+		fmt.Fprintf(&sb, "example.com/cov/p.go:%s 1 %d\n", block, count)
+	}
+	return sb.String()
+}
+
+// describeCoverage returns a line for each node of a chart, as "name classes", followed by a line
+// for each link, as "origin -label-> target classes".
+func describeCoverage(f *flowchart.Flowchart) []string {
+	var lines []string
+	nodes := append(f.Nodes, f.Subgraphs[0].Nodes...)
+	for _, node := range nodes {
+		lines = append(lines, strings.TrimSpace(node.Name()+" "+strings.Join(node.Classes, " ")))
+	}
+	for _, link := range f.Links {
+		label := ""
+		if link.Label != nil {
+			label = *link.Label
+		}
+		line := fmt.Sprintf("%s -%s-> %s %s", link.Origin.(*flowchart.Node).Name(), label, link.Target.(*flowchart.Node).Name(), strings.Join(link.Classes, " "))
+		if link.LineType == flowchart.LineTypeDotted {
+			line += " dotted"
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return lines
+}
+
+func TestParseProfile(t *testing.T) {
+	profile := `mode: count
+example.com/p/a.go:3.2,4.10 2 1
+example.com/p/a.go:1.5,2.1 1 0
+
+example.com/p/b.go:1.1,1.9 1 3
+mode: count
+example.com/p/a.go:3.2,4.10 2 4
+`
+	p, err := ParseProfile(strings.NewReader(profile))
+	if err != nil {
+		t.Fatalf("ParseProfile() error = %v", err)
+	}
+	expected := &Profile{
+		Mode: "count",
+		Blocks: map[string][]ProfileBlock{
+			"example.com/p/a.go": {
+				{StartLine: 1, StartCol: 5, EndLine: 2, EndCol: 1, NumStmt: 1, Count: 0},
+				{StartLine: 3, StartCol: 2, EndLine: 4, EndCol: 10, NumStmt: 2, Count: 5},
+			},
+			"example.com/p/b.go": {
+				{StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 9, NumStmt: 1, Count: 3},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, p); diff != "" {
+		t.Errorf("ParseProfile() mismatch (-expected +got):\n%s", diff)
+	}
+
+	set, err := ParseProfile(strings.NewReader("mode: set\na.go:1.1,1.9 1 1\na.go:1.1,1.9 1 0\n"))
+	if err != nil {
+		t.Fatalf("ParseProfile() error = %v", err)
+	}
+	if count := set.Blocks["a.go"][0].Count; count != 1 {
+		t.Errorf("ParseProfile() set mode count = %d, expected 1", count)
+	}
+}
+
+func TestParseProfile_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		profile       string
+		expectedError string
+	}{
+		{
+			name:          "empty",
+			profile:       "",
+			expectedError: "missing mode line",
+		},
+		{
+			name:          "block before mode",
+			profile:       "a.go:1.1,1.9 1 1\n",
+			expectedError: "line 1: expected mode line",
+		},
+		{
+			name:          "unknown mode",
+			profile:       "mode: sometimes\n",
+			expectedError: `line 1: unknown coverage mode "sometimes"`,
+		},
+		{
+			name:          "different modes",
+			profile:       "mode: set\nmode: count\n",
+			expectedError: `line 2: coverage mode "count" does not match mode "set"`,
+		},
+		{
+			name:          "invalid block",
+			profile:       "mode: set\na.go:1.1-1.9 1 1\n",
+			expectedError: `line 2: invalid block "a.go:1.1-1.9 1 1"`,
+		},
+		{
+			name:          "missing file",
+			profile:       "mode: set\n1.1,1.9 1 1\n",
+			expectedError: `line 2: invalid block "1.1,1.9 1 1"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseProfile(strings.NewReader(tt.profile))
+			if err == nil || err.Error() != tt.expectedError {
+				t.Errorf("ParseProfile() error = %v, expected %q", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestCoverFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		expected []string
+	}{
+		{
+			name: "count",
+			mode: "count",
+			expected: []string{
+				"start covered",
+				"n2 partial",
+				"n3 covered",
+				"n4 covered",
+				"n5 covered",
+				"n6 covered",
+				"n7 covered",
+				"n8 covered",
+				"n9 covered",
+				"n10 uncovered",
+				"n11 uncovered",
+				"n12 covered",
+				"n13 covered",
+				"n14 covered",
+				"n15 uncovered",
+				"start --> n2 taken",
+				"n2 --> n3 taken",
+				"n3 -true (taken)-> n4 taken",
+				"n4 --> n5 taken",
+				"n3 -false (not taken)-> n5 not-taken dotted",
+				"n5 --> n6 taken",
+				"n6 -true (taken)-> n7 taken",
+				"n7 --> n8 taken",
+				"n8 --> n6 taken",
+				"n6 -false (taken)-> n9 taken",
+				"n9 -n > 5 (not taken)-> n10 not-taken dotted",
+				"n9 -n < 0 (not taken)-> n11 not-taken dotted",
+				"n9 -default (taken)-> n12 taken",
+				"n13 --> n14 taken",
+				"n14 -true (not taken)-> n15 not-taken dotted",
+			},
+		},
+		{
+			name: "set",
+			mode: "set",
+			expected: []string{
+				"start covered",
+				"n2 partial",
+				"n3 covered",
+				"n4 covered",
+				"n5 covered",
+				"n6 covered",
+				"n7 covered",
+				"n8 covered",
+				"n9 covered",
+				"n10 uncovered",
+				"n11 uncovered",
+				"n12 covered",
+				"n13 covered",
+				"n14 covered",
+				"n15 uncovered",
+				"start --> n2 taken",
+				"n2 --> n3 taken",
+				"n3 -true (taken)-> n4 taken",
+				"n4 --> n5 taken",
+				"n3 -false-> n5",
+				"n5 --> n6 taken",
+				"n6 -true (taken)-> n7 taken",
+				"n7 --> n8 taken",
+				"n8 --> n6 taken",
+				"n6 -false (taken)-> n9 taken",
+				"n9 -n > 5 (not taken)-> n10 not-taken dotted",
+				"n9 -n < 0 (not taken)-> n11 not-taken dotted",
+				"n9 -default (taken)-> n12 taken",
+				"n13 --> n14 taken",
+				"n14 -true (not taken)-> n15 not-taken dotted",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := ParseProfile(strings.NewReader(coverProfile(tt.mode)))
+			if err != nil {
+				t.Fatalf("ParseProfile() error = %v", err)
+			}
+			f, err := CoverFunc("cov/p.go", coverSource, "F", profile)
+			if err != nil {
+				t.Fatalf("CoverFunc() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, describeCoverage(f)); diff != "" {
+				t.Errorf("CoverFunc() mismatch (-expected +got):\n%s", diff)
+			}
+			mermaid, err := flowchart.RenderMermaid(f)
+			if err != nil {
+				t.Fatalf("RenderMermaid() error = %v", err)
+			}
+			if !strings.Contains(mermaid, "classDef partial fill:#fff3cd,stroke:#e0a800;") {
+				t.Errorf("RenderMermaid() = %s, expected it to define the partial class", mermaid)
+			}
+			if _, err := flowchart.RenderSVG(f); err != nil {
+				t.Errorf("RenderSVG() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCoverFunc_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		filename      string
+		profile       string
+		expectedError string
+	}{
+		{
+			name:          "file not in profile",
+			filename:      "cov/q.go",
+			profile:       coverProfile("set"),
+			expectedError: "cov/q.go: no coverage data in profile",
+		},
+		{
+			name:          "ambiguous file",
+			filename:      "p.go",
+			profile:       coverProfile("set") + "example.com/other/p.go:1.1,1.9 1 1\n",
+			expectedError: "p.go: several files of the profile match, use a longer path",
+		},
+		{
+			name:          "function not in profile",
+			filename:      "cov/p.go",
+			profile:       "mode: set\nexample.com/cov/p.go:30.2,31.4 1 1\n",
+			expectedError: "cov/p.go: no coverage data for function F in profile",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := ParseProfile(strings.NewReader(tt.profile))
+			if err != nil {
+				t.Fatalf("ParseProfile() error = %v", err)
+			}
+			_, err = CoverFunc(tt.filename, coverSource, "F", profile)
+			if err == nil || err.Error() != tt.expectedError {
+				t.Errorf("CoverFunc() error = %v, expected %q", err, tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package flowchart
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// graphmlShapes holds the configuration of the yEd flowchart shape drawn for each node type.
+var graphmlShapes = map[NodeTypeEnum]string{
+	NodeTypeTerminator:  "com.yworks.flowchart.terminator",
+	NodeTypeProcess:     "com.yworks.flowchart.process",
+	NodeTypeSubprocess:  "com.yworks.flowchart.predefinedProcess",
+	NodeTypeDecision:    "com.yworks.flowchart.decision",
+	NodeTypeInputOutput: "com.yworks.flowchart.data",
+	NodeTypeConnector:   "com.yworks.flowchart.onPageReference",
+	NodeTypeDatabase:    "com.yworks.flowchart.dataBase",
+}
+
+// graphmlKeys declares the data keys of a GraphML document, in the order they are written.
+var graphmlKeys = []string{
+	`<key id="title" for="graph" attr.name="title" attr.type="string" />`,
+	`<key id="type" for="node" attr.name="type" attr.type="string" />`,
+	`<key id="label" for="node" attr.name="label" attr.type="string" />`,
+	`<key id="subgraph" for="node" attr.name="subgraph" attr.type="string" />`,
+	`<key id="nodegraphics" for="node" yfiles.type="nodegraphics" />`,
+	`<key id="linkLabel" for="edge" attr.name="label" attr.type="string" />`,
+	`<key id="lineType" for="edge" attr.name="lineType" attr.type="string" />`,
+	`<key id="arrowType" for="edge" attr.name="arrowType" attr.type="string" />`,
+	`<key id="originArrow" for="edge" attr.name="originArrow" attr.type="boolean" />`,
+	`<key id="targetArrow" for="edge" attr.name="targetArrow" attr.type="boolean" />`,
+	`<key id="edgegraphics" for="edge" yfiles.type="edgegraphics" />`,
+}
+
+// Size of the box given to untitled subgraphs without nodes, which have no place in the layout.
+const (
+	graphEmptyWidth  = 100.0
+	graphEmptyHeight = 50.0
+)
+
+// graphExport holds what the GraphML and GEXF exporters need to know about a flowchart: the id,
+// containing subgraph and box of every node and subgraph, and the links to export.
+type graphExport struct {
+	sb        strings.Builder
+	elements  treeElements
+	ids       map[Linkable]string
+	parents   map[Linkable]*Flowchart
+	rects     map[Linkable]Rect
+	bends     map[*Link][]Point
+	classDefs []StyleClass
+	links     []*Link // Visible links, in tree order
+}
+
+// newGraphExport lays out f and collects the ids of its elements. Nodes and titled subgraphs are
+// identified by their names; untitled subgraphs are numbered.
+func newGraphExport(f *Flowchart) (*graphExport, error) {
+	l, err := ComputeLayout(f, LayoutOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	g := &graphExport{
+		elements:  newTreeElements(f),
+		ids:       make(map[Linkable]string),
+		parents:   make(map[Linkable]*Flowchart),
+		rects:     make(map[Linkable]Rect),
+		bends:     make(map[*Link][]Point),
+		classDefs: allClassDefs(f),
+	}
+	used := make(map[string]bool)
+	for _, name := range f.allNames() {
+		used[name] = true
+	}
+	for _, n := range l.Nodes {
+		g.rects[n.Node] = n.Rect
+	}
+	for _, c := range l.Clusters {
+		g.rects[c.Subgraph] = c.Rect
+	}
+	for _, e := range l.Edges {
+		if len(e.Points) > 2 {
+			g.bends[e.Link] = e.Points[1 : len(e.Points)-1]
+		}
+	}
+	untitled, spare := 0, 20.0
+	walkFlowchart(f, func(sub *Flowchart, _ []string) {
+		for _, node := range sub.Nodes {
+			g.ids[node] = node.name
+			g.parents[node] = sub
+		}
+		for _, child := range sub.Subgraphs {
+			g.parents[child] = sub
+			if child.Title != nil && *child.Title != "" {
+				g.ids[child] = *child.Title
+			} else {
+				untitled++
+				id := "subgraph-" + strconv.Itoa(untitled)
+				unique := id
+				for i := 2; used[unique]; i++ {
+					unique = id + "-" + strconv.Itoa(i)
+				}
+				used[unique] = true
+				g.ids[child] = unique
+			}
+			// Untitled subgraphs without nodes are not laid out, and are placed below the drawing.
+			if _, ok := g.rects[child]; !ok {
+				g.rects[child] = Rect{spare, l.Height, graphEmptyWidth, graphEmptyHeight}
+				spare += graphEmptyWidth + 20
+			}
+		}
+		for i := range sub.Links {
+			if sub.Links[i].LineType != LineTypeNone {
+				g.links = append(g.links, &sub.Links[i])
+			}
+		}
+	})
+	return g, nil
+}
+
+// line writes a line of the document indented to the given depth.
+func (g *graphExport) line(depth int, format string, args ...any) {
+	g.sb.WriteString(strings.Repeat("  ", depth))
+	g.sb.WriteString(fmt.Sprintf(format, args...))
+	g.sb.WriteString("\n")
+}
+
+// endpoint returns the id of the node or subgraph a link endpoint stands for.
+func (g *graphExport) endpoint(l Linkable) string {
+	return g.ids[g.elements.resolve(l)]
+}
+
+// subgraph returns the id of the subgraph directly containing a node or subgraph, or "" if it is
+// at the top level.
+func (g *graphExport) subgraph(l Linkable) string {
+	parent := g.parents[l]
+	if g.parents[parent] == nil {
+		return ""
+	}
+	return g.ids[parent]
+}
+
+// enumName returns the name of an enum value, or "" if the value has no name.
+func enumName(names []string, value int) string {
+	if value < 0 || value >= len(names) {
+		return ""
+	}
+	return names[value]
+}
+
+// colorOr returns a style colour as "#rrggbb", or the fallback if it is not set or not understood.
+func colorOr(value, fallback string) string {
+	if c := hexColor(value); c != "" {
+		return c
+	}
+	return fallback
+}
+
+// graphmlArrow returns the yEd arrow drawn at an end of a link. yEd has no cross arrowhead, so
+// ArrowTypeCross is approximated with "t_shape".
+func graphmlArrow(link *Link, drawn bool) string {
+	if !drawn {
+		return "none"
+	}
+	switch link.ArrowType {
+	case ArrowTypeNormal:
+		return "standard"
+	case ArrowTypeCircle:
+		return "circle"
+	case ArrowTypeCross:
+		return "t_shape"
+	default:
+		return "none"
+	}
+}
+
+// graphmlLabel returns the attributes of a yEd label drawn with the given style.
+func graphmlLabel(style Style) string {
+	attrs := fmt.Sprintf(` textColor="%s"`, colorOr(style.Color, svgTextColor))
+	if style.FontFamily != "" {
+		attrs += fmt.Sprintf(` fontFamily="%s"`, xmlEscaper.Replace(style.FontFamily))
+	}
+	if style.FontSize != "" {
+		attrs += fmt.Sprintf(` fontSize="%s"`, svgNumber(styleWidth(style.FontSize, 12)))
+	}
+	if style.FontWeight == "bold" || style.FontWeight == "bolder" {
+		attrs += ` fontStyle="bold"`
+	}
+	return attrs
+}
+
+// writeBox writes the geometry, fill and border of a yEd node drawn with the given style.
+func (g *graphExport) writeBox(depth int, r Rect, style Style, fill, stroke string) {
+	g.line(depth, `<y:Geometry x="%s" y="%s" width="%s" height="%s" />`, svgNumber(r.X), svgNumber(r.Y), svgNumber(r.Width), svgNumber(r.Height))
+	g.line(depth, `<y:Fill color="%s" transparent="false" />`, colorOr(style.Fill, fill))
+	g.line(depth, `<y:BorderStyle color="%s" type="line" width="%s" />`, colorOr(style.Stroke, stroke), svgNumber(styleWidth(style.StrokeWidth, 1)))
+}
+
+// writeData writes a data element holding text, unless the text is empty.
+func (g *graphExport) writeData(depth int, key, text string) {
+	if text != "" {
+		g.line(depth, `<data key="%s">%s</data>`, key, xmlEscaper.Replace(text))
+	}
+}
+
+// writeGraphML writes the nodes of f, and the subgraphs of f as group nodes holding a nested graph.
+func (g *graphExport) writeGraphML(f *Flowchart, depth int) {
+	for _, node := range f.Nodes {
+		style := resolveStyle(g.classDefs, node.Classes, node.Style)
+		g.line(depth, `<node id="%s">`, xmlEscaper.Replace(g.ids[node]))
+		g.writeData(depth+1, "type", enumName(nodeTypeNames, int(node.Type)))
+		g.writeData(depth+1, "label", nodeText(node))
+		g.writeData(depth+1, "subgraph", g.subgraph(node))
+		g.line(depth+1, `<data key="nodegraphics">`)
+		g.line(depth+2, `<y:GenericNode configuration="%s">`, graphmlShapes[node.Type])
+		g.writeBox(depth+3, g.rects[node], style, svgNodeFill, svgNodeStroke)
+		g.line(depth+3, `<y:NodeLabel%s>%s</y:NodeLabel>`, graphmlLabel(style), xmlEscaper.Replace(nodeText(node)))
+		g.line(depth+2, `</y:GenericNode>`)
+		g.line(depth+1, `</data>`)
+		g.line(depth, `</node>`)
+	}
+	for _, sub := range f.Subgraphs {
+		style := resolveStyle(g.classDefs, sub.Classes, sub.Style)
+		id := xmlEscaper.Replace(g.ids[sub])
+		g.line(depth, `<node id="%s" yfiles.foldertype="group">`, id)
+		g.writeData(depth+1, "type", "subgraph")
+		g.writeData(depth+1, "label", sub.nodeName())
+		g.writeData(depth+1, "subgraph", g.subgraph(sub))
+		g.line(depth+1, `<data key="nodegraphics">`)
+		g.line(depth+2, `<y:ProxyAutoBoundsNode>`)
+		g.line(depth+3, `<y:Realizers active="0">`)
+		g.line(depth+4, `<y:GroupNode>`)
+		g.writeBox(depth+5, g.rects[sub], style, svgClusterFill, svgClusterStroke)
+		g.line(depth+5, `<y:NodeLabel modelName="internal" modelPosition="t"%s>%s</y:NodeLabel>`, graphmlLabel(style), xmlEscaper.Replace(sub.nodeName()))
+		g.line(depth+5, `<y:Shape type="rectangle" />`)
+		g.line(depth+5, `<y:State closed="false" />`)
+		g.line(depth+4, `</y:GroupNode>`)
+		g.line(depth+3, `</y:Realizers>`)
+		g.line(depth+2, `</y:ProxyAutoBoundsNode>`)
+		g.line(depth+1, `</data>`)
+		if len(sub.Nodes) == 0 && len(sub.Subgraphs) == 0 {
+			g.line(depth+1, `<graph id="%s:" edgedefault="directed" />`, id)
+		} else {
+			g.line(depth+1, `<graph id="%s:" edgedefault="directed">`, id)
+			g.writeGraphML(sub, depth+2)
+			g.line(depth+1, `</graph>`)
+		}
+		g.line(depth, `</node>`)
+	}
+}
+
+// writeGraphMLEdges writes the edges of the exported links.
+func (g *graphExport) writeGraphMLEdges(depth int) {
+	for i, link := range g.links {
+		style := resolveStyle(g.classDefs, link.Classes, link.Style)
+		label := ""
+		if link.Label != nil {
+			label = linkText(link)
+		}
+		lineType, width := "line", 1.0
+		switch link.LineType {
+		case LineTypeDotted:
+			lineType = "dashed"
+		case LineTypeThick:
+			width = 3
+		}
+		g.line(depth, `<edge id="e%d" source="%s" target="%s">`, i, xmlEscaper.Replace(g.endpoint(link.Origin)), xmlEscaper.Replace(g.endpoint(link.Target)))
+		g.writeData(depth+1, "linkLabel", label)
+		g.writeData(depth+1, "lineType", enumName(lineTypeNames, int(link.LineType)))
+		g.writeData(depth+1, "arrowType", enumName(arrowTypeNames, int(link.ArrowType)))
+		g.writeData(depth+1, "originArrow", strconv.FormatBool(link.OriginArrow))
+		g.writeData(depth+1, "targetArrow", strconv.FormatBool(link.TargetArrow))
+		g.line(depth+1, `<data key="edgegraphics">`)
+		g.line(depth+2, `<y:PolyLineEdge>`)
+		if bends := g.bends[link]; len(bends) > 0 {
+			g.line(depth+3, `<y:Path sx="0" sy="0" tx="0" ty="0">`)
+			for _, p := range bends {
+				g.line(depth+4, `<y:Point x="%s" y="%s" />`, svgNumber(p.X), svgNumber(p.Y))
+			}
+			g.line(depth+3, `</y:Path>`)
+		}
+		g.line(depth+3, `<y:LineStyle color="%s" type="%s" width="%s" />`, colorOr(style.Stroke, svgLineStroke), lineType, svgNumber(styleWidth(style.StrokeWidth, width)))
+		g.line(depth+3, `<y:Arrows source="%s" target="%s" />`, graphmlArrow(link, link.OriginArrow), graphmlArrow(link, link.TargetArrow))
+		if label != "" {
+			g.line(depth+3, `<y:EdgeLabel%s>%s</y:EdgeLabel>`, graphmlLabel(style), xmlEscaper.Replace(label))
+		}
+		g.line(depth+2, `</y:PolyLineEdge>`)
+		g.line(depth+1, `</data>`)
+		g.line(depth, `</edge>`)
+	}
+}
+
+// RenderGraphML generates a GraphML document of the flowchart, for graph analysis tools and the
+// yEd editor.
+//
+// Nodes are identified by their names and carry their type and label as typed data. Subgraphs are
+// group nodes of type "subgraph" holding a nested graph with their contents, and every node also
+// records the subgraph directly containing it, for tools that flatten nested graphs. Links are
+// edges of the top-level graph carrying their label, line type, arrow type and arrow ends;
+// invisible links only affect the layout and are left out. Positions, yEd flowchart shapes and
+// styles are included as yFiles graphics, so yEd opens the document as drawn by RenderSVG.
+// It returns the GraphML document or an error if validation fails.
+func RenderGraphML(f *Flowchart) (string, error) {
+	if err := validateGraphML(f); err != nil {
+		return "", err
+	}
+	g, err := newGraphExport(f)
+	if err != nil {
+		return "", err
+	}
+
+	g.line(0, `<?xml version="1.0" encoding="UTF-8"?>`)
+	g.line(0, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:y="http://www.yworks.com/xml/graphml" xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://www.yworks.com/xml/schema/graphml/1.1/ygraphml.xsd">`)
+	for _, key := range graphmlKeys {
+		g.line(1, "%s", key)
+	}
+	g.line(1, `<graph id="G" edgedefault="directed">`)
+	if f.Title != nil {
+		g.writeData(2, "title", *f.Title)
+	}
+	g.writeGraphML(f, 2)
+	g.writeGraphMLEdges(2)
+	g.line(1, `</graph>`)
+	g.line(0, `</graphml>`)
+	return g.sb.String(), nil
+}
+
+// validateGraphML validates the Flowchart structure to ensure it can be rendered as GraphML.
+// It checks for the following violations:
+// 1. All node and subgraph names must be unique across the whole flowchart.
+// 2. Style classes must have valid, unique names, and every class assigned must be defined.
+//
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func validateGraphML(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	violations = append(violations, styleClassViolations(f)...)
+	return newValidationError(violations...)
+}
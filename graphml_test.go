@@ -0,0 +1,218 @@
+package flowchart
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func graphExportFixture() *Flowchart {
+	start, check := TerminatorNode("start", pointTo("Start")), DecisionNode("check", pointTo("**Valid?**"))
+	check.LabelFormat = LabelFormatMarkdown
+	check.Classes = []string{"warn"}
+	db := DatabaseNode("db", pointTo("Orders"))
+	storage := &Flowchart{Title: pointTo("Storage"), Nodes: []*Node{db}}
+	thick := ThickLink(check, db, pointTo("yes & save"))
+	thick.ArrowType = ArrowTypeCircle
+	return &Flowchart{
+		Title:     pointTo("Orders"),
+		Nodes:     []*Node{start, check},
+		Subgraphs: []*Flowchart{storage},
+		ClassDefs: []StyleClass{{Name: "warn", Style: Style{Fill: "#f96", StrokeWidth: "2px"}}},
+		Links: []Link{
+			SolidLink(start, check, nil),
+			thick,
+			DottedLink(start, storage, nil),
+			BlankLink(start, db, nil),
+		},
+	}
+}
+
+func TestRenderGraphML(t *testing.T) {
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:y="http://www.yworks.com/xml/graphml" xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://www.yworks.com/xml/schema/graphml/1.1/ygraphml.xsd">
+  <key id="title" for="graph" attr.name="title" attr.type="string" />
+  <key id="type" for="node" attr.name="type" attr.type="string" />
+  <key id="label" for="node" attr.name="label" attr.type="string" />
+  <key id="subgraph" for="node" attr.name="subgraph" attr.type="string" />
+  <key id="nodegraphics" for="node" yfiles.type="nodegraphics" />
+  <key id="linkLabel" for="edge" attr.name="label" attr.type="string" />
+  <key id="lineType" for="edge" attr.name="lineType" attr.type="string" />
+  <key id="arrowType" for="edge" attr.name="arrowType" attr.type="string" />
+  <key id="originArrow" for="edge" attr.name="originArrow" attr.type="boolean" />
+  <key id="targetArrow" for="edge" attr.name="targetArrow" attr.type="boolean" />
+  <key id="edgegraphics" for="edge" yfiles.type="edgegraphics" />
+  <graph id="G" edgedefault="directed">
+    <data key="title">Orders</data>
+    <node id="start">
+      <data key="type">terminator</data>
+      <data key="label">Start</data>
+      <data key="nodegraphics">
+        <y:GenericNode configuration="com.yworks.flowchart.terminator">
+          <y:Geometry x="20" y="75" width="72" height="38" />
+          <y:Fill color="#ECECFF" transparent="false" />
+          <y:BorderStyle color="#9370DB" type="line" width="1" />
+          <y:NodeLabel textColor="#333333">Start</y:NodeLabel>
+        </y:GenericNode>
+      </data>
+    </node>
+    <node id="check">
+      <data key="type">decision</data>
+      <data key="label">Valid?</data>
+      <data key="nodegraphics">
+        <y:GenericNode configuration="com.yworks.flowchart.decision">
+          <y:Geometry x="142" y="35" width="118" height="118" />
+          <y:Fill color="#ff9966" transparent="false" />
+          <y:BorderStyle color="#9370DB" type="line" width="2" />
+          <y:NodeLabel textColor="#333333">Valid?</y:NodeLabel>
+        </y:GenericNode>
+      </data>
+    </node>
+    <node id="Storage" yfiles.foldertype="group">
+      <data key="type">subgraph</data>
+      <data key="label">Storage</data>
+      <data key="nodegraphics">
+        <y:ProxyAutoBoundsNode>
+          <y:Realizers active="0">
+            <y:GroupNode>
+              <y:Geometry x="310" y="33" width="110" height="100" />
+              <y:Fill color="#FFFFDE" transparent="false" />
+              <y:BorderStyle color="#AAAA33" type="line" width="1" />
+              <y:NodeLabel modelName="internal" modelPosition="t" textColor="#333333">Storage</y:NodeLabel>
+              <y:Shape type="rectangle" />
+              <y:State closed="false" />
+            </y:GroupNode>
+          </y:Realizers>
+        </y:ProxyAutoBoundsNode>
+      </data>
+      <graph id="Storage:" edgedefault="directed">
+        <node id="db">
+          <data key="type">database</data>
+          <data key="label">Orders</data>
+          <data key="subgraph">Storage</data>
+          <data key="nodegraphics">
+            <y:GenericNode configuration="com.yworks.flowchart.dataBase">
+              <y:Geometry x="325" y="70" width="80" height="48" />
+              <y:Fill color="#ECECFF" transparent="false" />
+              <y:BorderStyle color="#9370DB" type="line" width="1" />
+              <y:NodeLabel textColor="#333333">Orders</y:NodeLabel>
+            </y:GenericNode>
+          </data>
+        </node>
+      </graph>
+    </node>
+    <edge id="e0" source="start" target="check">
+      <data key="lineType">solid</data>
+      <data key="arrowType">normal</data>
+      <data key="originArrow">false</data>
+      <data key="targetArrow">true</data>
+      <data key="edgegraphics">
+        <y:PolyLineEdge>
+          <y:LineStyle color="#333333" type="line" width="1" />
+          <y:Arrows source="none" target="standard" />
+        </y:PolyLineEdge>
+      </data>
+    </edge>
+    <edge id="e1" source="check" target="db">
+      <data key="linkLabel">yes &amp; save</data>
+      <data key="lineType">thick</data>
+      <data key="arrowType">circle</data>
+      <data key="originArrow">false</data>
+      <data key="targetArrow">true</data>
+      <data key="edgegraphics">
+        <y:PolyLineEdge>
+          <y:LineStyle color="#333333" type="line" width="3" />
+          <y:Arrows source="none" target="circle" />
+          <y:EdgeLabel textColor="#333333">yes &amp; save</y:EdgeLabel>
+        </y:PolyLineEdge>
+      </data>
+    </edge>
+    <edge id="e2" source="start" target="Storage">
+      <data key="lineType">dotted</data>
+      <data key="arrowType">normal</data>
+      <data key="originArrow">false</data>
+      <data key="targetArrow">true</data>
+      <data key="edgegraphics">
+        <y:PolyLineEdge>
+          <y:Path sx="0" sy="0" tx="0" ty="0">
+            <y:Point x="201" y="20" />
+          </y:Path>
+          <y:LineStyle color="#333333" type="dashed" width="1" />
+          <y:Arrows source="none" target="standard" />
+        </y:PolyLineEdge>
+      </data>
+    </edge>
+  </graph>
+</graphml>
+`
+
+	got, err := RenderGraphML(graphExportFixture())
+	if err != nil {
+		t.Fatalf("RenderGraphML() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("RenderGraphML() mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestRenderGraphML_Subgraphs(t *testing.T) {
+	inner := &Flowchart{Title: pointTo("inner"), Nodes: []*Node{ProcessNode("a", nil)}}
+	f := &Flowchart{
+		Nodes:     []*Node{ProcessNode("subgraph-1", nil)},
+		Subgraphs: []*Flowchart{{Title: pointTo("outer"), Subgraphs: []*Flowchart{inner}}, {}},
+	}
+
+	got, err := RenderGraphML(f)
+	if err != nil {
+		t.Fatalf("RenderGraphML() unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`<node id="outer" yfiles.foldertype="group">`,
+		`<graph id="outer:" edgedefault="directed">`,
+		`<node id="inner" yfiles.foldertype="group">`,
+		`<data key="subgraph">outer</data>`,
+		`<data key="subgraph">inner</data>`,
+		`<node id="subgraph-1-2" yfiles.foldertype="group">`,
+		`<graph id="subgraph-1-2:" edgedefault="directed" />`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderGraphML() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestRenderGraphML_Errors(t *testing.T) {
+	a := &Node{name: "a", Classes: []string{"missing"}}
+	_, err := RenderGraphML(&Flowchart{
+		Nodes: []*Node{a, ProcessNode("a", nil)},
+		Links: []Link{SolidLink(a, &Node{name: "Phantom"}, nil)},
+	})
+	for _, code := range []error{ErrDuplicateName, ErrUndefinedStyleClass, ErrOrphanedEndpoint} {
+		if !errors.Is(err, code) {
+			t.Errorf("RenderGraphML() error = %v, expected %v", err, code)
+		}
+	}
+}
+
+func TestGraphMLArrow(t *testing.T) {
+	tests := []struct {
+		arrowType ArrowTypeEnum
+		drawn     bool
+		expected  string
+	}{
+		{arrowType: ArrowTypeNormal, drawn: true, expected: "standard"},
+		{arrowType: ArrowTypeCircle, drawn: true, expected: "circle"},
+		{arrowType: ArrowTypeCross, drawn: true, expected: "t_shape"},
+		{arrowType: ArrowTypeNone, drawn: true, expected: "none"},
+		{arrowType: ArrowTypeNormal, drawn: false, expected: "none"},
+	}
+
+	for _, tt := range tests {
+		link := &Link{ArrowType: tt.arrowType}
+		if got := graphmlArrow(link, tt.drawn); got != tt.expected {
+			t.Errorf("graphmlArrow(%v, %v) = %q, expected %q", tt.arrowType, tt.drawn, got, tt.expected)
+		}
+	}
+}
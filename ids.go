@@ -0,0 +1,99 @@
+package flowchart
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+)
+
+// idMap maps the names of the nodes and subgraphs of a flowchart to renderer-safe identifiers.
+// Names are the identity of nodes and subgraphs in this package and may contain any character;
+// each renderer derives its own identifiers from them with a sanitize function.
+type idMap map[string]string
+
+// newIDMap assigns an identifier to every node name and subgraph title in the flowchart tree.
+// Each identifier is sanitize(name). When several names sanitize to the same identifier, a name
+// that is identical to the identifier keeps it and the others get a hash of their name appended,
+// so the result does not depend on the order of the nodes.
+// It returns an error naming the clashing names if two names still share an identifier.
+func newIDMap(f *Flowchart, sanitize func(string) string) (idMap, error) {
+	ids := assignIDs(f, sanitize)
+
+	names := make([]string, 0, len(ids))
+	for name := range ids {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	owners := make(map[string]string)
+	for _, name := range names {
+		if other, ok := owners[ids[name]]; ok {
+			return nil, fmt.Errorf("names %q and %q both map to id %q", other, name, ids[name])
+		}
+		owners[ids[name]] = name
+	}
+	return ids, nil
+}
+
+// assignIDs assigns identifiers as described by newIDMap, without checking that they are distinct.
+func assignIDs(f *Flowchart, sanitize func(string) string) idMap {
+	byID := make(map[string][]string)
+	for _, name := range linkableNames(f) {
+		id := sanitize(name)
+		if !slices.Contains(byID[id], name) {
+			byID[id] = append(byID[id], name)
+		}
+	}
+
+	ids := make(idMap)
+	for id, names := range byID {
+		if len(names) == 1 {
+			ids[names[0]] = id
+			continue
+		}
+		for _, name := range names {
+			if name == id {
+				ids[name] = id
+			} else {
+				ids[name] = collisionID(id, name)
+			}
+		}
+	}
+	return ids
+}
+
+// lookup returns the identifier assigned to name, or fallback(name) if the name is not in the map,
+// as happens for links to nodes that were never added to the flowchart.
+func (m idMap) lookup(name string, fallback func(string) string) string {
+	if id, ok := m[name]; ok {
+		return id
+	}
+	return fallback(name)
+}
+
+// linkableNames returns the names of all nodes and the titles of all subgraphs in the flowchart
+// tree, excluding the title of the flowchart itself.
+func linkableNames(f *Flowchart) []string {
+	var names []string
+	for _, node := range f.Nodes {
+		names = append(names, node.name)
+	}
+	for _, subgraph := range f.Subgraphs {
+		if subgraph.Title != nil && *subgraph.Title != "" {
+			names = append(names, *subgraph.Title)
+		}
+		names = append(names, linkableNames(subgraph)...)
+	}
+	return names
+}
+
+// collisionID returns the identifier used for name when its sanitized id is shared with other names.
+func collisionID(id, name string) string {
+	return id + "_" + nameHash(name)
+}
+
+// nameHash returns a short, deterministic hash of a name for use in identifiers.
+func nameHash(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
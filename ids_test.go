@@ -0,0 +1,98 @@
+package flowchart
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewIDMap(t *testing.T) {
+	tests := []struct {
+		name        string
+		flowchart   *Flowchart
+		sanitize    func(string) string
+		expected    idMap
+		expectedErr bool
+	}{
+		{
+			name: "distinct ids",
+			flowchart: &Flowchart{
+				Title: pointTo("Root"),
+				Nodes: []*Node{{name: "Node One"}},
+				Subgraphs: []*Flowchart{{
+					Title:     pointTo("Group"),
+					Subgraphs: []*Flowchart{{Nodes: []*Node{{name: "Inner"}}}},
+				}},
+			},
+			sanitize: removeSpaces,
+			expected: idMap{"Node One": "NodeOne", "Group": "Group", "Inner": "Inner"},
+		},
+		{
+			name: "collision keeps exact name and hashes the others",
+			flowchart: &Flowchart{
+				Nodes:     []*Node{{name: "A B"}, {name: "AB"}},
+				Subgraphs: []*Flowchart{{Title: pointTo("A  B")}},
+			},
+			sanitize: removeSpaces,
+			expected: idMap{
+				"AB":   "AB",
+				"A B":  "AB_" + nameHash("A B"),
+				"A  B": "AB_" + nameHash("A  B"),
+			},
+		},
+		{
+			name: "collision without an exact name",
+			flowchart: &Flowchart{
+				Nodes: []*Node{{name: "A B"}, {name: " AB"}},
+			},
+			sanitize: removeSpaces,
+			expected: idMap{
+				"A B": "AB_" + nameHash("A B"),
+				" AB": "AB_" + nameHash(" AB"),
+			},
+		},
+		{
+			name: "unresolvable collision",
+			flowchart: &Flowchart{
+				Nodes: []*Node{{name: "A B"}, {name: "AB"}, {name: "AB_" + nameHash("A B")}},
+			},
+			sanitize:    removeSpaces,
+			expectedErr: true,
+		},
+		{
+			name: "repeated names share an id",
+			flowchart: &Flowchart{
+				Nodes:     []*Node{{name: "Repeated"}},
+				Subgraphs: []*Flowchart{{Title: pointTo("Group"), Nodes: []*Node{{name: "Repeated"}}}},
+			},
+			sanitize: removeSpaces,
+			expected: idMap{"Repeated": "Repeated", "Group": "Group"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newIDMap(tt.flowchart, tt.sanitize)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("newIDMap() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("newIDMap() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIDMap_lookup(t *testing.T) {
+	ids := idMap{"Node One": "NodeOne_1"}
+
+	if diff := cmp.Diff("NodeOne_1", ids.lookup("Node One", removeSpaces)); diff != "" {
+		t.Errorf("lookup() mismatch (-expected +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("NodeTwo", ids.lookup("Node Two", removeSpaces)); diff != "" {
+		t.Errorf("lookup() fallback mismatch (-expected +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("NodeThree", idMap(nil).lookup("Node Three", removeSpaces)); diff != "" {
+		t.Errorf("lookup() on nil map mismatch (-expected +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,153 @@
+package flowchart
+
+import "fmt"
+
+// IntegrityOptions selects the optional checks made by CheckIntegrity.
+type IntegrityOptions struct {
+	AllowSelfLoops      bool // Accept links whose origin and target are the same node or subgraph
+	AllowDuplicateLinks bool // Accept several links with the same origin, target and label
+}
+
+// renderIntegrity is the integrity checked by every renderer, on top of the rules of its format:
+// every link must have an origin and a target that are in the flowchart, but self-loops and
+// duplicate links are drawn as they are.
+var renderIntegrity = IntegrityOptions{AllowSelfLoops: true, AllowDuplicateLinks: true}
+
+// CheckIntegrity resolves the endpoints of every link in the flowchart tree and reports:
+//   - links without an origin or target (ErrMissingEndpoint),
+//   - endpoints that are not a node or subgraph of the flowchart tree, such as nodes that were never
+//     added with AddNode (ErrOrphanedEndpoint),
+//   - endpoints that are subgraphs without a title, which renderers cannot refer to (ErrUntitledEndpoint),
+//   - links repeating the origin, target and label of an earlier link (ErrDuplicateLink), and
+//   - links from a node or subgraph to itself (ErrSelfLoop),
+//
+// unless the options allow them. Endpoints are resolved by name, as every renderer resolves them,
+// so an endpoint that is a copy of a node or subgraph of the tree, with the same name, stands for
+// that element, and a node is only orphaned if no node of the tree has its name.
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func CheckIntegrity(f *Flowchart, opts IntegrityOptions) error {
+	return newValidationError(integrityViolations(f, opts)...)
+}
+
+// integrityViolations returns the violations reported by CheckIntegrity.
+func integrityViolations(f *Flowchart, opts IntegrityOptions) []Violation {
+	elements := newTreeElements(f)
+	var violations []Violation
+	type linkKey struct{ origin, target, label string }
+	seen := make(map[linkKey]bool)
+	walkFlowchart(f, func(f *Flowchart, path []string) {
+		for i := range f.Links {
+			link := &f.Links[i]
+			violation := func(code error, format string, args ...any) {
+				violations = append(violations, Violation{
+					Code:    code,
+					Path:    path,
+					Element: link,
+					Message: describeLink(link) + " " + fmt.Sprintf(format, args...),
+				})
+			}
+
+			if isNilLinkable(link.Origin) || isNilLinkable(link.Target) {
+				violation(ErrMissingEndpoint, "has no origin or target")
+				continue
+			}
+			resolved := true
+			for _, end := range []struct {
+				role     string
+				linkable Linkable
+			}{{"origin", link.Origin}, {"target", link.Target}} {
+				switch endpoint := end.linkable.(type) {
+				case *Node:
+					if elements.resolve(endpoint) == nil {
+						violation(ErrOrphanedEndpoint, "has %s node %q, which is not in the flowchart", end.role, endpoint.name)
+						resolved = false
+					}
+				case *Flowchart:
+					if endpoint.Title == nil || *endpoint.Title == "" {
+						violation(ErrUntitledEndpoint, "has an untitled subgraph as %s", end.role)
+						resolved = false
+					} else if elements.resolve(endpoint) == nil {
+						violation(ErrOrphanedEndpoint, "has %s subgraph %q, which is not in the flowchart", end.role, *endpoint.Title)
+						resolved = false
+					}
+				}
+			}
+			if !resolved {
+				continue
+			}
+
+			if !opts.AllowSelfLoops && link.Origin.nodeName() == link.Target.nodeName() {
+				violation(ErrSelfLoop, "starts and ends at the same element")
+			}
+			key := linkKey{link.Origin.nodeName(), link.Target.nodeName(), ""}
+			if link.Label != nil {
+				key.label = *link.Label
+			}
+			if !opts.AllowDuplicateLinks && seen[key] {
+				violation(ErrDuplicateLink, "repeats an earlier link")
+			}
+			seen[key] = true
+		}
+	})
+	return violations
+}
+
+// treeElements holds the nodes and titled subgraphs of a flowchart tree by name.
+type treeElements struct {
+	nodes     map[string]*Node
+	subgraphs map[string]*Flowchart
+}
+
+// newTreeElements collects the nodes and titled subgraphs of the flowchart tree. When several share
+// a name, the first in tree order is kept.
+func newTreeElements(f *Flowchart) treeElements {
+	t := treeElements{nodes: make(map[string]*Node), subgraphs: make(map[string]*Flowchart)}
+	walkFlowchart(f, func(f *Flowchart, _ []string) {
+		for _, node := range f.Nodes {
+			if _, ok := t.nodes[node.name]; !ok {
+				t.nodes[node.name] = node
+			}
+		}
+		for _, subgraph := range f.Subgraphs {
+			if title := subgraph.nodeName(); title != "" {
+				if _, ok := t.subgraphs[title]; !ok {
+					t.subgraphs[title] = subgraph
+				}
+			}
+		}
+	})
+	return t
+}
+
+// resolve returns the node or subgraph of the tree that a link endpoint stands for: the node with
+// the name of a node, or the subgraph with the title of a subgraph. Renderers that keep state by
+// element, rather than by name, resolve endpoints with it, so a link to a copy of an element is
+// drawn to the element itself. It returns nil if the tree has no such element; untitled subgraphs
+// are returned as they are.
+func (t treeElements) resolve(l Linkable) Linkable {
+	switch l := l.(type) {
+	case *Node:
+		if node, ok := t.nodes[l.name]; ok {
+			return node
+		}
+	case *Flowchart:
+		if l.nodeName() == "" {
+			return l
+		}
+		if subgraph, ok := t.subgraphs[l.nodeName()]; ok {
+			return subgraph
+		}
+	}
+	return nil
+}
+
+// isNilLinkable reports whether l is nil or a nil *Node or *Flowchart.
+func isNilLinkable(l Linkable) bool {
+	switch l := l.(type) {
+	case *Node:
+		return l == nil
+	case *Flowchart:
+		return l == nil
+	}
+	return l == nil
+}
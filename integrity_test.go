@@ -0,0 +1,189 @@
+package flowchart
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	a := ProcessNode("A", nil)
+	b := ProcessNode("B", nil)
+	group := &Flowchart{Title: pointTo("Group"), Nodes: []*Node{b}}
+	untitled := &Flowchart{}
+
+	// chart returns a flowchart holding a, b, group and untitled, with the given links.
+	chart := func(links ...Link) *Flowchart {
+		return &Flowchart{
+			Nodes:     []*Node{a},
+			Subgraphs: []*Flowchart{group, untitled},
+			Links:     links,
+		}
+	}
+
+	tests := []struct {
+		name             string
+		flowchart        *Flowchart
+		opts             IntegrityOptions
+		expectedCodes    []error
+		expectedMessages []string
+	}{
+		{
+			name:      "links between nodes and subgraphs",
+			flowchart: chart(SolidLink(a, b, nil), SolidLink(a, group, nil), SolidLink(group, a, nil)),
+		},
+		{
+			name:      "copy of a node with the same name",
+			flowchart: chart(SolidLink(ProcessNode("A", nil), b, nil)),
+		},
+		{
+			name:             "missing endpoints",
+			flowchart:        chart(Link{Origin: a}, Link{Origin: (*Node)(nil), Target: b}),
+			expectedCodes:    []error{ErrMissingEndpoint, ErrMissingEndpoint},
+			expectedMessages: []string{`link from "A" to "?" has no origin or target`, `link from "?" to "B" has no origin or target`},
+		},
+		{
+			name:             "node never added",
+			flowchart:        chart(SolidLink(a, ProcessNode("Phantom", nil), nil)),
+			expectedCodes:    []error{ErrOrphanedEndpoint},
+			expectedMessages: []string{`link from "A" to "Phantom" has target node "Phantom", which is not in the flowchart`},
+		},
+		{
+			name:             "subgraph never added",
+			flowchart:        chart(SolidLink(&Flowchart{Title: pointTo("Elsewhere")}, a, nil)),
+			expectedCodes:    []error{ErrOrphanedEndpoint},
+			expectedMessages: []string{`link from "Elsewhere" to "A" has origin subgraph "Elsewhere", which is not in the flowchart`},
+		},
+		{
+			name:             "node name used as subgraph",
+			flowchart:        chart(SolidLink(a, &Flowchart{Title: pointTo("A")}, nil)),
+			expectedCodes:    []error{ErrOrphanedEndpoint},
+			expectedMessages: []string{`link from "A" to "A" has target subgraph "A", which is not in the flowchart`},
+		},
+		{
+			name:             "untitled subgraph",
+			flowchart:        chart(SolidLink(a, untitled, nil)),
+			expectedCodes:    []error{ErrUntitledEndpoint},
+			expectedMessages: []string{`link from "A" to "" has an untitled subgraph as target`},
+		},
+		{
+			name:             "self-loop",
+			flowchart:        chart(SolidLink(a, a, nil)),
+			expectedCodes:    []error{ErrSelfLoop},
+			expectedMessages: []string{`link from "A" to "A" starts and ends at the same element`},
+		},
+		{
+			name:      "allowed self-loop",
+			flowchart: chart(SolidLink(a, a, nil)),
+			opts:      IntegrityOptions{AllowSelfLoops: true},
+		},
+		{
+			name:             "duplicate link",
+			flowchart:        chart(SolidLink(a, b, nil), DottedLink(a, b, nil), SolidLink(a, b, pointTo("other"))),
+			expectedCodes:    []error{ErrDuplicateLink},
+			expectedMessages: []string{`link from "A" to "B" repeats an earlier link`},
+		},
+		{
+			name:      "allowed duplicate link",
+			flowchart: chart(SolidLink(a, b, nil), SolidLink(a, b, nil)),
+			opts:      IntegrityOptions{AllowDuplicateLinks: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckIntegrity(tt.flowchart, tt.opts)
+			var codes []error
+			var messages []string
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				for _, v := range validationErr.Violations {
+					codes = append(codes, v.Code)
+					messages = append(messages, v.Message)
+				}
+			} else if err != nil {
+				t.Fatalf("CheckIntegrity() returned %T, expected *ValidationError", err)
+			}
+			if diff := cmp.Diff(tt.expectedCodes, codes, cmp.Comparer(func(a, b error) bool { return a == b })); diff != "" {
+				t.Errorf("CheckIntegrity() codes mismatch (-expected +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.expectedMessages, messages); diff != "" {
+				t.Errorf("CheckIntegrity() messages mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderersCheckIntegrity(t *testing.T) {
+	a := ProcessNode("A", nil)
+	chart := &Flowchart{
+		Nodes: []*Node{a},
+		Links: []Link{SolidLink(a, ProcessNode("Phantom", nil), nil), SolidLink(a, a, nil)},
+	}
+
+	renderers := map[string]func(*Flowchart) (string, error){
+		"RenderMermaid": RenderMermaid,
+		"RenderDOT":     RenderDOT,
+	}
+	for name, render := range renderers {
+		t.Run(name, func(t *testing.T) {
+			_, err := render(chart)
+			if !errors.Is(err, ErrOrphanedEndpoint) {
+				t.Errorf("%s() error = %v, expected %v", name, err, ErrOrphanedEndpoint)
+			}
+			if errors.Is(err, ErrSelfLoop) {
+				t.Errorf("%s() error = %v, expected self-loops to be rendered", name, err)
+			}
+		})
+	}
+}
+
+func TestRenderersResolveEndpointCopies(t *testing.T) {
+	// chart returns a flowchart with links into nested subgraphs, whose endpoints are the nodes
+	// and subgraphs of the tree or, with copies, other elements with the same names.
+	chart := func(copies bool) *Flowchart {
+		start, a, e, x := TerminatorNode("Start", nil), ProcessNode("A", nil), TerminatorNode("E", nil), ProcessNode("X", nil)
+		inner := &Flowchart{Title: pointTo("Inner"), Nodes: []*Node{x}}
+		group := &Flowchart{Title: pointTo("G"), Nodes: []*Node{a, e}, Subgraphs: []*Flowchart{inner}}
+		f := &Flowchart{Nodes: []*Node{start}, Subgraphs: []*Flowchart{group}}
+		if copies {
+			start, a, e, x = TerminatorNode("Start", nil), ProcessNode("A", nil), TerminatorNode("E", nil), ProcessNode("X", nil)
+			inner, group = &Flowchart{Title: pointTo("Inner")}, &Flowchart{Title: pointTo("G")}
+		}
+		f.Links = []Link{SolidLink(start, group, nil), SolidLink(a, e, nil), SolidLink(x, e, nil), SolidLink(start, inner, nil)}
+		return f
+	}
+
+	renderers := map[string]func(*Flowchart) (string, error){
+		"RenderMermaid":  RenderMermaid,
+		"RenderDOT":      RenderDOT,
+		"RenderPlantUML": RenderPlantUML,
+		"RenderD2":       RenderD2,
+		"RenderSVG":      RenderSVG,
+		"RenderDrawio":   RenderDrawio,
+		"RenderBPMN":     RenderBPMN,
+		"RenderGraphML":  RenderGraphML,
+		"RenderGEXF":     RenderGEXF,
+		"RenderText":     func(f *Flowchart) (string, error) { return RenderText(f, TextOptions{}) },
+		"RenderPNG": func(f *Flowchart) (string, error) {
+			png, err := RenderPNG(f, RasterOptions{})
+			return string(png), err
+		},
+	}
+	for name, render := range renderers {
+		t.Run(name, func(t *testing.T) {
+			expected, err := render(chart(false))
+			if err != nil {
+				t.Fatalf("%s() unexpected error: %v", name, err)
+			}
+			got, err := render(chart(true))
+			if err != nil {
+				t.Fatalf("%s() with copies unexpected error: %v", name, err)
+			}
+			if got != expected {
+				t.Errorf("%s() with copies of the endpoints differs from the chart with the endpoints themselves", name)
+			}
+		})
+	}
+}
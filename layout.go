@@ -0,0 +1,941 @@
+package flowchart
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Point is a position in a layout, in pixels from its top-left corner.
+type Point struct {
+	X, Y float64
+}
+
+// Rect is an axis-aligned rectangle in a layout, given by its top-left corner and its size.
+type Rect struct {
+	X, Y, Width, Height float64
+}
+
+// Center returns the center of the rectangle.
+func (r Rect) Center() Point {
+	return Point{r.X + r.Width/2, r.Y + r.Height/2}
+}
+
+// Layout holds the coordinates computed by ComputeLayout for drawing a flowchart.
+type Layout struct {
+	Width    float64         // Width of the drawing, including the margins
+	Height   float64         // Height of the drawing, including the margins
+	Nodes    []NodeLayout    // Nodes of the flowchart tree, in tree order
+	Clusters []ClusterLayout // Subgraphs containing a node or titled, outer subgraphs before inner ones
+	Edges    []EdgeLayout    // Links, in the order in which they are rendered
+}
+
+// NodeLayout is the position of a node in a layout.
+type NodeLayout struct {
+	Node *Node // The node
+	Rect Rect  // Bounding box of the node's shape
+	Rank int   // Layer of the node, 0 for the first layer in the flow direction
+}
+
+// ClusterLayout is the box drawn around the nodes of a subgraph.
+type ClusterLayout struct {
+	Subgraph *Flowchart // The subgraph
+	Rect     Rect       // The box, with room for the title at its top
+	Depth    int        // Nesting depth, 1 for the subgraphs of the root flowchart
+}
+
+// EdgeLayout is the route of a link in a layout.
+type EdgeLayout struct {
+	Link   *Link   // The link
+	Points []Point // Polyline from the border of the origin to the border of the target
+}
+
+// Midpoint returns the point halfway along the polyline of the edge, where its label is drawn.
+func (e EdgeLayout) Midpoint() Point {
+	var length float64
+	for i := 1; i < len(e.Points); i++ {
+		length += distance(e.Points[i-1], e.Points[i])
+	}
+	remaining := length / 2
+	for i := 1; i < len(e.Points); i++ {
+		segment := distance(e.Points[i-1], e.Points[i])
+		if segment >= remaining && segment > 0 {
+			t := remaining / segment
+			return Point{
+				e.Points[i-1].X + t*(e.Points[i].X-e.Points[i-1].X),
+				e.Points[i-1].Y + t*(e.Points[i].Y-e.Points[i-1].Y),
+			}
+		}
+		remaining -= segment
+	}
+	if len(e.Points) == 0 {
+		return Point{}
+	}
+	return e.Points[0]
+}
+
+// LayoutOptions controls the spacing and node sizes used by ComputeLayout. Zero values select
+// the defaults.
+type LayoutOptions struct {
+	NodeSpacing    float64                               // Space between neighbouring nodes of a layer; default 30
+	RankSpacing    float64                               // Space between consecutive layers; default 50
+	ClusterPadding float64                               // Space between a subgraph's box and its contents; default 15
+	Margin         float64                               // Space around the drawing; default 20
+	NodeSize       func(n *Node) (width, height float64) // Size of each node's shape; default estimated from its label
+}
+
+// Measurements of the default label font, used to estimate the size of nodes and titles.
+const (
+	layoutCharWidth    = 8.0  // Average width of a character
+	layoutLineHeight   = 18.0 // Height of a line of text
+	layoutPaddingX     = 16.0 // Space between a label and the left and right of its shape
+	layoutPaddingY     = 10.0 // Space between a label and the top and bottom of its shape
+	layoutMinNodeWidth = 40.0 // Width of the narrowest node
+)
+
+// withDefaults returns the options with zero values replaced by the defaults.
+func (o LayoutOptions) withDefaults() LayoutOptions {
+	if o.NodeSpacing == 0 {
+		o.NodeSpacing = 30
+	}
+	if o.RankSpacing == 0 {
+		o.RankSpacing = 50
+	}
+	if o.ClusterPadding == 0 {
+		o.ClusterPadding = 15
+	}
+	if o.Margin == 0 {
+		o.Margin = 20
+	}
+	if o.NodeSize == nil {
+		o.NodeSize = defaultNodeSize
+	}
+	return o
+}
+
+// nodeText returns the text drawn inside a node: its label, or its name if it has none, without
+// markdown markers.
+func nodeText(n *Node) string {
+	text := n.name
+	if n.Label != nil {
+		text = *n.Label
+	}
+	if n.LabelFormat == LabelFormatMarkdown {
+		text = strings.NewReplacer("**", "", "_", "").Replace(text)
+	}
+	return text
+}
+
+// textSize estimates the size of a block of text in the default label font.
+func textSize(text string) (float64, float64) {
+	lines := strings.Split(text, "\n")
+	widest := 0
+	for _, line := range lines {
+		widest = max(widest, utf8.RuneCountInString(line))
+	}
+	return float64(widest) * layoutCharWidth, float64(len(lines)) * layoutLineHeight
+}
+
+// defaultNodeSize estimates the size of a node's shape from its text: decisions are diamonds and
+// connectors circles large enough to hold the text, and databases leave room for their lids.
+func defaultNodeSize(n *Node) (float64, float64) {
+	w, h := textSize(nodeText(n))
+	w = max(w+2*layoutPaddingX, layoutMinNodeWidth)
+	h += 2 * layoutPaddingY
+	switch n.Type {
+	case NodeTypeDecision:
+		return w + h, w + h
+	case NodeTypeConnector:
+		d := math.Hypot(w, h)
+		return d, d
+	case NodeTypeDatabase:
+		return w, h + layoutPaddingY
+	case NodeTypeInputOutput:
+		return w + h/2, h
+	}
+	return w, h
+}
+
+// layoutVertex is a node of the layered graph: a flowchart node, the empty space inside a titled
+// subgraph without nodes, or a dummy point where an edge crosses a layer.
+type layoutVertex struct {
+	node       *Node           // The node, or nil for empty space and dummies
+	empty      bool            // Whether the vertex is the empty space inside a subgraph
+	cluster    *layoutCluster  // Innermost cluster containing the vertex, or nil for the root
+	rank       int             // Layer of the vertex
+	order      int             // Position of the vertex within its layer
+	width      float64         // Width of the node's shape
+	height     float64         // Height of the node's shape
+	breadth    float64         // Extent of the vertex along its layer
+	pos        float64         // Position of the vertex's center along its layer
+	succ, pred []*layoutVertex // Neighbours in the next and previous layers
+}
+
+// layoutCluster is a subgraph of the layered graph.
+type layoutCluster struct {
+	subgraph *Flowchart
+	parent   *layoutCluster
+	depth    int
+	members  []*layoutVertex // Nodes inside the subgraph, at any depth
+	minRank  int
+	maxRank  int
+	rect     Rect
+}
+
+// ancestors returns the cluster and its parents, innermost first. The root is nil and not included.
+func (c *layoutCluster) ancestors() []*layoutCluster {
+	var chain []*layoutCluster
+	for ; c != nil; c = c.parent {
+		chain = append(chain, c)
+	}
+	return chain
+}
+
+// commonCluster returns the innermost cluster containing both clusters, or nil for the root.
+func commonCluster(a, b *layoutCluster) *layoutCluster {
+	chain := a.ancestors()
+	for ; b != nil; b = b.parent {
+		if slices.Contains(chain, b) {
+			return b
+		}
+	}
+	return nil
+}
+
+// layoutEdge is a link routed through the layered graph.
+type layoutEdge struct {
+	link          *Link
+	chain         []*layoutVertex // Vertices from origin to target, including dummies
+	originCluster *layoutCluster  // Cluster the edge leaves from, if the origin is a subgraph
+	targetCluster *layoutCluster  // Cluster the edge enters, if the target is a subgraph
+}
+
+// layeredGraph holds the state of ComputeLayout.
+type layeredGraph struct {
+	opts       LayoutOptions
+	direction  DirectionEnum
+	vertices   []*layoutVertex // Nodes and empty spaces in tree order, followed by dummies
+	nodes      map[string]*layoutVertex
+	clusters   []*layoutCluster // Clusters in tree order
+	titled     map[string]*layoutCluster
+	edges      []*layoutEdge
+	ranks      [][]*layoutVertex
+	titleSpace float64
+}
+
+// ComputeLayout arranges the flowchart in layers, following the Sugiyama method used by Graphviz
+// dot and dagre, so that it can be drawn without an external layout engine:
+//  1. links that close cycles are reversed, and nodes are assigned to layers by longest path so
+//     that links point forward;
+//  2. links spanning several layers get a dummy point in each layer they cross;
+//  3. nodes are ordered within their layers by repeated barycenter sweeps to reduce crossings,
+//     keeping the nodes of each subgraph together;
+//  4. nodes are placed close to the average of their neighbours, and layers are stacked in the
+//     direction of the flowchart (top to bottom, left to right or right to left).
+//
+// Subgraphs are drawn as boxes around their nodes; their own Direction is ignored. A link to or
+// from a subgraph ends at the border of its box, and a link between a subgraph and an element
+// nested in it is drawn as a loop, like a link from a node to itself. Titled subgraphs without
+// nodes are drawn as empty boxes, so that they can be linked; untitled ones are left out. Links
+// with LineTypeNone are laid out like any other, so that they affect the placement of nodes as they
+// do in Mermaid, and renderers may choose not to draw them.
+// It returns an error if the flowchart has repeated names or links to elements it does not contain.
+func ComputeLayout(f *Flowchart, opts LayoutOptions) (*Layout, error) {
+	if err := validateLayout(f); err != nil {
+		return nil, err
+	}
+
+	g := &layeredGraph{
+		opts:       opts.withDefaults(),
+		direction:  f.Direction,
+		nodes:      make(map[string]*layoutVertex),
+		titled:     make(map[string]*layoutCluster),
+		titleSpace: layoutLineHeight + 4,
+	}
+	g.collect(f, nil)
+	g.rank(g.addEdges(f))
+	g.order()
+	g.place()
+	return g.layout(), nil
+}
+
+// validateLayout checks that the flowchart can be laid out: names must be unique and links must
+// refer to elements of the flowchart, as for every renderer.
+func validateLayout(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	return newValidationError(violations...)
+}
+
+// collect adds the nodes and subgraphs of the flowchart tree to the graph, returning the nodes
+// inside f at any depth.
+func (g *layeredGraph) collect(f *Flowchart, cluster *layoutCluster) []*layoutVertex {
+	var inside []*layoutVertex
+	for _, node := range f.Nodes {
+		w, h := g.opts.NodeSize(node)
+		v := &layoutVertex{node: node, cluster: cluster, width: w, height: h, breadth: w}
+		if g.direction != DirectionVertical {
+			v.breadth = h
+		}
+		g.nodes[node.name] = v
+		g.vertices = append(g.vertices, v)
+		inside = append(inside, v)
+	}
+	for _, subgraph := range f.Subgraphs {
+		c := &layoutCluster{subgraph: subgraph, parent: cluster, depth: 1}
+		if cluster != nil {
+			c.depth = cluster.depth + 1
+		}
+		g.clusters = append(g.clusters, c)
+		if subgraph.Title != nil && *subgraph.Title != "" {
+			g.titled[*subgraph.Title] = c
+		}
+		c.members = g.collect(subgraph, c)
+		if len(c.members) == 0 && subgraph.nodeName() != "" {
+			v := &layoutVertex{cluster: c, empty: true, width: layoutMinNodeWidth, height: layoutLineHeight}
+			v.breadth = v.width
+			if g.direction != DirectionVertical {
+				v.breadth = v.height
+			}
+			g.vertices = append(g.vertices, v)
+			c.members = []*layoutVertex{v}
+		}
+		inside = append(inside, c.members...)
+	}
+	return inside
+}
+
+// resolve returns the vertices a link endpoint stands for, and the cluster if it is a subgraph.
+func (g *layeredGraph) resolve(l Linkable) ([]*layoutVertex, *layoutCluster) {
+	switch l := l.(type) {
+	case *Node:
+		return []*layoutVertex{g.nodes[l.name]}, nil
+	case *Flowchart:
+		c := g.titled[l.nodeName()]
+		return c.members, c
+	}
+	return nil, nil
+}
+
+// addEdges records the links of the flowchart tree, in the order in which they are rendered, and
+// returns the edges between nodes used for ranking, where subgraph endpoints stand for all of
+// their nodes.
+func (g *layeredGraph) addEdges(f *Flowchart) [][2]*layoutVertex {
+	var links []*Link
+	walkFlowchart(f, func(f *Flowchart, _ []string) {
+		for i := range f.Links {
+			links = append(links, &f.Links[i])
+		}
+	})
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Origin.nodeName() == links[j].Origin.nodeName() {
+			return links[i].Target.nodeName() < links[j].Target.nodeName()
+		}
+		return links[i].Origin.nodeName() < links[j].Origin.nodeName()
+	})
+
+	var rankEdges [][2]*layoutVertex
+	for _, link := range links {
+		origins, originCluster := g.resolve(link.Origin)
+		targets, targetCluster := g.resolve(link.Target)
+		if len(origins) == 0 || len(targets) == 0 {
+			continue
+		}
+		g.edges = append(g.edges, &layoutEdge{link: link, originCluster: originCluster, targetCluster: targetCluster})
+		for _, origin := range origins {
+			for _, target := range targets {
+				if origin != target {
+					rankEdges = append(rankEdges, [2]*layoutVertex{origin, target})
+				}
+			}
+		}
+	}
+	return rankEdges
+}
+
+// rank assigns layers to the nodes by longest path, after reversing the edges that close cycles,
+// then routes every edge through dummy vertices in the layers it crosses.
+func (g *layeredGraph) rank(edges [][2]*layoutVertex) {
+	out := make(map[*layoutVertex][]*layoutVertex)
+	for _, e := range edges {
+		out[e[0]] = append(out[e[0]], e[1])
+	}
+
+	// A depth-first search in tree order finds the edges closing cycles, which point back to a
+	// vertex on the stack; the others form a DAG. Post-order gives a reverse topological order.
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make(map[*layoutVertex]int)
+	in := make(map[*layoutVertex][]*layoutVertex)
+	var postOrder []*layoutVertex
+	var visit func(v *layoutVertex)
+	visit = func(v *layoutVertex) {
+		state[v] = onStack
+		for _, w := range out[v] {
+			switch state[w] {
+			case unvisited:
+				in[w] = append(in[w], v)
+				visit(w)
+			case done:
+				in[w] = append(in[w], v)
+			case onStack:
+				in[v] = append(in[v], w)
+			}
+		}
+		state[v] = done
+		postOrder = append(postOrder, v)
+	}
+	for _, v := range g.vertices {
+		if state[v] == unvisited {
+			visit(v)
+		}
+	}
+	for i := len(postOrder) - 1; i >= 0; i-- {
+		v := postOrder[i]
+		for _, u := range in[v] {
+			v.rank = max(v.rank, u.rank+1)
+		}
+	}
+
+	maxRank := 0
+	for _, v := range g.vertices {
+		maxRank = max(maxRank, v.rank)
+	}
+	for _, c := range g.clusters {
+		c.minRank, c.maxRank = math.MaxInt, -1
+		for _, v := range c.members {
+			c.minRank = min(c.minRank, v.rank)
+			c.maxRank = max(c.maxRank, v.rank)
+		}
+	}
+
+	for _, e := range g.edges {
+		origin := g.endpoint(e.link.Origin, e.originCluster, func(a, b *layoutVertex) bool { return a.rank > b.rank })
+		target := g.endpoint(e.link.Target, e.targetCluster, func(a, b *layoutVertex) bool { return a.rank < b.rank })
+		e.chain = []*layoutVertex{origin}
+		if origin == target {
+			e.chain = append(e.chain, target)
+			continue
+		}
+
+		// Dummies belong to the innermost cluster holding both ends, outside the subgraphs
+		// the edge leaves or enters.
+		originContext, targetContext := origin.cluster, target.cluster
+		if e.originCluster != nil {
+			originContext = e.originCluster.parent
+		}
+		if e.targetCluster != nil {
+			targetContext = e.targetCluster.parent
+		}
+		cluster := commonCluster(originContext, targetContext)
+		step := 1
+		if target.rank < origin.rank {
+			step = -1
+		}
+		for r := origin.rank + step; r != target.rank && origin.rank != target.rank; r += step {
+			dummy := &layoutVertex{cluster: cluster, rank: r}
+			g.vertices = append(g.vertices, dummy)
+			e.chain = append(e.chain, dummy)
+		}
+		e.chain = append(e.chain, target)
+		for i := 1; i < len(e.chain); i++ {
+			u, v := e.chain[i-1], e.chain[i]
+			if u.rank > v.rank {
+				u, v = v, u
+			}
+			if u.rank < v.rank {
+				u.succ = append(u.succ, v)
+				v.pred = append(v.pred, u)
+			}
+		}
+	}
+
+	g.ranks = make([][]*layoutVertex, maxRank+1)
+	g.initialOrder()
+}
+
+// endpoint returns the vertex an edge is routed to for one of its endpoints: the node itself, or
+// for a subgraph the first of its nodes that is preferred by better.
+func (g *layeredGraph) endpoint(l Linkable, cluster *layoutCluster, better func(a, b *layoutVertex) bool) *layoutVertex {
+	if cluster == nil {
+		return g.nodes[l.nodeName()]
+	}
+	best := cluster.members[0]
+	for _, v := range cluster.members[1:] {
+		if better(v, best) {
+			best = v
+		}
+	}
+	return best
+}
+
+// initialOrder fills the layers by a depth-first search from the nodes in tree order, so that
+// connected nodes start close to each other.
+func (g *layeredGraph) initialOrder() {
+	visited := make(map[*layoutVertex]bool)
+	var visit func(v *layoutVertex)
+	visit = func(v *layoutVertex) {
+		visited[v] = true
+		v.order = len(g.ranks[v.rank])
+		g.ranks[v.rank] = append(g.ranks[v.rank], v)
+		for _, w := range v.succ {
+			if !visited[w] {
+				visit(w)
+			}
+		}
+	}
+	for _, v := range g.vertices {
+		if !visited[v] {
+			visit(v)
+		}
+	}
+	for i := range g.ranks {
+		g.ranks[i] = g.groupClusters(g.ranks[i], nil)
+	}
+}
+
+// order reduces edge crossings by sweeping down and up the layers, sorting each layer by the
+// average position of the neighbours in the layer just placed, and keeps the best ordering found.
+func (g *layeredGraph) order() {
+	best := g.snapshot()
+	bestCrossings := g.crossings()
+	for sweep := 0; sweep < 8 && bestCrossings > 0; sweep++ {
+		if sweep%2 == 0 {
+			for r := 1; r < len(g.ranks); r++ {
+				g.sortByBarycenter(r, func(v *layoutVertex) []*layoutVertex { return v.pred })
+			}
+		} else {
+			for r := len(g.ranks) - 2; r >= 0; r-- {
+				g.sortByBarycenter(r, func(v *layoutVertex) []*layoutVertex { return v.succ })
+			}
+		}
+		if crossings := g.crossings(); crossings < bestCrossings {
+			best, bestCrossings = g.snapshot(), crossings
+		}
+	}
+	g.ranks = best
+	for _, rank := range g.ranks {
+		for i, v := range rank {
+			v.order = i
+		}
+	}
+}
+
+// snapshot returns a copy of the current ordering of the layers.
+func (g *layeredGraph) snapshot() [][]*layoutVertex {
+	ranks := make([][]*layoutVertex, len(g.ranks))
+	for i, rank := range g.ranks {
+		ranks[i] = slices.Clone(rank)
+	}
+	return ranks
+}
+
+// sortByBarycenter sorts layer r by the average position of each vertex's neighbours. Vertices
+// without neighbours keep their position.
+func (g *layeredGraph) sortByBarycenter(r int, neighbours func(*layoutVertex) []*layoutVertex) {
+	barycenter := make(map[*layoutVertex]float64)
+	for _, v := range g.ranks[r] {
+		barycenter[v] = float64(v.order)
+		if ns := neighbours(v); len(ns) > 0 {
+			sum := 0.0
+			for _, n := range ns {
+				sum += float64(n.order)
+			}
+			barycenter[v] = sum / float64(len(ns))
+		}
+	}
+	rank := g.ranks[r]
+	sort.SliceStable(rank, func(i, j int) bool { return barycenter[rank[i]] < barycenter[rank[j]] })
+	g.ranks[r] = g.groupClusters(rank, nil)
+	for i, v := range g.ranks[r] {
+		v.order = i
+	}
+}
+
+// groupClusters reorders the vertices of a layer inside cluster so that the vertices of each
+// child cluster are contiguous, placing each child cluster at the average position of its vertices.
+func (g *layeredGraph) groupClusters(vertices []*layoutVertex, cluster *layoutCluster) []*layoutVertex {
+	type item struct {
+		cluster  *layoutCluster // Child cluster, or nil for a vertex directly in cluster
+		vertices []*layoutVertex
+		position float64
+	}
+	var items []*item
+	byCluster := make(map[*layoutCluster]*item)
+	for i, v := range vertices {
+		// Find the child of cluster that holds v.
+		var child *layoutCluster
+		for c := v.cluster; c != cluster; c = c.parent {
+			child = c
+		}
+		it := byCluster[child]
+		if child == nil || it == nil {
+			it = &item{cluster: child}
+			items = append(items, it)
+			if child != nil {
+				byCluster[child] = it
+			}
+		}
+		it.vertices = append(it.vertices, v)
+		it.position += float64(i)
+	}
+	for _, it := range items {
+		it.position /= float64(len(it.vertices))
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].position < items[j].position })
+
+	grouped := make([]*layoutVertex, 0, len(vertices))
+	for _, it := range items {
+		if it.cluster == nil {
+			grouped = append(grouped, it.vertices...)
+		} else {
+			grouped = append(grouped, g.groupClusters(it.vertices, it.cluster)...)
+		}
+	}
+	return grouped
+}
+
+// crossings counts the pairs of edges that cross between consecutive layers.
+func (g *layeredGraph) crossings() int {
+	position := make(map[*layoutVertex]int)
+	for _, rank := range g.ranks {
+		for i, v := range rank {
+			position[v] = i
+		}
+	}
+	count := 0
+	for _, rank := range g.ranks {
+		var edges [][2]int
+		for _, u := range rank {
+			for _, v := range u.succ {
+				edges = append(edges, [2]int{position[u], position[v]})
+			}
+		}
+		for i := range edges {
+			for j := i + 1; j < len(edges); j++ {
+				if (edges[i][0]-edges[j][0])*(edges[i][1]-edges[j][1]) < 0 {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// separation returns the minimum distance between the centers of two neighbouring vertices of a
+// layer, a before b, leaving room for the borders of the clusters between them.
+func (g *layeredGraph) separation(a, b *layoutVertex) float64 {
+	spacing := g.opts.NodeSpacing
+	if (a.node == nil && !a.empty) || (b.node == nil && !b.empty) {
+		spacing /= 2
+	}
+	common := commonCluster(a.cluster, b.cluster)
+	for c := a.cluster; c != common; c = c.parent {
+		spacing += g.opts.ClusterPadding
+	}
+	for c := b.cluster; c != common; c = c.parent {
+		spacing += g.opts.ClusterPadding
+		if g.direction != DirectionVertical {
+			spacing += g.titleSpace // Titles are on the near side of the layer in horizontal layouts
+		}
+	}
+	return (a.breadth+b.breadth)/2 + spacing
+}
+
+// place positions the vertices along their layers, moving each towards the average position of
+// its neighbours while keeping the order and separation of each layer.
+func (g *layeredGraph) place() {
+	for _, rank := range g.ranks {
+		for i, v := range rank {
+			if i > 0 {
+				v.pos = rank[i-1].pos + g.separation(rank[i-1], v)
+			}
+		}
+	}
+	for pass := 0; pass < 8; pass++ {
+		if pass%2 == 0 {
+			for r := 1; r < len(g.ranks); r++ {
+				g.align(g.ranks[r], func(v *layoutVertex) []*layoutVertex { return v.pred })
+			}
+		} else {
+			for r := len(g.ranks) - 2; r >= 0; r-- {
+				g.align(g.ranks[r], func(v *layoutVertex) []*layoutVertex { return v.succ })
+			}
+		}
+	}
+}
+
+// align moves the vertices of a layer towards the average position of their neighbours. It
+// packs the layer once from the left and once from the right and takes the mean of both
+// placements, which keeps the required separation.
+func (g *layeredGraph) align(rank []*layoutVertex, neighbours func(*layoutVertex) []*layoutVertex) {
+	desired := make([]float64, len(rank))
+	for i, v := range rank {
+		desired[i] = v.pos
+		if ns := neighbours(v); len(ns) > 0 {
+			sum := 0.0
+			for _, n := range ns {
+				sum += n.pos
+			}
+			desired[i] = sum / float64(len(ns))
+		}
+	}
+	left := slices.Clone(desired)
+	for i := 1; i < len(rank); i++ {
+		left[i] = max(left[i], left[i-1]+g.separation(rank[i-1], rank[i]))
+	}
+	right := slices.Clone(desired)
+	for i := len(rank) - 2; i >= 0; i-- {
+		right[i] = min(right[i], right[i+1]-g.separation(rank[i], rank[i+1]))
+	}
+	for i, v := range rank {
+		v.pos = (left[i] + right[i]) / 2
+	}
+}
+
+// rankCenters returns the position of the center of each layer along the flow, leaving room
+// between layers for the borders and titles of the clusters that end or start there.
+func (g *layeredGraph) rankCenters() []float64 {
+	extent := make([]float64, len(g.ranks))
+	for r, rank := range g.ranks {
+		for _, v := range rank {
+			if g.direction == DirectionVertical {
+				extent[r] = max(extent[r], v.height)
+			} else {
+				extent[r] = max(extent[r], v.width)
+			}
+		}
+	}
+	centers := make([]float64, len(g.ranks))
+	for r := 1; r < len(g.ranks); r++ {
+		gap := g.opts.RankSpacing
+		for _, c := range g.clusters {
+			if c.maxRank == r-1 {
+				gap += g.opts.ClusterPadding
+			}
+			if c.minRank == r {
+				gap += g.opts.ClusterPadding
+				if g.direction == DirectionVertical {
+					gap += g.titleSpace
+				}
+			}
+		}
+		centers[r] = centers[r-1] + extent[r-1]/2 + gap + extent[r]/2
+	}
+	return centers
+}
+
+// layout converts the positions of the vertices to coordinates in the drawing, boxes the
+// clusters and routes the edges.
+func (g *layeredGraph) layout() *Layout {
+	centers := g.rankCenters()
+	at := make(map[*layoutVertex]Point)
+	for _, v := range g.vertices {
+		switch g.direction {
+		case DirectionVertical:
+			at[v] = Point{v.pos, centers[v.rank]}
+		case DirectionHorizontalLeft:
+			at[v] = Point{-centers[v.rank], v.pos}
+		default:
+			at[v] = Point{centers[v.rank], v.pos}
+		}
+	}
+	rect := func(v *layoutVertex) Rect {
+		p := at[v]
+		return Rect{p.X - v.width/2, p.Y - v.height/2, v.width, v.height}
+	}
+
+	l := &Layout{}
+	for _, v := range g.vertices {
+		if v.node != nil {
+			l.Nodes = append(l.Nodes, NodeLayout{Node: v.node, Rect: rect(v), Rank: v.rank})
+		}
+	}
+
+	// Clusters are boxed innermost first so that each box encloses the boxes nested in it, and
+	// must also enclose the dummies routing edges through it.
+	for i := len(g.clusters) - 1; i >= 0; i-- {
+		c := g.clusters[i]
+		if len(c.members) == 0 {
+			continue
+		}
+		bounds := rect(c.members[0])
+		for _, v := range g.vertices {
+			if v.node == nil && !v.empty && v.cluster != nil && slices.Contains(v.cluster.ancestors(), c) {
+				bounds = union(bounds, Rect{at[v].X, at[v].Y, 0, 0})
+			}
+		}
+		for _, v := range c.members[1:] {
+			bounds = union(bounds, rect(v))
+		}
+		for _, child := range g.clusters[i+1:] {
+			if child.parent == c && len(child.members) > 0 {
+				bounds = union(bounds, child.rect)
+			}
+		}
+		padding := g.opts.ClusterPadding
+		bounds = Rect{bounds.X - padding, bounds.Y - padding - g.titleSpace, bounds.Width + 2*padding, bounds.Height + 2*padding + g.titleSpace}
+		if titleWidth, _ := textSize(c.subgraph.nodeName()); titleWidth+2*padding > bounds.Width {
+			bounds.X -= (titleWidth + 2*padding - bounds.Width) / 2
+			bounds.Width = titleWidth + 2*padding
+		}
+		c.rect = bounds
+	}
+	for _, c := range g.clusters {
+		if len(c.members) > 0 {
+			l.Clusters = append(l.Clusters, ClusterLayout{Subgraph: c.subgraph, Rect: c.rect, Depth: c.depth})
+		}
+	}
+
+	for _, e := range g.edges {
+		l.Edges = append(l.Edges, EdgeLayout{Link: e.link, Points: g.route(e, at, rect)})
+	}
+
+	l.translate(g.opts.Margin)
+	return l
+}
+
+// route returns the polyline of an edge from the border of its origin to the border of its
+// target, through the dummies in between.
+func (g *layeredGraph) route(e *layoutEdge, at map[*layoutVertex]Point, rect func(*layoutVertex) Rect) []Point {
+	origin, target := e.chain[0], e.chain[len(e.chain)-1]
+	if origin == target {
+		// A self-loop leaves and re-enters the node on the side away from the flow. So does a link
+		// between a subgraph and a node or subgraph nested in it, from one box to the other.
+		from, to := rect(origin), rect(target)
+		if e.originCluster != nil {
+			from = e.originCluster.rect
+		}
+		if e.targetCluster != nil {
+			to = e.targetCluster.rect
+		}
+		loop := g.opts.NodeSpacing / 2
+		if g.direction == DirectionVertical {
+			x := max(from.X+from.Width, to.X+to.Width) + loop
+			top, bottom := from.Y+from.Height/4, to.Y+3*to.Height/4
+			return []Point{{from.X + from.Width, top}, {x, top}, {x, bottom}, {to.X + to.Width, bottom}}
+		}
+		y := max(from.Y+from.Height, to.Y+to.Height) + loop
+		left, right := from.X+from.Width/4, to.X+3*to.Width/4
+		return []Point{{left, from.Y + from.Height}, {left, y}, {right, y}, {right, to.Y + to.Height}}
+	}
+
+	points := make([]Point, len(e.chain))
+	for i, v := range e.chain {
+		points[i] = at[v]
+	}
+	points[0] = borderPoint(rect(origin), points[1], g.direction)
+	points[len(points)-1] = borderPoint(rect(target), points[len(points)-2], g.direction)
+
+	if e.originCluster != nil && !contains(e.originCluster.rect, points[1]) {
+		points[0] = clip(e.originCluster.rect, points[1], points[0])
+	}
+	if e.targetCluster != nil && !contains(e.targetCluster.rect, points[len(points)-2]) {
+		points[len(points)-1] = clip(e.targetCluster.rect, points[len(points)-2], points[len(points)-1])
+	}
+	return points
+}
+
+// borderPoint returns where a line towards p leaves a node's shape: the middle of the side facing
+// the next layer, or of the side facing p if it is in the same layer.
+func borderPoint(r Rect, p Point, direction DirectionEnum) Point {
+	c := r.Center()
+	sameLayer := (direction == DirectionVertical && p.Y == c.Y) || (direction != DirectionVertical && p.X == c.X)
+	if (direction == DirectionVertical) != sameLayer {
+		if p.Y < c.Y {
+			return Point{c.X, r.Y}
+		}
+		return Point{c.X, r.Y + r.Height}
+	}
+	if p.X < c.X {
+		return Point{r.X, c.Y}
+	}
+	return Point{r.X + r.Width, c.Y}
+}
+
+// clip returns where the segment from p, outside the rectangle, to q, inside it, crosses its border.
+func clip(r Rect, p, q Point) Point {
+	t := 1.0
+	dx, dy := q.X-p.X, q.Y-p.Y
+	for _, edge := range []struct{ delta, from, to float64 }{
+		{dx, p.X, r.X}, {dx, p.X, r.X + r.Width}, {dy, p.Y, r.Y}, {dy, p.Y, r.Y + r.Height},
+	} {
+		if edge.delta == 0 {
+			continue
+		}
+		s := (edge.to - edge.from) / edge.delta
+		if s >= 0 && s < t {
+			crossing := Point{p.X + s*dx, p.Y + s*dy}
+			if contains(r, crossing) {
+				t = s
+			}
+		}
+	}
+	return Point{p.X + t*dx, p.Y + t*dy}
+}
+
+// contains reports whether the point is inside the rectangle or on its border.
+func contains(r Rect, p Point) bool {
+	const epsilon = 1e-9
+	return p.X >= r.X-epsilon && p.X <= r.X+r.Width+epsilon && p.Y >= r.Y-epsilon && p.Y <= r.Y+r.Height+epsilon
+}
+
+// union returns the smallest rectangle containing both rectangles.
+func union(a, b Rect) Rect {
+	x, y := min(a.X, b.X), min(a.Y, b.Y)
+	return Rect{x, y, max(a.X+a.Width, b.X+b.Width) - x, max(a.Y+a.Height, b.Y+b.Height) - y}
+}
+
+// distance returns the distance between two points.
+func distance(a, b Point) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
+
+// translate moves the layout so that its top-left element is at the margin, and sets its size.
+func (l *Layout) translate(margin float64) {
+	if len(l.Nodes) == 0 && len(l.Clusters) == 0 {
+		l.Width, l.Height = 2*margin, 2*margin
+		return
+	}
+	var bounds Rect
+	if len(l.Nodes) > 0 {
+		bounds = l.Nodes[0].Rect
+	} else {
+		bounds = l.Clusters[0].Rect
+	}
+	for _, n := range l.Nodes {
+		bounds = union(bounds, n.Rect)
+	}
+	for _, c := range l.Clusters {
+		bounds = union(bounds, c.Rect)
+	}
+	for _, e := range l.Edges {
+		for _, p := range e.Points {
+			bounds = union(bounds, Rect{p.X, p.Y, 0, 0})
+		}
+	}
+
+	dx, dy := margin-bounds.X, margin-bounds.Y
+	for i := range l.Nodes {
+		l.Nodes[i].Rect.X += dx
+		l.Nodes[i].Rect.Y += dy
+	}
+	for i := range l.Clusters {
+		l.Clusters[i].Rect.X += dx
+		l.Clusters[i].Rect.Y += dy
+	}
+	for i := range l.Edges {
+		for j := range l.Edges[i].Points {
+			l.Edges[i].Points[j].X += dx
+			l.Edges[i].Points[j].Y += dy
+		}
+	}
+	l.Width = bounds.Width + 2*margin
+	l.Height = bounds.Height + 2*margin
+}
@@ -0,0 +1,259 @@
+package flowchart
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// nodeRects returns the rectangles of the laid out nodes, by node name.
+func nodeRects(l *Layout) map[string]Rect {
+	rects := make(map[string]Rect)
+	for _, n := range l.Nodes {
+		rects[n.Node.name] = n.Rect
+	}
+	return rects
+}
+
+// onBorder reports whether p lies on the border of r.
+func onBorder(r Rect, p Point) bool {
+	const epsilon = 1e-6
+	near := func(a, b float64) bool { return a-b < epsilon && b-a < epsilon }
+	return contains(r, p) && (near(p.X, r.X) || near(p.X, r.X+r.Width) || near(p.Y, r.Y) || near(p.Y, r.Y+r.Height))
+}
+
+func TestComputeLayout_Directions(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction DirectionEnum
+		flows     func(a, b Rect) bool // Whether b follows a in the flow direction
+	}{
+		{"top to bottom", DirectionVertical, func(a, b Rect) bool { return a.Y+a.Height < b.Y && a.Center().X == b.Center().X }},
+		{"left to right", DirectionHorizontalRight, func(a, b Rect) bool { return a.X+a.Width < b.X && a.Center().Y == b.Center().Y }},
+		{"right to left", DirectionHorizontalLeft, func(a, b Rect) bool { return b.X+b.Width < a.X && a.Center().Y == b.Center().Y }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, step, end := TerminatorNode("Start", nil), ProcessNode("Step", nil), TerminatorNode("End", nil)
+			chart := &Flowchart{
+				Direction: tt.direction,
+				Nodes:     []*Node{end, step, start},
+				Links:     []Link{SolidLink(start, step, nil), SolidLink(step, end, nil)},
+			}
+			l, err := ComputeLayout(chart, LayoutOptions{})
+			if err != nil {
+				t.Fatalf("ComputeLayout() unexpected error: %v", err)
+			}
+			rects := nodeRects(l)
+			if !tt.flows(rects["Start"], rects["Step"]) || !tt.flows(rects["Step"], rects["End"]) {
+				t.Errorf("nodes do not follow the flow direction: %v", rects)
+			}
+			for _, n := range l.Nodes {
+				if n.Rect.X < 20 || n.Rect.Y < 20 || n.Rect.X+n.Rect.Width > l.Width-20 || n.Rect.Y+n.Rect.Height > l.Height-20 {
+					t.Errorf("node %q at %v is outside the margins of a %vx%v layout", n.Node.name, n.Rect, l.Width, l.Height)
+				}
+			}
+			for _, e := range l.Edges {
+				origin, target := rects[e.Link.Origin.nodeName()], rects[e.Link.Target.nodeName()]
+				if !onBorder(origin, e.Points[0]) || !onBorder(target, e.Points[len(e.Points)-1]) {
+					t.Errorf("edge %v does not run from the border of %v to the border of %v", e.Points, origin, target)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeLayout_Ranks(t *testing.T) {
+	a, b, c, d := ProcessNode("A", nil), ProcessNode("B", nil), ProcessNode("C", nil), ProcessNode("D", nil)
+	chart := &Flowchart{
+		Direction: DirectionVertical,
+		Nodes:     []*Node{a, b, c, d},
+		Links: []Link{
+			SolidLink(a, b, nil),
+			SolidLink(b, c, nil),
+			SolidLink(c, a, nil), // Closes a cycle
+			SolidLink(a, d, nil),
+			SolidLink(c, d, nil),
+			SolidLink(d, d, nil),
+		},
+	}
+	l, err := ComputeLayout(chart, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("ComputeLayout() unexpected error: %v", err)
+	}
+
+	ranks := make(map[string]int)
+	for _, n := range l.Nodes {
+		ranks[n.Node.name] = n.Rank
+	}
+	if diff := cmp.Diff(map[string]int{"A": 0, "B": 1, "C": 2, "D": 3}, ranks); diff != "" {
+		t.Errorf("ranks mismatch (-expected +got):\n%s", diff)
+	}
+
+	points := make(map[string]int)
+	for _, e := range l.Edges {
+		points[e.Link.Origin.nodeName()+"->"+e.Link.Target.nodeName()] = len(e.Points)
+	}
+	expected := map[string]int{
+		"A->B": 2, "B->C": 2, "C->D": 2,
+		"C->A": 3, // Reversed link routed back through the layer of B
+		"A->D": 4, // Long link routed through the layers of B and C
+		"D->D": 4, // Self-loop
+	}
+	if diff := cmp.Diff(expected, points); diff != "" {
+		t.Errorf("edge points mismatch (-expected +got):\n%s", diff)
+	}
+
+	again, _ := ComputeLayout(chart, LayoutOptions{})
+	if diff := cmp.Diff(l, again, cmp.AllowUnexported(Node{})); diff != "" {
+		t.Errorf("ComputeLayout() is not deterministic (-first +second):\n%s", diff)
+	}
+}
+
+func TestComputeLayout_Crossings(t *testing.T) {
+	// Tree order places C before D, which crosses the links unless the layer is reordered.
+	a, b, c, d := ProcessNode("A", nil), ProcessNode("B", nil), ProcessNode("C", nil), ProcessNode("D", nil)
+	chart := &Flowchart{
+		Direction: DirectionVertical,
+		Nodes:     []*Node{c, d, a, b},
+		Links:     []Link{SolidLink(a, d, nil), SolidLink(b, c, nil)},
+	}
+	l, err := ComputeLayout(chart, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("ComputeLayout() unexpected error: %v", err)
+	}
+	rects := nodeRects(l)
+	if (rects["A"].X < rects["B"].X) != (rects["D"].X < rects["C"].X) {
+		t.Errorf("links A->D and B->C cross: %v", rects)
+	}
+}
+
+func TestComputeLayout_Clusters(t *testing.T) {
+	start, end := TerminatorNode("Start", nil), TerminatorNode("End", nil)
+	check, fix, retry := DecisionNode("Check", pointTo("Is it valid?")), ProcessNode("Fix", nil), ProcessNode("Retry", nil)
+	inner := &Flowchart{Title: pointTo("Inner"), Nodes: []*Node{fix, retry}, Links: []Link{SolidLink(fix, retry, nil)}}
+	review := &Flowchart{Title: pointTo("Review"), Nodes: []*Node{check}, Subgraphs: []*Flowchart{inner}}
+	empty := &Flowchart{Title: pointTo("Empty")}
+	other := ProcessNode("Other", nil)
+
+	for _, direction := range []DirectionEnum{DirectionVertical, DirectionHorizontalRight} {
+		chart := &Flowchart{
+			Direction: direction,
+			Nodes:     []*Node{start, end, other},
+			Subgraphs: []*Flowchart{review, empty},
+			Links: []Link{
+				SolidLink(start, review, nil),
+				SolidLink(check, inner, pointTo("no")),
+				SolidLink(review, end, nil),
+				SolidLink(start, other, nil),
+				SolidLink(other, end, nil),
+				SolidLink(other, empty, nil),
+			},
+		}
+		l, err := ComputeLayout(chart, LayoutOptions{})
+		if err != nil {
+			t.Fatalf("ComputeLayout() unexpected error: %v", err)
+		}
+
+		clusters := make(map[string]Rect)
+		for _, c := range l.Clusters {
+			clusters[*c.Subgraph.Title] = c.Rect
+		}
+		if len(l.Clusters) != 3 || l.Clusters[0].Depth != 1 || l.Clusters[1].Depth != 2 || l.Clusters[2].Depth != 1 {
+			t.Fatalf("Clusters = %v, expected Review, Inner and Empty", l.Clusters)
+		}
+		rects := nodeRects(l)
+		for name, cluster := range map[string]string{"Check": "Review", "Fix": "Inner", "Retry": "Inner"} {
+			if union(clusters[cluster], rects[name]) != clusters[cluster] {
+				t.Errorf("node %q at %v is outside cluster %q at %v", name, rects[name], cluster, clusters[cluster])
+			}
+		}
+		for i, a := range l.Nodes {
+			for _, b := range l.Nodes[i+1:] {
+				if a.Rect.X < b.Rect.X+b.Rect.Width && b.Rect.X < a.Rect.X+a.Rect.Width && a.Rect.Y < b.Rect.Y+b.Rect.Height && b.Rect.Y < a.Rect.Y+a.Rect.Height {
+					t.Errorf("nodes %q at %v and %q at %v overlap", a.Node.name, a.Rect, b.Node.name, b.Rect)
+				}
+			}
+		}
+		if union(clusters["Review"], clusters["Inner"]) != clusters["Review"] {
+			t.Errorf("cluster Inner at %v is outside cluster Review at %v", clusters["Inner"], clusters["Review"])
+		}
+		for _, name := range []string{"Start", "End", "Other"} {
+			for _, cluster := range []string{"Review", "Empty"} {
+				if r := rects[name]; union(clusters[cluster], r) == clusters[cluster] {
+					t.Errorf("node %q at %v is inside cluster %q at %v", name, r, cluster, clusters[cluster])
+				}
+			}
+		}
+		if union(clusters["Review"], clusters["Empty"]) == clusters["Review"] {
+			t.Errorf("cluster Empty at %v is inside cluster Review at %v", clusters["Empty"], clusters["Review"])
+		}
+		if len(l.Edges) != 7 {
+			t.Errorf("got %d edges, expected 7", len(l.Edges))
+		}
+		for _, e := range l.Edges {
+			if target, ok := e.Link.Target.(*Flowchart); ok && !onBorder(clusters[*target.Title], e.Points[len(e.Points)-1]) {
+				t.Errorf("edge to %q ends at %v, expected the border of %v", *target.Title, e.Points[len(e.Points)-1], clusters[*target.Title])
+			}
+			if origin, ok := e.Link.Origin.(*Flowchart); ok && !onBorder(clusters[*origin.Title], e.Points[0]) {
+				t.Errorf("edge from %q starts at %v, expected the border of %v", *origin.Title, e.Points[0], clusters[*origin.Title])
+			}
+		}
+	}
+}
+
+func TestComputeLayout_NestedLinks(t *testing.T) {
+	a, b := ProcessNode("A", nil), ProcessNode("B", nil)
+	inner := &Flowchart{Title: pointTo("Inner"), Nodes: []*Node{a}}
+	outer := &Flowchart{Title: pointTo("Outer"), Subgraphs: []*Flowchart{inner}}
+	group := &Flowchart{Title: pointTo("Group"), Nodes: []*Node{b}}
+
+	for _, direction := range []DirectionEnum{DirectionVertical, DirectionHorizontalRight} {
+		chart := &Flowchart{
+			Direction: direction,
+			Subgraphs: []*Flowchart{outer, group},
+			Links:     []Link{SolidLink(outer, inner, nil), SolidLink(a, outer, nil), SolidLink(group, b, nil)},
+		}
+		l, err := ComputeLayout(chart, LayoutOptions{})
+		if err != nil {
+			t.Fatalf("ComputeLayout() unexpected error: %v", err)
+		}
+		rects := nodeRects(l)
+		for _, c := range l.Clusters {
+			rects[*c.Subgraph.Title] = c.Rect
+		}
+		for _, e := range l.Edges {
+			origin, target := e.Link.Origin.nodeName(), e.Link.Target.nodeName()
+			first, last := e.Points[0], e.Points[len(e.Points)-1]
+			if len(e.Points) != 4 || first == last {
+				t.Errorf("edge from %q to %q = %v, expected a loop", origin, target, e.Points)
+				continue
+			}
+			if !onBorder(rects[origin], first) || !onBorder(rects[target], last) {
+				t.Errorf("edge from %q to %q = %v, expected it to run from %v to %v", origin, target, e.Points, rects[origin], rects[target])
+			}
+		}
+	}
+}
+
+func TestComputeLayout_Errors(t *testing.T) {
+	a := ProcessNode("A", nil)
+	chart := &Flowchart{Nodes: []*Node{a}, Links: []Link{SolidLink(a, ProcessNode("Phantom", nil), nil)}}
+	if _, err := ComputeLayout(chart, LayoutOptions{}); !errors.Is(err, ErrOrphanedEndpoint) {
+		t.Errorf("ComputeLayout() error = %v, expected %v", err, ErrOrphanedEndpoint)
+	}
+
+	l, err := ComputeLayout(&Flowchart{}, LayoutOptions{Margin: 5})
+	if err != nil || l.Width != 10 || l.Height != 10 || len(l.Nodes) != 0 {
+		t.Errorf("ComputeLayout() of an empty chart = %v, %v, expected an empty 10x10 layout", l, err)
+	}
+}
+
+func TestEdgeLayout_Midpoint(t *testing.T) {
+	e := EdgeLayout{Points: []Point{{0, 0}, {0, 10}, {30, 10}}}
+	if diff := cmp.Diff(Point{10, 10}, e.Midpoint()); diff != "" {
+		t.Errorf("Midpoint() mismatch (-expected +got):\n%s", diff)
+	}
+}
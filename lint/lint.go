@@ -0,0 +1,297 @@
+// Package lint checks that flowcharts make sense as process descriptions, beyond being
+// renderable: decisions have labelled branches, the flow has one start and always reaches an end,
+// connectors come in pairs, and so on. Each check is a Rule, and a Linter runs a set of rules over
+// a chart and collects their findings into a Report that can be written as plain text, JSON or
+// SARIF for CI systems.
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/andre-a-alves/flowchart"
+	"github.com/andre-a-alves/flowchart/analysis"
+)
+
+// Severity is how serious a finding is.
+type Severity int
+
+// Constants for finding severities, from least to most serious.
+const (
+	SeverityInfo    Severity = iota // A suggestion
+	SeverityWarning                 // A likely mistake
+	SeverityError                   // A chart that does not describe a valid process
+)
+
+// severityNames maps severities to their text representation.
+var severityNames = map[Severity]string{
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+// String returns the name of the severity, e.g. "warning".
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText encodes the severity as its name.
+func (s Severity) MarshalText() ([]byte, error) {
+	name, ok := severityNames[s]
+	if !ok {
+		return nil, fmt.Errorf("invalid severity %d", int(s))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes a severity from its name.
+func (s *Severity) UnmarshalText(text []byte) error {
+	for severity, name := range severityNames {
+		if name == string(text) {
+			*s = severity
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// Finding is a problem reported by a rule.
+type Finding struct {
+	Rule     string   // Name of the rule that reported the finding
+	Severity Severity // Severity of the finding, after the linter's overrides
+	Path     []string // Titles of the subgraphs enclosing the element, outermost first
+	Element  any      // The offending *flowchart.Node, *flowchart.Link or *flowchart.Flowchart
+	Message  string   // Description of the problem
+}
+
+// String formats the finding as "severity: Outer > Inner: message [rule]".
+func (f Finding) String() string {
+	var b strings.Builder
+	b.WriteString(f.Severity.String())
+	b.WriteString(": ")
+	if len(f.Path) > 0 {
+		b.WriteString(strings.Join(f.Path, " > "))
+		b.WriteString(": ")
+	}
+	fmt.Fprintf(&b, "%s [%s]", f.Message, f.Rule)
+	return b.String()
+}
+
+// Rule is a single lint check.
+type Rule interface {
+	Name() string              // Unique name of the rule, e.g. "decision-branches"
+	Description() string       // One-sentence description of what the rule requires
+	DefaultSeverity() Severity // Severity of the findings unless the linter overrides it
+	Check(c *Chart) []Finding  // Findings for the chart; Rule and Severity are filled in by the linter
+}
+
+// NewRule returns a rule that runs check, for rules that need no state of their own.
+func NewRule(name, description string, severity Severity, check func(c *Chart) []Finding) Rule {
+	return &funcRule{name: name, description: description, severity: severity, check: check}
+}
+
+// funcRule is a Rule implemented by a function.
+type funcRule struct {
+	name        string
+	description string
+	severity    Severity
+	check       func(c *Chart) []Finding
+}
+
+func (r *funcRule) Name() string              { return r.name }
+func (r *funcRule) Description() string       { return r.description }
+func (r *funcRule) DefaultSeverity() Severity { return r.severity }
+func (r *funcRule) Check(c *Chart) []Finding  { return r.check(c) }
+
+// Chart is the flowchart being linted, together with the indexes shared by the rules.
+type Chart struct {
+	Flowchart *flowchart.Flowchart
+	Graph     *analysis.Graph
+	paths     map[string][]string          // Paths of the subgraphs enclosing each node, by node name
+	jumps     map[string][]*flowchart.Node // Connectors sharing a label, by connector key
+}
+
+// newChart indexes the flowchart for the rules.
+func newChart(f *flowchart.Flowchart) *Chart {
+	c := &Chart{Flowchart: f, Graph: analysis.New(f), paths: make(map[string][]string), jumps: make(map[string][]*flowchart.Node)}
+	for _, node := range c.Graph.Nodes() {
+		if node.Type == flowchart.NodeTypeConnector {
+			c.jumps[connectorKey(node)] = append(c.jumps[connectorKey(node)], node)
+		}
+	}
+	c.Walk(func(f *flowchart.Flowchart, path []string) {
+		for _, node := range f.Nodes {
+			if _, ok := c.paths[node.Name()]; !ok {
+				c.paths[node.Name()] = path
+			}
+		}
+	})
+	return c
+}
+
+// Walk calls visit for the flowchart and each of its subgraphs, depth first, with the titles of
+// the subgraphs from the root's children down to the visited one.
+func (c *Chart) Walk(visit func(f *flowchart.Flowchart, path []string)) {
+	var walk func(f *flowchart.Flowchart, path []string)
+	walk = func(f *flowchart.Flowchart, path []string) {
+		visit(f, path)
+		for _, subgraph := range f.Subgraphs {
+			title := ""
+			if subgraph.Title != nil {
+				title = *subgraph.Title
+			}
+			walk(subgraph, append(slices.Clip(path), title))
+		}
+	}
+	walk(c.Flowchart, nil)
+}
+
+// Path returns the titles of the subgraphs enclosing the node, outermost first.
+func (c *Chart) Path(n *flowchart.Node) []string {
+	return c.paths[n.Name()]
+}
+
+// Next returns the nodes that the flow continues to from the node: its successors and, for a
+// connector, the other connectors with the same label.
+func (c *Chart) Next(n *flowchart.Node) []*flowchart.Node {
+	return append(c.Graph.Successors(n), c.pairedConnectors(n)...)
+}
+
+// Previous returns the nodes that the flow comes to the node from: its predecessors and, for a
+// connector, the other connectors with the same label.
+func (c *Chart) Previous(n *flowchart.Node) []*flowchart.Node {
+	return append(c.Graph.Predecessors(n), c.pairedConnectors(n)...)
+}
+
+// pairedConnectors returns the other connectors with the same label as a connector.
+func (c *Chart) pairedConnectors(n *flowchart.Node) []*flowchart.Node {
+	if n.Type != flowchart.NodeTypeConnector {
+		return nil
+	}
+	var paired []*flowchart.Node
+	for _, connector := range c.jumps[connectorKey(n)] {
+		if connector != n {
+			paired = append(paired, connector)
+		}
+	}
+	return paired
+}
+
+// Reachable returns which nodes can be reached by following the flow from the given nodes, in
+// the direction given by step, which is Next or Previous.
+func (c *Chart) Reachable(step func(*flowchart.Node) []*flowchart.Node, from ...*flowchart.Node) map[*flowchart.Node]bool {
+	reached := make(map[*flowchart.Node]bool)
+	queue := from
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if reached[node] {
+			continue
+		}
+		reached[node] = true
+		queue = append(queue, step(node)...)
+	}
+	return reached
+}
+
+// NodeFinding returns a finding about a node, located at its path.
+func (c *Chart) NodeFinding(n *flowchart.Node, format string, args ...any) Finding {
+	return Finding{Path: c.Path(n), Element: n, Message: fmt.Sprintf(format, args...)}
+}
+
+// Suppression silences the findings of a rule for one chart, or for one of its elements.
+type Suppression struct {
+	Rule    string // Name of the rule, or "*" for every rule
+	Element string // Name of a node or title of a subgraph whose findings are silenced; empty for the whole chart
+}
+
+// ParseSuppression parses a suppression written as "rule" or "rule:element".
+func ParseSuppression(s string) (Suppression, error) {
+	rule, element, _ := strings.Cut(s, ":")
+	if rule == "" {
+		return Suppression{}, fmt.Errorf("suppression %q has no rule", s)
+	}
+	return Suppression{Rule: rule, Element: element}, nil
+}
+
+// matches reports whether the suppression silences the finding. A suppression naming a subgraph
+// silences the findings of every element inside it.
+func (s Suppression) matches(f Finding) bool {
+	if s.Rule != "*" && s.Rule != f.Rule {
+		return false
+	}
+	if s.Element == "" || slices.Contains(f.Path, s.Element) {
+		return true
+	}
+	return elementName(f.Element) == s.Element
+}
+
+// Linter runs a set of rules over flowcharts.
+type Linter struct {
+	Rules      []Rule              // Rules to run, in order; DefaultRules() if nil
+	Severities map[string]Severity // Severities overriding the defaults of the rules, by rule name
+}
+
+// Lint runs the default rules over the flowchart.
+func Lint(f *flowchart.Flowchart, suppressions ...Suppression) Report {
+	return (&Linter{}).Lint(f, suppressions...)
+}
+
+// Lint runs the rules over the flowchart and returns their findings, in rule order, except those
+// silenced by the suppressions given for this chart.
+func (l *Linter) Lint(f *flowchart.Flowchart, suppressions ...Suppression) Report {
+	rules := l.Rules
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	report := Report{Rules: rules}
+	c := newChart(f)
+	for _, rule := range rules {
+		severity, ok := l.Severities[rule.Name()]
+		if !ok {
+			severity = rule.DefaultSeverity()
+		}
+		for _, finding := range rule.Check(c) {
+			finding.Rule = rule.Name()
+			finding.Severity = severity
+			if !slices.ContainsFunc(suppressions, func(s Suppression) bool { return s.matches(finding) }) {
+				report.Findings = append(report.Findings, finding)
+			}
+		}
+	}
+	return report
+}
+
+// elementName returns the name of a node, the title of a subgraph or "origin -> target" for a link.
+func elementName(element any) string {
+	switch e := element.(type) {
+	case *flowchart.Node:
+		return e.Name()
+	case *flowchart.Flowchart:
+		if e.Title != nil {
+			return *e.Title
+		}
+	case *flowchart.Link:
+		return linkableName(e.Origin) + " -> " + linkableName(e.Target)
+	}
+	return ""
+}
+
+// linkableName returns the name of a node or the title of a subgraph, or "?" if there is none.
+func linkableName(l flowchart.Linkable) string {
+	switch l := l.(type) {
+	case *flowchart.Node:
+		if l != nil {
+			return l.Name()
+		}
+	case *flowchart.Flowchart:
+		if l != nil && l.Title != nil {
+			return *l.Title
+		}
+	}
+	return "?"
+}
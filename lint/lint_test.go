@@ -0,0 +1,121 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/andre-a-alves/flowchart"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLinter_Lint(t *testing.T) {
+	// A chart whose decision has a single, unlabelled branch inside the "Review" subgraph.
+	broken := func() *flowchart.Flowchart {
+		chart := approvalChart()
+		chart.Links = chart.Links[:1]
+		chart.Subgraphs[0].Links[0].Label = nil
+		chart.Subgraphs[0].Links = chart.Subgraphs[0].Links[:1]
+		return chart
+	}
+	onlyDecisions := []Rule{DefaultRules()[0]}
+
+	tests := []struct {
+		name         string
+		linter       *Linter
+		suppressions []Suppression
+		expected     []string
+	}{
+		{
+			name:   "rule set and severity override",
+			linter: &Linter{Rules: onlyDecisions, Severities: map[string]Severity{"decision-branches": SeverityWarning}},
+			expected: []string{
+				`warning: Review: decision "Check" has 1 outgoing link, expected at least 2 [decision-branches]`,
+				`warning: Review: link from decision "Check" to "Fix" has no label [decision-branches]`,
+			},
+		},
+		{
+			name:         "suppressed for the chart",
+			linter:       &Linter{},
+			suppressions: []Suppression{{Rule: "*"}},
+		},
+		{
+			name:         "suppressed for a subgraph",
+			linter:       &Linter{Rules: onlyDecisions},
+			suppressions: []Suppression{{Rule: "decision-branches", Element: "Review"}},
+		},
+		{
+			name:         "suppressed for a node",
+			linter:       &Linter{Rules: onlyDecisions},
+			suppressions: []Suppression{{Rule: "decision-branches", Element: "Check"}},
+			expected:     []string{`error: Review: link from decision "Check" to "Fix" has no label [decision-branches]`},
+		},
+		{
+			name:         "suppression of another rule",
+			linter:       &Linter{Rules: onlyDecisions},
+			suppressions: []Suppression{{Rule: "reaches-end"}},
+			expected: []string{
+				`error: Review: decision "Check" has 1 outgoing link, expected at least 2 [decision-branches]`,
+				`error: Review: link from decision "Check" to "Fix" has no label [decision-branches]`,
+			},
+		},
+		{
+			name: "custom rule",
+			linter: &Linter{Rules: []Rule{NewRule("no-database", "Charts must not use databases.", SeverityInfo, func(c *Chart) []Finding {
+				return []Finding{c.NodeFinding(c.Graph.Node("Fix"), "node %q is fine", "Fix")}
+			})}},
+			expected: []string{`info: Review: node "Fix" is fine [no-database]`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := tt.linter.Lint(broken(), tt.suppressions...)
+			var got []string
+			for _, finding := range report.Findings {
+				got = append(got, finding.String())
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("Lint() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSuppression(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    Suppression
+		expectedErr bool
+	}{
+		{input: "reaches-end", expected: Suppression{Rule: "reaches-end"}},
+		{input: "reaches-end:Fix", expected: Suppression{Rule: "reaches-end", Element: "Fix"}},
+		{input: ":Fix", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSuppression(tt.input)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("ParseSuppression() error = %v, expected error: %v", err, tt.expectedErr)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("ParseSuppression() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSeverity_Text(t *testing.T) {
+	for _, severity := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		text, err := severity.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText() unexpected error: %v", err)
+		}
+		var got Severity
+		if err := got.UnmarshalText(text); err != nil || got != severity {
+			t.Errorf("UnmarshalText(%q) = %v, %v, expected %v", text, got, err, severity)
+		}
+	}
+	if err := new(Severity).UnmarshalText([]byte("fatal")); err == nil {
+		t.Errorf("UnmarshalText(%q) = nil error, expected an error", "fatal")
+	}
+}
@@ -0,0 +1,215 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Report holds the findings of linting one chart.
+type Report struct {
+	Source   string    // Name of the linted file, shown in the output; may be empty
+	Rules    []Rule    // Rules that were run
+	Findings []Finding // Findings that were not suppressed, in rule order
+}
+
+// Count returns the number of findings with the given severity.
+func (r Report) Count(severity Severity) int {
+	count := 0
+	for _, finding := range r.Findings {
+		if finding.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// HasErrors reports whether any finding has SeverityError.
+func (r Report) HasErrors() bool {
+	return r.Count(SeverityError) > 0
+}
+
+// WriteText writes one line per finding, prefixed with the source of its report, e.g.
+// "chart.mmd: error: Review: decision "Check" has 1 outgoing link, expected at least 2 [decision-branches]".
+func WriteText(w io.Writer, reports ...Report) error {
+	for _, report := range reports {
+		for _, finding := range report.Findings {
+			prefix := ""
+			if report.Source != "" {
+				prefix = report.Source + ": "
+			}
+			if _, err := fmt.Fprintf(w, "%s%s\n", prefix, finding); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFinding is the JSON representation of a finding.
+type jsonFinding struct {
+	Source   string   `json:"source,omitempty"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Path     []string `json:"path,omitempty"`
+	Element  string   `json:"element,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// WriteJSON writes the findings of every report as a JSON object with a "findings" array and a
+// "summary" counting the findings of each severity.
+func WriteJSON(w io.Writer, reports ...Report) error {
+	document := struct {
+		Findings []jsonFinding  `json:"findings"`
+		Summary  map[string]int `json:"summary"`
+	}{
+		Findings: []jsonFinding{},
+		Summary:  map[string]int{},
+	}
+	for _, severity := range []Severity{SeverityError, SeverityWarning, SeverityInfo} {
+		document.Summary[severity.String()] = 0
+	}
+	for _, report := range reports {
+		for _, finding := range report.Findings {
+			document.Findings = append(document.Findings, jsonFinding{
+				Source:   report.Source,
+				Rule:     finding.Rule,
+				Severity: finding.Severity,
+				Path:     finding.Path,
+				Element:  elementName(finding.Element),
+				Message:  finding.Message,
+			})
+			document.Summary[finding.Severity.String()]++
+		}
+	}
+	return writeIndentedJSON(w, document)
+}
+
+// sarifLevels maps severities to SARIF result levels.
+var sarifLevels = map[Severity]string{
+	SeverityInfo:    "note",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+// Types of the SARIF 2.1.0 log, limited to the properties written by WriteSARIF.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		ShortDescription     sarifMessage       `json:"shortDescription"`
+		DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	}
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		RuleIndex int             `json:"ruleIndex"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+	sarifLocation struct {
+		PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+		LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifLogicalLocation struct {
+		Name               string `json:"name"`
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+		Kind               string `json:"kind"`
+	}
+)
+
+// WriteSARIF writes the reports as a SARIF 2.1.0 log with a single run, as expected by code
+// scanning services. Each result is located in the file named by the Source of its report and,
+// logically, at the element it refers to, qualified by the titles of its enclosing subgraphs.
+func WriteSARIF(w io.Writer, reports ...Report) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "flowchart-lint",
+			InformationURI: "https://github.com/andre-a-alves/flowchart",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	ruleIndexes := make(map[string]int)
+	for _, report := range reports {
+		for _, rule := range report.Rules {
+			if _, ok := ruleIndexes[rule.Name()]; ok {
+				continue
+			}
+			ruleIndexes[rule.Name()] = len(run.Tool.Driver.Rules)
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:                   rule.Name(),
+				ShortDescription:     sarifMessage{Text: rule.Description()},
+				DefaultConfiguration: sarifConfiguration{Level: sarifLevels[rule.DefaultSeverity()]},
+			})
+		}
+	}
+
+	for _, report := range reports {
+		for _, finding := range report.Findings {
+			var location sarifLocation
+			if report.Source != "" {
+				location.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: report.Source}}
+			}
+			if name := elementName(finding.Element); name != "" {
+				location.LogicalLocations = []sarifLogicalLocation{{
+					Name:               name,
+					FullyQualifiedName: strings.Join(append(append([]string{}, finding.Path...), name), "/"),
+					Kind:               "element",
+				}}
+			}
+			result := sarifResult{
+				RuleID:    finding.Rule,
+				RuleIndex: ruleIndexes[finding.Rule],
+				Level:     sarifLevels[finding.Severity],
+				Message:   sarifMessage{Text: finding.Message},
+			}
+			if location.PhysicalLocation != nil || location.LogicalLocations != nil {
+				result.Locations = []sarifLocation{location}
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	return writeIndentedJSON(w, sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// writeIndentedJSON writes v as indented JSON followed by a newline.
+func writeIndentedJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,129 @@
+package lint
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/andre-a-alves/flowchart"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteReports(t *testing.T) {
+	fix := flowchart.ProcessNode("Fix", nil)
+	rule := NewRule("dead-end", "Steps must lead somewhere.", SeverityWarning, nil)
+	report := Report{
+		Source: "chart.mmd",
+		Rules:  []Rule{rule},
+		Findings: []Finding{
+			{Rule: "dead-end", Severity: SeverityError, Path: []string{"Review"}, Element: fix, Message: `node "Fix" leads nowhere`},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		write    func(*bytes.Buffer) error
+		expected string
+	}{
+		{
+			name:     "text",
+			write:    func(b *bytes.Buffer) error { return WriteText(b, report, Report{Source: "clean.mmd"}) },
+			expected: "chart.mmd: error: Review: node \"Fix\" leads nowhere [dead-end]\n",
+		},
+		{
+			name:  "JSON",
+			write: func(b *bytes.Buffer) error { return WriteJSON(b, report) },
+			expected: `{
+  "findings": [
+    {
+      "source": "chart.mmd",
+      "rule": "dead-end",
+      "severity": "error",
+      "path": [
+        "Review"
+      ],
+      "element": "Fix",
+      "message": "node \"Fix\" leads nowhere"
+    }
+  ],
+  "summary": {
+    "error": 1,
+    "info": 0,
+    "warning": 0
+  }
+}
+`,
+		},
+		{
+			name:  "SARIF",
+			write: func(b *bytes.Buffer) error { return WriteSARIF(b, report) },
+			expected: `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "flowchart-lint",
+          "informationUri": "https://github.com/andre-a-alves/flowchart",
+          "rules": [
+            {
+              "id": "dead-end",
+              "shortDescription": {
+                "text": "Steps must lead somewhere."
+              },
+              "defaultConfiguration": {
+                "level": "warning"
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "dead-end",
+          "ruleIndex": 0,
+          "level": "error",
+          "message": {
+            "text": "node \"Fix\" leads nowhere"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "chart.mmd"
+                }
+              },
+              "logicalLocations": [
+                {
+                  "name": "Fix",
+                  "fullyQualifiedName": "Review/Fix",
+                  "kind": "element"
+                }
+              ]
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tt.write(&b); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, b.String()); diff != "" {
+				t.Errorf("output mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+
+	if !report.HasErrors() || report.Count(SeverityWarning) != 0 {
+		t.Errorf("HasErrors() = %v, Count(SeverityWarning) = %d, expected true and 0", report.HasErrors(), report.Count(SeverityWarning))
+	}
+}
@@ -0,0 +1,189 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/andre-a-alves/flowchart"
+)
+
+// DefaultRules returns the rules run by Lint, which check the meaning of each node type.
+//
+//   - decision-branches: decisions have at least two outgoing links, each with a label.
+//   - single-start: the chart has exactly one start terminator.
+//   - reaches-end: every step leads to an end terminator.
+//   - unreachable: every step can be reached from a start terminator.
+//   - terminator-flow: terminators either start or end the flow, not both.
+//   - connector-pairs: every connector has a matching connector with the same label.
+//   - labelled-invisible-link: links without a line have no label, which would float unattached.
+func DefaultRules() []Rule {
+	return []Rule{
+		NewRule("decision-branches", "Decision nodes must have at least two outgoing links, each with a label.", SeverityError, checkDecisionBranches),
+		NewRule("single-start", "The chart must have exactly one start terminator.", SeverityError, checkSingleStart),
+		NewRule("reaches-end", "Every node other than a terminator must lead to an end terminator.", SeverityError, checkReachesEnd),
+		NewRule("unreachable", "Every node must be reachable from a start terminator.", SeverityWarning, checkUnreachable),
+		NewRule("terminator-flow", "Terminators must either start or end the flow, not both.", SeverityWarning, checkTerminatorFlow),
+		NewRule("connector-pairs", "Every connector must have a matching connector with the same label on the other side of the flow.", SeverityWarning, checkConnectorPairs),
+		NewRule("labelled-invisible-link", "Links without a line must not have a label.", SeverityWarning, checkLabelledInvisibleLinks),
+	}
+}
+
+// checkDecisionBranches reports decisions with fewer than two visible outgoing links, and
+// outgoing links without a label.
+func checkDecisionBranches(c *Chart) []Finding {
+	type branch struct {
+		link *flowchart.Link
+		path []string
+	}
+	branches := make(map[string][]branch)
+	c.Walk(func(f *flowchart.Flowchart, path []string) {
+		for i := range f.Links {
+			link := &f.Links[i]
+			if origin, ok := link.Origin.(*flowchart.Node); ok && origin != nil && link.LineType != flowchart.LineTypeNone {
+				branches[origin.Name()] = append(branches[origin.Name()], branch{link, path})
+			}
+		}
+	})
+
+	var findings []Finding
+	for _, node := range c.Graph.Nodes() {
+		if node.Type != flowchart.NodeTypeDecision {
+			continue
+		}
+		outgoing := branches[node.Name()]
+		if len(outgoing) < 2 {
+			findings = append(findings, c.NodeFinding(node, "decision %q has %d outgoing %s, expected at least 2",
+				node.Name(), len(outgoing), plural(len(outgoing), "link", "links")))
+		}
+		for _, b := range outgoing {
+			if b.link.Label == nil || *b.link.Label == "" {
+				findings = append(findings, Finding{
+					Path:    b.path,
+					Element: b.link,
+					Message: fmt.Sprintf("link from decision %q to %q has no label", node.Name(), linkableName(b.link.Target)),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// checkSingleStart reports charts without a start terminator, and every start terminator after
+// the first.
+func checkSingleStart(c *Chart) []Finding {
+	starts := c.Graph.StartNodes()
+	if len(c.Graph.Nodes()) > 0 && len(starts) == 0 {
+		return []Finding{{Element: c.Flowchart, Message: "chart has no start terminator"}}
+	}
+	var findings []Finding
+	for _, node := range starts[min(1, len(starts)):] {
+		findings = append(findings, c.NodeFinding(node, "terminator %q is another start of the flow, after %q", node.Name(), starts[0].Name()))
+	}
+	return findings
+}
+
+// checkReachesEnd reports the nodes other than terminators from which no end terminator can be
+// reached, following connectors to their pairs.
+func checkReachesEnd(c *Chart) []Finding {
+	leadsToEnd := c.Reachable(c.Previous, c.Graph.EndNodes()...)
+
+	var findings []Finding
+	for _, node := range c.Graph.Nodes() {
+		if node.Type != flowchart.NodeTypeTerminator && !leadsToEnd[node] {
+			findings = append(findings, c.NodeFinding(node, "node %q does not lead to an end terminator", node.Name()))
+		}
+	}
+	return findings
+}
+
+// checkUnreachable reports the nodes that cannot be reached from a start terminator, following
+// connectors to their pairs. Charts without a start terminator are left to single-start.
+func checkUnreachable(c *Chart) []Finding {
+	starts := c.Graph.StartNodes()
+	if len(starts) == 0 {
+		return nil
+	}
+	reached := c.Reachable(c.Next, starts...)
+	var findings []Finding
+	for _, node := range c.Graph.Nodes() {
+		if !reached[node] {
+			findings = append(findings, c.NodeFinding(node, "node %q cannot be reached from a start terminator", node.Name()))
+		}
+	}
+	return findings
+}
+
+// checkTerminatorFlow reports terminators with both incoming and outgoing links.
+func checkTerminatorFlow(c *Chart) []Finding {
+	var findings []Finding
+	for _, node := range c.Graph.Nodes() {
+		if node.Type == flowchart.NodeTypeTerminator && len(c.Graph.Predecessors(node)) > 0 && len(c.Graph.Successors(node)) > 0 {
+			findings = append(findings, c.NodeFinding(node, "terminator %q both receives and continues the flow", node.Name()))
+		}
+	}
+	return findings
+}
+
+// checkConnectorPairs reports connectors that the flow enters without a connector with the same
+// label to leave from, and the reverse. Connectors without a label are matched by name.
+func checkConnectorPairs(c *Chart) []Finding {
+	var connectors []*flowchart.Node
+	entered := make(map[string]bool)
+	left := make(map[string]bool)
+	for _, node := range c.Graph.Nodes() {
+		if node.Type != flowchart.NodeTypeConnector {
+			continue
+		}
+		connectors = append(connectors, node)
+		key := connectorKey(node)
+		entered[key] = entered[key] || len(c.Graph.Predecessors(node)) > 0
+		left[key] = left[key] || len(c.Graph.Successors(node)) > 0
+	}
+
+	var findings []Finding
+	for _, node := range connectors {
+		key := connectorKey(node)
+		switch {
+		case !entered[key] && !left[key]:
+			findings = append(findings, c.NodeFinding(node, "connector %q is not linked", node.Name()))
+		case !left[key]:
+			findings = append(findings, c.NodeFinding(node, "connector %q has no matching connector labelled %q to continue the flow", node.Name(), key))
+		case !entered[key]:
+			findings = append(findings, c.NodeFinding(node, "connector %q has no matching connector labelled %q leading to it", node.Name(), key))
+		}
+	}
+	return findings
+}
+
+// connectorKey returns the label that pairs a connector with others.
+func connectorKey(n *flowchart.Node) string {
+	if n.Label != nil && *n.Label != "" {
+		return *n.Label
+	}
+	return n.Name()
+}
+
+// checkLabelledInvisibleLinks reports links with LineTypeNone that carry a label.
+func checkLabelledInvisibleLinks(c *Chart) []Finding {
+	var findings []Finding
+	c.Walk(func(f *flowchart.Flowchart, path []string) {
+		for i := range f.Links {
+			link := &f.Links[i]
+			if link.LineType == flowchart.LineTypeNone && link.Label != nil && *link.Label != "" {
+				findings = append(findings, Finding{
+					Path:    path,
+					Element: link,
+					Message: fmt.Sprintf("link from %q to %q has label %q but no line", linkableName(link.Origin), linkableName(link.Target), *link.Label),
+				})
+			}
+		}
+	})
+	return findings
+}
+
+// plural returns singular if n is 1, and plural otherwise.
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
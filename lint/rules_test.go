@@ -0,0 +1,141 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/andre-a-alves/flowchart"
+	"github.com/google/go-cmp/cmp"
+)
+
+func pointTo(s string) *string {
+	return &s
+}
+
+// messages returns the messages of the findings.
+func messages(findings []Finding) []string {
+	var result []string
+	for _, finding := range findings {
+		result = append(result, finding.Message)
+	}
+	return result
+}
+
+// approvalChart returns a well-formed chart: a start, a decision with labelled branches inside a
+// subgraph, a pair of connectors and an end.
+func approvalChart() *flowchart.Flowchart {
+	start := flowchart.TerminatorNode("Start", nil)
+	check := flowchart.DecisionNode("Check", nil)
+	fix := flowchart.ProcessNode("Fix", nil)
+	out := flowchart.ConnectorNode("Out", pointTo("A"))
+	in := flowchart.ConnectorNode("In", pointTo("A"))
+	end := flowchart.TerminatorNode("End", nil)
+	review := &flowchart.Flowchart{
+		Title: pointTo("Review"),
+		Nodes: []*flowchart.Node{check, fix},
+		Links: []flowchart.Link{
+			flowchart.SolidLink(check, fix, pointTo("no")),
+			flowchart.SolidLink(fix, check, nil),
+		},
+	}
+	return &flowchart.Flowchart{
+		Nodes:     []*flowchart.Node{start, out, in, end},
+		Subgraphs: []*flowchart.Flowchart{review},
+		Links: []flowchart.Link{
+			flowchart.SolidLink(start, check, nil),
+			flowchart.SolidLink(check, out, pointTo("yes")),
+			flowchart.SolidLink(in, end, nil),
+			flowchart.BlankLink(start, end, nil),
+		},
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		change   func(f *flowchart.Flowchart)
+		expected []string
+	}{
+		{
+			name:     "decision with one branch",
+			rule:     "decision-branches",
+			change:   func(f *flowchart.Flowchart) { f.Links = f.Links[:1] },
+			expected: []string{`decision "Check" has 1 outgoing link, expected at least 2`},
+		},
+		{
+			name:     "unlabelled branch",
+			rule:     "decision-branches",
+			change:   func(f *flowchart.Flowchart) { f.Subgraphs[0].Links[0].Label = nil },
+			expected: []string{`link from decision "Check" to "Fix" has no label`},
+		},
+		{
+			name:     "no start",
+			rule:     "single-start",
+			change:   func(f *flowchart.Flowchart) { f.Nodes[0].Type = flowchart.NodeTypeProcess },
+			expected: []string{"chart has no start terminator"},
+		},
+		{
+			name: "two starts",
+			rule: "single-start",
+			change: func(f *flowchart.Flowchart) {
+				f.Nodes = append(f.Nodes, flowchart.TerminatorNode("Restart", nil))
+				f.Links = append(f.Links, flowchart.SolidLink(f.Nodes[4], f.Nodes[2], nil))
+			},
+			expected: []string{`terminator "Restart" is another start of the flow, after "Start"`},
+		},
+		{
+			name:     "step without end",
+			rule:     "reaches-end",
+			change:   func(f *flowchart.Flowchart) { f.Links = f.Links[:2] },
+			expected: []string{`node "Out" does not lead to an end terminator`, `node "In" does not lead to an end terminator`, `node "Check" does not lead to an end terminator`, `node "Fix" does not lead to an end terminator`},
+		},
+		{
+			name:     "unreachable step",
+			rule:     "unreachable",
+			change:   func(f *flowchart.Flowchart) { f.Links = f.Links[1:] },
+			expected: []string{`node "Out" cannot be reached from a start terminator`, `node "In" cannot be reached from a start terminator`, `node "End" cannot be reached from a start terminator`, `node "Check" cannot be reached from a start terminator`, `node "Fix" cannot be reached from a start terminator`},
+		},
+		{
+			name: "terminator in the middle",
+			rule: "terminator-flow",
+			change: func(f *flowchart.Flowchart) {
+				f.Nodes[1].Type = flowchart.NodeTypeTerminator
+				f.Links[2].Origin = f.Nodes[1]
+			},
+			expected: []string{`terminator "Out" both receives and continues the flow`},
+		},
+		{
+			name:   "unpaired connectors",
+			rule:   "connector-pairs",
+			change: func(f *flowchart.Flowchart) { f.Nodes[2].Label = pointTo("B") },
+			expected: []string{
+				`connector "Out" has no matching connector labelled "A" to continue the flow`,
+				`connector "In" has no matching connector labelled "B" leading to it`,
+			},
+		},
+		{
+			name:     "labelled invisible link",
+			rule:     "labelled-invisible-link",
+			change:   func(f *flowchart.Flowchart) { f.Links[3].Label = pointTo("skip") },
+			expected: []string{`link from "Start" to "End" has label "skip" but no line`},
+		},
+	}
+
+	rules := make(map[string]Rule)
+	for _, rule := range DefaultRules() {
+		rules[rule.Name()] = rule
+	}
+	if findings := Lint(approvalChart()).Findings; len(findings) != 0 {
+		t.Fatalf("Lint() of a well-formed chart = %v, expected no findings", findings)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chart := approvalChart()
+			tt.change(chart)
+			got := rules[tt.rule].Check(newChart(chart))
+			if diff := cmp.Diff(tt.expected, messages(got)); diff != "" {
+				t.Errorf("%s findings mismatch (-expected +got):\n%s", tt.rule, diff)
+			}
+		})
+	}
+}
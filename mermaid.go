@@ -2,10 +2,10 @@ package flowchart
 
 import (
 	"fmt"
-	"regexp"
 	"slices"
 	"sort"
 	"strings"
+	"unicode"
 )
 
 // mermaidFlowchartDirection converts a DirectionEnum to a Mermaid.js flowchart direction string.
@@ -56,16 +56,70 @@ func renderArrows(l Link) (string, string) {
 	}
 }
 
+// mermaidEntities maps characters that would break a quoted Mermaid.js string to their entity codes.
+var mermaidEntities = map[rune]string{
+	'"':  "#quot;",
+	'#':  "#35;",
+	';':  "#59;",
+	'<':  "#lt;",
+	'>':  "#gt;",
+	'`':  "#96;",
+	'\r': "",
+}
+
+// escapeMermaidLabel converts characters that would break a quoted Mermaid.js label to entity codes.
+// Newlines become explicit "<br/>" line breaks.
+func escapeMermaidLabel(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if entity, ok := mermaidEntities[r]; ok {
+			sb.WriteString(entity)
+			continue
+		}
+		if r == '\n' {
+			sb.WriteString("<br/>")
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// escapeMermaidTitle escapes a subgraph title, which Mermaid.js reads unquoted between square brackets.
+func escapeMermaidTitle(s string) string {
+	s = escapeMermaidLabel(s)
+	s = strings.ReplaceAll(s, "[", "#91;")
+	return strings.ReplaceAll(s, "]", "#93;")
+}
+
+// mermaidLabel returns the text to place between the double quotes of a Mermaid.js label.
+// Markdown labels are wrapped in backticks, which makes Mermaid.js render them as a markdown string;
+// their newlines are kept as-is, since markdown strings break lines on actual newlines.
+func mermaidLabel(label string, format LabelFormatEnum) string {
+	if format != LabelFormatMarkdown {
+		return escapeMermaidLabel(label)
+	}
+
+	lines := strings.Split(label, "\n")
+	for i, line := range lines {
+		lines[i] = escapeMermaidLabel(line)
+	}
+	return "`" + strings.Join(lines, "\n") + "`"
+}
+
 // renderMermaidLink generates a Mermaid.js representation of a Link between two Nodes.
+// Endpoints are referenced by their identifier in ids, or by mermaidID if they have none.
 // It returns a string that defines the link, including the line type, any arrows, and optional labels.
 // Returns an empty string if either the origin or target nodes are nil.
-func renderMermaidLink(l Link) string {
+func renderMermaidLink(l Link, ids idMap) string {
 	if l.Target == nil || l.Origin == nil {
 		return ""
 	}
+	originID := ids.lookup(l.Origin.nodeName(), mermaidID)
+	targetID := ids.lookup(l.Target.nodeName(), mermaidID)
 
 	if l.LineType == LineTypeNone {
-		return fmt.Sprintf("%s ~~~ %s", removeSpaces(l.Origin.nodeName()), removeSpaces(l.Target.nodeName()))
+		return fmt.Sprintf("%s ~~~ %s", originID, targetID)
 	}
 
 	originArrow, targetArrow := renderArrows(l)
@@ -74,66 +128,77 @@ func renderMermaidLink(l Link) string {
 		switch l.LineType {
 		case LineTypeSolid:
 			if len(targetArrow) > 0 {
-				return fmt.Sprintf("%s %s--%s %s", removeSpaces(l.Origin.nodeName()), originArrow, targetArrow, removeSpaces(l.Target.nodeName()))
+				return fmt.Sprintf("%s %s--%s %s", originID, originArrow, targetArrow, targetID)
 			}
-			return fmt.Sprintf("%s --- %s", removeSpaces(l.Origin.nodeName()), removeSpaces(l.Target.nodeName()))
+			return fmt.Sprintf("%s --- %s", originID, targetID)
 		case LineTypeDotted:
-			return fmt.Sprintf("%s %s-.-%s %s", removeSpaces(l.Origin.nodeName()), originArrow, targetArrow, removeSpaces(l.Target.nodeName()))
+			return fmt.Sprintf("%s %s-.-%s %s", originID, originArrow, targetArrow, targetID)
 		case LineTypeThick:
 			if len(targetArrow) > 0 {
-				return fmt.Sprintf("%s %s==%s %s", removeSpaces(l.Origin.nodeName()), originArrow, targetArrow, removeSpaces(l.Target.nodeName()))
+				return fmt.Sprintf("%s %s==%s %s", originID, originArrow, targetArrow, targetID)
 			}
-			return fmt.Sprintf("%s === %s", removeSpaces(l.Origin.nodeName()), removeSpaces(l.Target.nodeName()))
+			return fmt.Sprintf("%s === %s", originID, targetID)
 		default:
 			return ""
 		}
 	}
 
+	label := mermaidLabel(*l.Label, l.LabelFormat)
 	switch l.LineType {
 	case LineTypeSolid:
-		return fmt.Sprintf("%s %s-- \"%s\" --%s %s", removeSpaces(l.Origin.nodeName()), originArrow, *l.Label, targetArrow, removeSpaces(l.Target.nodeName()))
+		return fmt.Sprintf("%s %s-- \"%s\" --%s %s", originID, originArrow, label, targetArrow, targetID)
 	case LineTypeDotted:
-		return fmt.Sprintf("%s %s-. \"%s\" .-%s %s", removeSpaces(l.Origin.nodeName()), originArrow, *l.Label, targetArrow, removeSpaces(l.Target.nodeName()))
+		return fmt.Sprintf("%s %s-. \"%s\" .-%s %s", originID, originArrow, label, targetArrow, targetID)
 	case LineTypeThick:
-		return fmt.Sprintf("%s %s== \"%s\" ==%s %s", removeSpaces(l.Origin.nodeName()), originArrow, *l.Label, targetArrow, removeSpaces(l.Target.nodeName()))
+		return fmt.Sprintf("%s %s== \"%s\" ==%s %s", originID, originArrow, label, targetArrow, targetID)
 	default:
 		return ""
 	}
 }
 
 // renderMermaidNode generates a Mermaid.js representation of a Node based on its type and label.
+// The node is identified by its identifier in ids, or by mermaidID if it has none. A node without a
+// label whose identifier differs from its name displays its name instead.
 // It returns a string with the proper indentation for the node's position in the flowchart.
-func renderMermaidNode(n *Node, indents int) string {
+func renderMermaidNode(n *Node, indents int, ids idMap) string {
 	indentSpaces := strings.Repeat(" ", 4*indents)
-
-	if n.Label == nil || *n.Label == "" {
-		return fmt.Sprintf("%s%s;\n", indentSpaces, removeSpaces(n.name))
+	id := ids.lookup(n.name, mermaidID)
+
+	var label string
+	switch {
+	case n.Label != nil && *n.Label != "":
+		label = mermaidLabel(*n.Label, n.LabelFormat)
+	case id != n.name:
+		label = escapeMermaidLabel(n.name)
+	default:
+		return fmt.Sprintf("%s%s;\n", indentSpaces, id)
 	}
 	switch n.Type {
 	case NodeTypeTerminator:
-		return fmt.Sprintf("%s%s(\"%s\");\n", indentSpaces, removeSpaces(n.name), *n.Label)
+		return fmt.Sprintf("%s%s(\"%s\");\n", indentSpaces, id, label)
 	case NodeTypeProcess:
-		return fmt.Sprintf("%s%s[\"%s\"];\n", indentSpaces, removeSpaces(n.name), *n.Label)
+		return fmt.Sprintf("%s%s[\"%s\"];\n", indentSpaces, id, label)
 	case NodeTypeSubprocess:
-		return fmt.Sprintf("%s%s[[\"%s\"]];\n", indentSpaces, removeSpaces(n.name), *n.Label)
+		return fmt.Sprintf("%s%s[[\"%s\"]];\n", indentSpaces, id, label)
 	case NodeTypeDecision:
-		return fmt.Sprintf("%s%s{\"%s\"};\n", indentSpaces, removeSpaces(n.name), *n.Label)
+		return fmt.Sprintf("%s%s{\"%s\"};\n", indentSpaces, id, label)
 	case NodeTypeInputOutput:
-		return fmt.Sprintf("%s%s[/\"%s\"/];\n", indentSpaces, removeSpaces(n.name), *n.Label)
+		return fmt.Sprintf("%s%s[/\"%s\"/];\n", indentSpaces, id, label)
 	case NodeTypeConnector:
-		return fmt.Sprintf("%s%s((\"%s\"));\n", indentSpaces, removeSpaces(n.name), *n.Label)
+		return fmt.Sprintf("%s%s((\"%s\"));\n", indentSpaces, id, label)
 	case NodeTypeDatabase:
-		return fmt.Sprintf("%s%s[(\"%s\")];\n", indentSpaces, removeSpaces(n.name), *n.Label)
+		return fmt.Sprintf("%s%s[(\"%s\")];\n", indentSpaces, id, label)
 	default:
-		return fmt.Sprintf("%s%s(\"%s\");\n", indentSpaces, removeSpaces(n.name), *n.Label)
+		return fmt.Sprintf("%s%s(\"%s\");\n", indentSpaces, id, label)
 	}
 }
 
 // renderMermaidFlowchart generates a Mermaid.js representation of a Flowchart.
-// It recursively renders subgraphs and their nodes, as well as links between nodes.
+// It recursively renders subgraphs and their nodes, as well as links between nodes, identifying
+// nodes and subgraphs by their identifier in ids.
 // If the flowchart is a subgraph, it starts and ends the subgraph block.
 // It returns a string that defines the entire flowchart in Mermaid.js syntax.
-func renderMermaidFlowchart(f *Flowchart, indents int, subgraph bool) string {
+func renderMermaidFlowchart(f *Flowchart, indents int, subgraph bool, ids idMap) string {
 	indentSpaces := strings.Repeat(" ", 4*indents)
 	var sb strings.Builder
 
@@ -144,8 +209,8 @@ func renderMermaidFlowchart(f *Flowchart, indents int, subgraph bool) string {
 		}
 		sb.WriteString(fmt.Sprintf("%ssubgraph %s [%s];\n",
 			indentSpaces,
-			removeSpaces(*f.Title),
-			*f.Title,
+			ids.lookup(*f.Title, mermaidID),
+			escapeMermaidTitle(*f.Title),
 		))
 		// subgraph direction - indented
 		sb.WriteString(fmt.Sprintf("%s%sdirection %s;\n",
@@ -162,12 +227,12 @@ func renderMermaidFlowchart(f *Flowchart, indents int, subgraph bool) string {
 
 	// nodes
 	for _, node := range f.Nodes {
-		sb.WriteString(fmt.Sprintf("%s", renderMermaidNode(node, indents+1)))
+		sb.WriteString(fmt.Sprintf("%s", renderMermaidNode(node, indents+1, ids)))
 	}
 
 	// subgraphs
 	for _, subgraph := range f.Subgraphs {
-		sb.WriteString(fmt.Sprintf("%s", renderMermaidFlowchart(subgraph, indents+1, true)))
+		sb.WriteString(fmt.Sprintf("%s", renderMermaidFlowchart(subgraph, indents+1, true, ids)))
 	}
 
 	if subgraph {
@@ -178,135 +243,199 @@ func renderMermaidFlowchart(f *Flowchart, indents int, subgraph bool) string {
 	if !subgraph {
 		allLinks := getAllLinks(f)
 		for _, link := range allLinks {
-			sb.WriteString(fmt.Sprintf("    %s;\n", renderMermaidLink(link)))
+			sb.WriteString(fmt.Sprintf("    %s;\n", renderMermaidLink(link, ids)))
 		}
+		sb.WriteString(renderMermaidStyles(f, allLinks, ids))
 	}
 
 	return sb.String()
 }
 
-// RenderMermaid generates a Mermaid.js flowchart string for the given Flowchart object.
-// It validates that all nodes in the flowchart have valid names, returning an error if any names are invalid.
-// It returns the Mermaid.js representation of the flowchart or an error if validation fails.
-func RenderMermaid(f *Flowchart) (string, error) {
-	err := validateMermaid(f)
-	if err != nil {
-		return "", err
+// mermaidStyleProperties renders a Style as the comma-separated CSS properties used by Mermaid.js
+// style statements. Commas inside values are escaped.
+func mermaidStyleProperties(s Style) string {
+	var props []string
+	for _, p := range []struct{ key, value string }{
+		{"fill", s.Fill},
+		{"stroke", s.Stroke},
+		{"stroke-width", s.StrokeWidth},
+		{"color", s.Color},
+		{"font-family", s.FontFamily},
+		{"font-size", s.FontSize},
+		{"font-weight", s.FontWeight},
+	} {
+		if p.value != "" {
+			props = append(props, p.key+":"+strings.ReplaceAll(p.value, ",", `\,`))
+		}
 	}
-
-	return renderMermaidFlowchart(f, 0, false), nil
+	return strings.Join(props, ",")
 }
 
-// validateMermaid validates the Flowchart structure to ensure it adheres to Mermaid.js requirements.
-// It checks for the following violations:
-// 1. All node and subgraph names must be valid according to Mermaid.js naming conventions.
-// 2. The flowchart must not contain nested subgraphs.
-// 3. All node and subgraph names must be unique within the flowchart.
+// renderMermaidStyles generates the styling statements of a Flowchart:
+// - a "classDef" statement for every style class defined anywhere in the flowchart,
+// - a "class" statement per class listing the nodes and subgraphs it is assigned to,
+// - a "style" statement for every node and subgraph with an inline style, and
+// - a "linkStyle" statement for every styled link, indexed by its position in links.
 //
-// If any of these conditions are not met, it aggregates the corresponding violation messages
-// and returns a single error detailing all violations. If no violations are found, it returns nil.
-//
-// Example:
-//
-//	err := validateMermaid(flowchart)
-//	if err != nil {
-//	    // Handle validation errors
-//	}
-func validateMermaid(f *Flowchart) error {
-	violations := make([]string, 0, 3)
+// Mermaid.js cannot assign classes to links, so the classes of a link are merged with its
+// inline style into a single linkStyle statement. Classes without any properties are skipped.
+func renderMermaidStyles(f *Flowchart, links []Link, ids idMap) string {
+	var sb strings.Builder
 
-	if !hasValidMermaidNames(f) {
-		violations = append(violations, "contains invalid mermaid names")
-	}
-	if haveSubgraphs(f.Subgraphs) {
-		violations = append(violations, "contains nested subgraphs")
+	classDefs := allClassDefs(f)
+	for _, class := range classDefs {
+		if class.Style.isEmpty() {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("    classDef %s %s;\n", class.Name, mermaidStyleProperties(class.Style)))
 	}
-	if !hasUniqueNodeAndSubgraphNames(f) {
-		violations = append(violations, "contains repeated node and/or subgraph names")
+
+	elements := styledElements(f, ids)
+	for _, class := range classDefs {
+		if class.Style.isEmpty() {
+			continue
+		}
+		var members []string
+		for _, element := range elements {
+			if slices.Contains(element.classes, class.Name) {
+				members = append(members, element.id)
+			}
+		}
+		if len(members) > 0 {
+			sb.WriteString(fmt.Sprintf("    class %s %s;\n", strings.Join(members, ","), class.Name))
+		}
 	}
 
-	if len(violations) > 0 {
-		return fmt.Errorf("flowchart contains violations: %s", strings.Join(violations, ", "))
+	for _, element := range elements {
+		if element.style != nil && !element.style.isEmpty() {
+			sb.WriteString(fmt.Sprintf("    style %s %s;\n", element.id, mermaidStyleProperties(*element.style)))
+		}
 	}
-	return nil
-}
 
-// haveSubgraphs checks whether any of the provided Flowcharts contain nested subgraphs.
-// It iterates through each Flowchart in the slice and returns true if any Flowchart has one or more subgraphs.
-//
-// Parameters:
-//   - charts: A slice of Flowchart pointers to be checked for nested subgraphs.
-//
-// Returns:
-//   - bool: true if at least one Flowchart contains nested subgraphs; false otherwise.
-//
-// Example:
-//
-//	hasNested := haveSubgraphs(flowchart.Subgraphs)
-//	if hasNested {
-//	    // Handle nested subgraphs
-//	}
-func haveSubgraphs(charts []*Flowchart) bool {
-	for _, chart := range charts {
-		if chart.Subgraphs != nil && len(chart.Subgraphs) > 0 {
-			return true
+	for i, link := range links {
+		style := resolveStyle(classDefs, link.Classes, link.Style)
+		if !style.isEmpty() {
+			sb.WriteString(fmt.Sprintf("    linkStyle %d %s;\n", i, mermaidStyleProperties(style)))
 		}
 	}
-	return false
+
+	return sb.String()
 }
 
-// hasValidMermaidNames checks if all nodes and subgraphs in the flowchart have valid Mermaid.js names.
-// It returns true if all names are valid; otherwise, it returns false.
-func hasValidMermaidNames(f *Flowchart) bool {
+// styledElement is a node or subgraph together with its Mermaid.js identifier and styling.
+type styledElement struct {
+	id      string
+	classes []string
+	style   *Style
+}
+
+// styledElements returns the nodes and subgraphs of the flowchart tree in rendering order, excluding
+// the flowchart itself.
+func styledElements(f *Flowchart, ids idMap) []styledElement {
+	var elements []styledElement
 	for _, node := range f.Nodes {
-		if !isValidMermaidNodeName(node.name) {
-			return false
-		}
+		elements = append(elements, styledElement{id: ids.lookup(node.name, mermaidID), classes: node.Classes, style: node.Style})
 	}
 	for _, subgraph := range f.Subgraphs {
-		if subgraph.Title == nil || *subgraph.Title == "" || !hasValidMermaidNames(subgraph) {
-			return false
+		if subgraph.Title != nil && *subgraph.Title != "" {
+			elements = append(elements, styledElement{id: ids.lookup(*subgraph.Title, mermaidID), classes: subgraph.Classes, style: subgraph.Style})
 		}
+		elements = append(elements, styledElements(subgraph, ids)...)
 	}
-	return true
+	return elements
 }
 
-// hasUniqueNodeAndSubgraphNames checks whether all node names and subgraph titles within the Flowchart are unique.
-// It ensures that there are no duplicate names among nodes and no duplicate titles among subgraphs.
-// This function iterates through all nodes and subgraphs, collecting their names and titles and verifying their uniqueness.
-//
-// Parameters:
-//   - f: A pointer to the Flowchart to be validated.
+// RenderMermaid generates a Mermaid.js flowchart string for the given Flowchart object.
+// It validates that all nodes in the flowchart have valid names, returning an error if any names are invalid.
+// Every node and subgraph is given a Mermaid.js identifier derived from its name by mermaidID.
+// It returns the Mermaid.js representation of the flowchart or an error if validation fails.
+func RenderMermaid(f *Flowchart) (string, error) {
+	err := validateMermaid(f)
+	if err != nil {
+		return "", err
+	}
+
+	ids, err := newIDMap(f, mermaidID)
+	if err != nil {
+		return "", err
+	}
+	return renderMermaidFlowchart(f, 0, false, ids), nil
+}
+
+// validateMermaid validates the Flowchart structure to ensure it adheres to Mermaid.js requirements.
+// It checks for the following violations:
+// 1. All node and subgraph names must be valid according to Mermaid.js naming conventions.
+// 2. All node and subgraph names must be unique across the whole flowchart, including nested subgraphs.
+// 3. No two names may end up with the same Mermaid.js identifier.
+// 4. Style classes must have valid, unique names and every assigned class must be defined.
 //
-// Returns:
-//   - bool: Returns true if all node names and subgraph titles are unique within the Flowchart.
-//     Returns false if there are any duplicate names or titles.
+// It returns a *ValidationError holding every violation found, or nil if there are none.
 //
 // Example:
 //
-//	unique := hasUniqueNodeAndSubgraphNames(flowchart)
-//	if !unique {
-//	    // Handle duplicate names or titles
+//	var validationErr *ValidationError
+//	if errors.As(validateMermaid(flowchart), &validationErr) {
+//	    // Highlight validationErr.Violations
 //	}
-func hasUniqueNodeAndSubgraphNames(f *Flowchart) bool {
-	var names []string
+func validateMermaid(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, mermaidNameViolations(f)...)
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, idCollisionViolations(f, mermaidID)...)
+	violations = append(violations, styleClassViolations(f)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	return newValidationError(violations...)
+}
 
-	for _, node := range f.Nodes {
-		if slices.Contains(names, node.name) {
-			return false
-		}
-		names = append(names, node.name)
-	}
-	for _, subgraph := range f.Subgraphs {
-		if subgraph.Title != nil {
-			if slices.Contains(names, *subgraph.Title) {
-				return false
+// mermaidNameViolations reports every node in the flowchart tree with an invalid Mermaid.js name and
+// every subgraph without a title.
+func mermaidNameViolations(f *Flowchart) []Violation {
+	violations := untitledSubgraphViolations(f)
+	walkFlowchart(f, func(f *Flowchart, path []string) {
+		for _, node := range f.Nodes {
+			if !isValidMermaidNodeName(node.name) {
+				violations = append(violations, Violation{
+					Code:    ErrInvalidName,
+					Path:    path,
+					Element: node,
+					Message: fmt.Sprintf("node %q has an invalid mermaid name", node.name),
+				})
 			}
-			names = append(names, *subgraph.Title)
 		}
+	})
+	return violations
+}
 
+// idCollisionViolations reports every node and subgraph whose name is given the same identifier as
+// a different name that comes earlier in the flowchart tree, even after newIDMap resolves collisions.
+func idCollisionViolations(f *Flowchart, sanitize func(string) string) []Violation {
+	ids := assignIDs(f, sanitize)
+	var violations []Violation
+	owners := make(map[string]string)
+	check := func(name string, path []string, element any, kind string) {
+		id := ids[name]
+		if owner, ok := owners[id]; ok && owner != name {
+			violations = append(violations, Violation{
+				Code:    ErrCollidingID,
+				Path:    path,
+				Element: element,
+				Message: fmt.Sprintf("%s %q has the same id %q as %q", kind, name, id, owner),
+			})
+			return
+		}
+		owners[id] = name
 	}
-	return true
+	walkFlowchart(f, func(f *Flowchart, path []string) {
+		for _, node := range f.Nodes {
+			check(node.name, path, node, "node")
+		}
+		for _, subgraph := range f.Subgraphs {
+			if subgraph.Title != nil && *subgraph.Title != "" {
+				check(*subgraph.Title, path, subgraph, "subgraph")
+			}
+		}
+	})
+	return violations
 }
 
 // getAllLinks collects all Links from the flowchart, including links from subgraphs.
@@ -329,39 +458,89 @@ func getAllLinks(f *Flowchart) []Link {
 	return allLinks
 }
 
-// isValidMermaidNodeName checks if a string is a valid Mermaid.js node name.
-// A valid node name contains only letters, digits, underscores, dashes, and spaces.
+// isValidMermaidNodeName checks if a string can be used as the name of a node or subgraph in Mermaid.js.
+// Any name with at least one non-whitespace character is valid, since mermaidID derives a safe
+// identifier from it.
 // It returns true if the name is valid; otherwise, it returns false.
 func isValidMermaidNodeName(s string) bool {
-	// Define regex for a valid Mermaid.js node name
-	// Allows letters, digits, underscores, and dashes only
-	re := regexp.MustCompile(`^[a-zA-Z0-9_\- ]+$`)
-	return re.MatchString(s)
+	return strings.TrimSpace(s) != ""
+}
+
+// mermaidID derives a Mermaid.js node identifier from a node name or subgraph title.
+// Spaces are removed and every character other than an ASCII letter, digit, underscore or
+// non-leading dash is replaced by an underscore. When a replacement was needed, or the result
+// is empty or the reserved word "end", a hash of the original name is appended so that the
+// identifier stays unique and stable.
+func mermaidID(name string) string {
+	compact := removeSpaces(name)
+
+	var sb strings.Builder
+	for i, r := range compact {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || (r == '-' && i > 0)) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+
+	id := sb.String()
+	if id != compact || id == "" || strings.EqualFold(id, "end") {
+		return id + "_" + nameHash(name)
+	}
+	return id
+}
+
+// isMermaidIDFor reports whether id is one of the identifiers newIDMap may assign to name.
+func isMermaidIDFor(id, name string) bool {
+	base := mermaidID(name)
+	return id == base || id == collisionID(base, name)
 }
 
 // GetMermaidFriendlyFlowchart transforms a Flowchart into a Mermaid-friendly version.
 // It performs the following steps:
-// 1. Flattens all nested subgraphs into a single-level structure.
+// 1. Flattens every subgraph without a title into its parent, since Mermaid.js cannot render it.
 // 2. Removes any nodes, subgraphs, and links that do not conform to Mermaid.js naming conventions.
 //
+// Titled subgraphs keep their nesting, which Mermaid.js renders natively.
+//
 // Parameters:
 // - f: A pointer to the original Flowchart to be transformed.
 //
 // Returns:
 // - *Flowchart: A new Flowchart instance that is compatible with Mermaid.js rendering.
 func GetMermaidFriendlyFlowchart(f *Flowchart) *Flowchart {
+	return removeNonMermaidNames(hoistUntitledSubgraphs(f))
+}
+
+// hoistUntitledSubgraphs returns a copy of the Flowchart in which every subgraph without a title
+// is flattened and its nodes and links are moved into the enclosing flowchart.
+func hoistUntitledSubgraphs(f *Flowchart) *Flowchart {
+	nodes := slices.Clone(f.Nodes)
+	links := slices.Clone(f.Links)
+	classDefs := slices.Clone(f.ClassDefs)
 	var subgraphs []*Flowchart
+
 	for _, subgraph := range f.Subgraphs {
-		subgraphs = append(subgraphs, flattenFlowchart(subgraph))
+		if subgraph.Title == nil || *subgraph.Title == "" {
+			flattened := flattenFlowchart(subgraph)
+			nodes = append(nodes, flattened.Nodes...)
+			links = append(links, flattened.Links...)
+			classDefs = append(classDefs, flattened.ClassDefs...)
+			continue
+		}
+		subgraphs = append(subgraphs, hoistUntitledSubgraphs(subgraph))
 	}
 
-	return removeNonMermaidNames(&Flowchart{
+	return &Flowchart{
 		Direction: f.Direction,
 		Title:     f.Title,
-		Nodes:     f.Nodes,
+		Nodes:     nodes,
 		Subgraphs: subgraphs,
-		Links:     f.Links,
-	})
+		Links:     links,
+		ClassDefs: classDefs,
+		Classes:   f.Classes,
+		Style:     f.Style,
+	}
 }
 
 // removeNonMermaidNames filters out any nodes, subgraphs, and links that have names
@@ -399,6 +578,9 @@ func removeNonMermaidNames(f *Flowchart) *Flowchart {
 		Nodes:     nodes,
 		Subgraphs: subgraphs,
 		Links:     links,
+		ClassDefs: f.ClassDefs,
+		Classes:   f.Classes,
+		Style:     f.Style,
 	}
 }
 
@@ -423,5 +605,8 @@ func flattenFlowchart(f *Flowchart) *Flowchart {
 		Nodes:     nodes,
 		Subgraphs: nil, // Subgraphs are flattened
 		Links:     links,
+		ClassDefs: allClassDefs(f),
+		Classes:   f.Classes,
+		Style:     f.Style,
 	}
 }
@@ -0,0 +1,755 @@
+package flowchart
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// mermaidHeaderRe matches the "flowchart" or "graph" declaration and captures its direction.
+	mermaidHeaderRe = regexp.MustCompile(`^(?:flowchart|graph)(?:\s+(\w+))?$`)
+	// mermaidSubgraphRe matches a subgraph header of the form "id [Title]" or `id["Title"]`.
+	mermaidSubgraphRe = regexp.MustCompile(`^(\S+?)\s*\[(.*)\]$`)
+	// mermaidPlainLinkRe matches an unlabelled link operator such as "-->", "<-.->", "o--o" or "~~~".
+	mermaidPlainLinkRe = regexp.MustCompile(`^([<ox]?)(~{3,}|-{2,}|={2,}|-\.+-)([>ox]?)`)
+	// mermaidLabelledLinkRe matches the opening half of a labelled link such as `-- "label" -->`.
+	mermaidLabelledLinkRe = regexp.MustCompile(`^([<ox]?)(--|==|-\.)\s+`)
+	// mermaidPipeLabelRe matches a label written after the link operator, as in "-->|label|".
+	mermaidPipeLabelRe = regexp.MustCompile(`^\s*\|([^|]*)\|`)
+	// mermaidEntityRe matches an entity code such as "#quot;" or "#35;".
+	mermaidEntityRe = regexp.MustCompile(`#(\w+);`)
+	// mermaidEntityPrefixRe matches text ending in an entity code that is missing its final semicolon.
+	mermaidEntityPrefixRe = regexp.MustCompile(`#\w+$`)
+	// mermaidLineBreakRe matches an HTML line break in a label.
+	mermaidLineBreakRe = regexp.MustCompile(`(?i)<br\s*/?>`)
+	// mermaidClassShorthandRe matches the ":::class" suffix that assigns a class to a node.
+	mermaidClassShorthandRe = regexp.MustCompile(`^:::([\w-]+)`)
+	// mermaidStyleSeparatorRe matches the commas separating style properties, but not escaped ones.
+	mermaidStyleSeparatorRe = regexp.MustCompile(`(^|[^\\]),`)
+)
+
+// mermaidNamedEntities maps the named entity codes understood by Mermaid to their characters.
+var mermaidNamedEntities = map[string]string{
+	"quot": `"`,
+	"amp":  "&",
+	"lt":   "<",
+	"gt":   ">",
+	"apos": "'",
+	"nbsp": "\u00a0",
+}
+
+// mermaidLinkClosers holds, for each labelled link opener, the pattern matching its closing half.
+var mermaidLinkClosers = map[string]*regexp.Regexp{
+	"--": regexp.MustCompile(`\s*(-{2,})([>ox]?)`),
+	"==": regexp.MustCompile(`\s*(={2,})([>ox]?)`),
+	"-.": regexp.MustCompile(`\s*(\.-+)([>ox]?)`),
+}
+
+// mermaidNodeShapes lists the supported node shape delimiters, longest openers first so that
+// "[[" is tried before "[".
+var mermaidNodeShapes = []struct {
+	open, close string
+	typ         NodeTypeEnum
+}{
+	{"(((", ")))", NodeTypeConnector},
+	{"[[", "]]", NodeTypeSubprocess},
+	{"[(", ")]", NodeTypeDatabase},
+	{"[/", "/]", NodeTypeInputOutput},
+	{"((", "))", NodeTypeConnector},
+	{"([", "])", NodeTypeTerminator},
+	{"[", "]", NodeTypeProcess},
+	{"(", ")", NodeTypeTerminator},
+	{"{", "}", NodeTypeDecision},
+}
+
+// mermaidIgnoredStatements lists statement keywords that are accepted but not represented in
+// the Flowchart model.
+var mermaidIgnoredStatements = []string{"click"}
+
+// pendingMermaidLink is a link whose endpoints are still identified by their Mermaid IDs.
+// Links are resolved once the whole source has been read, because a link may refer to a
+// subgraph that is only declared further down.
+type pendingMermaidLink struct {
+	origin, target string
+	link           Link
+	line           int
+}
+
+// pendingMermaidStyle is a class assignment or inline style for the node or subgraph with
+// the given Mermaid ID, applied once the whole source has been read.
+type pendingMermaidStyle struct {
+	id      string
+	classes []string
+	style   *Style
+	line    int
+}
+
+// mermaidParser holds the state needed while reading Mermaid flowchart source.
+type mermaidParser struct {
+	root      *Flowchart
+	stack     []*Flowchart              // Open subgraphs, with the root flowchart first
+	nodes     map[string]*Node          // Nodes by Mermaid ID
+	nodeScope map[string]*Flowchart     // The flowchart or subgraph each node belongs to
+	subgraphs map[string]*Flowchart     // Subgraphs by Mermaid ID
+	parents   map[*Flowchart]*Flowchart // The flowchart or subgraph containing each subgraph
+	links     []pendingMermaidLink
+	styles    []pendingMermaidStyle // Class assignments and inline styles of nodes and subgraphs
+	linkStyle map[int]Style         // Inline styles of links, by link index
+}
+
+// ParseMermaid reads Mermaid flowchart source and returns the equivalent Flowchart.
+// It understands everything RenderMermaid emits (front-matter titles, all node shapes,
+// subgraphs with a direction, and every line and arrow type, with or without labels) as well as
+// common hand-written forms such as "A --> B & C", chained links "A --> B --> C", pipe labels
+// "A -->|label| B" and nested subgraphs.
+//
+// Nodes are named after their Mermaid ID, unless their label is the name that RenderMermaid
+// derived the ID from, in which case the label becomes the name. Nodes without a shape become
+// process nodes. Each link is stored
+// in the innermost subgraph containing both of its endpoints. Style classes are defined on the
+// root flowchart, and linkStyle statements become inline link styles. Click statements are skipped.
+func ParseMermaid(r io.Reader) (*Flowchart, error) {
+	p := &mermaidParser{
+		nodes:     make(map[string]*Node),
+		nodeScope: make(map[string]*Flowchart),
+		subgraphs: make(map[string]*Flowchart),
+		parents:   make(map[*Flowchart]*Flowchart),
+		linkStyle: make(map[int]Style),
+	}
+
+	scanner := bufio.NewScanner(r)
+	var title *string
+	lineNo := 0
+	inFrontMatter := false
+	pending := ""
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if pending != "" {
+			line = pending + "\n" + strings.TrimRightFunc(scanner.Text(), unicode.IsSpace)
+			pending = ""
+		}
+		// a quoted markdown label may span several lines
+		if strings.Count(line, `"`)%2 == 1 {
+			pending = line
+			continue
+		}
+
+		// front matter
+		if line == "---" && p.root == nil && (lineNo == 1 || inFrontMatter) {
+			inFrontMatter = !inFrontMatter
+			continue
+		}
+		if inFrontMatter {
+			if value, ok := strings.CutPrefix(line, "title:"); ok {
+				title = pointTo(strings.Trim(strings.TrimSpace(value), `"'`))
+			}
+			continue
+		}
+
+		for _, stmt := range splitMermaidStatements(line) {
+			if err := p.parseStatement(stmt, lineNo, title); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if pending != "" {
+		return nil, fmt.Errorf("line %d: unterminated label", lineNo)
+	}
+
+	if p.root == nil {
+		return nil, fmt.Errorf("missing flowchart declaration")
+	}
+	if len(p.stack) > 1 {
+		return nil, fmt.Errorf("line %d: subgraph %s is not closed", lineNo, *p.stack[len(p.stack)-1].Title)
+	}
+
+	p.removeSubgraphNodes()
+	if err := p.applyStyles(); err != nil {
+		return nil, err
+	}
+	return p.root, p.resolveLinks()
+}
+
+// splitMermaidStatements splits a line into its semicolon-separated statements, ignoring
+// semicolons inside double quotes or ending an entity code, and drops comments and empty statements.
+func splitMermaidStatements(line string) []string {
+	if strings.HasPrefix(line, "%%") {
+		return nil
+	}
+
+	var statements []string
+	inQuotes := false
+	start := 0
+	for i, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ';' && !inQuotes && (isMermaidStyleStatement(line[start:i]) || !mermaidEntityPrefixRe.MatchString(line[start:i])):
+			statements = append(statements, line[start:i])
+			start = i + 1
+		}
+	}
+	statements = append(statements, line[start:])
+
+	return slices.DeleteFunc(statements, func(s string) bool {
+		return strings.TrimSpace(s) == ""
+	})
+}
+
+// isMermaidStyleStatement reports whether stmt is a styling statement. Styling statements have no
+// labels, so a semicolon following a colour such as "#f00" ends the statement.
+func isMermaidStyleStatement(stmt string) bool {
+	keyword, _, _ := strings.Cut(strings.TrimSpace(stmt), " ")
+	return slices.Contains([]string{"classDef", "class", "style", "linkStyle"}, keyword)
+}
+
+// parseStatement interprets a single Mermaid statement.
+func (p *mermaidParser) parseStatement(stmt string, line int, title *string) error {
+	stmt = strings.TrimSpace(stmt)
+	keyword, rest, _ := strings.Cut(stmt, " ")
+	rest = strings.TrimSpace(rest)
+
+	if p.root == nil {
+		match := mermaidHeaderRe.FindStringSubmatch(stmt)
+		if match == nil {
+			return fmt.Errorf("line %d: expected flowchart declaration, got %q", line, stmt)
+		}
+		direction, err := parseMermaidDirection(match[1])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		p.root = basicFlowchart(title, direction)
+		p.stack = []*Flowchart{p.root}
+		return nil
+	}
+
+	switch {
+	case keyword == "subgraph":
+		return p.openSubgraph(rest, line)
+	case keyword == "end" && rest == "":
+		if len(p.stack) == 1 {
+			return fmt.Errorf("line %d: end without subgraph", line)
+		}
+		p.stack = p.stack[:len(p.stack)-1]
+		return nil
+	case keyword == "direction":
+		direction, err := parseMermaidDirection(rest)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		p.current().Direction = direction
+		return nil
+	case keyword == "classDef":
+		return p.parseClassDef(rest, line)
+	case keyword == "class":
+		ids, classes, _ := strings.Cut(rest, " ")
+		for _, id := range strings.Split(ids, ",") {
+			p.styles = append(p.styles, pendingMermaidStyle{id: strings.TrimSpace(id), classes: strings.Split(strings.TrimSpace(classes), ","), line: line})
+		}
+		return nil
+	case keyword == "style":
+		id, props, _ := strings.Cut(rest, " ")
+		p.styles = append(p.styles, pendingMermaidStyle{id: id, style: pointTo(parseMermaidStyle(props)), line: line})
+		return nil
+	case keyword == "linkStyle":
+		return p.parseLinkStyle(rest, line)
+	case slices.Contains(mermaidIgnoredStatements, keyword):
+		return nil
+	}
+
+	return p.parseLinkStatement(stmt, line)
+}
+
+// parseMermaidDirection converts a Mermaid direction keyword to a DirectionEnum.
+// An empty direction defaults to DirectionVertical, as it does in Mermaid.
+func parseMermaidDirection(s string) (DirectionEnum, error) {
+	switch s {
+	case "LR":
+		return DirectionHorizontalRight, nil
+	case "RL":
+		return DirectionHorizontalLeft, nil
+	case "TB", "TD", "":
+		return DirectionVertical, nil
+	}
+	return DirectionVertical, fmt.Errorf("unsupported direction %q", s)
+}
+
+// current returns the innermost open subgraph, or the root flowchart if none is open.
+func (p *mermaidParser) current() *Flowchart {
+	return p.stack[len(p.stack)-1]
+}
+
+// openSubgraph starts a new subgraph inside the current one. The header may be "id [Title]",
+// `id["Title"]` or just a title, in which case the ID is the title without spaces.
+func (p *mermaidParser) openSubgraph(header string, line int) error {
+	if header == "" {
+		return fmt.Errorf("line %d: subgraph without a title", line)
+	}
+
+	id, title := removeSpaces(header), unquoteMermaidLabel(header)
+	if match := mermaidSubgraphRe.FindStringSubmatch(header); match != nil {
+		id, title = match[1], unquoteMermaidLabel(match[2])
+	}
+	title, _ = decodeMermaidLabel(title)
+	if _, ok := p.subgraphs[id]; ok {
+		return fmt.Errorf("line %d: subgraph %s is declared twice", line, id)
+	}
+
+	parent := p.current()
+	subgraph := basicFlowchart(pointTo(title), parent.Direction)
+	parent.Subgraphs = append(parent.Subgraphs, subgraph)
+	p.subgraphs[id] = subgraph
+	p.parents[subgraph] = parent
+	p.stack = append(p.stack, subgraph)
+	return nil
+}
+
+// parseLinkStatement parses a statement made of node groups separated by link operators, such
+// as "A", "A --> B", "A & B --> C" or "A -- text --> B --> C".
+func (p *mermaidParser) parseLinkStatement(stmt string, line int) error {
+	var groups [][]string
+	var links []Link
+
+	rest := stmt
+	for {
+		ids, remaining, err := p.parseNodeGroup(rest)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		groups = append(groups, ids)
+
+		remaining = strings.TrimSpace(remaining)
+		if remaining == "" {
+			break
+		}
+		link, remaining, err := parseMermaidLinkOperator(remaining)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		links = append(links, link)
+		rest = remaining
+	}
+
+	for i, link := range links {
+		for _, origin := range groups[i] {
+			for _, target := range groups[i+1] {
+				p.links = append(p.links, pendingMermaidLink{origin: origin, target: target, link: link, line: line})
+			}
+		}
+	}
+	return nil
+}
+
+// parseNodeGroup parses one or more node references joined by "&" and returns their IDs and
+// the unparsed remainder of the statement.
+func (p *mermaidParser) parseNodeGroup(s string) ([]string, string, error) {
+	var ids []string
+	for {
+		id, rest, err := p.parseNodeRef(s)
+		if err != nil {
+			return nil, "", err
+		}
+		ids = append(ids, id)
+
+		rest = strings.TrimSpace(rest)
+		after, ok := strings.CutPrefix(rest, "&")
+		if !ok {
+			return ids, rest, nil
+		}
+		s = after
+	}
+}
+
+// parseNodeRef parses a node ID with an optional shape and label, such as `A`, `A["Label"]`
+// or `A{Label}`, records the node, and returns its ID and the unparsed remainder.
+func (p *mermaidParser) parseNodeRef(s string) (string, string, error) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	id, rest := scanMermaidID(s)
+	if id == "" {
+		return "", "", fmt.Errorf("expected node id at %q", s)
+	}
+
+	for _, shape := range mermaidNodeShapes {
+		after, ok := strings.CutPrefix(rest, shape.open)
+		if !ok {
+			continue
+		}
+		label, remaining, err := scanMermaidShapeLabel(after, shape.close)
+		if err != nil {
+			return "", "", fmt.Errorf("node %s: %w", id, err)
+		}
+		text, format := decodeMermaidLabel(label)
+		node := &Node{name: id, Type: shape.typ, Label: pointTo(text), LabelFormat: format}
+		if format == LabelFormatText && text != id && isMermaidIDFor(id, text) {
+			// the label only displays the name that the id was derived from
+			node.name, node.Label = text, nil
+		}
+		p.mention(id, node)
+		return id, p.parseClassShorthand(id, remaining), nil
+	}
+
+	p.mention(id, nil)
+	return id, p.parseClassShorthand(id, rest), nil
+}
+
+// parseClassShorthand records the class of a ":::class" suffix at the start of s, if there is one,
+// and returns the rest of s.
+func (p *mermaidParser) parseClassShorthand(id, s string) string {
+	match := mermaidClassShorthandRe.FindStringSubmatch(s)
+	if match == nil {
+		return s
+	}
+	p.styles = append(p.styles, pendingMermaidStyle{id: id, classes: []string{match[1]}})
+	return s[len(match[0]):]
+}
+
+// parseClassDef adds the style classes of a "classDef" statement to the root flowchart.
+// The statement may define several classes with the same properties, as in "classDef a,b fill:red".
+func (p *mermaidParser) parseClassDef(s string, line int) error {
+	names, props, _ := strings.Cut(s, " ")
+	style := parseMermaidStyle(props)
+	for _, name := range strings.Split(names, ",") {
+		if err := p.root.AddClassDef(name, style); err != nil {
+			return fmt.Errorf("line %d: class %s: %w", line, name, err)
+		}
+	}
+	return nil
+}
+
+// parseLinkStyle records the inline style of a "linkStyle" statement for every listed link index.
+// Default link styles, as in "linkStyle default stroke:red", are not represented and are skipped.
+func (p *mermaidParser) parseLinkStyle(s string, line int) error {
+	indexes, props, _ := strings.Cut(s, " ")
+	if indexes == "default" {
+		return nil
+	}
+	style := parseMermaidStyle(props)
+	for _, index := range strings.Split(indexes, ",") {
+		i, err := strconv.Atoi(index)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid link index %q", line, index)
+		}
+		p.linkStyle[i] = p.linkStyle[i].merge(style)
+	}
+	return nil
+}
+
+// parseMermaidStyle parses comma-separated CSS properties such as "fill:#f9f,stroke-width:2px".
+// Escaped commas are kept in values; properties that Style cannot represent are skipped.
+func parseMermaidStyle(s string) Style {
+	var style Style
+	props := mermaidStyleSeparatorRe.ReplaceAllString(strings.TrimSpace(s), "$1\x00")
+	for _, prop := range strings.Split(props, "\x00") {
+		key, value, _ := strings.Cut(prop, ":")
+		value = strings.ReplaceAll(strings.TrimSpace(value), `\,`, ",")
+		switch strings.TrimSpace(key) {
+		case "fill":
+			style.Fill = value
+		case "stroke":
+			style.Stroke = value
+		case "stroke-width":
+			style.StrokeWidth = value
+		case "color":
+			style.Color = value
+		case "font-family":
+			style.FontFamily = value
+		case "font-size":
+			style.FontSize = value
+		case "font-weight":
+			style.FontWeight = value
+		}
+	}
+	return style
+}
+
+// applyStyles assigns the recorded classes and inline styles to their nodes and subgraphs.
+func (p *mermaidParser) applyStyles() error {
+	for _, pending := range p.styles {
+		var classes *[]string
+		var style **Style
+		if subgraph, ok := p.subgraphs[pending.id]; ok {
+			classes, style = &subgraph.Classes, &subgraph.Style
+		} else if node, ok := p.nodes[pending.id]; ok {
+			classes, style = &node.Classes, &node.Style
+		} else {
+			return fmt.Errorf("line %d: style for unknown node %s", pending.line, pending.id)
+		}
+
+		for _, class := range pending.classes {
+			if !slices.Contains(*classes, class) {
+				*classes = append(*classes, class)
+			}
+		}
+		if pending.style != nil {
+			*style = pending.style
+		}
+	}
+	return nil
+}
+
+// scanMermaidID reads a Mermaid node ID from the start of s. IDs consist of letters, digits,
+// underscores and dashes, as long as a dash does not start a link operator.
+func scanMermaidID(s string) (string, string) {
+	runes := []rune(s)
+	i := 0
+	for ; i < len(runes); i++ {
+		r := runes[i]
+		if r == '-' {
+			if i == 0 || i+1 >= len(runes) || strings.ContainsRune("-.>", runes[i+1]) {
+				break
+			}
+			continue
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			break
+		}
+	}
+	return string(runes[:i]), string(runes[i:])
+}
+
+// scanMermaidShapeLabel reads a node label up to the given closing delimiter. The label may be
+// wrapped in double quotes, in which case the delimiter may also appear inside it.
+func scanMermaidShapeLabel(s, closer string) (string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		end := strings.Index(s[1:], `"`)
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated label")
+		}
+		label, rest := s[1:end+1], s[end+2:]
+		after, ok := strings.CutPrefix(rest, closer)
+		if !ok {
+			return "", "", fmt.Errorf("expected %q after label", closer)
+		}
+		return label, after, nil
+	}
+
+	end := strings.Index(s, closer)
+	if end < 0 {
+		return "", "", fmt.Errorf("expected %q after label", closer)
+	}
+	return strings.TrimSpace(s[:end]), s[end+len(closer):], nil
+}
+
+// parseMermaidLinkOperator parses a link operator, including any label, from the start of s.
+// It returns a Link with its line and arrow fields set and the unparsed remainder.
+func parseMermaidLinkOperator(s string) (Link, string, error) {
+	if match := mermaidPlainLinkRe.FindStringSubmatch(s); match != nil && !isMermaidLinkOpener(match) {
+		link := mermaidLinkFromParts(match[1], match[2], match[3])
+		rest := s[len(match[0]):]
+		if label := mermaidPipeLabelRe.FindStringSubmatch(rest); label != nil {
+			link = link.withLabel(unquoteMermaidLabel(label[1]))
+			rest = rest[len(label[0]):]
+		}
+		return link, rest, nil
+	}
+
+	match := mermaidLabelledLinkRe.FindStringSubmatch(s)
+	if match == nil {
+		return Link{}, "", fmt.Errorf("expected link at %q", s)
+	}
+	closer := mermaidLinkClosers[match[2]]
+	rest := s[len(match[0]):]
+
+	var label string
+	if strings.HasPrefix(rest, `"`) {
+		end := strings.Index(rest[1:], `"`)
+		if end < 0 {
+			return Link{}, "", fmt.Errorf("unterminated link label at %q", s)
+		}
+		label, rest = rest[1:end+1], rest[end+2:]
+		loc := closer.FindStringSubmatchIndex(rest)
+		if loc == nil || loc[0] != 0 {
+			return Link{}, "", fmt.Errorf("unterminated link at %q", s)
+		}
+		return mermaidLinkFromParts(match[1], match[2], rest[loc[4]:loc[5]]).withLabel(label), rest[loc[1]:], nil
+	}
+
+	loc := closer.FindStringSubmatchIndex(rest)
+	if loc == nil || loc[0] == 0 {
+		return Link{}, "", fmt.Errorf("unterminated link at %q", s)
+	}
+	label = strings.TrimSpace(rest[:loc[0]])
+	return mermaidLinkFromParts(match[1], match[2], rest[loc[4]:loc[5]]).withLabel(label), rest[loc[1]:], nil
+}
+
+// isMermaidLinkOpener reports whether a plain link match is really the opening "--" or "=="
+// of a labelled link, which has no arrow and only two line characters.
+func isMermaidLinkOpener(match []string) bool {
+	return match[3] == "" && (match[2] == "--" || match[2] == "==")
+}
+
+// withLabel returns a copy of the link with the given label, decoded from its Mermaid form.
+func (l Link) withLabel(label string) Link {
+	text, format := decodeMermaidLabel(label)
+	l.Label = pointTo(text)
+	l.LabelFormat = format
+	return l
+}
+
+// mermaidLinkFromParts builds a Link from the origin arrow, line body and target arrow of a
+// Mermaid link operator. An invisible "~~~" link takes the defaults of BlankLink.
+func mermaidLinkFromParts(originArrow, body, targetArrow string) Link {
+	if strings.HasPrefix(body, "~") {
+		return BlankLink(nil, nil, nil)
+	}
+
+	link := Link{LineType: LineTypeSolid, ArrowType: ArrowTypeNone}
+	switch {
+	case strings.HasPrefix(body, "="):
+		link.LineType = LineTypeThick
+	case strings.Contains(body, "."):
+		link.LineType = LineTypeDotted
+	}
+
+	arrow := targetArrow
+	if arrow == "" {
+		arrow = originArrow
+	}
+	switch arrow {
+	case "<", ">":
+		link.ArrowType = ArrowTypeNormal
+	case "o":
+		link.ArrowType = ArrowTypeCircle
+	case "x":
+		link.ArrowType = ArrowTypeCross
+	}
+	link.OriginArrow = originArrow != ""
+	link.TargetArrow = targetArrow != ""
+	return link
+}
+
+// unquoteMermaidLabel trims whitespace and surrounding double quotes from a label.
+func unquoteMermaidLabel(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// decodeMermaidLabel reverses the escaping applied by mermaidLabel. A label wrapped in backticks
+// is a markdown string; otherwise "<br/>" line breaks become newlines. In both cases entity codes
+// are replaced by the characters they stand for.
+func decodeMermaidLabel(s string) (string, LabelFormatEnum) {
+	if len(s) >= 2 && strings.HasPrefix(s, "`") && strings.HasSuffix(s, "`") {
+		return decodeMermaidEntities(s[1 : len(s)-1]), LabelFormatMarkdown
+	}
+	return decodeMermaidEntities(mermaidLineBreakRe.ReplaceAllString(s, "\n")), LabelFormatText
+}
+
+// decodeMermaidEntities replaces named and decimal entity codes with their characters. Unknown
+// codes are left untouched.
+func decodeMermaidEntities(s string) string {
+	return mermaidEntityRe.ReplaceAllStringFunc(s, func(entity string) string {
+		code := entity[1 : len(entity)-1]
+		if char, ok := mermaidNamedEntities[code]; ok {
+			return char
+		}
+		if n, err := strconv.Atoi(code); err == nil {
+			return string(rune(n))
+		}
+		return entity
+	})
+}
+
+// mention records a reference to a node in the current scope. If shape is not nil, its name, type
+// and label replace those of any previously seen node with the same ID. As in Mermaid, a node
+// mentioned inside a subgraph moves into that subgraph.
+func (p *mermaidParser) mention(id string, shape *Node) {
+	if _, ok := p.subgraphs[id]; ok {
+		return
+	}
+
+	scope := p.current()
+	node, ok := p.nodes[id]
+	if !ok {
+		node = &Node{name: id, Type: NodeTypeProcess}
+		p.nodes[id] = node
+		p.nodeScope[id] = scope
+		scope.Nodes = append(scope.Nodes, node)
+	} else if scope != p.root && scope != p.nodeScope[id] {
+		old := p.nodeScope[id]
+		old.Nodes = slices.DeleteFunc(old.Nodes, func(n *Node) bool { return n == node })
+		p.nodeScope[id] = scope
+		scope.Nodes = append(scope.Nodes, node)
+	}
+
+	if shape != nil {
+		node.name = shape.name
+		node.Type = shape.Type
+		node.Label = shape.Label
+		node.LabelFormat = shape.LabelFormat
+	}
+}
+
+// removeSubgraphNodes removes nodes that were created for a link endpoint before it turned out
+// to be the ID of a subgraph declared later on.
+func (p *mermaidParser) removeSubgraphNodes() {
+	for id := range p.subgraphs {
+		node, ok := p.nodes[id]
+		if !ok {
+			continue
+		}
+		scope := p.nodeScope[id]
+		scope.Nodes = slices.DeleteFunc(scope.Nodes, func(n *Node) bool { return n == node })
+		delete(p.nodes, id)
+		delete(p.nodeScope, id)
+	}
+}
+
+// resolveLinks replaces the Mermaid IDs of every pending link with the matching node or
+// subgraph and adds the link to the innermost flowchart containing both endpoints.
+func (p *mermaidParser) resolveLinks() error {
+	for i, pending := range p.links {
+		origin, originScope := p.resolve(pending.origin)
+		target, targetScope := p.resolve(pending.target)
+		if origin == nil || target == nil {
+			return fmt.Errorf("line %d: link between unknown nodes %s and %s", pending.line, pending.origin, pending.target)
+		}
+
+		link := pending.link
+		link.Origin, link.Target = origin, target
+		if style, ok := p.linkStyle[i]; ok {
+			link.Style = pointTo(style)
+		}
+		scope := p.commonScope(originScope, targetScope)
+		scope.Links = append(scope.Links, link)
+	}
+	return nil
+}
+
+// resolve returns the node or subgraph with the given Mermaid ID, together with the flowchart
+// that contains it.
+func (p *mermaidParser) resolve(id string) (Linkable, *Flowchart) {
+	if subgraph, ok := p.subgraphs[id]; ok {
+		return subgraph, p.parents[subgraph]
+	}
+	if node, ok := p.nodes[id]; ok {
+		return node, p.nodeScope[id]
+	}
+	return nil, nil
+}
+
+// commonScope returns the innermost flowchart that contains both a and b.
+func (p *mermaidParser) commonScope(a, b *Flowchart) *Flowchart {
+	var ancestors []*Flowchart
+	for f := a; f != nil; f = p.parents[f] {
+		ancestors = append(ancestors, f)
+	}
+	for f := b; f != nil; f = p.parents[f] {
+		if slices.Contains(ancestors, f) {
+			return f
+		}
+	}
+	return p.root
+}
@@ -0,0 +1,529 @@
+package flowchart
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// parsedFlowchartOptions compares parsed flowcharts without caring about nil versus empty
+// slices or about the order of links, which RenderMermaid sorts.
+var parsedFlowchartOptions = cmp.Options{
+	cmp.AllowUnexported(Node{}),
+	cmpopts.EquateEmpty(),
+	cmpopts.SortSlices(func(a, b Link) bool {
+		if a.Origin.nodeName() == b.Origin.nodeName() {
+			return a.Target.nodeName() < b.Target.nodeName()
+		}
+		return a.Origin.nodeName() < b.Origin.nodeName()
+	}),
+}
+
+func TestParseMermaid(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		expected    func() *Flowchart
+		expectedErr bool
+	}{
+		{
+			name:   "header only",
+			source: "flowchart RL;\n",
+			expected: func() *Flowchart {
+				return RlFlowchart(nil)
+			},
+		},
+		{
+			name:   "graph keyword with TD direction and comment",
+			source: "graph TD\n%% a comment\nA\n",
+			expected: func() *Flowchart {
+				return &Flowchart{Direction: DirectionVertical, Nodes: []*Node{ProcessNode("A", nil)}}
+			},
+		},
+		{
+			name:   "front matter title",
+			source: "---\ntitle: \"My Chart\"\n---\nflowchart LR\n",
+			expected: func() *Flowchart {
+				return LrFlowchart(pointTo("My Chart"))
+			},
+		},
+		{
+			name: "all node shapes",
+			source: `flowchart TB
+    T("Terminator")
+    P["Process"]
+    S[["Subprocess"]]
+    D{"Decision"}
+    IO[/"Input"/]
+    C(("Connector"))
+    DB[("Database")]
+    St([Stadium])
+    U[Unquoted label]
+`,
+			expected: func() *Flowchart {
+				return &Flowchart{
+					Direction: DirectionVertical,
+					Nodes: []*Node{
+						TerminatorNode("T", pointTo("Terminator")),
+						ProcessNode("P", pointTo("Process")),
+						SubprocessNode("S", pointTo("Subprocess")),
+						DecisionNode("D", pointTo("Decision")),
+						InputOutputNode("IO", pointTo("Input")),
+						ConnectorNode("C", pointTo("Connector")),
+						DatabaseNode("DB", pointTo("Database")),
+						TerminatorNode("St", pointTo("Stadium")),
+						ProcessNode("U", pointTo("Unquoted label")),
+					},
+				}
+			},
+		},
+		{
+			name:   "ampersand and chained links",
+			source: "flowchart LR\nA --> B & C --> D\n",
+			expected: func() *Flowchart {
+				a, b, c, d := ProcessNode("A", nil), ProcessNode("B", nil), ProcessNode("C", nil), ProcessNode("D", nil)
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Nodes:     []*Node{a, b, c, d},
+					Links: []Link{
+						SolidLink(a, b, nil),
+						SolidLink(a, c, nil),
+						SolidLink(b, d, nil),
+						SolidLink(c, d, nil),
+					},
+				}
+			},
+		},
+		{
+			name:   "pipe and unquoted labels",
+			source: "flowchart LR\nA -->|yes| B\nA -- no way --> C\n",
+			expected: func() *Flowchart {
+				a, b, c := ProcessNode("A", nil), ProcessNode("B", nil), ProcessNode("C", nil)
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Nodes:     []*Node{a, b, c},
+					Links: []Link{
+						SolidLink(a, b, pointTo("yes")),
+						SolidLink(a, c, pointTo("no way")),
+					},
+				}
+			},
+		},
+		{
+			name:   "node defined after use",
+			source: "flowchart LR\nA --> B\nB{\"Check\"}\n",
+			expected: func() *Flowchart {
+				a, b := ProcessNode("A", nil), DecisionNode("B", pointTo("Check"))
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Nodes:     []*Node{a, b},
+					Links:     []Link{SolidLink(a, b, nil)},
+				}
+			},
+		},
+		{
+			name: "nested subgraphs and link to subgraph declared later",
+			source: `flowchart LR
+A --> Inner
+subgraph Outer [Outer Group]
+    subgraph Inner
+        direction TB
+        B --> C
+    end
+end
+`,
+			expected: func() *Flowchart {
+				a, b, c := ProcessNode("A", nil), ProcessNode("B", nil), ProcessNode("C", nil)
+				inner := &Flowchart{
+					Direction: DirectionVertical,
+					Title:     pointTo("Inner"),
+					Nodes:     []*Node{b, c},
+					Links:     []Link{SolidLink(b, c, nil)},
+				}
+				outer := &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Title:     pointTo("Outer Group"),
+					Subgraphs: []*Flowchart{inner},
+				}
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Nodes:     []*Node{a},
+					Subgraphs: []*Flowchart{outer},
+					Links:     []Link{SolidLink(a, inner, nil)},
+				}
+			},
+		},
+		{
+			name:   "node moves into subgraph",
+			source: "flowchart LR\nA --> B\nsubgraph G\nB\nend\n",
+			expected: func() *Flowchart {
+				a, b := ProcessNode("A", nil), ProcessNode("B", nil)
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Nodes:     []*Node{a},
+					Subgraphs: []*Flowchart{{
+						Direction: DirectionHorizontalRight,
+						Title:     pointTo("G"),
+						Nodes:     []*Node{b},
+					}},
+					Links: []Link{SolidLink(a, b, nil)},
+				}
+			},
+		},
+		{
+			name:   "entity codes in unquoted labels",
+			source: "flowchart LR\nA[Step #35;1] --> B\nsubgraph G [Group #91;x#93;]\nB\nend\n",
+			expected: func() *Flowchart {
+				a, b := ProcessNode("A", pointTo("Step #1")), ProcessNode("B", nil)
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Nodes:     []*Node{a},
+					Subgraphs: []*Flowchart{{
+						Direction: DirectionHorizontalRight,
+						Title:     pointTo("Group [x]"),
+						Nodes:     []*Node{b},
+					}},
+					Links: []Link{SolidLink(a, b, nil)},
+				}
+			},
+		},
+		{
+			name:   "multi-line markdown label",
+			source: "flowchart LR\nA[\"`**one**\n  _two_`\"]\n",
+			expected: func() *Flowchart {
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Nodes:     []*Node{{name: "A", Type: NodeTypeProcess, Label: pointTo("**one**\n  _two_"), LabelFormat: LabelFormatMarkdown}},
+				}
+			},
+		},
+		{
+			name: "styling statements",
+			source: `flowchart LR
+A:::done --> B
+subgraph G
+C
+end
+classDef done,ok fill:#0f0,font-family:Arial\, sans-serif,opacity:0.5;
+class B,G done
+style C stroke:#f00,stroke-width:2px
+linkStyle default stroke:#000
+linkStyle 0 color:#fff
+`,
+			expected: func() *Flowchart {
+				a := &Node{name: "A", Type: NodeTypeProcess, Classes: []string{"done"}}
+				b := &Node{name: "B", Type: NodeTypeProcess, Classes: []string{"done"}}
+				c := &Node{name: "C", Type: NodeTypeProcess, Style: &Style{Stroke: "#f00", StrokeWidth: "2px"}}
+				link := SolidLink(a, b, nil)
+				link.Style = &Style{Color: "#fff"}
+				done := Style{Fill: "#0f0", FontFamily: "Arial, sans-serif"}
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					ClassDefs: []StyleClass{{Name: "done", Style: done}, {Name: "ok", Style: done}},
+					Nodes:     []*Node{a, b},
+					Subgraphs: []*Flowchart{{
+						Title:     pointTo("G"),
+						Direction: DirectionHorizontalRight,
+						Classes:   []string{"done"},
+						Nodes:     []*Node{c},
+					}},
+					Links: []Link{link},
+				}
+			},
+		},
+		{
+			name:        "missing header",
+			source:      "A --> B\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unsupported direction",
+			source:      "flowchart BT\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unclosed subgraph",
+			source:      "flowchart LR\nsubgraph G\nA\n",
+			expectedErr: true,
+		},
+		{
+			name:        "end without subgraph",
+			source:      "flowchart LR\nend\n",
+			expectedErr: true,
+		},
+		{
+			name:        "link without target",
+			source:      "flowchart LR\nA -->\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unterminated label",
+			source:      "flowchart LR\nA[\"oops]\n",
+			expectedErr: true,
+		},
+		{
+			name:        "style for unknown node",
+			source:      "flowchart LR\nA\nstyle B fill:#f00\n",
+			expectedErr: true,
+		},
+		{
+			name:        "repeated class definition",
+			source:      "flowchart LR\nclassDef a fill:#f00\nclassDef a fill:#0f0\n",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid link style index",
+			source:      "flowchart LR\nA --> B\nlinkStyle first stroke:#f00\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMermaid(strings.NewReader(tt.source))
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("ParseMermaid() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if tt.expectedErr {
+				return
+			}
+			if diff := cmp.Diff(tt.expected(), got, parsedFlowchartOptions); diff != "" {
+				t.Errorf("ParseMermaid() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseMermaidLinkOperator(t *testing.T) {
+	tests := []struct {
+		name         string
+		operator     string
+		expected     Link
+		expectedRest string
+		expectedErr  bool
+	}{
+		{
+			name:         "invisible",
+			operator:     "~~~ B",
+			expected:     BlankLink(nil, nil, nil),
+			expectedRest: " B",
+		},
+		{
+			name:         "solid without arrow",
+			operator:     "--- B",
+			expected:     Link{LineType: LineTypeSolid, ArrowType: ArrowTypeNone},
+			expectedRest: " B",
+		},
+		{
+			name:         "solid arrow without spaces",
+			operator:     "-->B",
+			expected:     SolidLink(nil, nil, nil),
+			expectedRest: "B",
+		},
+		{
+			name:         "dotted arrow",
+			operator:     "-.-> B",
+			expected:     DottedLink(nil, nil, nil),
+			expectedRest: " B",
+		},
+		{
+			name:         "thick arrow",
+			operator:     "==> B",
+			expected:     ThickLink(nil, nil, nil),
+			expectedRest: " B",
+		},
+		{
+			name:         "bidirectional normal",
+			operator:     "<--> B",
+			expected:     Link{LineType: LineTypeSolid, ArrowType: ArrowTypeNormal, OriginArrow: true, TargetArrow: true},
+			expectedRest: " B",
+		},
+		{
+			name:         "bidirectional circle",
+			operator:     "o--o B",
+			expected:     Link{LineType: LineTypeSolid, ArrowType: ArrowTypeCircle, OriginArrow: true, TargetArrow: true},
+			expectedRest: " B",
+		},
+		{
+			name:         "bidirectional cross dotted",
+			operator:     "x-.-x B",
+			expected:     Link{LineType: LineTypeDotted, ArrowType: ArrowTypeCross, OriginArrow: true, TargetArrow: true},
+			expectedRest: " B",
+		},
+		{
+			name:         "labelled solid",
+			operator:     `-- "some label" --> B`,
+			expected:     SolidLink(nil, nil, pointTo("some label")),
+			expectedRest: " B",
+		},
+		{
+			name:         "labelled dotted without arrow",
+			operator:     `-. "some label" .- B`,
+			expected:     Link{LineType: LineTypeDotted, ArrowType: ArrowTypeNone, Label: pointTo("some label")},
+			expectedRest: " B",
+		},
+		{
+			name:         "labelled thick bidirectional",
+			operator:     `<== "some label" ==> B`,
+			expected:     Link{LineType: LineTypeThick, ArrowType: ArrowTypeNormal, OriginArrow: true, TargetArrow: true, Label: pointTo("some label")},
+			expectedRest: " B",
+		},
+		{
+			name:         "pipe label",
+			operator:     "-.->|maybe| B",
+			expected:     DottedLink(nil, nil, pointTo("maybe")),
+			expectedRest: " B",
+		},
+		{
+			name:        "unterminated label",
+			operator:    "-- label B",
+			expectedErr: true,
+		},
+		{
+			name:        "not a link",
+			operator:    "B",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := parseMermaidLinkOperator(tt.operator)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("parseMermaidLinkOperator() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if tt.expectedErr {
+				return
+			}
+			if diff := cmp.Diff(tt.expected, got, cmp.AllowUnexported(Node{})); diff != "" {
+				t.Errorf("parseMermaidLinkOperator() mismatch (-expected +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.expectedRest, rest); diff != "" {
+				t.Errorf("parseMermaidLinkOperator() rest mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseMermaid_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		flowchart func() *Flowchart
+	}{
+		{
+			name:      "fixture flowchart",
+			flowchart: func() *Flowchart { return GetMermaidFriendlyFlowchart(fixtureFlowchart()) },
+		},
+		{
+			name: "every link type",
+			flowchart: func() *Flowchart {
+				a, b := ProcessNode("A", pointTo("Start")), DecisionNode("B", pointTo("Choose"))
+				return &Flowchart{
+					Direction: DirectionHorizontalLeft,
+					Nodes:     []*Node{a, b},
+					Links: []Link{
+						BlankLink(a, b, nil),
+						{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeNone},
+						SolidLink(a, b, pointTo("solid")),
+						DottedLink(a, b, nil),
+						ThickLink(a, b, pointTo("thick")),
+						{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeNormal, OriginArrow: true, TargetArrow: true},
+						{Origin: a, Target: b, LineType: LineTypeDotted, ArrowType: ArrowTypeCircle, OriginArrow: true, TargetArrow: true},
+						{Origin: a, Target: b, LineType: LineTypeThick, ArrowType: ArrowTypeCross, OriginArrow: false, TargetArrow: true},
+					},
+				}
+			},
+		},
+
+		{
+			name: "names that need sanitising",
+			flowchart: func() *Flowchart {
+				a, b, c := ProcessNode("Überprüfung", nil), ProcessNode("Check (v2)", nil), ProcessNode("A B", nil)
+				d, e := ProcessNode("AB", nil), DatabaseNode("end", nil)
+				return &Flowchart{
+					Direction: DirectionVertical,
+					Nodes:     []*Node{a, b, c, d, e},
+					Subgraphs: []*Flowchart{{Direction: DirectionVertical, Title: pointTo("Group [1]")}},
+					Links:     []Link{SolidLink(a, b, nil), SolidLink(c, d, nil)},
+				}
+			},
+		},
+		{
+			name: "escaped and markdown labels",
+			flowchart: func() *Flowchart {
+				a := ProcessNode("A", pointTo("Check \"input\"; #1 <first>\nthen `retry`"))
+				b := &Node{name: "B", Type: NodeTypeDecision, Label: pointTo("**Valid**\n_maybe_"), LabelFormat: LabelFormatMarkdown}
+				link := SolidLink(a, b, pointTo("a; b"))
+				markdownLink := DottedLink(b, a, pointTo("**retry**"))
+				markdownLink.LabelFormat = LabelFormatMarkdown
+				return &Flowchart{
+					Direction: DirectionVertical,
+					Nodes:     []*Node{a, b},
+					Links:     []Link{link, markdownLink},
+				}
+			},
+		},
+		{
+			name: "styled flowchart",
+			flowchart: func() *Flowchart {
+				a := &Node{name: "A", Type: NodeTypeProcess, Classes: []string{"failed"}}
+				b := &Node{name: "B", Type: NodeTypeProcess, Classes: []string{"done"}, Style: &Style{FontSize: "14px"}}
+				c := &Node{name: "C", Type: NodeTypeProcess}
+				link := SolidLink(a, b, nil)
+				link.Style = &Style{Stroke: "#f00"}
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					ClassDefs: []StyleClass{
+						{Name: "failed", Style: Style{Fill: "#f00", Color: "#fff"}},
+						{Name: "done", Style: Style{Fill: "#0f0", FontFamily: "Arial, sans-serif"}},
+					},
+					Nodes: []*Node{a, b},
+					Links: []Link{link},
+					Subgraphs: []*Flowchart{{
+						Title:     pointTo("G"),
+						Direction: DirectionVertical,
+						Classes:   []string{"done"},
+						Style:     &Style{Stroke: "#000", StrokeWidth: "2px"},
+						Nodes:     []*Node{c},
+					}},
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.flowchart()
+			rendered, err := RenderMermaid(original)
+			if err != nil {
+				t.Fatalf("RenderMermaid() unexpected error: %v", err)
+			}
+
+			got, err := ParseMermaid(strings.NewReader(rendered))
+			if err != nil {
+				t.Fatalf("ParseMermaid() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(original, got, parsedFlowchartOptions); diff != "" {
+				t.Errorf("ParseMermaid(RenderMermaid()) mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+
+	for _, fixture := range mermaidFixtures() {
+		t.Run("reads back "+fixture.name, func(t *testing.T) {
+			parsed, err := ParseMermaid(strings.NewReader(fixture.expected))
+			if err != nil {
+				t.Fatalf("ParseMermaid() unexpected error: %v", err)
+			}
+			got, err := RenderMermaid(parsed)
+			if err != nil {
+				t.Fatalf("RenderMermaid() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(fixture.expected, got); diff != "" {
+				t.Errorf("RenderMermaid(ParseMermaid()) mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
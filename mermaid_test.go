@@ -388,7 +388,7 @@ func TestRenderMermaidLink(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := renderMermaidLink(tt.link)
+			got := renderMermaidLink(tt.link, nil)
 
 			if diff := cmp.Diff(tt.expected, got); diff != "" {
 				t.Errorf("toMermaid() mismatch (-expected +got):\n%s", diff)
@@ -412,8 +412,28 @@ func TestRenderMermaidNode(t *testing.T) {
 				Label: nil,
 			},
 			indents:  0,
+			expected: "FirstNode[\"First Node\"];\n",
+		},
+		{
+			name: "Node with no label and a name that is its own id",
+			node: &Node{
+				name:  "FirstNode",
+				Type:  NodeTypeProcess,
+				Label: nil,
+			},
+			indents:  0,
 			expected: "FirstNode;\n",
 		},
+		{
+			name: "Node with no label and a unicode name",
+			node: &Node{
+				name:  "Überprüfung",
+				Type:  NodeTypeDecision,
+				Label: nil,
+			},
+			indents:  0,
+			expected: "_berpr_fung_" + nameHash("Überprüfung") + "{\"Überprüfung\"};\n",
+		},
 		{
 			name: "Node with empty label and no links",
 			node: &Node{
@@ -422,7 +442,7 @@ func TestRenderMermaidNode(t *testing.T) {
 				Label: pointTo(""),
 			},
 			indents:  1,
-			expected: "    FirstNode;\n",
+			expected: "    FirstNode[\"First Node\"];\n",
 		},
 		{
 			name: "Node with label and no links",
@@ -437,7 +457,7 @@ func TestRenderMermaidNode(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := renderMermaidNode(tt.node, tt.indents)
+			got := renderMermaidNode(tt.node, tt.indents, nil)
 			if diff := cmp.Diff(tt.expected, got); diff != "" {
 				t.Errorf("toMermaid() mismatch (-expected +got):\n%s", diff)
 			}
@@ -518,7 +538,7 @@ func TestRenderMermaidNode(t *testing.T) {
 				Type:  tt.nodeType,
 				Label: tt.label,
 			}
-			got := renderMermaidNode(node, 0)
+			got := renderMermaidNode(node, 0, nil)
 			if diff := cmp.Diff(tt.expected, got); diff != "" {
 				t.Errorf("toMermaid() mismatch (-expected +got):\n%s", diff)
 			}
@@ -526,6 +546,53 @@ func TestRenderMermaidNode(t *testing.T) {
 	}
 }
 
+func TestEscapeMermaidLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		label    string
+		expected string
+	}{
+		{name: "plain text", label: "Plain text 123", expected: "Plain text 123"},
+		{name: "double quotes", label: `say "hi"`, expected: "say #quot;hi#quot;"},
+		{name: "hash and semicolon", label: "#1; #2", expected: "#35;1#59; #35;2"},
+		{name: "angle brackets", label: "a < b > c", expected: "a #lt; b #gt; c"},
+		{name: "backticks", label: "`code`", expected: "#96;code#96;"},
+		{name: "newlines", label: "first\r\nsecond\nthird", expected: "first<br/>second<br/>third"},
+		{name: "unicode", label: "Überprüfung ✓", expected: "Überprüfung ✓"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := escapeMermaidLabel(tt.label)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("escapeMermaidLabel() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMermaidLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		label    string
+		format   LabelFormatEnum
+		expected string
+	}{
+		{name: "text", label: "a \"b\"\nc", format: LabelFormatText, expected: "a #quot;b#quot;<br/>c"},
+		{name: "markdown", label: "**bold** and _italic_", format: LabelFormatMarkdown, expected: "`**bold** and _italic_`"},
+		{name: "markdown multi-line", label: "**one**\n\"two\"", format: LabelFormatMarkdown, expected: "`**one**\n#quot;two#quot;`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mermaidLabel(tt.label, tt.format)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("mermaidLabel() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestRenderMermaidFlowchart(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -558,7 +625,17 @@ func TestRenderMermaidFlowchart(t *testing.T) {
 				Subgraphs: []*Flowchart{},
 			},
 			indents:     1,
-			expected:    "    subgraph MainTitle [Main Title];\n        direction TB;\n        FirstNode;\n        SecondNode;\n    end;\n",
+			expected:    "    subgraph MainTitle [Main Title];\n        direction TB;\n        FirstNode(\"First Node\");\n        SecondNode(\"Second Node\");\n    end;\n",
+			expectPanic: false,
+		},
+		{
+			name: "Subgraph title with brackets",
+			flowchart: &Flowchart{
+				Direction: DirectionVertical,
+				Title:     pointTo("Main [v2]"),
+			},
+			indents:     0,
+			expected:    "subgraph Main_v2__" + nameHash("Main [v2]") + " [Main #91;v2#93;];\n    direction TB;\nend;\n",
 			expectPanic: false,
 		},
 		{
@@ -583,14 +660,14 @@ func TestRenderMermaidFlowchart(t *testing.T) {
 			indents: 0,
 			expected: `subgraph MainTitle [Main Title];
     direction TB;
-    FirstNode;
+    FirstNode("First Node");
     subgraph SubgraphOne [Subgraph One];
         direction RL;
-        SecondNode;
+        SecondNode("Second Node");
     end;
     subgraph SubgraphTwo [Subgraph Two];
         direction LR;
-        ThirdNode;
+        ThirdNode("Third Node");
     end;
 end;
 `,
@@ -610,7 +687,7 @@ end;
 					t.Error("expected panic but none occurred")
 				}
 			}()
-			got := renderMermaidFlowchart(tt.flowchart, tt.indents, true)
+			got := renderMermaidFlowchart(tt.flowchart, tt.indents, true, nil)
 			if tt.flowchart.Title == nil {
 				if diff := cmp.Diff(tt.expected, got); diff != "" {
 					t.Errorf("toMermaid() mismatch (-expected +got):\n%s", diff)
@@ -624,21 +701,24 @@ end;
 	}
 }
 
-func TestRenderMermaid(t *testing.T) {
-	tests := []struct {
-		name        string
-		flowchart   *Flowchart
-		expected    string
-		expectedErr bool
-	}{
+// mermaidFixture is a chart together with the Mermaid text it renders to.
+type mermaidFixture struct {
+	name      string
+	flowchart *Flowchart
+	expected  string
+}
+
+// mermaidFixtures returns the charts rendered by TestRenderMermaid, whose expected text is also
+// read back by TestParseMermaid_RoundTrip.
+func mermaidFixtures() []mermaidFixture {
+	return []mermaidFixture{
 		{
 			name: "Flowchart with no title",
 			flowchart: &Flowchart{
 				Direction: DirectionHorizontalRight,
 				Nodes:     []*Node{{name: "Node One"}},
 			},
-			expected:    "flowchart LR;\n    NodeOne;\n",
-			expectedErr: false,
+			expected: "flowchart LR;\n    NodeOne(\"Node One\");\n",
 		},
 		{
 			name:      "Full Flowchart with title",
@@ -647,16 +727,19 @@ func TestRenderMermaid(t *testing.T) {
 title: Test Title
 ---
 flowchart LR;
-    NodeOne;
-    NodeTwo;
-    NodeThree;
-    NodeFour;
+    NodeOne["Node One"];
+    NodeTwo["Node Two"];
+    NodeThree["Node Three"];
+    NodeFour["Node Four"];
     subgraph SubgraphOne [Subgraph One];
         direction TB;
-        NodeFive;
-        NodeSix;
-        NodeSeven;
-        NodeEight;
+        NodeFive["Node Five"];
+        NodeSix["Node Six"];
+        subgraph SubgraphTwo [Subgraph Two];
+            direction RL;
+            NodeSeven["Node Seven"];
+            NodeEight["Node Eight"];
+        end;
     end;
     NodeFive --> NodeSix;
     NodeOne --> NodeTwo;
@@ -664,18 +747,118 @@ flowchart LR;
     NodeTwo --> NodeFour;
     NodeTwo --> NodeThree;
 `,
-			expectedErr: false,
+		},
+		{
+			name: "Services, components and steps",
+			flowchart: &Flowchart{
+				Direction: DirectionVertical,
+				Subgraphs: []*Flowchart{{
+					Direction: DirectionHorizontalRight,
+					Title:     pointTo("Billing Service"),
+					Subgraphs: []*Flowchart{{
+						Direction: DirectionVertical,
+						Title:     pointTo("Invoice Component"),
+						Subgraphs: []*Flowchart{{
+							Direction: DirectionHorizontalLeft,
+							Title:     pointTo("Steps"),
+							Nodes:     []*Node{ProcessNode("Draft", nil), ProcessNode("Send", nil)},
+						}},
+					}},
+				}},
+			},
+			expected: `flowchart TB;
+    subgraph BillingService [Billing Service];
+        direction LR;
+        subgraph InvoiceComponent [Invoice Component];
+            direction TB;
+            subgraph Steps [Steps];
+                direction RL;
+                Draft;
+                Send;
+            end;
+        end;
+    end;
+`,
+		},
+		{
+			name: "Styled flowchart",
+			flowchart: func() *Flowchart {
+				failed := &Node{name: "Failed", Type: NodeTypeProcess, Classes: []string{"failed"}}
+				done := &Node{name: "Done", Type: NodeTypeProcess, Classes: []string{"done"}, Style: &Style{FontWeight: "bold"}}
+				link := SolidLink(failed, done, nil)
+				link.Classes = []string{"failed"}
+				link.Style = &Style{StrokeWidth: "2px"}
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					ClassDefs: []StyleClass{
+						{Name: "failed", Style: Style{Fill: "#f00", FontFamily: "Arial, sans-serif"}},
+						{Name: "done", Style: Style{Fill: "#0f0"}},
+						{Name: "unused"},
+					},
+					Nodes: []*Node{failed, done},
+					Links: []Link{link},
+					Subgraphs: []*Flowchart{{
+						Title:     pointTo("Group"),
+						Direction: DirectionVertical,
+						Classes:   []string{"done"},
+						Nodes:     []*Node{{name: "Other", Type: NodeTypeProcess}},
+					}},
+				}
+			}(),
+			expected: `flowchart LR;
+    Failed;
+    Done;
+    subgraph Group [Group];
+        direction TB;
+        Other;
+    end;
+    Failed --> Done;
+    classDef failed fill:#f00,font-family:Arial\, sans-serif;
+    classDef done fill:#0f0;
+    class Failed failed;
+    class Done,Group done;
+    style Done font-weight:bold;
+    linkStyle 0 fill:#f00,stroke-width:2px,font-family:Arial\, sans-serif;
+`,
+		},
+		{
+			name: "Escaped labels",
+			flowchart: func() *Flowchart {
+				check := ProcessNode("Check", pointTo("Check \"input\"; #1 <first>"))
+				valid := DecisionNode("Valid?", pointTo("[ok]"))
+				return &Flowchart{
+					Direction: DirectionVertical,
+					Nodes:     []*Node{check},
+					Links:     []Link{SolidLink(check, valid, pointTo("a; b | c"))},
+					Subgraphs: []*Flowchart{{
+						Title:     pointTo("Group [1]"),
+						Direction: DirectionHorizontalRight,
+						Nodes:     []*Node{valid},
+					}},
+				}
+			}(),
+			expected: "flowchart TB;\n" +
+				"    Check[\"Check #quot;input#quot;#59; #35;1 #lt;first#gt;\"];\n" +
+				"    subgraph Group_1__" + nameHash("Group [1]") + " [Group #91;1#93;];\n" +
+				"        direction LR;\n" +
+				"        Valid__" + nameHash("Valid?") + "{\"[ok]\"};\n" +
+				"    end;\n" +
+				"    Check -- \"a#59; b | c\" --> Valid__" + nameHash("Valid?") + ";\n",
 		},
 	}
+}
+
+func TestRenderMermaid(t *testing.T) {
+	tests := mermaidFixtures()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got, err := RenderMermaid(GetMermaidFriendlyFlowchart(tt.flowchart))
+			if err != nil {
+				t.Fatalf("RenderMermaid() unexpected error: %v", err)
+			}
 			if diff := cmp.Diff(tt.expected, got); diff != "" {
 				t.Errorf("toMermaid() mismatch (-expected +got):\n%s", diff)
 			}
-			if (err == nil) == tt.expectedErr {
-				t.Errorf("ToMermaid() error = %v, expected %v", err, tt.expectedErr)
-			}
 		})
 	}
 
@@ -689,7 +872,7 @@ flowchart LR;
 			name: "invalid mermaid name",
 			flowchart: &Flowchart{
 				Direction: DirectionHorizontalRight,
-				Nodes:     []*Node{{name: "("}},
+				Nodes:     []*Node{{name: " "}},
 			},
 			expected:    "",
 			expectedErr: true,
@@ -711,7 +894,7 @@ flowchart LR;
 func TestValidateMermaid(t *testing.T) {
 	// Helper nodes and subgraphs
 	validNode := &Node{name: "ValidNode", Type: NodeTypeProcess, Label: pointTo("Valid Node")}
-	invalidNode := &Node{name: "Invalid@Node", Type: NodeTypeProcess, Label: pointTo("Invalid Node")}
+	invalidNode := &Node{name: "   ", Type: NodeTypeProcess, Label: pointTo("Invalid Node")}
 	duplicateNode := &Node{name: "DuplicateNode", Type: NodeTypeProcess, Label: pointTo("Duplicate Node")}
 	subgraphWithNested := &Flowchart{
 		Title:     pointTo("SubgraphWithNested"),
@@ -755,17 +938,44 @@ func TestValidateMermaid(t *testing.T) {
 				Nodes:     []*Node{validNode, invalidNode},
 				Subgraphs: []*Flowchart{subgraphWithoutNested},
 			},
-			expectedError: "flowchart contains violations: contains invalid mermaid names",
+			expectedError: "node \"   \" has an invalid mermaid name",
 		},
 		{
-			name: "Invalid flowchart with nested subgraphs",
+			name: "Valid flowchart with nested subgraphs",
 			flowchart: &Flowchart{
 				Title:     pointTo("MainFlowchart"),
 				Direction: DirectionHorizontalRight,
 				Nodes:     []*Node{validNode},
 				Subgraphs: []*Flowchart{subgraphWithNested},
 			},
-			expectedError: "flowchart contains violations: contains nested subgraphs",
+			expectedError: "",
+		},
+		{
+			name: "Invalid flowchart with node name repeated in nested subgraph",
+			flowchart: &Flowchart{
+				Title:     pointTo("MainFlowchart"),
+				Direction: DirectionHorizontalRight,
+				Nodes:     []*Node{{name: "AnotherNestedNode"}},
+				Subgraphs: []*Flowchart{subgraphWithNested},
+			},
+			expectedError: "SubgraphWithNested > NestedSubgraph: node name \"AnotherNestedNode\" is used more than once",
+		},
+		{
+			name: "Invalid flowchart with nested subgraph title repeated",
+			flowchart: &Flowchart{
+				Title:     pointTo("MainFlowchart"),
+				Direction: DirectionHorizontalRight,
+				Nodes:     []*Node{validNode},
+				Subgraphs: []*Flowchart{
+					subgraphWithNested,
+					{
+						Title:     pointTo("Other"),
+						Direction: DirectionVertical,
+						Subgraphs: []*Flowchart{{Title: pointTo("NestedSubgraph")}},
+					},
+				},
+			},
+			expectedError: "Other: subgraph title \"NestedSubgraph\" is used more than once",
 		},
 		{
 			name: "Invalid flowchart with duplicate node names",
@@ -775,7 +985,7 @@ func TestValidateMermaid(t *testing.T) {
 				Nodes:     []*Node{duplicateNode, duplicateNode},
 				Subgraphs: []*Flowchart{subgraphWithoutNested},
 			},
-			expectedError: "flowchart contains violations: contains repeated node and/or subgraph names",
+			expectedError: "node name \"DuplicateNode\" is used more than once",
 		},
 		{
 			name: "Invalid flowchart with duplicate subgraph titles",
@@ -793,27 +1003,36 @@ func TestValidateMermaid(t *testing.T) {
 					},
 				},
 			},
-			expectedError: "flowchart contains violations: contains repeated node and/or subgraph names",
+			expectedError: "subgraph title \"SubgraphWithoutNested\" is used more than once",
 		},
 		{
-			name: "Invalid flowchart with multiple violations: invalid names and nested subgraphs",
+			name: "Invalid flowchart with invalid names and nested subgraphs",
 			flowchart: &Flowchart{
 				Title:     pointTo("MainFlowchart"),
 				Direction: DirectionVertical,
 				Nodes:     []*Node{validNode, invalidNode},
 				Subgraphs: []*Flowchart{subgraphWithNested},
 			},
-			expectedError: "flowchart contains violations: contains invalid mermaid names, contains nested subgraphs",
+			expectedError: "node \"   \" has an invalid mermaid name",
+		},
+		{
+			name: "Valid flowchart with names that differ only by spaces",
+			flowchart: &Flowchart{
+				Title:     pointTo("MainFlowchart"),
+				Direction: DirectionVertical,
+				Nodes:     []*Node{{name: "A B"}, {name: "AB"}, {name: "Check (v2)"}},
+			},
+			expectedError: "",
 		},
 		{
-			name: "Invalid flowchart with all three violations: invalid names, nested subgraphs, and duplicate names",
+			name: "Invalid flowchart with both violations: invalid names and duplicate names",
 			flowchart: &Flowchart{
 				Title:     pointTo("MainFlowchart"),
 				Direction: DirectionHorizontalRight,
 				Nodes:     []*Node{validNode, invalidNode, duplicateNode, duplicateNode},
 				Subgraphs: []*Flowchart{subgraphWithNested},
 			},
-			expectedError: "flowchart contains violations: contains invalid mermaid names, contains nested subgraphs, contains repeated node and/or subgraph names",
+			expectedError: "node \"   \" has an invalid mermaid name; node name \"DuplicateNode\" is used more than once",
 		},
 		{
 			name: "Valid flowchart with multiple unique subgraphs and nodes",
@@ -856,6 +1075,15 @@ func TestValidateMermaid(t *testing.T) {
 			},
 			expectedError: "",
 		},
+		{
+			name: "Undefined style class",
+			flowchart: &Flowchart{
+				Title:     pointTo("MainFlowchart"),
+				Direction: DirectionVertical,
+				Nodes:     []*Node{{name: "StyledNode", Classes: []string{"missing"}}},
+			},
+			expectedError: "node \"StyledNode\" uses undefined style class \"missing\"",
+		},
 		{
 			name: "Subgraph without title",
 			flowchart: &Flowchart{
@@ -870,7 +1098,7 @@ func TestValidateMermaid(t *testing.T) {
 					},
 				},
 			},
-			expectedError: "flowchart contains violations: contains invalid mermaid names",
+			expectedError: "subgraph has no title",
 		},
 	}
 
@@ -930,7 +1158,7 @@ func fixtureFlowchart() *Flowchart {
 	}
 }
 
-func TestHasValidMermaidNames(t *testing.T) {
+func TestMermaidNameViolations(t *testing.T) {
 	testCases := []struct {
 		name      string
 		flowchart Flowchart
@@ -962,7 +1190,7 @@ func TestHasValidMermaidNames(t *testing.T) {
 			flowchart: Flowchart{
 				Nodes: []*Node{
 					{name: "ValidNode"},
-					{name: "Invalid@Node"},
+					{name: "   "},
 				},
 			},
 			expected: false,
@@ -978,7 +1206,7 @@ func TestHasValidMermaidNames(t *testing.T) {
 						Title: pointTo("Subgraph1"),
 						Nodes: []*Node{
 							{name: "ValidSubNode"},
-							{name: "Invalid!SubNode"},
+							{name: "\t"},
 						},
 					},
 				},
@@ -1031,7 +1259,7 @@ func TestHasValidMermaidNames(t *testing.T) {
 						Subgraphs: []*Flowchart{{
 							Title: pointTo("Also Valid Subgraph"),
 							Nodes: []*Node{
-								{name: "Inv@alid"},
+								{name: "\n"},
 							},
 						}},
 					},
@@ -1043,7 +1271,7 @@ func TestHasValidMermaidNames(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result := hasValidMermaidNames(&tc.flowchart)
+			result := len(mermaidNameViolations(&tc.flowchart)) == 0
 			if result != tc.expected {
 				t.Errorf("Expected %v, got %v", tc.expected, result)
 			}
@@ -1064,12 +1292,22 @@ func TestIsValidMermaidNodeName(t *testing.T) {
 		},
 		{
 			"parenthesis",
-			"(",
+			"Check (v2)",
+			true,
+		},
+		{
+			"unicode letters",
+			"Überprüfung",
+			true,
+		},
+		{
+			"empty",
+			"",
 			false,
 		},
 		{
-			"random special character",
-			"@",
+			"whitespace only",
+			" \t ",
 			false,
 		},
 	}
@@ -1084,6 +1322,34 @@ func TestIsValidMermaidNodeName(t *testing.T) {
 	}
 }
 
+func TestMermaidID(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "already valid", input: "Valid_Node-1", expected: "Valid_Node-1"},
+		{name: "spaces removed", input: "Node One", expected: "NodeOne"},
+		{name: "unicode letters", input: "Überprüfung", expected: "_berpr_fung_" + nameHash("Überprüfung")},
+		{name: "parentheses", input: "Check (v2)", expected: "Check_v2__" + nameHash("Check (v2)")},
+		{name: "leading dash", input: "-x", expected: "_x_" + nameHash("-x")},
+		{name: "reserved word", input: "End", expected: "End_" + nameHash("End")},
+		{name: "only spaces", input: "  ", expected: "_" + nameHash("  ")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mermaidID(tt.input)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("mermaidID() mismatch (-expected +got):\n%s", diff)
+			}
+			if !isMermaidIDFor(got, tt.input) {
+				t.Errorf("isMermaidIDFor(%q, %q) = false, expected true", got, tt.input)
+			}
+		})
+	}
+}
+
 func TestFlattenFlowchart(t *testing.T) {
 	// Define helper nodes
 	node1 := &Node{name: "Node1", Type: NodeTypeProcess, Label: pointTo("Process 1")}
@@ -1373,11 +1639,11 @@ func TestRemoveNonMermaidNames(t *testing.T) {
 	// Define helper nodes
 	validNode1 := &Node{name: "ValidNode1", Type: NodeTypeProcess, Label: pointTo("Valid Node 1")}
 	validNode2 := &Node{name: "ValidNode2", Type: NodeTypeDecision, Label: pointTo("Valid Node 2")}
-	invalidNode := &Node{name: "Invalid@Node", Type: NodeTypeProcess, Label: pointTo("Invalid Node")}
+	invalidNode := &Node{name: "   ", Type: NodeTypeProcess, Label: pointTo("Invalid Node")}
 
 	// Define subgraph titles
 	validTitle := "ValidSubgraph"
-	invalidTitle := "Invalid@Subgraph"
+	invalidTitle := "  "
 	emptyTitle := ""
 	nilTitle := (*string)(nil)
 
@@ -1592,11 +1858,49 @@ func TestRemoveNonMermaidNames(t *testing.T) {
 	}
 }
 
+func TestHoistUntitledSubgraphs(t *testing.T) {
+	node1 := &Node{name: "Node1"}
+	node2 := &Node{name: "Node2"}
+	node3 := &Node{name: "Node3"}
+	link := SolidLink(node2, node3, nil)
+
+	original := &Flowchart{
+		Title: pointTo("Root"),
+		Nodes: []*Node{node1},
+		Subgraphs: []*Flowchart{{
+			Title: pointTo("Titled"),
+			Subgraphs: []*Flowchart{{
+				Nodes: []*Node{node2},
+				Subgraphs: []*Flowchart{{
+					Title: pointTo("Nested Titled"),
+					Nodes: []*Node{node3},
+				}},
+				Links: []Link{link},
+			}},
+		}},
+	}
+
+	expected := &Flowchart{
+		Title: pointTo("Root"),
+		Nodes: []*Node{node1},
+		Subgraphs: []*Flowchart{{
+			Title: pointTo("Titled"),
+			Nodes: []*Node{node2, node3},
+			Links: []Link{link},
+		}},
+	}
+
+	got := hoistUntitledSubgraphs(original)
+	if diff := cmp.Diff(expected, got, cmp.AllowUnexported(Node{}), cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("hoistUntitledSubgraphs() mismatch (-expected +got):\n%s", diff)
+	}
+}
+
 func TestGetMermaidFriendlyFlowchart(t *testing.T) {
 	// Define helper nodes
 	validNode1 := &Node{name: "ValidNode1", Type: NodeTypeProcess, Label: pointTo("Valid Node 1")}
 	validNode2 := &Node{name: "ValidNode2", Type: NodeTypeDecision, Label: pointTo("Valid Node 2")}
-	invalidNode := &Node{name: "Invalid@Node", Type: NodeTypeProcess, Label: pointTo("Invalid Node")}
+	invalidNode := &Node{name: "   ", Type: NodeTypeProcess, Label: pointTo("Invalid Node")}
 
 	firstLink := Link{
 		Origin:      validNode1,
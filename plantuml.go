@@ -0,0 +1,541 @@
+package flowchart
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// plantUMLEscaper escapes the Creole markup of PlantUML in plain text labels with its "~" escape
+// character, and writes newlines as PlantUML line breaks.
+var plantUMLEscaper = strings.NewReplacer("~", "~~", "**", "~**", "//", "~//", "__", "~__", "--", "~--", `""`, `~""`, "\n", `\n`)
+
+// plantUMLLabel returns the label of a node or link in PlantUML Creole markup. Markdown labels
+// keep their bold text and have their italic text converted to Creole.
+func plantUMLLabel(label string, format LabelFormatEnum) string {
+	if format != LabelFormatMarkdown {
+		return plantUMLEscaper.Replace(label)
+	}
+	var lines []string
+	for _, line := range strings.Split(label, "\n") {
+		var sb strings.Builder
+		for _, span := range markdownSpans(line) {
+			text := plantUMLEscaper.Replace(span.text)
+			if span.italic {
+				text = "//" + text + "//"
+			}
+			if span.bold {
+				text = "**" + text + "**"
+			}
+			sb.WriteString(text)
+		}
+		lines = append(lines, sb.String())
+	}
+	return strings.Join(lines, `\n`)
+}
+
+// plantUMLQuote returns a label as a double-quoted PlantUML string. PlantUML strings cannot
+// contain double quotes, so they are replaced by single ones.
+func plantUMLQuote(label string, format LabelFormatEnum) string {
+	return `"` + strings.ReplaceAll(plantUMLLabel(label, format), `"`, "'") + `"`
+}
+
+// plantUMLColor returns the fill colour of a style as a PlantUML colour, or "" if it has none.
+func plantUMLColor(s Style) string {
+	if s.Fill == "" {
+		return ""
+	}
+	return "#" + strings.TrimPrefix(s.Fill, "#")
+}
+
+// plantUMLID derives a PlantUML alias from a node name or subgraph title. Every character other
+// than an ASCII letter, digit or underscore is replaced by an underscore, and when a replacement
+// was needed, or the result is empty or starts with a digit, a hash of the name is appended.
+func plantUMLID(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	id := sb.String()
+	if id != name || id == "" || unicode.IsDigit(rune(id[0])) {
+		return "_" + id + "_" + nameHash(name)
+	}
+	return id
+}
+
+// plantUMLEdge is a visible link between two nodes of a plantUMLGraph.
+type plantUMLEdge struct {
+	link *Link
+	to   int
+}
+
+// plantUMLGraph holds the nodes and links of a flowchart while it is written as a PlantUML
+// activity diagram.
+type plantUMLGraph struct {
+	nodes      []*Node
+	partitions [][]string       // Titles of the subgraphs containing each node, outermost first
+	styles     []Style          // Style of each node
+	succ       [][]plantUMLEdge // Outgoing links of each node, in the order they were added
+	pred       [][]int
+	postdom    [][]bool // Nodes through which every path from each node to the end of the flow passes
+	emitted    []bool
+	sb         strings.Builder
+	open       []string // Titles of the partitions open at the current line
+}
+
+// newPlantUMLGraph indexes the nodes and visible links of a flowchart. It returns nil if a link
+// starts or ends at a subgraph, which activity diagrams cannot show.
+func newPlantUMLGraph(f *Flowchart) *plantUMLGraph {
+	g := &plantUMLGraph{}
+	index := make(map[string]int)
+	classDefs := allClassDefs(f)
+	walkFlowchart(f, func(sub *Flowchart, path []string) {
+		var partitions []string
+		for _, title := range path {
+			if title != "" {
+				partitions = append(partitions, title)
+			}
+		}
+		for _, node := range sub.Nodes {
+			index[node.name] = len(g.nodes)
+			g.nodes = append(g.nodes, node)
+			g.partitions = append(g.partitions, partitions)
+			g.styles = append(g.styles, resolveStyle(classDefs, node.Classes, node.Style))
+		}
+	})
+	g.succ = make([][]plantUMLEdge, len(g.nodes))
+	g.pred = make([][]int, len(g.nodes))
+	ok := true
+	walkFlowchart(f, func(sub *Flowchart, _ []string) {
+		for i := range sub.Links {
+			l := &sub.Links[i]
+			if l.LineType == LineTypeNone {
+				continue
+			}
+			_, originIsNode := l.Origin.(*Node)
+			_, targetIsNode := l.Target.(*Node)
+			if !originIsNode || !targetIsNode {
+				ok = false
+				continue
+			}
+			from, to := index[l.Origin.nodeName()], index[l.Target.nodeName()]
+			g.succ[from] = append(g.succ[from], plantUMLEdge{link: l, to: to})
+			g.pred[to] = append(g.pred[to], from)
+		}
+	})
+	if !ok {
+		return nil
+	}
+	return g
+}
+
+// computePostDominators fills in postdom. It reports false if the graph has a cycle.
+func (g *plantUMLGraph) computePostDominators() bool {
+	var order []int                    // Nodes with every successor before them
+	state := make([]int, len(g.nodes)) // 0 unvisited, 1 on the stack, 2 done
+	var visit func(n int) bool
+	visit = func(n int) bool {
+		state[n] = 1
+		for _, e := range g.succ[n] {
+			if state[e.to] == 1 || (state[e.to] == 0 && !visit(e.to)) {
+				return false
+			}
+		}
+		state[n] = 2
+		order = append(order, n)
+		return true
+	}
+	for n := range g.nodes {
+		if state[n] == 0 && !visit(n) {
+			return false
+		}
+	}
+
+	// A branch that ends at once, as a decision leading straight to a stop, does not keep the
+	// other branches from meeting again, so it is left out when they would not meet otherwise.
+	g.postdom = make([][]bool, len(g.nodes))
+	for _, n := range order {
+		set := g.intersect(n, false)
+		if !slices.Contains(set, true) {
+			set = g.intersect(n, true)
+		}
+		set[n] = true
+		g.postdom[n] = set
+	}
+	return true
+}
+
+// intersect returns the nodes in the postdom of every successor of n, skipping the successors
+// without successors of their own if skipEnds is true.
+func (g *plantUMLGraph) intersect(n int, skipEnds bool) []bool {
+	set := make([]bool, len(g.nodes))
+	first := true
+	for _, e := range g.succ[n] {
+		if skipEnds && len(g.succ[e.to]) == 0 {
+			continue
+		}
+		for m, in := range g.postdom[e.to] {
+			set[m] = in && (first || set[m])
+		}
+		first = false
+	}
+	return set
+}
+
+// join returns the node where the branches leaving n meet again: the nearest node other than n
+// through which every path from n passes, or -1 if the branches end separately.
+func (g *plantUMLGraph) join(n int) int {
+	best, bestSize := -1, -1
+	for m, in := range g.postdom[n] {
+		if !in || m == n {
+			continue
+		}
+		// The nearest post-dominator is post-dominated by all the others, so it has the most.
+		size := 0
+		for _, v := range g.postdom[m] {
+			if v {
+				size++
+			}
+		}
+		if size > bestSize {
+			best, bestSize = m, size
+		}
+	}
+	return best
+}
+
+// line writes a line indented for the depth of the open partitions and the given nesting.
+func (g *plantUMLGraph) line(depth int, format string, args ...any) {
+	g.sb.WriteString(strings.Repeat("  ", depth+len(g.open)))
+	g.sb.WriteString(fmt.Sprintf(format, args...))
+	g.sb.WriteString("\n")
+}
+
+// enter opens and closes partitions so that the partitions of a node are open, without closing
+// the first base partitions, which were opened by an enclosing branch.
+func (g *plantUMLGraph) enter(partitions []string, base, depth int) {
+	common := 0
+	for common < len(g.open) && common < len(partitions) && g.open[common] == partitions[common] {
+		common++
+	}
+	g.leave(max(common, base), depth)
+	if len(g.open) == common {
+		for _, title := range partitions[common:] {
+			g.line(depth, "partition %s {", plantUMLQuote(title, LabelFormatText))
+			g.open = append(g.open, title)
+		}
+	}
+}
+
+// leave closes the open partitions after the first keep.
+func (g *plantUMLGraph) leave(keep, depth int) {
+	for len(g.open) > keep {
+		g.open = g.open[:len(g.open)-1]
+		g.line(depth, "}")
+	}
+}
+
+// arrow writes the arrow leading to the next action when its link has a label or is not solid.
+func (g *plantUMLGraph) arrow(l *Link, depth int) {
+	if l == nil {
+		return
+	}
+	style := ""
+	switch l.LineType {
+	case LineTypeDotted:
+		style = "[dashed]"
+	case LineTypeThick:
+		style = "[bold]"
+	}
+	if style == "" && (l.Label == nil || *l.Label == "") {
+		return
+	}
+	label := ""
+	if l.Label != nil && *l.Label != "" {
+		label = " " + plantUMLLabel(*l.Label, l.LabelFormat)
+	}
+	g.line(depth, "-%s->%s;", style, label)
+}
+
+// action writes the statement of a node that is not a decision.
+func (g *plantUMLGraph) action(n int, depth int) {
+	node := g.nodes[n]
+	label := node.name
+	if node.Label != nil {
+		label = *node.Label
+	}
+	text := plantUMLLabel(label, node.LabelFormat)
+	color := plantUMLColor(g.styles[n])
+	switch {
+	case node.Type == NodeTypeTerminator && len(g.pred[n]) == 0:
+		g.line(depth, "start")
+	case node.Type == NodeTypeTerminator && len(g.succ[n]) == 0:
+		g.line(depth, "stop")
+	case node.Type == NodeTypeSubprocess:
+		g.line(depth, "%s:%s|", color, text)
+	case node.Type == NodeTypeInputOutput:
+		g.line(depth, "%s:%s/", color, text)
+	case node.Type == NodeTypeConnector:
+		g.line(depth, "(%s)", text)
+	case node.Type == NodeTypeDatabase:
+		g.line(depth, "%s:%s;", color, text)
+		g.line(depth, "note right: database")
+	default:
+		g.line(depth, "%s:%s;", color, text)
+	}
+}
+
+// sequence writes the flow from node n up to, but not including, node stop, as a sequence of
+// actions and of branches that meet again. incoming is the link leading to n, whose label is
+// written as an arrow. It reports false if a node is reached twice, which happens when branches
+// cross instead of nesting.
+func (g *plantUMLGraph) sequence(n, stop int, incoming *Link, depth int) bool {
+	base := len(g.open)
+	defer g.leave(base, depth)
+	for n >= 0 && n != stop {
+		if g.emitted[n] {
+			return false
+		}
+		g.emitted[n] = true
+		g.arrow(incoming, depth)
+		g.enter(g.partitions[n], base, depth)
+
+		outs := g.succ[n]
+		node := g.nodes[n]
+		if len(outs) < 2 {
+			g.action(n, depth)
+			if len(outs) == 0 {
+				if node.Type != NodeTypeTerminator && depth > 0 {
+					g.line(depth, "detach") // Keep the branch from flowing on to where the others meet
+				}
+				return true
+			}
+			n, incoming = outs[0].to, outs[0].link
+			continue
+		}
+
+		join := g.join(n)
+		condition := node.name
+		if node.Label != nil {
+			condition = *node.Label
+		}
+		condition = plantUMLLabel(condition, node.LabelFormat)
+		branchLabel := func(l *Link) string {
+			if l.Label == nil || *l.Label == "" {
+				return ""
+			}
+			return plantUMLLabel(*l.Label, l.LabelFormat)
+		}
+		switch {
+		case node.Type != NodeTypeDecision:
+			g.action(n, depth)
+			for i, out := range outs {
+				if i == 0 {
+					g.line(depth, "fork")
+				} else {
+					g.line(depth, "fork again")
+				}
+				if !g.sequence(out.to, join, out.link, depth+1) {
+					return false
+				}
+			}
+			g.line(depth, "end fork")
+		case len(outs) == 2:
+			g.line(depth, "%sif (%s) then (%s)", plantUMLColor(g.styles[n]), condition, branchLabel(outs[0].link))
+			if !g.sequence(outs[0].to, join, nil, depth+1) {
+				return false
+			}
+			g.line(depth, "else (%s)", branchLabel(outs[1].link))
+			if !g.sequence(outs[1].to, join, nil, depth+1) {
+				return false
+			}
+			g.line(depth, "endif")
+		default:
+			g.line(depth, "%sswitch (%s)", plantUMLColor(g.styles[n]), condition)
+			for _, out := range outs {
+				g.line(depth, "case (%s)", branchLabel(out.link))
+				if !g.sequence(out.to, join, nil, depth+1) {
+					return false
+				}
+			}
+			g.line(depth, "endswitch")
+		}
+		n, incoming = join, nil
+	}
+	return true
+}
+
+// renderPlantUMLActivity writes the flowchart as a PlantUML activity diagram. It reports false
+// if the flowchart is not structured: it must have a single node where the flow starts, from
+// which every node is reached, no cycles, no links to subgraphs, and branches that nest.
+func renderPlantUMLActivity(f *Flowchart) (string, bool) {
+	g := newPlantUMLGraph(f)
+	if g == nil || len(g.nodes) == 0 || !g.computePostDominators() {
+		return "", false
+	}
+	root := -1
+	for n := range g.nodes {
+		if len(g.pred[n]) == 0 {
+			if root >= 0 {
+				return "", false
+			}
+			root = n
+		}
+	}
+	g.emitted = make([]bool, len(g.nodes))
+	if root < 0 || !g.sequence(root, -1, nil, 0) || slices.Contains(g.emitted, false) {
+		return "", false
+	}
+	return g.sb.String(), true
+}
+
+// plantUMLElement returns the PlantUML element keyword that best represents a node type in the
+// arrow syntax of component and use case diagrams.
+func plantUMLElement(t NodeTypeEnum) string {
+	switch t {
+	case NodeTypeTerminator:
+		return "usecase"
+	case NodeTypeSubprocess:
+		return "component"
+	case NodeTypeDecision:
+		return "hexagon"
+	case NodeTypeInputOutput:
+		return "card"
+	case NodeTypeConnector:
+		return "circle"
+	case NodeTypeDatabase:
+		return "database"
+	default:
+		return "rectangle"
+	}
+}
+
+// plantUMLArrow returns the PlantUML arrow of a link in the arrow syntax, e.g. "-->" or "<..o".
+func plantUMLArrow(l Link) string {
+	body := "--"
+	switch l.LineType {
+	case LineTypeNone:
+		return "-[hidden]-"
+	case LineTypeDotted:
+		body = ".."
+	case LineTypeThick:
+		body = "-[bold]-"
+	}
+	heads := map[ArrowTypeEnum][2]string{
+		ArrowTypeNormal: {"<", ">"},
+		ArrowTypeCircle: {"o", "o"},
+		ArrowTypeCross:  {"x", "x"},
+	}[l.ArrowType]
+	if l.OriginArrow {
+		body = heads[0] + body
+	}
+	if l.TargetArrow {
+		body += heads[1]
+	}
+	return body
+}
+
+// renderPlantUMLElements writes the nodes of a flowchart as elements and its titled subgraphs as
+// nested rectangles. Untitled subgraphs cannot be named, so their contents are written in place.
+func renderPlantUMLElements(sb *strings.Builder, f *Flowchart, ids idMap, classDefs []StyleClass, indents int) {
+	indent := strings.Repeat("  ", indents)
+	for _, node := range f.Nodes {
+		label := node.name
+		if node.Label != nil {
+			label = *node.Label
+		}
+		color := plantUMLColor(resolveStyle(classDefs, node.Classes, node.Style))
+		if color != "" {
+			color = " " + color
+		}
+		sb.WriteString(fmt.Sprintf("%s%s %s as %s%s\n", indent, plantUMLElement(node.Type), plantUMLQuote(label, node.LabelFormat), ids[node.name], color))
+	}
+	for _, sub := range f.Subgraphs {
+		if sub.Title == nil || *sub.Title == "" {
+			renderPlantUMLElements(sb, sub, ids, classDefs, indents)
+			continue
+		}
+		color := plantUMLColor(resolveStyle(classDefs, sub.Classes, sub.Style))
+		if color != "" {
+			color = " " + color
+		}
+		sb.WriteString(fmt.Sprintf("%srectangle %s as %s%s {\n", indent, plantUMLQuote(*sub.Title, LabelFormatText), ids[*sub.Title], color))
+		renderPlantUMLElements(sb, sub, ids, classDefs, indents+1)
+		sb.WriteString(indent + "}\n")
+	}
+}
+
+// renderPlantUMLGraph writes the flowchart in the arrow syntax of component and use case
+// diagrams, which can show any graph.
+func renderPlantUMLGraph(f *Flowchart, ids idMap) string {
+	var sb strings.Builder
+	if f.Direction != DirectionVertical {
+		sb.WriteString("left to right direction\n")
+	}
+	renderPlantUMLElements(&sb, f, ids, allClassDefs(f), 0)
+	for _, l := range getAllLinks(f) {
+		label := ""
+		if l.Label != nil && *l.Label != "" && l.LineType != LineTypeNone {
+			label = " : " + plantUMLLabel(*l.Label, l.LabelFormat)
+		}
+		sb.WriteString(fmt.Sprintf("%s %s %s%s\n", ids[l.Origin.nodeName()], plantUMLArrow(l), ids[l.Target.nodeName()], label))
+	}
+	return sb.String()
+}
+
+// RenderPlantUML generates PlantUML source for the flowchart, for wikis and tools that render
+// PlantUML rather than Mermaid.js.
+//
+// Structured flowcharts are written as activity diagrams: the flow must start at a single node
+// and reach every node, without cycles or links to subgraphs, and the branches leaving a node
+// must meet again at a single node or end separately. Terminators become start and stop,
+// process, subprocess and input/output nodes actions with their SDL shape, connectors connectors,
+// and databases actions with a note. A decision becomes if/else, or switch when it has more than
+// two branches, labelled with its links; other nodes with several links fork. Subgraphs become
+// partitions, and labelled, dotted or thick links arrows. Activity diagrams are always drawn top
+// to bottom and cannot show arrow types.
+//
+// Other flowcharts fall back to the arrow syntax of component and use case diagrams, with an
+// element per node type, rectangles for subgraphs and arrows for every link.
+// It returns the PlantUML source or an error if validation fails.
+func RenderPlantUML(f *Flowchart) (string, error) {
+	if err := validatePlantUML(f); err != nil {
+		return "", err
+	}
+	body, ok := renderPlantUMLActivity(f)
+	if !ok {
+		ids, err := newIDMap(f, plantUMLID)
+		if err != nil {
+			return "", err
+		}
+		body = renderPlantUMLGraph(f, ids)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("@startuml\n")
+	if f.Title != nil && *f.Title != "" {
+		sb.WriteString(fmt.Sprintf("title %s\n", plantUMLLabel(*f.Title, LabelFormatText)))
+	}
+	sb.WriteString(body)
+	sb.WriteString("@enduml\n")
+	return sb.String(), nil
+}
+
+// validatePlantUML validates the Flowchart structure to ensure it can be rendered as PlantUML.
+// It checks for the following violations:
+// 1. All node and subgraph names must be unique across the whole flowchart.
+// 2. Style classes must have valid, unique names, and every class assigned must be defined.
+//
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func validatePlantUML(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	violations = append(violations, styleClassViolations(f)...)
+	return newValidationError(violations...)
+}
@@ -0,0 +1,199 @@
+package flowchart
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderPlantUML(t *testing.T) {
+	tests := []struct {
+		name      string
+		flowchart func() *Flowchart
+		expected  string
+	}{
+		{
+			name: "if and partition",
+			flowchart: func() *Flowchart {
+				start, check := TerminatorNode("start", pointTo("Start")), DecisionNode("check", pointTo("Valid?"))
+				save, log := SubprocessNode("save", pointTo("Save")), DatabaseNode("log", pointTo("Log"))
+				read, end := InputOutputNode("read", pointTo("Read")), TerminatorNode("end", nil)
+				read.Style = &Style{Fill: "f9f"}
+				return &Flowchart{
+					Title:     pointTo("Orders"),
+					Nodes:     []*Node{start, read, check, end},
+					Subgraphs: []*Flowchart{{Title: pointTo("Storage"), Nodes: []*Node{save, log}}},
+					Links: []Link{
+						SolidLink(start, read, nil),
+						SolidLink(read, check, pointTo("order")),
+						SolidLink(check, save, pointTo("yes")),
+						SolidLink(check, end, pointTo("no")),
+						ThickLink(save, log, nil),
+						SolidLink(log, end, nil),
+					},
+				}
+			},
+			expected: `@startuml
+title Orders
+start
+#f9f:Read/
+--> order;
+if (Valid?) then (yes)
+  partition "Storage" {
+    :Save|
+    -[bold]->;
+    :Log;
+    note right: database
+  }
+else (no)
+endif
+stop
+@enduml
+`,
+		},
+		{
+			name: "switch, fork and early stop",
+			flowchart: func() *Flowchart {
+				start, kind := TerminatorNode("start", nil), DecisionNode("kind", pointTo("Kind?"))
+				a, b, stop := ProcessNode("a", nil), ProcessNode("b", nil), TerminatorNode("stop", nil)
+				split, p, q := ProcessNode("split", nil), ConnectorNode("p", nil), ProcessNode("q", nil)
+				dead, done := ProcessNode("dead", nil), TerminatorNode("done", nil)
+				return &Flowchart{
+					Nodes: []*Node{start, kind, a, b, stop, split, p, q, dead, done},
+					Links: []Link{
+						SolidLink(start, kind, nil),
+						SolidLink(kind, a, pointTo("a")),
+						SolidLink(kind, b, pointTo("b")),
+						SolidLink(kind, stop, pointTo("c")),
+						SolidLink(a, split, nil),
+						SolidLink(b, split, nil),
+						SolidLink(split, p, nil),
+						DottedLink(split, q, pointTo("later")),
+						SolidLink(split, dead, nil),
+						SolidLink(p, done, nil),
+						SolidLink(q, done, nil),
+					},
+				}
+			},
+			expected: `@startuml
+start
+switch (Kind?)
+case (a)
+  :a;
+case (b)
+  :b;
+case (c)
+  stop
+endswitch
+:split;
+fork
+  (p)
+fork again
+  -[dashed]-> later;
+  :q;
+fork again
+  :dead;
+  detach
+end fork
+stop
+@enduml
+`,
+		},
+		{
+			name: "cycle falls back to arrows",
+			flowchart: func() *Flowchart {
+				start, check, retry := TerminatorNode("start", nil), DecisionNode("check", pointTo(`Say "ok"?`)), ProcessNode("retry", nil)
+				other := &Flowchart{Title: pointTo("Other steps"), Nodes: []*Node{retry}}
+				return &Flowchart{
+					Direction: DirectionHorizontalRight,
+					Nodes:     []*Node{start, check},
+					Subgraphs: []*Flowchart{other},
+					Links: []Link{
+						SolidLink(start, check, nil),
+						{Origin: check, Target: other, LineType: LineTypeDotted, ArrowType: ArrowTypeCircle, OriginArrow: true, TargetArrow: true, Label: pointTo("no")},
+						ThickLink(retry, check, nil),
+						{Origin: start, Target: retry, LineType: LineTypeNone},
+					},
+				}
+			},
+			expected: `@startuml
+left to right direction
+usecase "start" as start
+hexagon "Say 'ok'?" as check
+rectangle "Other steps" as _Other_steps_3546144a {
+  rectangle "retry" as retry
+}
+check o..o _Other_steps_3546144a : no
+retry -[bold]-> check
+start --> check
+start -[hidden]- retry
+@enduml
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderPlantUML(tt.flowchart())
+			if err != nil {
+				t.Fatalf("RenderPlantUML() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("RenderPlantUML() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderPlantUML_Errors(t *testing.T) {
+	a := &Node{name: "A", Classes: []string{"missing"}}
+	_, err := RenderPlantUML(&Flowchart{Nodes: []*Node{a}, Links: []Link{SolidLink(a, &Node{name: "Phantom"}, nil)}})
+	for _, code := range []error{ErrUndefinedStyleClass, ErrOrphanedEndpoint} {
+		if !errors.Is(err, code) {
+			t.Errorf("RenderPlantUML() error = %v, expected %v", err, code)
+		}
+	}
+}
+
+func TestPlantUMLLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		label    string
+		format   LabelFormatEnum
+		expected string
+	}{
+		{name: "plain", label: "Save order", expected: "Save order"},
+		{name: "creole markup", label: "a**b//c--d~e", expected: "a~**b~//c~--d~~e"},
+		{name: "newlines", label: "one\ntwo", expected: `one\ntwo`},
+		{name: "markdown", label: "**bold** and _italic_\nnext", format: LabelFormatMarkdown, expected: `**bold** and //italic//\nnext`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plantUMLLabel(tt.label, tt.format); got != tt.expected {
+				t.Errorf("plantUMLLabel() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPlantUMLID(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "order_1", expected: "order_1"},
+		{name: "Other steps", expected: "_Other_steps_" + nameHash("Other steps")},
+		{name: "1st", expected: "_1st_" + nameHash("1st")},
+		{name: "", expected: "__" + nameHash("")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plantUMLID(tt.name); got != tt.expected {
+				t.Errorf("plantUMLID(%q) = %q, expected %q", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
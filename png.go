@@ -0,0 +1,571 @@
+package flowchart
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RasterOptions controls the image generated by RenderPNG. Zero values select the defaults.
+type RasterOptions struct {
+	Scale      float64     // Pixels per unit of the layout, e.g. 2 for high-density screens; default 1
+	Background color.Color // Colour of the image behind the chart, color.Transparent for none; default white
+	Padding    int         // Extra space around the chart, in pixels of the image; default 0
+}
+
+// Sizes used by RenderPNG, in units of the layout. Colours are those of RenderSVG.
+const (
+	pngFontPixel    = 1.5 // Size of a pixel of the bitmap font, so that glyphs fill a layoutCharWidth cell
+	pngArrowSize    = 8.0 // Length and width of arrow heads, as the markers of RenderSVG
+	pngDashLength   = 3.0 // Length of the dashes and gaps of dotted lines
+	pngSubsamples   = 4   // Scanlines sampled per row of pixels when filling shapes
+	pngArcSegments  = 32  // Segments used to draw a full circle or ellipse
+	pngMaxImageSize = 1 << 26
+)
+
+// pngColors holds the colour names accepted in styles besides hexadecimal colours.
+var pngColors = map[string]color.RGBA{
+	"black":       {0x00, 0x00, 0x00, 0xFF},
+	"silver":      {0xC0, 0xC0, 0xC0, 0xFF},
+	"gray":        {0x80, 0x80, 0x80, 0xFF},
+	"grey":        {0x80, 0x80, 0x80, 0xFF},
+	"white":       {0xFF, 0xFF, 0xFF, 0xFF},
+	"maroon":      {0x80, 0x00, 0x00, 0xFF},
+	"red":         {0xFF, 0x00, 0x00, 0xFF},
+	"purple":      {0x80, 0x00, 0x80, 0xFF},
+	"fuchsia":     {0xFF, 0x00, 0xFF, 0xFF},
+	"magenta":     {0xFF, 0x00, 0xFF, 0xFF},
+	"green":       {0x00, 0x80, 0x00, 0xFF},
+	"lime":        {0x00, 0xFF, 0x00, 0xFF},
+	"olive":       {0x80, 0x80, 0x00, 0xFF},
+	"yellow":      {0xFF, 0xFF, 0x00, 0xFF},
+	"navy":        {0x00, 0x00, 0x80, 0xFF},
+	"blue":        {0x00, 0x00, 0xFF, 0xFF},
+	"teal":        {0x00, 0x80, 0x80, 0xFF},
+	"aqua":        {0x00, 0xFF, 0xFF, 0xFF},
+	"cyan":        {0x00, 0xFF, 0xFF, 0xFF},
+	"orange":      {0xFF, 0xA5, 0x00, 0xFF},
+	"pink":        {0xFF, 0xC0, 0xCB, 0xFF},
+	"none":        {},
+	"transparent": {},
+}
+
+// parseColor parses a colour of a style: "#rgb", "#rrggbb", "#rrggbbaa" or a basic CSS colour
+// name. It reports false for colours it does not understand.
+func parseColor(s string) (color.RGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := pngColors[s]; ok {
+		return c, true
+	}
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok {
+		return color.RGBA{}, false
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.RGBA{}, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	c := color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}
+	return color.RGBAModel.Convert(c).(color.RGBA), true
+}
+
+// styleColor returns the colour of a style property, or the fallback if it is empty or not understood.
+func styleColor(value, fallback string) color.RGBA {
+	if c, ok := parseColor(value); ok {
+		return c
+	}
+	c, _ := parseColor(fallback)
+	return c
+}
+
+// hexColor returns a style colour as "#rrggbb", or "" if it is not set, transparent or not
+// understood.
+func hexColor(value string) string {
+	c, ok := parseColor(value)
+	if !ok || c.A == 0 {
+		return ""
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// styleWidth returns the width of a style's stroke-width such as "2" or "2px", or the fallback if
+// it is empty or not understood.
+func styleWidth(value string, fallback float64) float64 {
+	w, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "px"), 64)
+	if err != nil || w < 0 {
+		return fallback
+	}
+	return w
+}
+
+// rasterCanvas draws shapes and text given in layout units on an image.
+type rasterCanvas struct {
+	img     *image.RGBA
+	scale   float64 // Pixels per layout unit
+	padding float64 // Pixels between the border of the image and the layout
+}
+
+// device returns the position in the image of a point of the layout.
+func (c *rasterCanvas) device(p Point) Point {
+	return Point{p.X*c.scale + c.padding, p.Y*c.scale + c.padding}
+}
+
+// blend paints the pixel at x, y with a colour covering the given fraction of it.
+func (c *rasterCanvas) blend(x, y int, col color.RGBA, coverage float64) {
+	i := c.img.PixOffset(x, y)
+	pix := c.img.Pix[i : i+4 : i+4]
+	k := coverage * float64(col.A) / 0xFF
+	for j, v := range []uint8{col.R, col.G, col.B, col.A} {
+		pix[j] = uint8(math.Round(float64(v)*coverage + float64(pix[j])*(1-k)))
+	}
+}
+
+// fill paints the union of the polygons, using the nonzero winding rule and antialiasing their edges.
+func (c *rasterCanvas) fill(polygons [][]Point, col color.RGBA) {
+	if col.A == 0 {
+		return
+	}
+	type edge struct {
+		a, b Point
+		dir  int
+	}
+	var edges []edge
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, polygon := range polygons {
+		for i := range polygon {
+			a, b := c.device(polygon[i]), c.device(polygon[(i+1)%len(polygon)])
+			minX, minY = min(minX, a.X), min(minY, a.Y)
+			maxX, maxY = max(maxX, a.X), max(maxY, a.Y)
+			switch {
+			case a.Y < b.Y:
+				edges = append(edges, edge{a, b, 1})
+			case a.Y > b.Y:
+				edges = append(edges, edge{b, a, -1})
+			}
+		}
+	}
+	bounds := c.img.Bounds()
+	x0, x1 := max(int(math.Floor(minX)), bounds.Min.X), min(int(math.Ceil(maxX)), bounds.Max.X)
+	y0, y1 := max(int(math.Floor(minY)), bounds.Min.Y), min(int(math.Ceil(maxY)), bounds.Max.Y)
+	if x0 >= x1 || y0 >= y1 {
+		return
+	}
+
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	cover := make([]float64, x1-x0)
+	var crossings []crossing
+	for py := y0; py < y1; py++ {
+		clear(cover)
+		for s := range pngSubsamples {
+			y := float64(py) + (float64(s)+0.5)/pngSubsamples
+			crossings = crossings[:0]
+			for _, e := range edges {
+				if e.a.Y <= y && y < e.b.Y {
+					crossings = append(crossings, crossing{e.a.X + (y-e.a.Y)*(e.b.X-e.a.X)/(e.b.Y-e.a.Y), e.dir})
+				}
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+			winding := 0
+			for i, cr := range crossings {
+				winding += cr.dir
+				if winding != 0 && i+1 < len(crossings) {
+					addSpan(cover, x0, cr.x, crossings[i+1].x, 1.0/pngSubsamples)
+				}
+			}
+		}
+		for i, v := range cover {
+			if v > 0 {
+				c.blend(x0+i, py, col, min(v, 1))
+			}
+		}
+	}
+}
+
+// addSpan adds weight times the part of each pixel covered by the span from a to b to the coverage
+// of a row of pixels starting at x0.
+func addSpan(cover []float64, x0 int, a, b, weight float64) {
+	a, b = max(a, float64(x0)), min(b, float64(x0+len(cover)))
+	for x := int(math.Floor(a)); float64(x) < b; x++ {
+		overlap := min(b, float64(x+1)) - max(a, float64(x))
+		if overlap > 0 {
+			cover[x-x0] += overlap * weight
+		}
+	}
+}
+
+// fillRect paints a rectangle given in layout units, with its edges snapped to whole pixels.
+func (c *rasterCanvas) fillRect(r Rect, col color.RGBA) {
+	topLeft, bottomRight := c.device(Point{r.X, r.Y}), c.device(Point{r.X + r.Width, r.Y + r.Height})
+	pixels := image.Rect(int(math.Round(topLeft.X)), int(math.Round(topLeft.Y)), int(math.Round(bottomRight.X)), int(math.Round(bottomRight.Y)))
+	pixels = pixels.Intersect(c.img.Bounds())
+	for y := pixels.Min.Y; y < pixels.Max.Y; y++ {
+		for x := pixels.Min.X; x < pixels.Max.X; x++ {
+			c.blend(x, y, col, 1)
+		}
+	}
+}
+
+// stroke draws a line of the given width along a path, closing it if closed is true. Dotted lines
+// alternate dashes and gaps of pngDashLength.
+func (c *rasterCanvas) stroke(path []Point, closed bool, width float64, dotted bool, col color.RGBA) {
+	if width <= 0 || len(path) < 2 {
+		return
+	}
+	if closed {
+		path = append(path[:len(path):len(path)], path[0])
+	}
+	pieces := [][]Point{path}
+	if dotted {
+		pieces = dashes(path, pngDashLength)
+		closed = false
+	}
+	var polygons [][]Point
+	for _, piece := range pieces {
+		polygons = append(polygons, strokePolygons(piece, closed, width/2)...)
+	}
+	c.fill(polygons, col)
+}
+
+// strokePolygons returns polygons covering a line of half width hw along a path: one quadrilateral
+// per segment, and a circle at each joint for rounded joins. All polygons turn the same way, so
+// that their union can be filled with the nonzero winding rule.
+func strokePolygons(path []Point, closed bool, hw float64) [][]Point {
+	var polygons [][]Point
+	for i := 1; i < len(path); i++ {
+		a, b := path[i-1], path[i]
+		length := distance(a, b)
+		if length == 0 {
+			continue
+		}
+		nx, ny := (b.Y-a.Y)/length*hw, -(b.X-a.X)/length*hw
+		polygons = append(polygons, []Point{{a.X + nx, a.Y + ny}, {b.X + nx, b.Y + ny}, {b.X - nx, b.Y - ny}, {a.X - nx, a.Y - ny}})
+	}
+	for i, p := range path {
+		if closed || (i > 0 && i < len(path)-1) {
+			polygons = append(polygons, ellipse(p, hw, hw))
+		}
+	}
+	for _, polygon := range polygons {
+		if signedArea(polygon) < 0 {
+			for i, j := 0, len(polygon)-1; i < j; i, j = i+1, j-1 {
+				polygon[i], polygon[j] = polygon[j], polygon[i]
+			}
+		}
+	}
+	return polygons
+}
+
+// signedArea returns the area of a polygon, positive if it turns clockwise on the image.
+func signedArea(polygon []Point) float64 {
+	var area float64
+	for i, p := range polygon {
+		q := polygon[(i+1)%len(polygon)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	return area / 2
+}
+
+// dashes splits a path into dashes of the given length, separated by gaps of the same length.
+func dashes(path []Point, length float64) [][]Point {
+	var pieces [][]Point
+	var current []Point
+	on, left := true, length // Whether the pen is down, and how far until it is lifted or put down
+	current = append(current, path[0])
+	for i := 1; i < len(path); i++ {
+		a, b := path[i-1], path[i]
+		segment := distance(a, b)
+		for done := 0.0; segment-done > 0; {
+			step := min(left, segment-done)
+			done += step
+			left -= step
+			t := done / segment
+			p := Point{a.X + t*(b.X-a.X), a.Y + t*(b.Y-a.Y)}
+			if on {
+				current = append(current, p)
+			}
+			if left == 0 {
+				if on {
+					pieces = append(pieces, current)
+				}
+				on, left, current = !on, length, []Point{p}
+			}
+		}
+	}
+	if on && len(current) > 1 {
+		pieces = append(pieces, current)
+	}
+	return pieces
+}
+
+// arc returns points along an arc of an ellipse centred on c, from angle start to angle end in
+// radians, clockwise on the image for increasing angles.
+func arc(c Point, rx, ry, start, end float64) []Point {
+	n := max(int(math.Ceil(math.Abs(end-start)/(2*math.Pi)*pngArcSegments)), 1)
+	points := make([]Point, n+1)
+	for i := range points {
+		angle := start + (end-start)*float64(i)/float64(n)
+		points[i] = Point{c.X + rx*math.Cos(angle), c.Y + ry*math.Sin(angle)}
+	}
+	return points
+}
+
+// ellipse returns the outline of an ellipse centred on c.
+func ellipse(c Point, rx, ry float64) []Point {
+	points := arc(c, rx, ry, 0, 2*math.Pi)
+	return points[:len(points)-1]
+}
+
+// nodeOutline returns the outline of the shape of a node, as drawn by RenderSVG.
+func nodeOutline(n NodeLayout) []Point {
+	r := n.Rect
+	c := r.Center()
+	left, top, right, bottom := r.X, r.Y, r.X+r.Width, r.Y+r.Height
+	switch n.Node.Type {
+	case NodeTypeTerminator:
+		radius := r.Height / 2
+		outline := arc(Point{right - radius, c.Y}, radius, radius, -math.Pi/2, math.Pi/2)
+		return append(outline, arc(Point{left + radius, c.Y}, radius, radius, math.Pi/2, 3*math.Pi/2)...)
+	case NodeTypeDecision:
+		return []Point{{c.X, top}, {right, c.Y}, {c.X, bottom}, {left, c.Y}}
+	case NodeTypeInputOutput:
+		skew := r.Height / 2
+		return []Point{{left + skew, top}, {right, top}, {right - skew, bottom}, {left, bottom}}
+	case NodeTypeConnector:
+		return ellipse(c, r.Width/2, r.Width/2)
+	case NodeTypeDatabase:
+		ry := layoutPaddingY / 2
+		outline := arc(Point{c.X, bottom - ry}, r.Width/2, ry, 0, math.Pi)
+		return append(outline, arc(Point{c.X, top + ry}, r.Width/2, ry, math.Pi, 2*math.Pi)...)
+	}
+	return []Point{{left, top}, {right, top}, {right, bottom}, {left, bottom}}
+}
+
+// drawText draws a label centred on p in the bitmap font, with one line per line of the label.
+// Bold markdown text is drawn twice, half a font pixel apart, and italic text is slanted.
+func (c *rasterCanvas) drawText(p Point, label string, format LabelFormatEnum, col color.RGBA) {
+	lines := strings.Split(label, "\n")
+	y := p.Y - float64(len(lines)-1)*layoutLineHeight/2
+	for i, line := range lines {
+		spans := []markdownSpan{{text: line}}
+		if format == LabelFormatMarkdown {
+			spans = markdownSpans(line)
+		}
+		count := 0
+		for _, span := range spans {
+			count += len([]rune(span.text))
+		}
+		x := p.X - float64(count)*layoutCharWidth/2
+		top := y + float64(i)*layoutLineHeight - glyphAscent*pngFontPixel/2
+		for _, span := range spans {
+			for _, r := range span.text {
+				origin := Point{x + (layoutCharWidth-glyphWidth*pngFontPixel)/2, top}
+				c.drawGlyph(origin, glyphFor(r), span.italic, col)
+				if span.bold {
+					c.drawGlyph(Point{origin.X + pngFontPixel/2, origin.Y}, glyphFor(r), span.italic, col)
+				}
+				x += layoutCharWidth
+			}
+		}
+	}
+}
+
+// drawGlyph draws a glyph with its top-left corner at origin.
+func (c *rasterCanvas) drawGlyph(origin Point, g glyph, italic bool, col color.RGBA) {
+	for gy := range glyphHeight {
+		slant := 0.0
+		if italic {
+			slant = float64(glyphAscent-1-gy) * pngFontPixel / 4
+		}
+		for gx := range glyphWidth {
+			if g.set(gx, gy) {
+				c.fillRect(Rect{origin.X + float64(gx)*pngFontPixel + slant, origin.Y + float64(gy)*pngFontPixel, pngFontPixel, pngFontPixel}, col)
+			}
+		}
+	}
+}
+
+// drawNode draws the shape and label of a node.
+func (c *rasterCanvas) drawNode(n NodeLayout, style Style) {
+	outline := nodeOutline(n)
+	stroke := styleColor(style.Stroke, svgNodeStroke)
+	width := styleWidth(style.StrokeWidth, 1)
+	c.fill([][]Point{outline}, styleColor(style.Fill, svgNodeFill))
+	c.stroke(outline, true, width, false, stroke)
+
+	r, center := n.Rect, n.Rect.Center()
+	switch n.Node.Type {
+	case NodeTypeSubprocess:
+		inset := 8.0
+		for _, x := range []float64{r.X + inset, r.X + r.Width - inset} {
+			c.stroke([]Point{{x, r.Y}, {x, r.Y + r.Height}}, false, width, false, stroke)
+		}
+	case NodeTypeDatabase:
+		c.stroke(arc(Point{center.X, r.Y + layoutPaddingY/2}, r.Width/2, layoutPaddingY/2, 0, math.Pi), false, width, false, stroke)
+		center.Y += layoutPaddingY / 2 // Centre the label below the lid
+	}
+
+	label := n.Node.name
+	if n.Node.Label != nil {
+		label = *n.Node.Label
+	}
+	c.drawText(center, label, n.Node.LabelFormat, styleColor(style.Color, svgTextColor))
+}
+
+// drawEdge draws the line of a link and its arrow heads. Links with LineTypeNone are not drawn.
+func (c *rasterCanvas) drawEdge(e EdgeLayout, style Style) {
+	if e.Link.LineType == LineTypeNone || len(e.Points) < 2 {
+		return
+	}
+	col := styleColor(style.Stroke, svgLineStroke)
+	width := svgLineWidth
+	if e.Link.LineType == LineTypeThick {
+		width = svgThickLineWidth
+	}
+	width = styleWidth(style.StrokeWidth, width)
+
+	points := e.Points
+	var heads [][2]Point // Tip of each arrow head and the point the line comes from
+	if e.Link.ArrowType != ArrowTypeNone {
+		if e.Link.OriginArrow {
+			heads = append(heads, [2]Point{points[0], points[1]})
+		}
+		if e.Link.TargetArrow {
+			heads = append(heads, [2]Point{points[len(points)-1], points[len(points)-2]})
+		}
+	}
+	c.stroke(points, false, width, e.Link.LineType == LineTypeDotted, col)
+	for _, head := range heads {
+		c.drawArrowHead(head[0], head[1], e.Link.ArrowType, col)
+	}
+}
+
+// drawArrowHead draws the marker of an arrow type at the end of a line reaching tip from from.
+func (c *rasterCanvas) drawArrowHead(tip, from Point, a ArrowTypeEnum, col color.RGBA) {
+	length := distance(from, tip)
+	if length == 0 {
+		return
+	}
+	dx, dy := (tip.X-from.X)/length, (tip.Y-from.Y)/length // Direction of the line
+	at := func(along, across float64) Point {
+		return Point{tip.X + dx*along - dy*across, tip.Y + dy*along + dx*across}
+	}
+	half := pngArrowSize / 2
+	switch a {
+	case ArrowTypeCircle:
+		c.fill([][]Point{ellipse(tip, half*0.8, half*0.8)}, col)
+	case ArrowTypeCross:
+		arm := half * 0.8
+		c.stroke([]Point{at(-arm, -arm), at(arm, arm)}, false, 1.6, false, col)
+		c.stroke([]Point{at(-arm, arm), at(arm, -arm)}, false, 1.6, false, col)
+	default:
+		c.fill([][]Point{{tip, at(-pngArrowSize, half), at(-pngArrowSize, -half)}}, col)
+	}
+}
+
+// drawEdgeLabel draws the label of a link on a background box at the middle of its line.
+func (c *rasterCanvas) drawEdgeLabel(e EdgeLayout, style Style) {
+	if e.Link.LineType == LineTypeNone || e.Link.Label == nil || *e.Link.Label == "" {
+		return
+	}
+	p := e.Midpoint()
+	w, h := textSize(nodeText(&Node{Label: e.Link.Label, LabelFormat: e.Link.LabelFormat}))
+	w, h = w+8, h+4
+	background := Rect{p.X - w/2, p.Y - h/2, w, h}
+	c.fill([][]Point{{{background.X, background.Y}, {background.X + w, background.Y}, {background.X + w, background.Y + h}, {background.X, background.Y + h}}},
+		styleColor("", svgLabelFill))
+	c.drawText(p, *e.Link.Label, e.Link.LabelFormat, styleColor(style.Color, svgTextColor))
+}
+
+// drawCluster draws the frame and title of a subgraph.
+func (c *rasterCanvas) drawCluster(cl ClusterLayout, style Style) {
+	r := cl.Rect
+	outline := []Point{{r.X, r.Y}, {r.X + r.Width, r.Y}, {r.X + r.Width, r.Y + r.Height}, {r.X, r.Y + r.Height}}
+	c.fill([][]Point{outline}, styleColor(style.Fill, svgClusterFill))
+	c.stroke(outline, true, styleWidth(style.StrokeWidth, 1), false, styleColor(style.Stroke, svgClusterStroke))
+	if title := cl.Subgraph.nodeName(); title != "" {
+		c.drawText(Point{r.X + r.Width/2, r.Y + 6 + layoutLineHeight/2}, title, LabelFormatText, styleColor(style.Color, svgTextColor))
+	}
+}
+
+// RenderPNG generates a PNG image of the flowchart, for places that do not accept SVG such as
+// emails and PDF documents. It draws the same picture as RenderSVG, laid out with ComputeLayout,
+// using only the standard image packages: labels are written in an embedded bitmap font that
+// covers printable ASCII, so other characters are drawn as boxes and font styles other than bold
+// and italic are ignored. Colours in styles may be hexadecimal or basic CSS colour names.
+// It returns the encoded image or an error if validation fails or the options are invalid.
+func RenderPNG(f *Flowchart, opts RasterOptions) ([]byte, error) {
+	if opts.Scale < 0 || math.IsNaN(opts.Scale) || math.IsInf(opts.Scale, 0) {
+		return nil, fmt.Errorf("invalid scale %v, must be positive", opts.Scale)
+	}
+	if opts.Padding < 0 {
+		return nil, fmt.Errorf("invalid padding %d, must not be negative", opts.Padding)
+	}
+	if opts.Scale == 0 {
+		opts.Scale = 1
+	}
+	if opts.Background == nil {
+		opts.Background = color.White
+	}
+	if err := validatePNG(f); err != nil {
+		return nil, err
+	}
+	l, err := ComputeLayout(f, LayoutOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	width := int(math.Ceil(l.Width*opts.Scale)) + 2*opts.Padding
+	height := int(math.Ceil(l.Height*opts.Scale)) + 2*opts.Padding
+	if float64(width)*float64(height) > pngMaxImageSize {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large, use a smaller scale", width, height)
+	}
+	c := &rasterCanvas{img: image.NewRGBA(image.Rect(0, 0, width, height)), scale: opts.Scale, padding: float64(opts.Padding)}
+	draw.Draw(c.img, c.img.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+
+	classDefs := allClassDefs(f)
+	for _, cl := range l.Clusters {
+		c.drawCluster(cl, resolveStyle(classDefs, cl.Subgraph.Classes, cl.Subgraph.Style))
+	}
+	for _, e := range l.Edges {
+		c.drawEdge(e, resolveStyle(classDefs, e.Link.Classes, e.Link.Style))
+	}
+	for _, n := range l.Nodes {
+		c.drawNode(n, resolveStyle(classDefs, n.Node.Classes, n.Node.Style))
+	}
+	for _, e := range l.Edges {
+		c.drawEdgeLabel(e, resolveStyle(classDefs, e.Link.Classes, e.Link.Style))
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// validatePNG validates the Flowchart structure to ensure it can be rendered as PNG.
+// It checks for the same violations as validateSVG.
+//
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func validatePNG(f *Flowchart) error {
+	return validateSVG(f)
+}
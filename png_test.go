@@ -0,0 +1,188 @@
+package flowchart
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// decodePNG renders the flowchart as PNG and decodes the image.
+func decodePNG(t *testing.T, f *Flowchart, opts RasterOptions) image.Image {
+	t.Helper()
+	data, err := RenderPNG(f, opts)
+	if err != nil {
+		t.Fatalf("RenderPNG() unexpected error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("RenderPNG() generated an invalid PNG: %v", err)
+	}
+	return img
+}
+
+// rgbaAt returns the colour of a pixel of an image, without alpha premultiplication.
+func rgbaAt(img image.Image, x, y int) color.NRGBA {
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+func TestRenderPNG(t *testing.T) {
+	a, b := TerminatorNode("A", pointTo("Start")), ProcessNode("B", pointTo("R&D"))
+	b.Style = &Style{Fill: "#f00"}
+	chart := &Flowchart{
+		Direction: DirectionVertical,
+		Nodes:     []*Node{a, b},
+		Links:     []Link{{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeNormal, TargetArrow: true, Label: pointTo("go")}},
+	}
+	white := color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	nodeFill := color.NRGBA{0xEC, 0xEC, 0xFF, 0xFF}
+	red := color.NRGBA{0xFF, 0x00, 0x00, 0xFF}
+
+	// The layout is 112x166, with A at (20, 20, 72, 38) and B at (28, 108, 56, 38), as in TestRenderSVG.
+	tests := []struct {
+		name     string
+		opts     RasterOptions
+		size     image.Point
+		expected map[image.Point]color.NRGBA // Colours expected at some pixels
+	}{
+		{
+			name: "defaults",
+			size: image.Pt(112, 166),
+			expected: map[image.Point]color.NRGBA{
+				{5, 5}:    white,
+				{25, 39}:  nodeFill,
+				{30, 140}: red,
+			},
+		},
+		{
+			name: "scale and padding",
+			opts: RasterOptions{Scale: 2, Padding: 10},
+			size: image.Pt(244, 352),
+			expected: map[image.Point]color.NRGBA{
+				{5, 5}:    white,
+				{60, 88}:  nodeFill,
+				{70, 290}: red,
+			},
+		},
+		{
+			name: "background",
+			opts: RasterOptions{Background: color.Black},
+			size: image.Pt(112, 166),
+			expected: map[image.Point]color.NRGBA{
+				{5, 5}:   {0x00, 0x00, 0x00, 0xFF},
+				{25, 39}: nodeFill,
+			},
+		},
+		{
+			name: "transparent background",
+			opts: RasterOptions{Background: color.Transparent},
+			size: image.Pt(112, 166),
+			expected: map[image.Point]color.NRGBA{
+				{5, 5}:   {},
+				{25, 39}: nodeFill,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := decodePNG(t, chart, tt.opts)
+			if got := img.Bounds().Size(); got != tt.size {
+				t.Errorf("RenderPNG() size = %v, expected %v", got, tt.size)
+			}
+			for p, expected := range tt.expected {
+				if got := rgbaAt(img, p.X, p.Y); got != expected {
+					t.Errorf("RenderPNG() pixel at %v = %v, expected %v", p, got, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPNG_Text(t *testing.T) {
+	// count returns the number of pixels of the text colour in the label of a single process node.
+	count := func(label string, format LabelFormatEnum) int {
+		n := ProcessNode("A", pointTo(label))
+		n.LabelFormat = format
+		img := decodePNG(t, &Flowchart{Nodes: []*Node{n}}, RasterOptions{Scale: 2})
+		text := color.NRGBA{0x33, 0x33, 0x33, 0xFF}
+		pixels := 0
+		for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+			for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+				if rgbaAt(img, x, y) == text {
+					pixels++
+				}
+			}
+		}
+		return pixels
+	}
+
+	// At scale 2, each pixel of the font is 3x3 pixels of the image.
+	if got, expected := count("-", LabelFormatText), 5*9; got != expected {
+		t.Errorf("RenderPNG() label \"-\" has %d text pixels, expected %d", got, expected)
+	}
+	if got, expected := count("**-**", LabelFormatMarkdown), 6*9; got <= count("-", LabelFormatText) {
+		t.Errorf("RenderPNG() bold label has %d text pixels, expected about %d", got, expected)
+	}
+	if got, expected := count("é", LabelFormatText), count("☃", LabelFormatText); got != expected || got == 0 {
+		t.Errorf("RenderPNG() characters outside the font have %d and %d text pixels, expected the same box", got, expected)
+	}
+}
+
+func TestRenderPNG_Errors(t *testing.T) {
+	chart := &Flowchart{Nodes: []*Node{ProcessNode("A", nil)}}
+	for _, opts := range []RasterOptions{{Scale: -1}, {Padding: -1}} {
+		if _, err := RenderPNG(chart, opts); err == nil {
+			t.Errorf("RenderPNG(%+v) expected an error", opts)
+		}
+	}
+
+	a := &Node{name: "A", Classes: []string{"missing"}}
+	_, err := RenderPNG(&Flowchart{Nodes: []*Node{a}, Links: []Link{SolidLink(a, &Node{name: "Phantom"}, nil)}}, RasterOptions{})
+	for _, code := range []error{ErrUndefinedStyleClass, ErrOrphanedEndpoint} {
+		if !errors.Is(err, code) {
+			t.Errorf("RenderPNG() error = %v, expected %v", err, code)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		input      string
+		expected   color.RGBA
+		expectedOk bool
+	}{
+		{input: "#f9f", expected: color.RGBA{0xFF, 0x99, 0xFF, 0xFF}, expectedOk: true},
+		{input: "#1A2b3C", expected: color.RGBA{0x1A, 0x2B, 0x3C, 0xFF}, expectedOk: true},
+		{input: "#ff000080", expected: color.RGBA{0x80, 0x00, 0x00, 0x80}, expectedOk: true},
+		{input: " Red ", expected: color.RGBA{0xFF, 0x00, 0x00, 0xFF}, expectedOk: true},
+		{input: "none", expected: color.RGBA{}, expectedOk: true},
+		{input: "#12345"},
+		{input: "#ggg"},
+		{input: "rgb(1, 2, 3)"},
+		{input: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parseColor(tt.input)
+			if ok != tt.expectedOk {
+				t.Fatalf("parseColor(%q) ok = %v, expected %v", tt.input, ok, tt.expectedOk)
+			}
+			if got != tt.expected {
+				t.Errorf("parseColor(%q) = %v, expected %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDashes(t *testing.T) {
+	expected := [][]Point{{{0, 0}, {3, 0}}, {{6, 0}, {9, 0}}, {{12, 0}, {14, 0}, {14, 1}}}
+	if diff := cmp.Diff(expected, dashes([]Point{{0, 0}, {14, 0}, {14, 1}}, 3)); diff != "" {
+		t.Errorf("dashes() mismatch (-expected +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,151 @@
+package flowchart
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// styleClassNameRe matches the names that can be used for style classes.
+var styleClassNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// Style holds the visual properties of a node, link or subgraph.
+// Empty properties are left to the defaults of the renderer.
+type Style struct {
+	Fill        string `json:"fill,omitempty" yaml:"fill,omitempty"`               // Background colour, e.g. "#f9f" or "red"
+	Stroke      string `json:"stroke,omitempty" yaml:"stroke,omitempty"`           // Border or line colour
+	StrokeWidth string `json:"strokeWidth,omitempty" yaml:"strokeWidth,omitempty"` // Border or line width, e.g. "2px"
+	Color       string `json:"color,omitempty" yaml:"color,omitempty"`             // Text colour
+	FontFamily  string `json:"fontFamily,omitempty" yaml:"fontFamily,omitempty"`   // Font family of the text
+	FontSize    string `json:"fontSize,omitempty" yaml:"fontSize,omitempty"`       // Font size of the text, e.g. "14px"
+	FontWeight  string `json:"fontWeight,omitempty" yaml:"fontWeight,omitempty"`   // Font weight of the text, e.g. "bold"
+}
+
+// StyleClass is a named Style that can be assigned to nodes, links and subgraphs.
+type StyleClass struct {
+	Name  string `json:"name" yaml:"name"`   // Name used to assign the class
+	Style Style  `json:"style" yaml:"style"` // Properties applied by the class
+}
+
+// AddClassDef adds a named style class to the flowchart, ensuring it has a valid, unique name.
+// Classes defined on a subgraph can be used anywhere in the enclosing flowchart.
+// It returns a *ValidationError with code ErrInvalidStyleClass if the name is invalid or already defined.
+func (f *Flowchart) AddClassDef(name string, style Style) error {
+	if !styleClassNameRe.MatchString(name) {
+		return newValidationError(Violation{
+			Code:    ErrInvalidStyleClass,
+			Element: f,
+			Message: fmt.Sprintf("cannot add class %q with invalid name", name),
+		})
+	}
+	if slices.ContainsFunc(allClassDefs(f), func(c StyleClass) bool { return c.Name == name }) {
+		return newValidationError(Violation{
+			Code:    ErrInvalidStyleClass,
+			Element: f,
+			Message: fmt.Sprintf("cannot add class %q with non-unique name", name),
+		})
+	}
+	f.ClassDefs = append(f.ClassDefs, StyleClass{Name: name, Style: style})
+	return nil
+}
+
+// isEmpty reports whether none of the style's properties are set.
+func (s Style) isEmpty() bool {
+	return s == Style{}
+}
+
+// merge returns the style with every property that is set in other applied on top of it.
+func (s Style) merge(other Style) Style {
+	for _, p := range []struct{ dst, src *string }{
+		{&s.Fill, &other.Fill},
+		{&s.Stroke, &other.Stroke},
+		{&s.StrokeWidth, &other.StrokeWidth},
+		{&s.Color, &other.Color},
+		{&s.FontFamily, &other.FontFamily},
+		{&s.FontSize, &other.FontSize},
+		{&s.FontWeight, &other.FontWeight},
+	} {
+		if *p.src != "" {
+			*p.dst = *p.src
+		}
+	}
+	return s
+}
+
+// allClassDefs returns the style classes defined in the flowchart and all of its subgraphs, in tree order.
+func allClassDefs(f *Flowchart) []StyleClass {
+	classes := slices.Clone(f.ClassDefs)
+	for _, subgraph := range f.Subgraphs {
+		classes = append(classes, allClassDefs(subgraph)...)
+	}
+	return classes
+}
+
+// resolveStyle returns the style obtained by applying the named classes in order, followed by the
+// inline style if there is one. Unknown class names are ignored.
+func resolveStyle(classDefs []StyleClass, classes []string, inline *Style) Style {
+	var style Style
+	for _, name := range classes {
+		for _, def := range classDefs {
+			if def.Name == name {
+				style = style.merge(def.Style)
+			}
+		}
+	}
+	if inline != nil {
+		style = style.merge(*inline)
+	}
+	return style
+}
+
+// styleClassViolations reports every style class defined in the flowchart tree with an invalid or
+// repeated name, and every class assigned to a node, link or subgraph that is not defined.
+func styleClassViolations(f *Flowchart) []Violation {
+	var violations []Violation
+	var names []string
+	walkFlowchart(f, func(f *Flowchart, path []string) {
+		for _, class := range f.ClassDefs {
+			if !styleClassNameRe.MatchString(class.Name) {
+				violations = append(violations, Violation{
+					Code:    ErrInvalidStyleClass,
+					Path:    path,
+					Element: f,
+					Message: fmt.Sprintf("style class %q has an invalid name", class.Name),
+				})
+			} else if slices.Contains(names, class.Name) {
+				violations = append(violations, Violation{
+					Code:    ErrInvalidStyleClass,
+					Path:    path,
+					Element: f,
+					Message: fmt.Sprintf("style class %q is defined more than once", class.Name),
+				})
+			}
+			names = append(names, class.Name)
+		}
+	})
+
+	undefined := func(classes []string, path []string, element any, description string) {
+		for _, class := range classes {
+			if !slices.Contains(names, class) {
+				violations = append(violations, Violation{
+					Code:    ErrUndefinedStyleClass,
+					Path:    path,
+					Element: element,
+					Message: fmt.Sprintf("%s uses undefined style class %q", description, class),
+				})
+			}
+		}
+	}
+	walkFlowchart(f, func(f *Flowchart, path []string) {
+		for _, node := range f.Nodes {
+			undefined(node.Classes, path, node, fmt.Sprintf("node %q", node.name))
+		}
+		for i := range f.Links {
+			undefined(f.Links[i].Classes, path, &f.Links[i], describeLink(&f.Links[i]))
+		}
+		for _, subgraph := range f.Subgraphs {
+			undefined(subgraph.Classes, path, subgraph, fmt.Sprintf("subgraph %q", subgraph.nodeName()))
+		}
+	})
+	return violations
+}
@@ -0,0 +1,182 @@
+package flowchart
+
+import (
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestFlowchart_AddClassDef(t *testing.T) {
+	red := Style{Fill: "#f00"}
+	tests := []struct {
+		name              string
+		chart             *Flowchart
+		className         string
+		expectedErr       error
+		expectedClassDefs []StyleClass
+	}{
+		{
+			name:              "Add valid class",
+			chart:             basicFlowchart(nil, DirectionVertical),
+			className:         "failed",
+			expectedErr:       nil,
+			expectedClassDefs: []StyleClass{{Name: "failed", Style: red}},
+		},
+		{
+			name:              "Add class with hyphens and digits",
+			chart:             basicFlowchart(nil, DirectionVertical),
+			className:         "step-2_done",
+			expectedErr:       nil,
+			expectedClassDefs: []StyleClass{{Name: "step-2_done", Style: red}},
+		},
+		{
+			name:              "Add class with invalid name",
+			chart:             basicFlowchart(nil, DirectionVertical),
+			className:         "not valid",
+			expectedErr:       fmt.Errorf(`cannot add class "not valid" with invalid name`),
+			expectedClassDefs: nil,
+		},
+		{
+			name:              "Add class with empty name",
+			chart:             basicFlowchart(nil, DirectionVertical),
+			className:         "",
+			expectedErr:       fmt.Errorf(`cannot add class "" with invalid name`),
+			expectedClassDefs: nil,
+		},
+		{
+			name: "Add class already defined in a subgraph",
+			chart: &Flowchart{
+				Subgraphs: []*Flowchart{{ClassDefs: []StyleClass{{Name: "failed"}}}},
+			},
+			className:         "failed",
+			expectedErr:       fmt.Errorf(`cannot add class "failed" with non-unique name`),
+			expectedClassDefs: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.chart.AddClassDef(tt.className, red)
+			if !compareErrors(tt.expectedErr, err) {
+				t.Errorf("AddClassDef() error = %v, want %v", err, tt.expectedErr)
+			}
+			if diff := cmp.Diff(tt.expectedClassDefs, tt.chart.ClassDefs); diff != "" {
+				t.Errorf("AddClassDef() ClassDefs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveStyle(t *testing.T) {
+	classDefs := []StyleClass{
+		{Name: "failed", Style: Style{Fill: "#f00", Stroke: "#900"}},
+		{Name: "bold", Style: Style{FontWeight: "bold", Stroke: "#000"}},
+	}
+	tests := []struct {
+		name     string
+		classes  []string
+		inline   *Style
+		expected Style
+	}{
+		{
+			name:     "No classes or inline style",
+			expected: Style{},
+		},
+		{
+			name:     "Single class",
+			classes:  []string{"failed"},
+			expected: Style{Fill: "#f00", Stroke: "#900"},
+		},
+		{
+			name:     "Later classes override earlier ones",
+			classes:  []string{"failed", "bold"},
+			expected: Style{Fill: "#f00", Stroke: "#000", FontWeight: "bold"},
+		},
+		{
+			name:     "Inline style overrides classes",
+			classes:  []string{"failed"},
+			inline:   &Style{Fill: "#0f0", Color: "#fff"},
+			expected: Style{Fill: "#0f0", Stroke: "#900", Color: "#fff"},
+		},
+		{
+			name:     "Unknown classes are ignored",
+			classes:  []string{"missing"},
+			expected: Style{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveStyle(classDefs, tt.classes, tt.inline)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("resolveStyle() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStyleClassViolations(t *testing.T) {
+	tests := []struct {
+		name      string
+		flowchart *Flowchart
+		expected  bool
+	}{
+		{
+			name:      "No classes",
+			flowchart: &Flowchart{Nodes: []*Node{{name: "A"}}},
+			expected:  true,
+		},
+		{
+			name: "Classes defined in a subgraph and used at the root",
+			flowchart: &Flowchart{
+				Nodes:     []*Node{{name: "A", Classes: []string{"done"}}},
+				Subgraphs: []*Flowchart{{Title: pointTo("G"), ClassDefs: []StyleClass{{Name: "done"}}, Classes: []string{"done"}}},
+			},
+			expected: true,
+		},
+		{
+			name: "Undefined node class",
+			flowchart: &Flowchart{
+				Nodes: []*Node{{name: "A", Classes: []string{"done"}}},
+			},
+			expected: false,
+		},
+		{
+			name: "Undefined link class",
+			flowchart: &Flowchart{
+				Links: []Link{{Classes: []string{"done"}}},
+			},
+			expected: false,
+		},
+		{
+			name: "Undefined subgraph class",
+			flowchart: &Flowchart{
+				Subgraphs: []*Flowchart{{Title: pointTo("G"), Classes: []string{"done"}}},
+			},
+			expected: false,
+		},
+		{
+			name: "Repeated class definition",
+			flowchart: &Flowchart{
+				ClassDefs: []StyleClass{{Name: "done"}},
+				Subgraphs: []*Flowchart{{Title: pointTo("G"), ClassDefs: []StyleClass{{Name: "done"}}}},
+			},
+			expected: false,
+		},
+		{
+			name: "Invalid class name",
+			flowchart: &Flowchart{
+				ClassDefs: []StyleClass{{Name: "not valid"}},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(styleClassViolations(tt.flowchart)) == 0; got != tt.expected {
+				t.Errorf("styleClassViolations() empty = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package flowchart
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Colours and sizes used by RenderSVG for elements without a style, close to the defaults of Mermaid.js.
+const (
+	svgNodeFill       = "#ECECFF"
+	svgNodeStroke     = "#9370DB"
+	svgClusterFill    = "#FFFFDE"
+	svgClusterStroke  = "#AAAA33"
+	svgLineStroke     = "#333333"
+	svgTextColor      = "#333333"
+	svgLabelFill      = "#E8E8E8"
+	svgFontFamily     = "sans-serif"
+	svgFontSize       = 14
+	svgLineWidth      = 1.5 // Width of solid and dotted lines
+	svgThickLineWidth = 3.5 // Width of thick lines
+)
+
+// svgNumber formats a coordinate with at most two decimals.
+func svgNumber(v float64) string {
+	s := strings.TrimRight(strconv.FormatFloat(v, 'f', 2, 64), "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// svgStyle returns the value of an SVG style attribute for a shape or line with the given style,
+// falling back to the given fill and stroke colours.
+func svgStyle(s Style, fill, stroke string) string {
+	if s.Fill != "" {
+		fill = s.Fill
+	}
+	if s.Stroke != "" {
+		stroke = s.Stroke
+	}
+	properties := []string{"fill:" + fill, "stroke:" + stroke}
+	if s.StrokeWidth != "" {
+		properties = append(properties, "stroke-width:"+s.StrokeWidth)
+	}
+	return xmlEscaper.Replace(strings.Join(properties, ";"))
+}
+
+// svgTextStyle returns the value of an SVG style attribute for text with the given style.
+func svgTextStyle(s Style) string {
+	color := svgTextColor
+	if s.Color != "" {
+		color = s.Color
+	}
+	properties := []string{"fill:" + color}
+	for _, p := range []struct{ name, value string }{
+		{"font-family", s.FontFamily},
+		{"font-size", s.FontSize},
+		{"font-weight", s.FontWeight},
+	} {
+		if p.value != "" {
+			properties = append(properties, p.name+":"+p.value)
+		}
+	}
+	return xmlEscaper.Replace(strings.Join(properties, ";"))
+}
+
+// markdownSpan is a run of text with the same formatting in a markdown label.
+type markdownSpan struct {
+	text   string
+	bold   bool
+	italic bool
+}
+
+// markdownSpans splits a line of a markdown label into runs of text, toggling bold at "**" and
+// italic at "_".
+func markdownSpans(line string) []markdownSpan {
+	var spans []markdownSpan
+	var current markdownSpan
+	flush := func() {
+		if current.text != "" {
+			spans = append(spans, current)
+		}
+		current.text = ""
+	}
+	for len(line) > 0 {
+		switch {
+		case strings.HasPrefix(line, "**"):
+			flush()
+			current.bold = !current.bold
+			line = line[2:]
+		case line[0] == '_':
+			flush()
+			current.italic = !current.italic
+			line = line[1:]
+		default:
+			next := len(line)
+			if i := strings.IndexAny(line, "*_"); i > 0 {
+				next = i
+			} else if i == 0 {
+				next = 1 // A single "*" is plain text
+			}
+			current.text += line[:next]
+			line = line[next:]
+		}
+	}
+	flush()
+	return spans
+}
+
+// renderSVGText generates a text element centred on p, with one line per line of the label.
+func renderSVGText(sb *strings.Builder, p Point, label string, format LabelFormatEnum, style Style) {
+	lines := strings.Split(label, "\n")
+	y := p.Y - float64(len(lines)-1)*layoutLineHeight/2
+	sb.WriteString(fmt.Sprintf(`<text text-anchor="middle" dominant-baseline="central" style="%s">`, svgTextStyle(style)))
+	for i, line := range lines {
+		sb.WriteString(fmt.Sprintf(`<tspan x="%s" y="%s">`, svgNumber(p.X), svgNumber(y+float64(i)*layoutLineHeight)))
+		if format != LabelFormatMarkdown {
+			sb.WriteString(xmlEscaper.Replace(line))
+		} else {
+			for _, span := range markdownSpans(line) {
+				var attributes []string
+				if span.bold {
+					attributes = append(attributes, `font-weight="bold"`)
+				}
+				if span.italic {
+					attributes = append(attributes, `font-style="italic"`)
+				}
+				if len(attributes) == 0 {
+					sb.WriteString(xmlEscaper.Replace(span.text))
+					continue
+				}
+				sb.WriteString(fmt.Sprintf("<tspan %s>%s</tspan>", strings.Join(attributes, " "), xmlEscaper.Replace(span.text)))
+			}
+		}
+		sb.WriteString("</tspan>")
+	}
+	sb.WriteString("</text>\n")
+}
+
+// renderSVGNode generates the shape and label of a node. The shape depends on the node type:
+// a stadium for terminators, a box with inner borders for subprocesses, a diamond for decisions,
+// a parallelogram for input/output, a circle for connectors and a cylinder for databases.
+func renderSVGNode(sb *strings.Builder, n NodeLayout, style Style) {
+	r := n.Rect
+	x, y, w, h := svgNumber(r.X), svgNumber(r.Y), svgNumber(r.Width), svgNumber(r.Height)
+	shapeStyle := svgStyle(style, svgNodeFill, svgNodeStroke)
+	c := r.Center()
+
+	sb.WriteString(`  <g class="node">`)
+	switch n.Node.Type {
+	case NodeTypeTerminator:
+		radius := svgNumber(r.Height / 2)
+		sb.WriteString(fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s" rx="%s" ry="%s" style="%s"/>`, x, y, w, h, radius, radius, shapeStyle))
+	case NodeTypeSubprocess:
+		inset := 8.0
+		sb.WriteString(fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s" style="%s"/>`, x, y, w, h, shapeStyle))
+		for _, lineX := range []float64{r.X + inset, r.X + r.Width - inset} {
+			sb.WriteString(fmt.Sprintf(`<line x1="%s" y1="%s" x2="%s" y2="%s" style="%s"/>`,
+				svgNumber(lineX), y, svgNumber(lineX), svgNumber(r.Y+r.Height), shapeStyle))
+		}
+	case NodeTypeDecision:
+		sb.WriteString(fmt.Sprintf(`<polygon points="%s,%s %s,%s %s,%s %s,%s" style="%s"/>`,
+			svgNumber(c.X), y, svgNumber(r.X+r.Width), svgNumber(c.Y), svgNumber(c.X), svgNumber(r.Y+r.Height), x, svgNumber(c.Y), shapeStyle))
+	case NodeTypeInputOutput:
+		skew := r.Height / 2
+		sb.WriteString(fmt.Sprintf(`<polygon points="%s,%s %s,%s %s,%s %s,%s" style="%s"/>`,
+			svgNumber(r.X+skew), y, svgNumber(r.X+r.Width), y, svgNumber(r.X+r.Width-skew), svgNumber(r.Y+r.Height), x, svgNumber(r.Y+r.Height), shapeStyle))
+	case NodeTypeConnector:
+		sb.WriteString(fmt.Sprintf(`<circle cx="%s" cy="%s" r="%s" style="%s"/>`, svgNumber(c.X), svgNumber(c.Y), svgNumber(r.Width/2), shapeStyle))
+	case NodeTypeDatabase:
+		rx, ry := svgNumber(r.Width/2), svgNumber(layoutPaddingY/2)
+		top, bottom := svgNumber(r.Y+layoutPaddingY/2), svgNumber(r.Height-layoutPaddingY)
+		sb.WriteString(fmt.Sprintf(`<path d="M %s %s a %s %s 0 0 0 %s 0 a %s %s 0 0 0 -%s 0 l 0 %s a %s %s 0 0 0 %s 0 l 0 -%s" style="%s"/>`,
+			x, top, rx, ry, w, rx, ry, w, bottom, rx, ry, w, bottom, shapeStyle))
+	default:
+		sb.WriteString(fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s" style="%s"/>`, x, y, w, h, shapeStyle))
+	}
+
+	label := n.Node.name
+	if n.Node.Label != nil {
+		label = *n.Node.Label
+	}
+	if n.Node.Type == NodeTypeDatabase {
+		c.Y += layoutPaddingY / 2 // Centre the label below the lid
+	}
+	renderSVGText(sb, c, label, n.Node.LabelFormat, style)
+	sb.WriteString("  </g>\n")
+}
+
+// svgMarkers collects the markers drawn at the ends of links, one per arrow type and colour.
+type svgMarkers struct {
+	ids  map[string]string
+	defs []string
+}
+
+// id returns the ID of the marker for an arrow type and colour, defining it on first use.
+// Markers are drawn at the end of a line; at its start they are reversed by auto-start-reverse.
+func (m *svgMarkers) id(a ArrowTypeEnum, color string) string {
+	key := fmt.Sprintf("%d %s", a, color)
+	if id, ok := m.ids[key]; ok {
+		return id
+	}
+	id := fmt.Sprintf("marker-%d", len(m.defs))
+	fill := xmlEscaper.Replace(color)
+	var shape string
+	switch a {
+	case ArrowTypeCircle:
+		shape = fmt.Sprintf(`<circle cx="5" cy="5" r="4" style="fill:%s;stroke:%s"/>`, fill, fill)
+	case ArrowTypeCross:
+		shape = fmt.Sprintf(`<path d="M 1 1 L 9 9 M 1 9 L 9 1" style="fill:none;stroke:%s;stroke-width:2"/>`, fill)
+	default:
+		shape = fmt.Sprintf(`<path d="M 0 0 L 10 5 L 0 10 z" style="fill:%s;stroke:%s"/>`, fill, fill)
+	}
+	refX := "10"
+	if a != ArrowTypeNormal {
+		refX = "5"
+	}
+	m.defs = append(m.defs, fmt.Sprintf(`    <marker id="%s" viewBox="0 0 10 10" refX="%s" refY="5" markerWidth="8" markerHeight="8" markerUnits="userSpaceOnUse" orient="auto-start-reverse">%s</marker>`,
+		id, refX, shape))
+	if m.ids == nil {
+		m.ids = make(map[string]string)
+	}
+	m.ids[key] = id
+	return id
+}
+
+// renderSVGEdge generates the line of a link and its markers. Links with LineTypeNone are not drawn.
+func renderSVGEdge(sb *strings.Builder, e EdgeLayout, style Style, markers *svgMarkers) {
+	if e.Link.LineType == LineTypeNone || len(e.Points) < 2 {
+		return
+	}
+	var d []string
+	for i, p := range e.Points {
+		command := "L"
+		if i == 0 {
+			command = "M"
+		}
+		d = append(d, fmt.Sprintf("%s %s %s", command, svgNumber(p.X), svgNumber(p.Y)))
+	}
+
+	stroke := svgLineStroke
+	if style.Stroke != "" {
+		stroke = style.Stroke
+	}
+	properties := []string{"fill:none", "stroke:" + stroke}
+	switch {
+	case style.StrokeWidth != "":
+		properties = append(properties, "stroke-width:"+style.StrokeWidth)
+	case e.Link.LineType == LineTypeThick:
+		properties = append(properties, "stroke-width:"+svgNumber(svgThickLineWidth))
+	default:
+		properties = append(properties, "stroke-width:"+svgNumber(svgLineWidth))
+	}
+	if e.Link.LineType == LineTypeDotted {
+		properties = append(properties, "stroke-dasharray:3 3")
+	}
+
+	attributes := ""
+	if e.Link.ArrowType != ArrowTypeNone {
+		if e.Link.OriginArrow {
+			attributes += fmt.Sprintf(` marker-start="url(#%s)"`, markers.id(e.Link.ArrowType, stroke))
+		}
+		if e.Link.TargetArrow {
+			attributes += fmt.Sprintf(` marker-end="url(#%s)"`, markers.id(e.Link.ArrowType, stroke))
+		}
+	}
+	sb.WriteString(fmt.Sprintf(`  <path class="edge" d="%s" style="%s"%s/>`+"\n", strings.Join(d, " "), xmlEscaper.Replace(strings.Join(properties, ";")), attributes))
+}
+
+// renderSVGEdgeLabel generates the label of a link on a background box at the middle of its line.
+func renderSVGEdgeLabel(sb *strings.Builder, e EdgeLayout, style Style) {
+	if e.Link.LineType == LineTypeNone || e.Link.Label == nil || *e.Link.Label == "" {
+		return
+	}
+	p := e.Midpoint()
+	w, h := textSize(nodeText(&Node{Label: e.Link.Label, LabelFormat: e.Link.LabelFormat}))
+	w, h = w+8, h+4
+	sb.WriteString(fmt.Sprintf(`  <g class="edge-label"><rect x="%s" y="%s" width="%s" height="%s" style="fill:%s;stroke:none"/>`,
+		svgNumber(p.X-w/2), svgNumber(p.Y-h/2), svgNumber(w), svgNumber(h), svgLabelFill))
+	renderSVGText(sb, p, *e.Link.Label, e.Link.LabelFormat, style)
+	sb.WriteString("  </g>\n")
+}
+
+// renderSVGCluster generates the frame and title of a subgraph.
+func renderSVGCluster(sb *strings.Builder, c ClusterLayout, style Style) {
+	r := c.Rect
+	sb.WriteString(fmt.Sprintf(`  <g class="cluster"><rect x="%s" y="%s" width="%s" height="%s" style="%s"/>`,
+		svgNumber(r.X), svgNumber(r.Y), svgNumber(r.Width), svgNumber(r.Height), svgStyle(style, svgClusterFill, svgClusterStroke)))
+	if title := c.Subgraph.nodeName(); title != "" {
+		renderSVGText(sb, Point{r.X + r.Width/2, r.Y + 6 + layoutLineHeight/2}, title, LabelFormatText, style)
+	} else {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("  </g>\n")
+}
+
+// RenderSVG generates a standalone SVG image of the flowchart, laid out with ComputeLayout, so
+// that charts can be drawn without a browser, Mermaid.js or Graphviz.
+// Nodes are drawn with a shape for their type, links with their line type and with markers for
+// their arrow type at the origin and/or target, and subgraphs as titled frames. Styles assigned
+// through classes or inline are applied to nodes, links and subgraphs.
+// It returns the SVG document or an error if validation fails.
+func RenderSVG(f *Flowchart) (string, error) {
+	if err := validateSVG(f); err != nil {
+		return "", err
+	}
+	l, err := ComputeLayout(f, LayoutOptions{})
+	if err != nil {
+		return "", err
+	}
+	classDefs := allClassDefs(f)
+
+	var body strings.Builder
+	for _, c := range l.Clusters {
+		renderSVGCluster(&body, c, resolveStyle(classDefs, c.Subgraph.Classes, c.Subgraph.Style))
+	}
+	markers := &svgMarkers{}
+	for _, e := range l.Edges {
+		renderSVGEdge(&body, e, resolveStyle(classDefs, e.Link.Classes, e.Link.Style), markers)
+	}
+	for _, n := range l.Nodes {
+		renderSVGNode(&body, n, resolveStyle(classDefs, n.Node.Classes, n.Node.Style))
+	}
+	for _, e := range l.Edges {
+		renderSVGEdgeLabel(&body, e, resolveStyle(classDefs, e.Link.Classes, e.Link.Style))
+	}
+
+	var sb strings.Builder
+	width, height := svgNumber(l.Width), svgNumber(l.Height)
+	sb.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s" font-family="%s" font-size="%d">`+"\n",
+		width, height, width, height, svgFontFamily, svgFontSize))
+	if f.Title != nil && *f.Title != "" {
+		sb.WriteString(fmt.Sprintf("  <title>%s</title>\n", xmlEscaper.Replace(*f.Title)))
+	}
+	if len(markers.defs) > 0 {
+		sb.WriteString("  <defs>\n")
+		sb.WriteString(strings.Join(markers.defs, "\n"))
+		sb.WriteString("\n  </defs>\n")
+	}
+	sb.WriteString(fmt.Sprintf(`  <rect width="%s" height="%s" style="fill:#FFFFFF"/>`+"\n", width, height))
+	sb.WriteString(body.String())
+	sb.WriteString("</svg>\n")
+	return sb.String(), nil
+}
+
+// validateSVG validates the Flowchart structure to ensure it can be rendered as SVG.
+// It checks for the following violations:
+// 1. All node and subgraph names must be unique across the whole flowchart.
+// 2. Style classes must have valid, unique names, and every class assigned must be defined.
+//
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func validateSVG(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	violations = append(violations, styleClassViolations(f)...)
+	return newValidationError(violations...)
+}
@@ -0,0 +1,156 @@
+package flowchart
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderSVG(t *testing.T) {
+	a, b := TerminatorNode("A", pointTo("Start")), ProcessNode("B", pointTo("R&D"))
+	chart := &Flowchart{
+		Direction: DirectionVertical,
+		Nodes:     []*Node{a, b},
+		Links:     []Link{{Origin: a, Target: b, LineType: LineTypeSolid, ArrowType: ArrowTypeNormal, TargetArrow: true, Label: pointTo("go")}},
+	}
+	expected := `<svg xmlns="http://www.w3.org/2000/svg" width="112" height="166" viewBox="0 0 112 166" font-family="sans-serif" font-size="14">
+  <defs>
+    <marker id="marker-0" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" markerUnits="userSpaceOnUse" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" style="fill:#333333;stroke:#333333"/></marker>
+  </defs>
+  <rect width="112" height="166" style="fill:#FFFFFF"/>
+  <path class="edge" d="M 56 58 L 56 108" style="fill:none;stroke:#333333;stroke-width:1.5" marker-end="url(#marker-0)"/>
+  <g class="node"><rect x="20" y="20" width="72" height="38" rx="19" ry="19" style="fill:#ECECFF;stroke:#9370DB"/><text text-anchor="middle" dominant-baseline="central" style="fill:#333333"><tspan x="56" y="39">Start</tspan></text>
+  </g>
+  <g class="node"><rect x="28" y="108" width="56" height="38" style="fill:#ECECFF;stroke:#9370DB"/><text text-anchor="middle" dominant-baseline="central" style="fill:#333333"><tspan x="56" y="127">R&amp;D</tspan></text>
+  </g>
+  <g class="edge-label"><rect x="44" y="72" width="24" height="22" style="fill:#E8E8E8;stroke:none"/><text text-anchor="middle" dominant-baseline="central" style="fill:#333333"><tspan x="56" y="83">go</tspan></text>
+  </g>
+</svg>
+`
+
+	got, err := RenderSVG(chart)
+	if err != nil {
+		t.Fatalf("RenderSVG() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("RenderSVG() mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestRenderSVG_Elements(t *testing.T) {
+	// chart returns a chart with a node of the given type linked to a process node by link.
+	chart := func(typ NodeTypeEnum, change func(l *Link)) *Flowchart {
+		a, b := basicNode("A", nil, typ), ProcessNode("B", nil)
+		link := SolidLink(a, b, nil)
+		if change != nil {
+			change(&link)
+		}
+		return &Flowchart{Direction: DirectionVertical, Nodes: []*Node{a, b}, Links: []Link{link}}
+	}
+
+	tests := []struct {
+		name        string
+		flowchart   *Flowchart
+		expected    []string // Substrings expected in the SVG
+		notExpected []string // Substrings that must not be in the SVG
+	}{
+		{name: "terminator", flowchart: chart(NodeTypeTerminator, nil), expected: []string{`rx="19" ry="19"`}},
+		{name: "subprocess", flowchart: chart(NodeTypeSubprocess, nil), expected: []string{`<line x1="28" y1="20" x2="28" y2="58"`, `<line x1="52" y1="20" x2="52" y2="58"`}},
+		{name: "decision", flowchart: chart(NodeTypeDecision, nil), expected: []string{`<polygon points="59,20 98,59 59,98 20,59"`}},
+		{name: "input/output", flowchart: chart(NodeTypeInputOutput, nil), expected: []string{`<polygon points="39,20 79,20 60,58 20,58"`}},
+		{name: "connector", flowchart: chart(NodeTypeConnector, nil), expected: []string{`<circle cx="47.59" cy="47.59" r="27.59"`}},
+		{name: "database", flowchart: chart(NodeTypeDatabase, nil), expected: []string{`<path d="M 20 25 a 20 5 0 0 0 40 0 a 20 5 0 0 0 -40 0 l 0 38 a 20 5 0 0 0 40 0 l 0 -38"`}},
+		{
+			name: "dotted line with markers at both ends",
+			flowchart: chart(NodeTypeProcess, func(l *Link) {
+				*l = DottedLink(l.Origin, l.Target, nil)
+				l.ArrowType = ArrowTypeCross
+				l.OriginArrow, l.TargetArrow = true, true
+			}),
+			expected: []string{"stroke-dasharray:3 3", `marker-start="url(#marker-0)" marker-end="url(#marker-0)"`, `<path d="M 1 1 L 9 9 M 1 9 L 9 1"`},
+		},
+		{
+			name:      "thick line with circle marker",
+			flowchart: chart(NodeTypeProcess, func(l *Link) { l.LineType = LineTypeThick; l.ArrowType = ArrowTypeCircle; l.TargetArrow = true }),
+			expected:  []string{"stroke-width:3.5", `<circle cx="5" cy="5" r="4"`},
+		},
+		{
+			name:        "invisible line",
+			flowchart:   chart(NodeTypeProcess, func(l *Link) { l.LineType = LineTypeNone; l.Label = pointTo("hidden") }),
+			notExpected: []string{`class="edge"`, "hidden"},
+		},
+		{
+			name:      "markdown label",
+			flowchart: &Flowchart{Nodes: []*Node{{name: "A", Label: pointTo("**bold** and _it_\nnext"), LabelFormat: LabelFormatMarkdown}}},
+			expected:  []string{`<tspan font-weight="bold">bold</tspan> and <tspan font-style="italic">it</tspan></tspan><tspan x="80" y="57">next</tspan>`},
+		},
+		{
+			name: "styles",
+			flowchart: func() *Flowchart {
+				f := chart(NodeTypeProcess, func(l *Link) {
+					l.Style = &Style{Stroke: "red", StrokeWidth: "4px"}
+					l.ArrowType = ArrowTypeNormal
+					l.TargetArrow = true
+				})
+				f.ClassDefs = []StyleClass{{Name: "hot", Style: Style{Fill: "#f00", Color: "white", FontWeight: "bold"}}}
+				f.Nodes[0].Classes = []string{"hot"}
+				return f
+			}(),
+			expected: []string{`style="fill:#f00;stroke:#9370DB"`, `style="fill:white;font-weight:bold"`, `style="fill:none;stroke:red;stroke-width:4px"`, `style="fill:red;stroke:red"`},
+		},
+		{
+			name: "subgraph frame with title",
+			flowchart: &Flowchart{
+				Title:     pointTo("Chart <1>"),
+				Subgraphs: []*Flowchart{{Title: pointTo("Group"), Nodes: []*Node{{name: "A"}}, Style: &Style{Fill: "#eee"}}},
+			},
+			expected: []string{"<title>Chart &lt;1&gt;</title>", `<g class="cluster"><rect x="20" y="20" width="70" height="90" style="fill:#eee;stroke:#AAAA33"/>`, ">Group</tspan>"},
+		},
+		{
+			name: "linked subgraph without nodes",
+			flowchart: func() *Flowchart {
+				a, empty := &Node{name: "A"}, &Flowchart{Title: pointTo("Empty")}
+				return &Flowchart{Nodes: []*Node{a}, Subgraphs: []*Flowchart{empty}, Links: []Link{SolidLink(a, empty, nil)}}
+			}(),
+			expected: []string{`<g class="cluster"><rect x="110" y="20" width="70" height="70"`, ">Empty</tspan>", `<path class="edge" d="M 60 66 L 110 66"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderSVG(tt.flowchart)
+			if err != nil {
+				t.Fatalf("RenderSVG() unexpected error: %v", err)
+			}
+			for _, s := range tt.expected {
+				if !strings.Contains(got, s) {
+					t.Errorf("RenderSVG() does not contain %q:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.notExpected {
+				if strings.Contains(got, s) {
+					t.Errorf("RenderSVG() contains %q:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderSVG_Errors(t *testing.T) {
+	a := &Node{name: "A", Classes: []string{"missing"}}
+	_, err := RenderSVG(&Flowchart{Nodes: []*Node{a}, Links: []Link{SolidLink(a, &Node{name: "Phantom"}, nil)}})
+	for _, code := range []error{ErrUndefinedStyleClass, ErrOrphanedEndpoint} {
+		if !errors.Is(err, code) {
+			t.Errorf("RenderSVG() error = %v, expected %v", err, code)
+		}
+	}
+}
+
+func TestMarkdownSpans(t *testing.T) {
+	expected := []markdownSpan{{text: "a "}, {text: "b", bold: true}, {text: " * ", bold: true, italic: true}, {text: "c", italic: true}}
+	if diff := cmp.Diff(expected, markdownSpans("a **b_ * **c_"), cmp.AllowUnexported(markdownSpan{})); diff != "" {
+		t.Errorf("markdownSpans() mismatch (-expected +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,529 @@
+package flowchart
+
+import (
+	"image"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextOptions controls the drawing generated by RenderText.
+type TextOptions struct {
+	ASCII bool // Draw with ASCII characters only, for terminals and logs without Unicode support
+}
+
+// Size of a character cell of a terminal, in units of the layout. Nodes are sized in whole cells
+// and the layout is snapped to the cells.
+const (
+	textCellWidth  = layoutCharWidth
+	textCellHeight = layoutLineHeight
+)
+
+// Directions in which a line leaves a cell, combined into the mask of the cell.
+const (
+	textUp uint8 = 1 << iota
+	textDown
+	textLeft
+	textRight
+)
+
+// textCharset holds the characters used by RenderText.
+type textCharset struct {
+	lines   map[LineTypeEnum]string   // Line characters, indexed by the mask of directions of a cell
+	borders map[NodeTypeEnum]string   // Top-left, top, top-right, left, right, bottom-left, bottom and bottom-right of each node type
+	lid     string                    // Left, middle and right of the line under the lid of a database
+	cluster string                    // Borders of subgraph frames, in the order of borders
+	arrows  map[ArrowTypeEnum][4]rune // Arrow heads pointing up, down, left and right
+}
+
+// Characters of the Unicode and ASCII charsets. Process nodes have plain boxes, terminators
+// rounded ones, subprocesses double lines, decisions pointed sides, input/output slanted sides,
+// connectors round sides and databases a lid.
+var (
+	unicodeCharset = &textCharset{
+		lines: map[LineTypeEnum]string{
+			LineTypeSolid:  " │││─┘┐┤─└┌├─┴┬┼",
+			LineTypeDotted: " ┆┆┆┄┘┐┤┄└┌├┄┴┬┼",
+			LineTypeThick:  " ┃┃┃━┛┓┫━┗┏┣━┻┳╋",
+		},
+		borders: map[NodeTypeEnum]string{
+			NodeTypeTerminator:  "╭─╮()╰─╯",
+			NodeTypeProcess:     "┌─┐││└─┘",
+			NodeTypeSubprocess:  "╔═╗║║╚═╝",
+			NodeTypeDecision:    "╱─╲<>╲─╱",
+			NodeTypeInputOutput: "╱─╱╱╱╱─╱",
+			NodeTypeConnector:   "╭─╮││╰─╯",
+			NodeTypeDatabase:    "╭─╮││╰─╯",
+		},
+		lid:     "├─┤",
+		cluster: "┌┄┐┆┆└┄┘",
+		arrows: map[ArrowTypeEnum][4]rune{
+			ArrowTypeNormal: {'▲', '▼', '◀', '▶'},
+			ArrowTypeCircle: {'●', '●', '●', '●'},
+			ArrowTypeCross:  {'✕', '✕', '✕', '✕'},
+		},
+	}
+	asciiCharset = &textCharset{
+		lines: map[LineTypeEnum]string{
+			LineTypeSolid:  " |||-+++-+++-+++",
+			LineTypeDotted: " :::.+++.+++.+++",
+			LineTypeThick:  " ###=###=###=###",
+		},
+		borders: map[NodeTypeEnum]string{
+			NodeTypeTerminator:  ".-.()'-'",
+			NodeTypeProcess:     "+-+||+-+",
+			NodeTypeSubprocess:  "#=#[]#=#",
+			NodeTypeDecision:    "/-\\<>\\-/",
+			NodeTypeInputOutput: "/-////-/",
+			NodeTypeConnector:   ".-.||'-'",
+			NodeTypeDatabase:    ".-.||'-'",
+		},
+		lid:     "|-|",
+		cluster: "+.+::+.+",
+		arrows: map[ArrowTypeEnum][4]rune{
+			ArrowTypeNormal: {'^', 'v', '<', '>'},
+			ArrowTypeCircle: {'o', 'o', 'o', 'o'},
+			ArrowTypeCross:  {'x', 'x', 'x', 'x'},
+		},
+	}
+)
+
+// textNodeSize returns the size of a node in cells: its text with a space and a border on each
+// side, and room for the pointed sides of decisions and the lid of databases.
+func textNodeSize(n *Node) (int, int) {
+	lines := strings.Split(nodeText(n), "\n")
+	widest := 0
+	for _, line := range lines {
+		widest = max(widest, utf8.RuneCountInString(line))
+	}
+	w, h := widest+4, len(lines)+2
+	switch n.Type {
+	case NodeTypeDecision, NodeTypeInputOutput:
+		w += 2
+	case NodeTypeDatabase:
+		h++
+	}
+	return w, h
+}
+
+// textCell is a character cell of a textCanvas.
+type textCell struct {
+	r     rune
+	mask  uint8        // Directions of the lines through the cell
+	line  LineTypeEnum // Heaviest line through the cell
+	owned bool         // Whether the cell is part of a node
+}
+
+// textCanvas is a grid of character cells.
+type textCanvas struct {
+	cells   [][]textCell
+	charset *textCharset
+}
+
+// cell returns the cell at p, or nil if p is outside the canvas.
+func (c *textCanvas) cell(p image.Point) *textCell {
+	if p.Y < 0 || p.Y >= len(c.cells) || p.X < 0 || p.X >= len(c.cells[p.Y]) {
+		return nil
+	}
+	return &c.cells[p.Y][p.X]
+}
+
+// set writes a character at p.
+func (c *textCanvas) set(p image.Point, r rune) {
+	if cell := c.cell(p); cell != nil {
+		cell.r = r
+	}
+}
+
+// write writes a line of text starting at p.
+func (c *textCanvas) write(p image.Point, text string) {
+	for _, r := range text {
+		c.set(p, r)
+		p.X++
+	}
+}
+
+// box draws the border of a rectangle of cells from its border characters.
+func (c *textCanvas) box(r image.Rectangle, border []rune) {
+	right, bottom := r.Max.X-1, r.Max.Y-1
+	for x := r.Min.X + 1; x < right; x++ {
+		c.set(image.Pt(x, r.Min.Y), border[1])
+		c.set(image.Pt(x, bottom), border[6])
+	}
+	for y := r.Min.Y + 1; y < bottom; y++ {
+		c.set(image.Pt(r.Min.X, y), border[3])
+		c.set(image.Pt(right, y), border[4])
+	}
+	c.set(r.Min, border[0])
+	c.set(image.Pt(right, r.Min.Y), border[2])
+	c.set(image.Pt(r.Min.X, bottom), border[5])
+	c.set(image.Pt(right, bottom), border[7])
+}
+
+// toCell returns the cell containing a point of the layout.
+func toCell(p Point) image.Point {
+	return image.Pt(int(math.Round(p.X/textCellWidth)), int(math.Round(p.Y/textCellHeight)))
+}
+
+// textRect returns the cells of a rectangle of the layout.
+func textRect(r Rect) image.Rectangle {
+	return image.Rectangle{Min: toCell(Point{r.X, r.Y}), Max: toCell(Point{r.X + r.Width, r.Y + r.Height})}
+}
+
+// textPath returns the cells along a polyline of cells, turning each slanted segment into
+// straight ones that bend soon after leaving the layer of its start, one row below it in
+// vertical charts and two columns beside it in horizontal ones, so that the links leaving a node
+// branch off next to it.
+func textPath(points []image.Point, vertical bool) []image.Point {
+	path := []image.Point{points[0]}
+	walk := func(to image.Point) {
+		for p := path[len(path)-1]; p != to; {
+			switch {
+			case p.X < to.X:
+				p.X++
+			case p.X > to.X:
+				p.X--
+			case p.Y < to.Y:
+				p.Y++
+			default:
+				p.Y--
+			}
+			path = append(path, p)
+		}
+	}
+	for _, to := range points[1:] {
+		from := path[len(path)-1]
+		if from.X != to.X && from.Y != to.Y {
+			if vertical {
+				bend := from.Y + sign(to.Y-from.Y)
+				walk(image.Pt(from.X, bend))
+				walk(image.Pt(to.X, bend))
+			} else {
+				bend := from.X + sign(to.X-from.X)*min(2, abs(to.X-from.X)-1)
+				walk(image.Pt(bend, from.Y))
+				walk(image.Pt(bend, to.Y))
+			}
+		}
+		walk(to)
+	}
+	return path
+}
+
+// labelCell returns the cell where the label of a link is centred: the middle of the longest
+// straight run of its path, so that the label does not hide where the path turns.
+func labelCell(path []image.Point) image.Point {
+	best, bestLength := path[len(path)/2], 0
+	start := 1
+	for i := 1; i < len(path)-1; i++ {
+		if direction(path[i-1], path[i]) != direction(path[i], path[i+1]) {
+			start = i + 1
+			continue
+		}
+		if length := i - start + 1; length > bestLength {
+			best, bestLength = path[start+(i-start)/2], length
+		}
+	}
+	return best
+}
+
+// sign returns -1, 0 or 1 for negative, zero and positive numbers.
+func sign(v int) int {
+	return min(max(v, -1), 1)
+}
+
+// abs returns the absolute value of a number.
+func abs(v int) int {
+	return max(v, -v)
+}
+
+// snapToNode returns the cell of a point on the border of a node's shape, on the border of the
+// node's box of cells.
+func snapToNode(p Point, shape Rect, box image.Rectangle) image.Point {
+	cell := toCell(p)
+	switch {
+	case p.X <= shape.X:
+		cell.X = box.Min.X
+	case p.X >= shape.X+shape.Width:
+		cell.X = box.Max.X - 1
+	default:
+		cell.X = min(max(cell.X, box.Min.X), box.Max.X-1)
+	}
+	switch {
+	case p.Y <= shape.Y:
+		cell.Y = box.Min.Y
+	case p.Y >= shape.Y+shape.Height:
+		cell.Y = box.Max.Y - 1
+	default:
+		cell.Y = min(max(cell.Y, box.Min.Y), box.Max.Y-1)
+	}
+	return cell
+}
+
+// direction returns the mask of the direction from one cell to a neighbouring one.
+func direction(from, to image.Point) uint8 {
+	switch {
+	case to.Y < from.Y:
+		return textUp
+	case to.Y > from.Y:
+		return textDown
+	case to.X < from.X:
+		return textLeft
+	}
+	return textRight
+}
+
+// opposite returns the mask of the opposite direction.
+func opposite(d uint8) uint8 {
+	switch d {
+	case textUp:
+		return textDown
+	case textDown:
+		return textUp
+	case textLeft:
+		return textRight
+	}
+	return textLeft
+}
+
+// line adds a path to the masks of its cells. Where lines of different types meet, thick lines
+// win over solid ones and solid ones over dotted ones.
+func (c *textCanvas) line(path []image.Point, lineType LineTypeEnum) {
+	weight := map[LineTypeEnum]int{LineTypeDotted: 1, LineTypeSolid: 2, LineTypeThick: 3}
+	for i := 1; i < len(path); i++ {
+		d := direction(path[i-1], path[i])
+		for _, end := range []struct {
+			p    image.Point
+			mask uint8
+		}{{path[i-1], d}, {path[i], opposite(d)}} {
+			if cell := c.cell(end.p); cell != nil {
+				cell.mask |= end.mask
+				if weight[lineType] > weight[cell.line] {
+					cell.line = lineType
+				}
+			}
+		}
+	}
+}
+
+// arrowHead draws the arrow head of a path at its end, on the last cell outside the node it
+// reaches, pointing into the node.
+func (c *textCanvas) arrowHead(path []image.Point, arrowType ArrowTypeEnum) {
+	for i := len(path) - 1; i > 0; i-- {
+		if cell := c.cell(path[i]); cell != nil && !cell.owned {
+			heads := c.charset.arrows[arrowType]
+			d := direction(path[i-1], path[i])
+			if i+1 < len(path) {
+				d = direction(path[i], path[i+1])
+			}
+			switch d {
+			case textUp:
+				cell.r = heads[0]
+			case textDown:
+				cell.r = heads[1]
+			case textLeft:
+				cell.r = heads[2]
+			default:
+				cell.r = heads[3]
+			}
+			return
+		}
+	}
+}
+
+// drawNode draws the border and text of a node, clearing the cells it covers.
+func (c *textCanvas) drawNode(n *Node, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if cell := c.cell(image.Pt(x, y)); cell != nil {
+				*cell = textCell{r: ' ', owned: true}
+			}
+		}
+	}
+	border, ok := c.charset.borders[n.Type]
+	if !ok {
+		border = c.charset.borders[NodeTypeProcess]
+	}
+	c.box(r, []rune(border))
+	top := r.Min.Y + 1
+	if n.Type == NodeTypeDatabase {
+		lid := []rune(c.charset.lid)
+		c.set(image.Pt(r.Min.X, top), lid[0])
+		for x := r.Min.X + 1; x < r.Max.X-1; x++ {
+			c.set(image.Pt(x, top), lid[1])
+		}
+		c.set(image.Pt(r.Max.X-1, top), lid[2])
+		top++
+	}
+	for i, line := range strings.Split(nodeText(n), "\n") {
+		c.write(image.Pt(r.Min.X+(r.Dx()-utf8.RuneCountInString(line))/2, top+i), line)
+	}
+}
+
+// String returns the drawing with trailing spaces, and blank rows and columns around it, removed.
+func (c *textCanvas) String() string {
+	lines := make([]string, len(c.cells))
+	indent := -1
+	for y, row := range c.cells {
+		runes := make([]rune, len(row))
+		for x, cell := range row {
+			runes[x] = cell.r
+		}
+		lines[y] = strings.TrimRight(string(runes), " ")
+		if lines[y] != "" {
+			spaces := len(lines[y]) - len(strings.TrimLeft(lines[y], " "))
+			if indent < 0 || spaces < indent {
+				indent = spaces
+			}
+		}
+	}
+	indent = max(indent, 0)
+	var sb strings.Builder
+	for _, line := range lines {
+		if line != "" || sb.Len() > 0 {
+			sb.WriteString(strings.TrimRight(line[min(indent, len(line)):], " "))
+			sb.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+// RenderText draws the flowchart with box-drawing characters, for terminals, SSH sessions and CI
+// logs where no image viewer is available. The chart is laid out with ComputeLayout in the
+// direction of the flowchart, with the layout snapped to character cells: nodes are boxes whose
+// borders depend on their type, subgraphs dotted frames with their title, and links lines of
+// their line type with arrow heads at the origin and/or target. Labels are drawn on the middle
+// of their links. Styles are ignored, and links with LineTypeNone are not drawn.
+// It returns the drawing or an error if validation fails.
+func RenderText(f *Flowchart, opts TextOptions) (string, error) {
+	if err := validateText(f); err != nil {
+		return "", err
+	}
+	l, err := ComputeLayout(f, LayoutOptions{
+		ClusterPadding: textCellHeight,
+		Margin:         3 * textCellHeight, // Room for boxes snapped outwards, trimmed from the drawing
+		NodeSize: func(n *Node) (float64, float64) {
+			w, h := textNodeSize(n)
+			return float64(w) * textCellWidth, float64(h) * textCellHeight
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	c := &textCanvas{charset: unicodeCharset}
+	if opts.ASCII {
+		c.charset = asciiCharset
+	}
+	size := toCell(Point{l.Width, l.Height})
+	c.cells = make([][]textCell, size.Y+1)
+	for y := range c.cells {
+		c.cells[y] = make([]textCell, size.X+1)
+		for x := range c.cells[y] {
+			c.cells[y][x].r = ' '
+		}
+	}
+
+	// Nodes are boxed around the cell of their center, so that nodes and dummies on the same
+	// line of the layout are on the same line of cells.
+	boxes := make(map[string]image.Rectangle)
+	shapes := make(map[string]Rect)
+	for _, n := range l.Nodes {
+		w, h := textNodeSize(n.Node)
+		center := toCell(n.Rect.Center())
+		boxes[n.Node.name] = image.Rect(center.X-w/2, center.Y-h/2, center.X-w/2+w, center.Y-h/2+h)
+		shapes[n.Node.name] = n.Rect
+	}
+
+	frames := make([]image.Rectangle, len(l.Clusters))
+	for i, cl := range l.Clusters {
+		r := textRect(cl.Rect)
+		r.Max = r.Max.Add(image.Pt(1, 1))
+		for _, n := range l.Nodes {
+			if contains(cl.Rect, n.Rect.Center()) {
+				r = r.Union(boxes[n.Node.name].Inset(-1))
+			}
+		}
+		if title := cl.Subgraph.nodeName(); title != "" {
+			r.Max.X = max(r.Max.X, r.Min.X+utf8.RuneCountInString(title)+6) // Corners, dashes and spaces around the title
+		}
+		frames[i] = r
+		c.box(r, []rune(c.charset.cluster))
+	}
+
+	var edges []EdgeLayout
+	paths := make(map[*Link][]image.Point)
+	for _, e := range l.Edges {
+		if e.Link.LineType == LineTypeNone || len(e.Points) < 2 {
+			continue
+		}
+		cells := make([]image.Point, len(e.Points))
+		for i, p := range e.Points {
+			cells[i] = toCell(p)
+		}
+		if name := e.Link.Origin.nodeName(); !boxes[name].Empty() {
+			cells[0] = snapToNode(e.Points[0], shapes[name], boxes[name])
+		}
+		if name := e.Link.Target.nodeName(); !boxes[name].Empty() {
+			cells[len(cells)-1] = snapToNode(e.Points[len(cells)-1], shapes[name], boxes[name])
+		}
+		edges = append(edges, e)
+		paths[e.Link] = textPath(cells, f.Direction == DirectionVertical)
+		c.line(paths[e.Link], e.Link.LineType)
+	}
+	for _, row := range c.cells {
+		for x, cell := range row {
+			if cell.mask != 0 {
+				row[x].r = []rune(c.charset.lines[cell.line])[cell.mask]
+			}
+		}
+	}
+	for _, e := range edges {
+		if e.Link.Label == nil || *e.Link.Label == "" {
+			continue
+		}
+		middle := labelCell(paths[e.Link])
+		lines := strings.Split(nodeText(&Node{Label: e.Link.Label, LabelFormat: e.Link.LabelFormat}), "\n")
+		for i, line := range lines {
+			c.write(image.Pt(middle.X-utf8.RuneCountInString(line)/2, middle.Y-(len(lines)-1)/2+i), line)
+		}
+	}
+	for i, cl := range l.Clusters {
+		if title := cl.Subgraph.nodeName(); title != "" {
+			c.write(image.Pt(frames[i].Min.X+2, frames[i].Min.Y), " "+title+" ")
+		}
+	}
+
+	for _, n := range l.Nodes {
+		c.drawNode(n.Node, boxes[n.Node.name])
+	}
+
+	for _, e := range edges {
+		if e.Link.ArrowType == ArrowTypeNone {
+			continue
+		}
+		path := paths[e.Link]
+		if e.Link.TargetArrow {
+			c.arrowHead(path, e.Link.ArrowType)
+		}
+		if e.Link.OriginArrow {
+			reversed := make([]image.Point, len(path))
+			for i, p := range path {
+				reversed[len(path)-1-i] = p
+			}
+			c.arrowHead(reversed, e.Link.ArrowType)
+		}
+	}
+	return c.String(), nil
+}
+
+// validateText validates the Flowchart structure to ensure it can be drawn as text.
+// It checks for the following violations:
+// 1. All node and subgraph names must be unique across the whole flowchart.
+//
+// It returns a *ValidationError holding every violation found, or nil if there are none.
+func validateText(f *Flowchart) error {
+	var violations []Violation
+	violations = append(violations, duplicateNameViolations(f)...)
+	violations = append(violations, integrityViolations(f, renderIntegrity)...)
+	return newValidationError(violations...)
+}
@@ -0,0 +1,234 @@
+package flowchart
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderText(t *testing.T) {
+	start, check := TerminatorNode("A", pointTo("Start")), DecisionNode("B", pointTo("OK?"))
+	yes, no := ProcessNode("C", pointTo("Ship")), DatabaseNode("D", pointTo("Log"))
+	chart := &Flowchart{
+		Direction: DirectionVertical,
+		Nodes:     []*Node{start, check, yes, no},
+		Links: []Link{
+			SolidLink(start, check, nil),
+			SolidLink(check, yes, pointTo("yes")),
+			DottedLink(check, no, pointTo("no")),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		opts     TextOptions
+		expected string
+	}{
+		{
+			name: "unicode",
+			expected: `     ╭───────╮
+     ( Start )
+     ╰───────╯
+         │
+         ▼
+     ╱───────╲
+     <  OK?  >
+     ╲───────╱
+    ┌─yes┴┄no┄┄┐
+    │          ┆
+    │          ▼
+    ▼       ╭─────╮
+┌──────┐    ├─────┤
+│ Ship │    │ Log │
+└──────┘    ╰─────╯
+`,
+		},
+		{
+			name: "ascii",
+			opts: TextOptions{ASCII: true},
+			expected: `     .-------.
+     ( Start )
+     '-------'
+         |
+         v
+     /-------\
+     <  OK?  >
+     \-------/
+    +-yes+.no..+
+    |          :
+    |          v
+    v       .-----.
++------+    |-----|
+| Ship |    | Log |
++------+    '-----'
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderText(chart, tt.opts)
+			if err != nil {
+				t.Fatalf("RenderText() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("RenderText() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderText_Elements(t *testing.T) {
+	// chart returns a chart with a node of the given type linked to a process node by link.
+	chart := func(direction DirectionEnum, typ NodeTypeEnum, change func(l *Link)) *Flowchart {
+		a, b := basicNode("A", nil, typ), ProcessNode("B", nil)
+		link := SolidLink(a, b, nil)
+		if change != nil {
+			change(&link)
+		}
+		return &Flowchart{Direction: direction, Nodes: []*Node{a, b}, Links: []Link{link}}
+	}
+
+	tests := []struct {
+		name        string
+		flowchart   *Flowchart
+		expected    []string // Substrings expected in the drawing
+		notExpected []string // Substrings that must not be in the drawing
+	}{
+		{name: "process", flowchart: chart(DirectionVertical, NodeTypeProcess, nil), expected: []string{"┌───┐\n│ A │\n└───┘"}},
+		{name: "terminator", flowchart: chart(DirectionVertical, NodeTypeTerminator, nil), expected: []string{"╭───╮\n( A )\n╰───╯"}},
+		{name: "subprocess", flowchart: chart(DirectionVertical, NodeTypeSubprocess, nil), expected: []string{"╔═══╗\n║ A ║\n╚═══╝"}},
+		{name: "decision", flowchart: chart(DirectionVertical, NodeTypeDecision, nil), expected: []string{"╱─────╲\n<  A  >\n╲─────╱"}},
+		{name: "input/output", flowchart: chart(DirectionVertical, NodeTypeInputOutput, nil), expected: []string{"╱─────╱\n╱  A  ╱\n╱─────╱"}},
+		{name: "connector", flowchart: chart(DirectionVertical, NodeTypeConnector, nil), expected: []string{"╭───╮\n│ A │\n╰───╯"}},
+		{name: "database", flowchart: chart(DirectionVertical, NodeTypeDatabase, nil), expected: []string{"╭───╮\n├───┤\n│ A │\n╰───╯"}},
+		{name: "solid link", flowchart: chart(DirectionVertical, NodeTypeProcess, nil), expected: []string{"│\n  ▼"}},
+		{
+			name:      "dotted link",
+			flowchart: chart(DirectionVertical, NodeTypeProcess, func(l *Link) { l.LineType = LineTypeDotted }),
+			expected:  []string{"┆\n  ▼"},
+		},
+		{
+			name:      "thick link",
+			flowchart: chart(DirectionVertical, NodeTypeProcess, func(l *Link) { l.LineType = LineTypeThick }),
+			expected:  []string{"┃\n  ▼"},
+		},
+		{
+			name:        "invisible link",
+			flowchart:   chart(DirectionVertical, NodeTypeProcess, func(l *Link) { l.LineType = LineTypeNone; l.Label = pointTo("hidden") }),
+			notExpected: []string{"▼", "hidden"},
+		},
+		{
+			name:        "no arrow",
+			flowchart:   chart(DirectionVertical, NodeTypeProcess, func(l *Link) { l.ArrowType = ArrowTypeNone }),
+			expected:    []string{"│\n  │"},
+			notExpected: []string{"▼"},
+		},
+		{
+			name:      "arrows at both ends",
+			flowchart: chart(DirectionVertical, NodeTypeProcess, func(l *Link) { l.OriginArrow = true }),
+			expected:  []string{"▲\n  ▼"},
+		},
+		{
+			name:      "circle and cross arrows",
+			flowchart: chart(DirectionVertical, NodeTypeProcess, func(l *Link) { l.OriginArrow = true; l.ArrowType = ArrowTypeCircle }),
+			expected:  []string{"●\n  ●"},
+		},
+		{
+			name:      "horizontal right",
+			flowchart: chart(DirectionHorizontalRight, NodeTypeProcess, nil),
+			expected:  []string{"│ A │──────▶│ B │"},
+		},
+		{
+			name:      "horizontal left",
+			flowchart: chart(DirectionHorizontalLeft, NodeTypeProcess, nil),
+			expected:  []string{"│ B │◀──────│ A │"},
+		},
+		{
+			name: "subgraph frame",
+			flowchart: func() *Flowchart {
+				f := chart(DirectionVertical, NodeTypeProcess, nil)
+				f.Subgraphs = []*Flowchart{{Title: pointTo("Group"), Nodes: []*Node{ProcessNode("C", nil)}}}
+				return f
+			}(),
+			expected: []string{"┌┄ Group ┄┐", "┆ │ C │   ┆", "└┄┄┄┄┄┄┄┄┄┘"},
+		},
+		{
+			name:      "multi-line labels",
+			flowchart: &Flowchart{Nodes: []*Node{ProcessNode("A", pointTo("one\nthree"))}},
+			expected:  []string{"│  one  │\n│ three │"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderText(tt.flowchart, TextOptions{})
+			if err != nil {
+				t.Fatalf("RenderText() unexpected error: %v", err)
+			}
+			for _, s := range tt.expected {
+				if !strings.Contains(got, s) {
+					t.Errorf("RenderText() does not contain %q:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.notExpected {
+				if strings.Contains(got, s) {
+					t.Errorf("RenderText() unexpectedly contains %q:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderText_Errors(t *testing.T) {
+	a := &Node{name: "A"}
+	_, err := RenderText(&Flowchart{Nodes: []*Node{a, {name: "A"}}, Links: []Link{SolidLink(a, &Node{name: "Phantom"}, nil)}}, TextOptions{})
+	for _, code := range []error{ErrDuplicateName, ErrOrphanedEndpoint} {
+		if !errors.Is(err, code) {
+			t.Errorf("RenderText() error = %v, expected %v", err, code)
+		}
+	}
+}
+
+func TestTextPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		points   []image.Point
+		vertical bool
+		expected []image.Point
+	}{
+		{
+			name:     "straight",
+			points:   []image.Point{{0, 0}, {0, 2}},
+			vertical: true,
+			expected: []image.Point{{0, 0}, {0, 1}, {0, 2}},
+		},
+		{
+			name:     "vertical bend",
+			points:   []image.Point{{0, 0}, {2, 3}},
+			vertical: true,
+			expected: []image.Point{{0, 0}, {0, 1}, {1, 1}, {2, 1}, {2, 2}, {2, 3}},
+		},
+		{
+			name:     "horizontal bend",
+			points:   []image.Point{{0, 0}, {4, -1}},
+			expected: []image.Point{{0, 0}, {1, 0}, {2, 0}, {2, -1}, {3, -1}, {4, -1}},
+		},
+		{
+			name:     "horizontal bend next to the target",
+			points:   []image.Point{{0, 0}, {2, 1}},
+			expected: []image.Point{{0, 0}, {1, 0}, {1, 1}, {2, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, textPath(tt.points, tt.vertical)); diff != "" {
+				t.Errorf("textPath() mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -2,6 +2,11 @@ package flowchart
 
 import "strings"
 
+// xmlEscaper escapes text for use in the character data and attribute values of the XML formats:
+// SVG, GraphML, GEXF and BPMN. Newlines are kept as character references so that they survive
+// attribute value normalisation.
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&#39;", "\n", "&#10;")
+
 func pointTo[T any](value T) *T {
 	return &value
 }
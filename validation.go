@@ -0,0 +1,161 @@
+package flowchart
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel codes identifying the kind of a Violation. They can be matched against a
+// ValidationError with errors.Is.
+var (
+	ErrInvalidName         = errors.New("invalid name")           // A name cannot be rendered
+	ErrMissingTitle        = errors.New("missing title")          // A subgraph has no title
+	ErrDuplicateName       = errors.New("duplicate name")         // A name is used by more than one node or subgraph
+	ErrCollidingID         = errors.New("colliding identifier")   // Two names map to the same renderer identifier
+	ErrMissingEndpoint     = errors.New("missing link endpoint")  // A link has no origin or no target
+	ErrInvalidStyleClass   = errors.New("invalid style class")    // A style class has an invalid or repeated name
+	ErrUndefinedStyleClass = errors.New("undefined style class")  // An element uses a style class that is not defined
+	ErrOrphanedEndpoint    = errors.New("orphaned link endpoint") // A link refers to a node or subgraph that is not in the flowchart
+	ErrUntitledEndpoint    = errors.New("untitled link endpoint") // A link refers to a subgraph without a title
+	ErrDuplicateLink       = errors.New("duplicate link")         // Two links join the same endpoints with the same label
+	ErrSelfLoop            = errors.New("self-loop")              // A link starts and ends at the same node or subgraph
+)
+
+// Violation describes a single problem with an element of a flowchart.
+type Violation struct {
+	Code    error    // One of the Err sentinel codes, e.g. ErrDuplicateName
+	Path    []string // Titles of the subgraphs enclosing the element, outermost first ("" if untitled)
+	Element any      // The offending *Node, *Link or *Flowchart (for subgraphs), if there is one
+	Message string   // Description of the violation, naming the element
+}
+
+// Error returns the message of the violation, preceded by its path if the element is in a subgraph.
+func (v Violation) Error() string {
+	if len(v.Path) == 0 {
+		return v.Message
+	}
+	titles := make([]string, len(v.Path))
+	for i, title := range v.Path {
+		titles[i] = title
+		if title == "" {
+			titles[i] = "(untitled)"
+		}
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(titles, " > "), v.Message)
+}
+
+// Unwrap returns the code of the violation, so that errors.Is can match it.
+func (v Violation) Unwrap() error {
+	return v.Code
+}
+
+// ValidationError is returned when a flowchart, or an element added to it, breaks the rules of the
+// package or of a renderer. It holds every violation found.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error returns the violations, separated by semicolons.
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the violations, so that errors.Is and errors.As can match their codes.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Violations))
+	for i, v := range e.Violations {
+		errs[i] = v
+	}
+	return errs
+}
+
+// newValidationError returns a ValidationError holding the violations, or nil if there are none.
+func newValidationError(violations ...Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+// walkFlowchart calls visit for f and every subgraph in its tree, in depth-first order, together
+// with the path of subgraph titles leading to it. The path of f itself is empty.
+func walkFlowchart(f *Flowchart, visit func(f *Flowchart, path []string)) {
+	var walk func(f *Flowchart, path []string)
+	walk = func(f *Flowchart, path []string) {
+		visit(f, path)
+		for _, subgraph := range f.Subgraphs {
+			walk(subgraph, append(path[:len(path):len(path)], subgraph.nodeName()))
+		}
+	}
+	walk(f, nil)
+}
+
+// describeLink returns a short description of a link for use in violation messages.
+func describeLink(l *Link) string {
+	origin, target := "?", "?"
+	if !isNilLinkable(l.Origin) {
+		origin = l.Origin.nodeName()
+	}
+	if !isNilLinkable(l.Target) {
+		target = l.Target.nodeName()
+	}
+	return fmt.Sprintf("link from %q to %q", origin, target)
+}
+
+// untitledSubgraphViolations reports every subgraph in the flowchart tree that has no title.
+func untitledSubgraphViolations(f *Flowchart) []Violation {
+	var violations []Violation
+	walkFlowchart(f, func(f *Flowchart, path []string) {
+		for _, subgraph := range f.Subgraphs {
+			if subgraph.Title == nil || *subgraph.Title == "" {
+				violations = append(violations, Violation{
+					Code:    ErrMissingTitle,
+					Path:    path,
+					Element: subgraph,
+					Message: "subgraph has no title",
+				})
+			}
+		}
+	})
+	return violations
+}
+
+// duplicateNameViolations reports every node name and subgraph title that is already used by a
+// node or subgraph earlier in the flowchart tree.
+func duplicateNameViolations(f *Flowchart) []Violation {
+	var violations []Violation
+	seen := make(map[string]bool)
+	walkFlowchart(f, func(f *Flowchart, path []string) {
+		for _, node := range f.Nodes {
+			if seen[node.name] {
+				violations = append(violations, Violation{
+					Code:    ErrDuplicateName,
+					Path:    path,
+					Element: node,
+					Message: fmt.Sprintf("node name %q is used more than once", node.name),
+				})
+			}
+			seen[node.name] = true
+		}
+		for _, subgraph := range f.Subgraphs {
+			if subgraph.Title == nil {
+				continue
+			}
+			if seen[*subgraph.Title] {
+				violations = append(violations, Violation{
+					Code:    ErrDuplicateName,
+					Path:    path,
+					Element: subgraph,
+					Message: fmt.Sprintf("subgraph title %q is used more than once", *subgraph.Title),
+				})
+			}
+			seen[*subgraph.Title] = true
+		}
+	})
+	return violations
+}
@@ -0,0 +1,159 @@
+package flowchart
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidationError(t *testing.T) {
+	node := &Node{name: "A"}
+	err := newValidationError(
+		Violation{Code: ErrInvalidName, Path: []string{"Outer", ""}, Element: node, Message: `node "A" has an invalid mermaid name`},
+		Violation{Code: ErrDuplicateName, Message: `node name "B" is used more than once`},
+	)
+
+	expected := `Outer > (untitled): node "A" has an invalid mermaid name; node name "B" is used more than once`
+	if diff := cmp.Diff(expected, err.Error()); diff != "" {
+		t.Errorf("Error() mismatch (-expected +got):\n%s", diff)
+	}
+	for _, code := range []error{ErrInvalidName, ErrDuplicateName} {
+		if !errors.Is(err, code) {
+			t.Errorf("errors.Is(err, %v) = false, expected true", code)
+		}
+	}
+	if errors.Is(err, ErrMissingTitle) {
+		t.Errorf("errors.Is(err, %v) = true, expected false", ErrMissingTitle)
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("errors.As(err, *ValidationError) = false, expected true")
+	}
+	if validationErr.Violations[0].Element != node {
+		t.Errorf("Violations[0].Element = %v, expected the offending node", validationErr.Violations[0].Element)
+	}
+	var violation Violation
+	if !errors.As(err, &violation) || violation.Code != ErrInvalidName {
+		t.Errorf("errors.As(err, Violation) = %v, expected the first violation", violation)
+	}
+
+	if newValidationError() != nil {
+		t.Errorf("newValidationError() with no violations = non-nil, expected nil")
+	}
+}
+
+func TestFlowchart_AddErrors(t *testing.T) {
+	chart := &Flowchart{Nodes: []*Node{{name: "A"}}}
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode error
+	}{
+		{"AddNode with repeated name", chart.AddNode(&Node{name: "A"}), ErrDuplicateName},
+		{"AddSubgraph without title", chart.AddSubgraph(&Flowchart{}), ErrMissingTitle},
+		{"AddSubgraph with repeated title", chart.AddSubgraph(&Flowchart{Title: pointTo("A")}), ErrDuplicateName},
+		{"AddLink without origin", chart.AddLink(Link{Target: chart.Nodes[0]}), ErrMissingEndpoint},
+		{"AddClassDef with invalid name", chart.AddClassDef("a b", Style{}), ErrInvalidStyleClass},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validationErr *ValidationError
+			if !errors.As(tt.err, &validationErr) {
+				t.Fatalf("error %v is not a *ValidationError", tt.err)
+			}
+			if !errors.Is(tt.err, tt.expectedCode) {
+				t.Errorf("errors.Is(%v, %v) = false, expected true", tt.err, tt.expectedCode)
+			}
+		})
+	}
+}
+
+func TestViolationLocations(t *testing.T) {
+	inner := &Flowchart{
+		Title: pointTo("Inner"),
+		Nodes: []*Node{{name: "A"}, {name: " "}},
+		Links: []Link{{Origin: &Node{name: "A"}}},
+	}
+	untitled := &Flowchart{Nodes: []*Node{{name: "B"}}}
+	chart := &Flowchart{
+		Title:     pointTo("Root"),
+		Nodes:     []*Node{{name: "A"}},
+		Subgraphs: []*Flowchart{{Title: pointTo("Outer"), Subgraphs: []*Flowchart{inner, untitled}}},
+	}
+
+	tests := []struct {
+		name       string
+		violations []Violation
+		expected   []Violation
+	}{
+		{
+			name:       "untitled subgraphs",
+			violations: untitledSubgraphViolations(chart),
+			expected:   []Violation{{Code: ErrMissingTitle, Path: []string{"Outer"}, Element: untitled, Message: "subgraph has no title"}},
+		},
+		{
+			name:       "duplicate names",
+			violations: duplicateNameViolations(chart),
+			expected: []Violation{{
+				Code: ErrDuplicateName, Path: []string{"Outer", "Inner"}, Element: inner.Nodes[0],
+				Message: `node name "A" is used more than once`,
+			}},
+		},
+		{
+			name:       "incomplete links",
+			violations: integrityViolations(chart, renderIntegrity),
+			expected: []Violation{{
+				Code: ErrMissingEndpoint, Path: []string{"Outer", "Inner"}, Element: &inner.Links[0],
+				Message: `link from "A" to "?" has no origin or target`,
+			}},
+		},
+		{
+			name:       "mermaid names",
+			violations: mermaidNameViolations(chart),
+			expected: []Violation{
+				{Code: ErrMissingTitle, Path: []string{"Outer"}, Element: untitled, Message: "subgraph has no title"},
+				{Code: ErrInvalidName, Path: []string{"Outer", "Inner"}, Element: inner.Nodes[1], Message: `node " " has an invalid mermaid name`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.violations) != len(tt.expected) {
+				t.Fatalf("got %d violations, expected %d: %v", len(tt.violations), len(tt.expected), tt.violations)
+			}
+			for i, got := range tt.violations {
+				expected := tt.expected[i]
+				// Codes and elements are compared by identity, as the editor relies on them to find the element.
+				if got.Code != expected.Code || got.Element != expected.Element {
+					t.Errorf("violation %d = {%v, %v}, expected {%v, %v}", i, got.Code, got.Element, expected.Code, expected.Element)
+				}
+				if diff := cmp.Diff(expected.Path, got.Path); diff != "" {
+					t.Errorf("violation %d Path mismatch (-expected +got):\n%s", i, diff)
+				}
+				if diff := cmp.Diff(expected.Message, got.Message); diff != "" {
+					t.Errorf("violation %d Message mismatch (-expected +got):\n%s", i, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestIDCollisionViolations(t *testing.T) {
+	// "A B" and "AB_<hash>" cannot both be given an id once "AB" keeps its own.
+	colliding := &Node{name: "AB_" + nameHash("A B")}
+	chart := &Flowchart{Nodes: []*Node{{name: "A B"}, {name: "AB"}, colliding}}
+
+	got := idCollisionViolations(chart, mermaidID)
+	if len(got) != 1 || got[0].Code != ErrCollidingID || got[0].Element != colliding {
+		t.Errorf("idCollisionViolations() = %v, expected one ErrCollidingID violation for %q", got, colliding.name)
+	}
+
+	resolvable := &Flowchart{Nodes: []*Node{{name: "A B"}, {name: "AB"}}}
+	if got := idCollisionViolations(resolvable, mermaidID); len(got) != 0 {
+		t.Errorf("idCollisionViolations() = %v, expected none for collisions resolved by hashing", got)
+	}
+}